@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Icon palettes of config.yaml
+var palettes = []string{"default", "colorblind"}
+
+// accessibilityConfig makes notifications easy to tell apart without
+// color and to follow with a screen reader
+type accessibilityConfig struct {
+	Enabled bool   `yaml:"enabled"` // high-contrast icons, the type in every title and long toasts
+	Speak   bool   `yaml:"speak"`   // also read every notification aloud
+	Palette string `yaml:"palette"` // icon colors, see palettes
+}
+
+// validate checks the palette
+func (c accessibilityConfig) validate() error {
+	if c.Palette != "" && !slices.Contains(palettes, c.Palette) {
+		return fmt.Errorf("invalid accessibility palette %q.%s Valid palettes are: %s",
+			c.Palette, suggest.DidYouMean(c.Palette, palettes, ""), strings.Join(palettes, ", "))
+	}
+	return nil
+}
+
+// apply marks n for accessible display
+func (c accessibilityConfig) apply(n *Notification) {
+	n.Accessible = n.Accessible || c.Enabled
+	n.Speak = n.Speak || c.Speak
+	if c.Palette == notify.IconColorblind {
+		n.Palette = c.Palette
+	}
+}
+
+// iconStyle returns the style of n's icon. Accessible notifications get
+// the high-contrast one, as do all while Windows uses a high contrast theme.
+func (n *Notification) iconStyle() string {
+	switch {
+	case n.Accessible || highContrastMode():
+		return notify.IconHighContrast
+	case n.Palette == notify.IconColorblind:
+		return notify.IconColorblind
+	}
+	return notify.IconDefault
+}
+
+// typedTitle starts the title with the notification type, so it is
+// announced without relying on the icon's color
+func typedTitle(nType, title string) string {
+	name := typeTitle(nType)
+	if strings.HasPrefix(strings.ToLower(title), strings.ToLower(name)) {
+		return title
+	}
+	return name + ": " + title
+}
+
+// spokenText returns what --speak reads aloud. Private notifications only
+// read their title, as anyone nearby can hear it.
+func spokenText(n *Notification) string {
+	title := typedTitle(n.Type, n.Title)
+	if n.Private || n.Message == "" {
+		return title
+	}
+	return title + ". " + oneLine(n.Message, 500)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// highContrastMode is only available on Windows
+func highContrastMode() bool {
+	return false
+}
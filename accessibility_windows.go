@@ -0,0 +1,26 @@
+package main
+
+import "unsafe"
+
+var procSystemParametersInfoW = user32.NewProc("SystemParametersInfoW")
+
+// SystemParametersInfo action and HIGHCONTRAST flag
+const (
+	spiGetHighContrast = 0x42
+	hcfHighContrastOn  = 0x1
+)
+
+// highContrastInfo is HIGHCONTRAST
+type highContrastInfo struct {
+	Size          uint32
+	Flags         uint32
+	DefaultScheme *uint16
+}
+
+// highContrastMode reports whether a Windows high contrast theme is on
+func highContrastMode() bool {
+	var info highContrastInfo
+	info.Size = uint32(unsafe.Sizeof(info))
+	ok, _, _ := procSystemParametersInfoW.Call(spiGetHighContrast, uintptr(info.Size), uintptr(unsafe.Pointer(&info)), 0)
+	return ok != 0 && info.Flags&hcfHighContrastOn != 0
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Acknowledged notifications stay listed by 'notify pending --all' this
+// long, and pending ones are dropped after ackExpiry
+const (
+	ackKeep   = 7 * 24 * time.Hour
+	ackExpiry = 30 * 24 * time.Hour
+)
+
+// ackRecord tracks a notification sent with --require-ack
+type ackRecord struct {
+	Type    string    `json:"type"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+	Acked   time.Time `json:"acked,omitzero"`
+}
+
+// addPendingAck stores a new pending acknowledgment, replacing any earlier
+// notification with the same id
+func addPendingAck(id string, n *Notification) error {
+	return updateState(func(s *State) error {
+		if s.Acks == nil {
+			s.Acks = map[string]*ackRecord{}
+		}
+		s.Acks[id] = &ackRecord{
+			Type:    n.Type,
+			Title:   n.Title,
+			Message: n.storedMessage(),
+			Created: time.Now(),
+		}
+		return nil
+	})
+}
+
+// expired reports whether an ack is no longer kept
+func (a *ackRecord) expired(now time.Time) bool {
+	if !a.Acked.IsZero() {
+		return now.Sub(a.Acked) > ackKeep
+	}
+	return now.Sub(a.Created) > ackExpiry
+}
+
+// acknowledge marks a notification as done, returning false if it was
+// already acknowledged
+func acknowledge(id string) (bool, error) {
+	changed := false
+	err := updateState(func(s *State) error {
+		record, ok := s.Acks[id]
+		if !ok {
+			return fmt.Errorf("no notification with ack id %q", id)
+		}
+		if record.Acked.IsZero() {
+			record.Acked = time.Now()
+			changed = true
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// runAck implements "notify ack ID..."
+func runAck(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showAckHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("usage: notify ack ID...")
+	}
+
+	for _, id := range words {
+		changed, err := acknowledge(id)
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Printf("Acknowledged %s\n", id)
+		} else {
+			fmt.Printf("%s was already acknowledged\n", id)
+		}
+	}
+	return nil
+}
+
+// runPending implements "notify pending"
+func runPending(args []string) error {
+	all := false
+
+	flags := []cliFlag{
+		{Name: "all", Bool: true, Set: func(v string) (err error) { all, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showAckHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	now := time.Now()
+	for id, record := range state.Acks {
+		if record.expired(now) {
+			continue
+		}
+		if all || record.Acked.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Println("Nothing pending")
+		return nil
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return state.Acks[ids[i]].Created.Before(state.Acks[ids[j]].Created)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSENT\tSTATE\tTYPE\tTITLE\tMESSAGE")
+	for _, id := range ids {
+		record := state.Acks[id]
+		status := "pending"
+		if !record.Acked.IsZero() {
+			status = "acked " + record.Acked.Format("15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, record.Created.Format("2006-01-02 15:04"), status,
+			record.Type, oneLine(record.Title, 30), oneLine(record.Message, 50))
+	}
+	return w.Flush()
+}
+
+func showAckHelp() {
+	fmt.Print(`Track notifications that must be acknowledged
+
+Usage:
+  notify MESSAGE --require-ack ID   Send a notification that stays pending until acknowledged
+  notify ack ID...                  Acknowledge notifications (clicking the toast does the same)
+  notify pending [--all]            List outstanding notifications (--all includes acknowledged)
+
+Acknowledged notifications are listed for a week, and notifications still
+pending after 30 days are dropped.
+
+Example:
+  notify "Rotate the backup tapes" --type warning --require-ack tapes
+  notify ack tapes
+`)
+}
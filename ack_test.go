@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+func TestAddPendingAckHidesPrivateMessages(t *testing.T) {
+	isolate(t)
+	n := &Notification{Notification: notify.Notification{Type: notify.Info, Title: "Login", Message: "code 123456", Private: true}}
+	if err := addPendingAck("a1", n); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := dataFile("state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "123456") {
+		t.Errorf("state holds the private message: %s", data)
+	}
+	if strings.Contains(string(data), `"acked"`) {
+		t.Errorf("state holds an acked time for a pending ack: %s", data)
+	}
+
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Acks["a1"]; got == nil || got.Message != "(private)" || !got.Acked.IsZero() {
+		t.Fatalf("ack = %+v, want a pending ack with the message hidden", got)
+	}
+}
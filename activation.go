@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+)
+
+// URI scheme registered for toast clicks. Windows starts notify with
+// activateArg and the URI, so clicks work after the sender has exited.
+const protocolScheme = "notify"
+
+// activateArg precedes the URI of a toast click in the handler's command
+const activateArg = "--activate"
+
+// activationActions are the actions of notify: URIs, see handleActivation
+var activationActions = []string{"ack", "continue", "history", "attachment"}
+
+// psRegisterProtocol registers the notify: URI scheme for the current user
+// so that clicking a toast launches $HANDLER with the activation URI
+const psRegisterProtocol = `
+$protocolKey = 'HKCU:\Software\Classes\` + protocolScheme + `'
+$command = '"' + $HANDLER + '" ` + activateArg + ` "%1"'
+if ((Get-ItemProperty -Path "$protocolKey\shell\open\command" -ErrorAction SilentlyContinue).'(default)' -ne $command) {
+    New-Item -Path "$protocolKey\shell\open\command" -Force | Out-Null
+    Set-ItemProperty -Path $protocolKey -Name '(default)' -Value 'URL:Notify'
+    Set-ItemProperty -Path $protocolKey -Name 'URL Protocol' -Value ''
+    Set-ItemProperty -Path "$protocolKey\shell\open\command" -Name '(default)' -Value $command
+}
+`
+
+// Windows starts notify's toast activator with this argument when a toast
+// is clicked, see serveActivator
+const activatorArg = "-ToastActivated"
+
+// activatorCLSID identifies notify's toast activator COM class, and must
+// match clsidActivator
+const activatorCLSID = "{B7D3A91E-5C42-4F0B-9E6A-2D8C41F7A530}"
+
+// psRegisterActivator registers $HANDLER as the COM server Windows starts
+// for clicks on toasts of $APP_ID. Unlike the notify: URI, the activator
+// also receives what was typed or chosen in the toast.
+const psRegisterActivator = `
+$activatorKey = 'HKCU:\Software\Classes\CLSID\` + activatorCLSID + `\LocalServer32'
+$activatorCommand = '"' + $HANDLER + '" ` + activatorArg + `'
+if ((Get-ItemProperty -Path $activatorKey -ErrorAction SilentlyContinue).'(default)' -ne $activatorCommand) {
+    New-Item -Path $activatorKey -Force | Out-Null
+    Set-ItemProperty -Path $activatorKey -Name '(default)' -Value $activatorCommand
+}
+$appKey = 'HKCU:\Software\Classes\AppUserModelId\' + $APP_ID
+if ((Get-ItemProperty -Path $appKey -ErrorAction SilentlyContinue).CustomActivator -ne '` + activatorCLSID + `') {
+    if (-not (Test-Path $appKey)) {
+        New-Item -Path $appKey -Force | Out-Null
+        New-ItemProperty -Path $appKey -Name DisplayName -Value $APP_ID -PropertyType String -Force | Out-Null
+    }
+    New-ItemProperty -Path $appKey -Name CustomActivator -Value '` + activatorCLSID + `' -PropertyType String -Force | Out-Null
+}
+`
+
+// activation is a click on a toast delivered to the toast activator
+type activation struct {
+	Args  string            // launch arguments of the toast or its button
+	Input map[string]string // typed text and choices by input id
+}
+
+// activationToast returns the id of the toast added by activationWithToast
+func activationToast(uri string) string {
+	_, query, _ := strings.Cut(uri, "?")
+	values, _ := url.ParseQuery(query)
+	return values.Get("toast")
+}
+
+// isActivatorLaunch reports whether Windows started notify as the toast
+// activator. COM adds -Embedding when starting servers on its own.
+func isActivatorLaunch(args []string) bool {
+	return len(args) > 0 && (strings.EqualFold(args[0], activatorArg) || strings.EqualFold(args[0], "-Embedding"))
+}
+
+// activationURI builds the URI launched when a toast is clicked
+func activationURI(action, arg string) string {
+	return protocolScheme + ":" + action + "/" + url.PathEscape(arg)
+}
+
+// activationWithToast adds the id of the toast to an activation URI, so
+// the click is recorded with the toast in history
+func activationWithToast(uri, id string) string {
+	return uri + "?toast=" + url.QueryEscape(id)
+}
+
+// isActivation reports whether arg is a notify: URI of a toast click with
+// one of the activationActions
+func isActivation(arg string) bool {
+	action, _, ok := activationParts(arg)
+	return ok && slices.Contains(activationActions, action)
+}
+
+// activationParts splits a notify: URI into its action and escaped
+// argument
+func activationParts(uri string) (action, arg string, ok bool) {
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok || !strings.EqualFold(scheme, protocolScheme) {
+		return "", "", false
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	action, arg, _ = strings.Cut(strings.Trim(rest, "/"), "/")
+	return action, arg, true
+}
+
+// activationScript returns the PowerShell lines registering this
+// executable as the toast activator and notify: handler of $APP_ID
+func activationScript() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return "$HANDLER = " + powershell.Quote(exe) + "\n" + psRegisterProtocol + psRegisterActivator, nil
+}
+
+// handleActivation runs the action encoded in a notify: URI
+func handleActivation(uri string) error {
+	if !isActivation(uri) {
+		return fmt.Errorf("unknown activation %q", uri)
+	}
+	action, arg, _ := activationParts(uri)
+	arg, err := url.PathUnescape(arg)
+	if err != nil {
+		return fmt.Errorf("invalid activation %q: %w", uri, err)
+	}
+
+	switch action {
+	case "ack":
+		_, err := acknowledge(arg)
+		return err
+	case "continue":
+		return markClicked(arg)
+	case "history":
+		return showHistoryView(arg)
+	case "attachment":
+		return revealAttachment(arg)
+	}
+	return fmt.Errorf("unknown activation %q", uri)
+}
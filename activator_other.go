@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// serveActivator is only available on Windows
+func serveActivator(handle func(activation)) error {
+	return errors.New("the toast activator is only available on Windows")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	procCoRegisterClassObject = ole32.NewProc("CoRegisterClassObject")
+	procCoRevokeClassObject   = ole32.NewProc("CoRevokeClassObject")
+	procLstrlenW              = kernel32.NewProc("lstrlenW")
+)
+
+// COM server constants
+const (
+	comMultithreaded  = 0x0 // COINIT_MULTITHREADED
+	clsctxLocalServer = 0x4
+	regclsMultipleUse = 0x1
+	hrOK              = 0
+	hrNoInterface     = 0x80004002
+	hrNoAggregation   = 0x80040110
+
+	// The activator exits once no click arrived for this long
+	activatorIdle = 10 * time.Second
+)
+
+var (
+	// Must match activatorCLSID
+	clsidActivator       = syscall.GUID{Data1: 0xB7D3A91E, Data2: 0x5C42, Data3: 0x4F0B, Data4: [8]byte{0x9E, 0x6A, 0x2D, 0x8C, 0x41, 0xF7, 0xA5, 0x30}}
+	iidUnknown           = syscall.GUID{Data1: 0x00000000, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidClassFactory      = syscall.GUID{Data1: 0x00000001, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidActivationHandler = syscall.GUID{Data1: 0x53E31837, Data2: 0x6600, Data3: 0x4A81, Data4: [8]byte{0x93, 0x95, 0x75, 0xCF, 0xFE, 0x74, 0x6F, 0x94}}
+)
+
+// comServerObject is a COM object implemented by notify. Both objects
+// live for the whole process, so reference counting is a no-op.
+type comServerObject struct {
+	vtable *[5]uintptr
+}
+
+var (
+	activations = make(chan activation, 16)
+
+	// IClassFactory
+	activatorFactory = &comServerObject{vtable: &[5]uintptr{
+		syscall.NewCallback(factoryQueryInterface),
+		syscall.NewCallback(comAddRef),
+		syscall.NewCallback(comRelease),
+		syscall.NewCallback(factoryCreateInstance),
+		syscall.NewCallback(factoryLockServer),
+	}}
+
+	// INotificationActivationCallback
+	activatorCallback = &comServerObject{vtable: &[5]uintptr{
+		syscall.NewCallback(callbackQueryInterface),
+		syscall.NewCallback(comAddRef),
+		syscall.NewCallback(comRelease),
+		syscall.NewCallback(callbackActivate),
+	}}
+)
+
+// serveActivator registers the toast activator and hands each click
+// Windows delivers to handle, returning once clicks stop coming
+func serveActivator(handle func(activation)) error {
+	// COM calls must stay on the thread that initialized it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	procCoInitializeEx.Call(0, comMultithreaded)
+	defer procCoUninitialize.Call()
+
+	var cookie uint32
+	if hr, _, _ := procCoRegisterClassObject.Call(uintptr(unsafe.Pointer(&clsidActivator)), uintptr(unsafe.Pointer(activatorFactory)),
+		clsctxLocalServer, regclsMultipleUse, uintptr(unsafe.Pointer(&cookie))); hr != hrOK {
+		return fmt.Errorf("registering the toast activator (0x%08X)", uint32(hr))
+	}
+	defer procCoRevokeClassObject.Call(uintptr(cookie))
+
+	idle := time.NewTimer(activatorIdle)
+	for {
+		select {
+		case a := <-activations:
+			handle(a)
+			idle.Reset(activatorIdle)
+		case <-idle.C:
+			return nil
+		}
+	}
+}
+
+func factoryQueryInterface(this, iid, object uintptr) uintptr {
+	return queryInterface(this, iid, object, iidClassFactory)
+}
+
+func callbackQueryInterface(this, iid, object uintptr) uintptr {
+	return queryInterface(this, iid, object, iidActivationHandler)
+}
+
+// queryInterface hands out this for IUnknown and the object's interface
+func queryInterface(this, iid, object uintptr, implemented syscall.GUID) uintptr {
+	var requested syscall.GUID
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&requested)), iid, unsafe.Sizeof(requested))
+	if requested != iidUnknown && requested != implemented {
+		writePointer(object, 0)
+		return hrNoInterface
+	}
+	writePointer(object, this)
+	return hrOK
+}
+
+func comAddRef(this uintptr) uintptr  { return 1 }
+func comRelease(this uintptr) uintptr { return 1 }
+
+func factoryCreateInstance(this, outer, iid, object uintptr) uintptr {
+	if outer != 0 {
+		writePointer(object, 0)
+		return hrNoAggregation
+	}
+	return callbackQueryInterface(uintptr(unsafe.Pointer(activatorCallback)), iid, object)
+}
+
+func factoryLockServer(this, lock uintptr) uintptr { return hrOK }
+
+// callbackActivate receives a click on an RPC thread, copying its
+// arguments and NOTIFICATION_USER_INPUT_DATA key and value pairs
+func callbackActivate(this, app, args, data, count uintptr) uintptr {
+	a := activation{Args: wideString(args), Input: map[string]string{}}
+	for i := uintptr(0); i < count; i++ {
+		var pair [2]uintptr
+		procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&pair)), data+i*unsafe.Sizeof(pair), unsafe.Sizeof(pair))
+		a.Input[wideString(pair[0])] = wideString(pair[1])
+	}
+	select {
+	case activations <- a:
+	default:
+		// Dropped rather than blocking Windows when clicks pile up
+	}
+	return hrOK
+}
+
+// writePointer stores a pointer at an address given by COM
+func writePointer(at, p uintptr) {
+	procRtlMoveMemory.Call(at, uintptr(unsafe.Pointer(&p)), unsafe.Sizeof(p))
+}
+
+// wideString copies a NUL-terminated UTF-16 string owned by COM
+func wideString(p uintptr) string {
+	if p == 0 {
+		return ""
+	}
+	n, _, _ := procLstrlenW.Call(p)
+	if n == 0 {
+		return ""
+	}
+	text := make([]uint16, n)
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&text[0])), p, n*2)
+	return syscall.UTF16ToString(text)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// cliFlag describes a single command line option
+type cliFlag struct {
+	Name string             // option name without leading dashes
+	Bool bool               // option is a switch and takes no value
+	Set  func(string) error // applies the option value
+}
+
+// optionPattern matches the arguments that are options; others starting
+// with a dash, like -5 or ->, are words of the message
+var optionPattern = regexp.MustCompile(`^--?[A-Za-z][A-Za-z0-9-]*(=|$)`)
+
+// parseArgs applies the options in args and returns the positional words.
+// Options may be written as --name value, --name=value or -name; everything
+// after a bare "--" is treated as positional.
+func parseArgs(args []string, flags []cliFlag) ([]string, error) {
+	var words []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			words = append(words, args[i+1:]...)
+			break
+		}
+
+		if !optionPattern.MatchString(arg) {
+			words = append(words, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value, hasValue = name[:idx], name[idx+1:], true
+		}
+
+		flag := findFlag(flags, name)
+		if flag == nil {
+			hint := suggest.DidYouMean(name, flagNames(flags), "--")
+			if hint == "" {
+				hint = " Words starting with a dash go after --, e.g. -- " + arg
+			}
+			return nil, fmt.Errorf("unknown option: --%s.%s", name, hint)
+		}
+
+		if flag.Bool {
+			if !hasValue {
+				value = "true"
+			}
+		} else if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option --%s requires a value", flag.Name)
+			}
+			i++
+			value = args[i]
+		}
+
+		if err := flag.Set(value); err != nil {
+			return nil, fmt.Errorf("invalid value for --%s: %v", flag.Name, err)
+		}
+	}
+
+	return words, nil
+}
+
+// findFlag looks up a flag by name
+func findFlag(flags []cliFlag, name string) *cliFlag {
+	for i := range flags {
+		if flags[i].Name == name {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+// flagNames lists the names of all flags, for suggestions
+func flagNames(flags []cliFlag) []string {
+	names := make([]string, 0, len(flags))
+	for _, f := range flags {
+		if len(f.Name) > 1 {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		words []string
+		set   map[string]string // values given to the flags
+		err   string            // part of the error, empty when valid
+	}{
+		{name: "words", args: []string{"Build", "finished"}, words: []string{"Build", "finished"}},
+		{name: "value after name", args: []string{"done", "--type", "success"},
+			words: []string{"done"}, set: map[string]string{"type": "success"}},
+		{name: "value after equals", args: []string{"--type=error", "failed"},
+			words: []string{"failed"}, set: map[string]string{"type": "error"}},
+		{name: "single dash", args: []string{"-type", "warning", "low"},
+			words: []string{"low"}, set: map[string]string{"type": "warning"}},
+		{name: "switch", args: []string{"--urgent", "now"},
+			words: []string{"now"}, set: map[string]string{"urgent": "true"}},
+		{name: "switch with value", args: []string{"--urgent=false", "later"},
+			words: []string{"later"}, set: map[string]string{"urgent": "false"}},
+		{name: "empty value", args: []string{"--type=", "x"},
+			words: []string{"x"}, set: map[string]string{"type": ""}},
+		{name: "negative number", args: []string{"Temperature", "-5", "degrees"}, words: []string{"Temperature", "-5", "degrees"}},
+		{name: "arrow", args: []string{"a", "->", "b"}, words: []string{"a", "->", "b"}},
+		{name: "lone dash", args: []string{"-"}, words: []string{"-"}},
+		{name: "after double dash", args: []string{"--type", "info", "--", "-verbose", "--type", "x"},
+			words: []string{"-verbose", "--type", "x"}, set: map[string]string{"type": "info"}},
+		{name: "unknown option", args: []string{"--tpye", "x"}, err: "unknown option: --tpye. Did you mean --type?"},
+		{name: "unknown dash word", args: []string{"-verbose"}, err: "go after --, e.g. -- -verbose"},
+		{name: "missing value", args: []string{"x", "--type"}, err: "option --type requires a value"},
+		{name: "rejected value", args: []string{"--fail", "x"}, err: "invalid value for --fail: no"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := map[string]string{}
+			flag := func(name string, isBool bool) cliFlag {
+				return cliFlag{Name: name, Bool: isBool, Set: func(v string) error { set[name] = v; return nil }}
+			}
+			flags := []cliFlag{
+				flag("type", false),
+				flag("urgent", true),
+				{Name: "fail", Set: func(string) error { return errors.New("no") }},
+			}
+
+			words, err := parseArgs(tt.args, flags)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseArgs() = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(words, tt.words) {
+				t.Errorf("words = %q, want %q", words, tt.words)
+			}
+			for name, want := range tt.set {
+				if got, ok := set[name]; !ok || got != want {
+					t.Errorf("--%s = %q (set: %t), want %q", name, got, ok, want)
+				}
+			}
+			if len(set) != len(tt.set) {
+				t.Errorf("flags set = %v, want %v", set, tt.set)
+			}
+		})
+	}
+}
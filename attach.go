@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Attachments travel base64 encoded inside the notification, so they are
+// kept small enough to hold in memory
+const maxAttachmentSize = 8 << 20
+
+// Received attachments are removed after a week
+const attachmentLifetime = 7 * 24 * time.Hour
+
+// readAttachment reads the file of --attach
+func readAttachment(path string) (*notify.Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("--attach %s is a directory", path)
+	}
+	if info.Size() > maxAttachmentSize {
+		return nil, fmt.Errorf("--attach %s is %d bytes, the maximum is %d", path, info.Size(), maxAttachmentSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &notify.Attachment{Name: filepath.Base(path), Data: data}
+	return a, a.Validate()
+}
+
+// attachmentDir returns the directory of the attachment stored under id
+func attachmentDir(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid attachment id %q", id)
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "attachments", id), nil
+}
+
+// save stores the attachment under id, removing those older than
+// attachmentLifetime, and returns its path
+func saveAttachment(a *notify.Attachment, id string) (string, error) {
+	dir, err := attachmentDir(id)
+	if err != nil {
+		return "", err
+	}
+	pruneAttachments(filepath.Dir(dir))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, a.Name)
+	return path, os.WriteFile(path, a.Data, 0600)
+}
+
+// pruneAttachments removes stored attachments older than attachmentLifetime
+func pruneAttachments(root string) {
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > attachmentLifetime {
+			os.RemoveAll(filepath.Join(root, entry.Name()))
+		}
+	}
+}
+
+// storedAttachment returns the path of the attachment stored under id
+func storedAttachment(id string) (string, error) {
+	dir, err := attachmentDir(id)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		return "", fmt.Errorf("attachment %s was not found, attachments are kept for %d days", id, int(attachmentLifetime.Hours()/24))
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
+
+// revealAttachment selects the attachment stored under id in Explorer.
+// Clicks never open it directly, as it may be a program from another machine.
+func revealAttachment(id string) error {
+	path, err := storedAttachment(id)
+	if err != nil {
+		return err
+	}
+	// Explorer exits with 1 even when it succeeds
+	var exit *exec.ExitError
+	if err := exec.Command("explorer.exe", "/select,"+path).Run(); err != nil && !errors.As(err, &exit) {
+		return err
+	}
+	return nil
+}
+
+// hostAttachment stores an attachment for GET /attachments and returns its
+// URL under the relay's --public-url
+func (s *relayServer) hostAttachment(a *notify.Attachment) (string, error) {
+	id := newToken()
+	if _, err := saveAttachment(a, id); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(s.publicURL, "/") + "/attachments/" + id + "/" + url.PathEscape(a.Name), nil
+}
+
+// handleAttachment serves GET /attachments/{id}/{name}. The random id in
+// the link is what grants access, so links work without the token.
+func (s *relayServer) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	path, err := storedAttachment(r.PathValue("id"))
+	if err != nil || filepath.Base(path) != r.PathValue("name") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, path)
+}
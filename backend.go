@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/internal/wsl"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Values of --backend and the backend setting of config.yaml; auto picks
+// the backend of the platform. The backends are notify.Backends.
+var validBackends = []string{"auto", "toast", "desktop", "console"}
+
+// parseBackend checks a --backend value
+func parseBackend(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || slices.Contains(validBackends, s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid backend %q.%s Valid backends are: %s",
+		s, suggest.DidYouMean(s, validBackends, ""), strings.Join(validBackends, ", "))
+}
+
+// backendName returns the name of the backend a --backend value selects,
+// the platform's for "" and auto
+func backendName(name string) string {
+	if _, ok := notify.Backends[name]; ok {
+		return name
+	}
+	return notify.PlatformBackend()
+}
+
+// backendFor returns the backend a --backend value selects
+func backendFor(name string) notify.Backend {
+	return notify.Backends[backendName(name)]
+}
+
+// displayNotification shows a notification with its backend, after any
+// injected delay or failure, tracing the rendering and delivery below
+// parent
+func displayNotification(n *Notification, parent *span) error {
+	name := backendName(n.Backend)
+	if err := n.Inject.apply(name, parent); err != nil {
+		return err
+	}
+	return showWith(name, n, parent)
+}
+
+// printNotification prints a notification that couldn't be shown with the
+// console backend
+func printNotification(n *Notification, parent *span) error {
+	return showWith("console", n, parent)
+}
+
+// showWith shows a notification with the named backend
+func showWith(name string, n *Notification, parent *span) error {
+	render := startSpan(parent, "render")
+	t, err := n.toast(name)
+	render.finish(err)
+	if err != nil {
+		return err
+	}
+
+	deliver := startSpan(parent, "deliver", "notify.target", name)
+	action, err := (&notify.Local{Backend: name}).Show(t)
+	deliver.finish(err)
+	if err != nil {
+		return err
+	}
+	if action != "" {
+		n.interaction = &toastInteraction{Action: action}
+	}
+
+	switch {
+	case n.Wait && (name == "console" || name == "desktop" && runtime.GOOS == "darwin"):
+		fmt.Fprintf(os.Stderr, "Warning: --wait is not supported by the %s backend, clicks can't be reported\n", name)
+	case name == "toast" && n.Session == "":
+		// Focus Assist sends toasts straight to Action Center
+		if mode := focusAssist(); mode != "" {
+			fmt.Fprintf(os.Stderr, "Warning: Focus Assist is on (%s), the notification went to Action Center without a banner\n", mode)
+		}
+	}
+	return nil
+}
+
+// toast returns the toast the notify package builds for a notification
+// with the named backend, completed with the settings of config.yaml,
+// attachments and click actions
+func (n *Notification) toast(backend string) (*notify.Toast, error) {
+	// Servers and toasts read the icon after notify exits, so it is the
+	// cached copy or the one config.yaml sets
+	public := n.Notification
+	public.Icon, public.IconStyle = typeIcon(n.Type), n.iconStyle()
+	t, warnings := public.Toast(backend)
+	for _, err := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	t.IconAlt = typeTitle(n.Type)
+	t.Sound = cmp.Or(n.Sound, notify.DefaultSound(n.Type))
+	t.Volume, t.Duck, t.Long = n.Volume, n.Duck, n.Accessible
+	t.Source, t.Collection = n.Source, n.Collection
+	t.Progress, t.Wait, t.Session = n.Progress, n.Wait, n.Session
+	if backend == "console" {
+		return t, nil
+	}
+
+	// Clicking an ack-required toast acknowledges it
+	if backend == "toast" && n.AckID != "" && n.OnClick == "" {
+		n.OnClick = activationURI("ack", n.AckID)
+		n.ClickHint = "Click to acknowledge"
+	}
+
+	// Attachments are saved for the click showing them, and images are
+	// shown in the notification
+	if n.Attachment != nil {
+		path, err := saveAttachment(n.Attachment, n.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: showing the notification without its attachment: %v\n", err)
+		} else {
+			if n.Image == "" && n.Attachment.ToastImage() {
+				t.Image, t.ImageAlt = path, n.Attachment.Name
+			}
+			if backend == "toast" && n.OnClick == "" && n.Link == "" {
+				n.OnClick = activationURI("attachment", n.ID)
+				n.ClickHint = "Click to show " + n.Attachment.Name
+			}
+		}
+	}
+
+	// The Windows host can't run notify inside WSL for clicks
+	if n.OnClick != "" && wsl.Detected() {
+		n.OnClick = ""
+	}
+	if backend == "toast" && n.OnClick != "" {
+		handler, err := activationScript()
+		if err != nil {
+			return nil, err
+		}
+		t.Launch, t.Handler = activationWithToast(n.OnClick, n.ID), handler
+	}
+	t.ClickHint = n.ClickHint
+
+	// Accessible notifications announce their type
+	if n.Accessible {
+		t.Title = typedTitle(n.Type, n.Title)
+	}
+	if n.Speak {
+		t.Speech = spokenText(n)
+	}
+	return t, nil
+}
+
+// updateToast shows the progress of the notification with the tag of n,
+// returning false when it no longer exists because it was dismissed or
+// expired
+func updateToast(n *Notification, p *notify.Progress, sequence uint32) (bool, error) {
+	return backendFor(n.Backend).Update(&notify.Update{
+		App:      n.App,
+		Tag:      n.Tag,
+		Group:    n.Group,
+		Progress: p,
+		Sequence: sequence,
+		Session:  n.Session,
+	})
+}
+
+// removeToast removes the notification with the tag of n
+func removeToast(n *Notification) error {
+	return backendFor(n.Backend).Remove(&notify.Toast{App: n.App, Tag: n.Tag, Group: n.Group, Session: n.Session})
+}
+
+// checkBackend checks that the named backend can show notifications
+func checkBackend(name string) error {
+	return backendFor(name).Check()
+}
+
+// printInstead reports whether a notification that couldn't be shown is
+// printed by the console backend instead, e.g. over SSH or in a session
+// without a desktop. That is when the platform's backend was used, isn't
+// merely blocked by the notification settings, and someone is watching
+// the terminal. Injected failures are never printed, so they can be
+// observed.
+func printInstead(n *Notification, err error) bool {
+	if n.Backend != "" && n.Backend != "auto" || errors.Is(err, notify.ErrBlocked) || errors.Is(err, errInjected) {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
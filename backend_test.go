@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      string
+	}{
+		{"", "", ""},
+		{"auto", "auto", ""},
+		{" Console ", "console", ""},
+		{"toast", "toast", ""},
+		{"desktop", "desktop", ""},
+		{"consle", "", "Did you mean console?"},
+		{"pager", "", "Valid backends are: auto, toast, desktop, console"},
+	}
+	for _, tt := range tests {
+		got, err := parseBackend(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseBackend(%q) = %v, want an error containing %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseBackend(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestBackendName(t *testing.T) {
+	for in, want := range map[string]string{
+		"":        notify.PlatformBackend(),
+		"auto":    notify.PlatformBackend(),
+		"console": "console",
+		"toast":   "toast",
+		"desktop": "desktop",
+	} {
+		if got := backendName(in); got != want {
+			t.Errorf("backendName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrintInstead(t *testing.T) {
+	failed := errors.New("no desktop session")
+	tests := []struct {
+		name    string
+		backend string
+		err     error
+	}{
+		{"chosen backend", "toast", failed},
+		{"blocked", "", &notify.BlockedError{Setting: "DisabledForUser"}},
+		{"injected failure", "auto", (&faultInjection{Fail: "error"}).apply("toast", nil)},
+		{"injected block", "", (&faultInjection{Fail: "blocked"}).apply("toast", nil)},
+	}
+	for _, tt := range tests {
+		n := &Notification{Backend: tt.backend}
+		if printInstead(n, tt.err) {
+			t.Errorf("%s: printInstead() = true, want the error to be returned", tt.name)
+		}
+	}
+}
+
+func TestDisplayNotification(t *testing.T) {
+	isolate(t)
+	backend := fakeBackend(t, "fake")
+
+	n, err := buildNotification(t, "Tests", "passed", "--type", "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Backend = "fake"
+	if err := displayNotification(n, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	shown := backend.Shown()
+	if len(shown) != 1 {
+		t.Fatalf("backend showed %d toasts, want 1", len(shown))
+	}
+	toast := shown[0]
+	if toast.Type != "success" || toast.Title != "Success" || toast.Message != "Tests passed" {
+		t.Errorf("toast = %q %q %q, want the notification", toast.Type, toast.Title, toast.Message)
+	}
+	if toast.Icon == "" || toast.Sound != notify.DefaultSound("success") {
+		t.Errorf("toast icon %q, sound %q, want the icon and sound of the type", toast.Icon, toast.Sound)
+	}
+}
+
+func TestDisplayNotificationInjected(t *testing.T) {
+	isolate(t)
+	backend := fakeBackend(t, "fake")
+
+	tests := []struct {
+		fail string
+		want error
+	}{
+		{"error", errInjected},
+		{"blocked", notify.ErrBlocked},
+	}
+	for _, tt := range tests {
+		n, err := buildNotification(t, "x", "--inject-fail", tt.fail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.Backend = "fake"
+		if err := displayNotification(n, nil); !errors.Is(err, tt.want) {
+			t.Errorf("--inject-fail %s: displayNotification() = %v, want %v", tt.fail, err, tt.want)
+		}
+	}
+	if len(backend.Shown()) != 0 {
+		t.Fatal("a notification failing by injection was shown")
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	isolate(t)
+	backend := fakeBackend(t, "fake")
+
+	n, err := buildNotification(t, "Deployed", "--category", "deploys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Backend = "fake"
+	if err := sendNotification(n); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.Shown()) != 1 {
+		t.Fatalf("backend showed %d toasts, want 1", len(backend.Shown()))
+	}
+
+	// A chosen backend that fails isn't printed instead
+	backend.Err = errors.New("server went away")
+	n, _ = buildNotification(t, "Deployed again")
+	n.Backend = "fake"
+	if err := sendNotification(n); err == nil || !strings.Contains(err.Error(), "server went away") {
+		t.Fatalf("sendNotification() = %v, want the backend's error", err)
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Status != statusDelivered || entries[1].Status != statusFailed {
+		t.Fatalf("history = %+v, want a delivered and a failed entry", entries)
+	}
+}
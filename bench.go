@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GOMAXPROCS suffix of benchmark names, left out so baselines carry over
+// between machines
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Regressions listed in the notification
+const maxBenchLines = 3
+
+// benchRun holds the measurements of a run of go test -bench
+type benchRun struct {
+	pkg     string
+	results map[string]map[string][]float64 // by package and benchmark, then unit
+	lines   []string                        // kept as the baseline, readable by benchstat
+}
+
+func newBenchRun() *benchRun {
+	return &benchRun{results: map[string]map[string][]float64{}}
+}
+
+// read takes a line of go test -bench output
+func (r *benchRun) read(line string) {
+	if pkg, ok := strings.CutPrefix(line, "pkg: "); ok {
+		r.pkg = strings.TrimSpace(pkg)
+		r.lines = append(r.lines, line)
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return
+	}
+	if _, err := strconv.Atoi(fields[1]); err != nil {
+		return
+	}
+
+	name := strings.TrimSpace(r.pkg + " " + procsSuffix.ReplaceAllString(fields[0], ""))
+	for i := 2; i+1 < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return
+		}
+		if r.results[name] == nil {
+			r.results[name] = map[string][]float64{}
+		}
+		r.results[name][fields[i+1]] = append(r.results[name][fields[i+1]], value)
+	}
+	r.lines = append(r.lines, line)
+}
+
+// readFrom reads the output of go test -bench, passing it through
+func (r *benchRun) readFrom(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fmt.Println(scanner.Text())
+		r.read(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// benchChange is how much a benchmark changed in one unit
+type benchChange struct {
+	Name     string
+	Unit     string
+	Old, New float64
+	Worse    float64 // relative change for the worse, negative when better
+}
+
+func (c benchChange) String() string {
+	sign := "+"
+	if c.New < c.Old {
+		sign = ""
+	}
+	return fmt.Sprintf("%s %s %s%.0f%% (%s → %s)", c.Name, c.Unit, sign, (c.New/c.Old-1)*100,
+		formatBenchValue(c.Old, c.Unit), formatBenchValue(c.New, c.Unit))
+}
+
+// compareBench compares the medians of the benchmarks both runs have,
+// returning those that got worse and better by more than threshold percent
+// and the number compared. Throughput units ending in /s are better higher.
+func compareBench(old, current *benchRun, threshold float64) (worse, better []benchChange, compared int) {
+	for name, units := range current.results {
+		before, ok := old.results[name]
+		if !ok {
+			continue
+		}
+		compared++
+		for unit, values := range units {
+			if len(before[unit]) == 0 {
+				continue
+			}
+			c := benchChange{Name: benchDisplayName(name), Unit: unit, Old: median(before[unit]), New: median(values)}
+			if c.Old == 0 {
+				continue
+			}
+			c.Worse = c.New/c.Old - 1
+			if strings.HasSuffix(unit, "/s") {
+				c.Worse = -c.Worse
+			}
+			switch {
+			case c.Worse*100 > threshold:
+				worse = append(worse, c)
+			case -c.Worse*100 > threshold:
+				better = append(better, c)
+			}
+		}
+	}
+	slices.SortFunc(worse, func(a, b benchChange) int { return -cmpFloat(a.Worse, b.Worse) })
+	slices.SortFunc(better, func(a, b benchChange) int { return cmpFloat(a.Worse, b.Worse) })
+	return worse, better, compared
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// median returns the middle of the measurements, which a slow outlier
+// run doesn't move
+func median(values []float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// benchDisplayName leaves the package out of a benchmark's name
+func benchDisplayName(name string) string {
+	if _, bench, ok := strings.Cut(name, " "); ok {
+		return bench
+	}
+	return name
+}
+
+// formatBenchValue shows times per operation as durations
+func formatBenchValue(v float64, unit string) string {
+	if unit == "ns/op" && v >= 1 {
+		return time.Duration(math.Round(v)).String()
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64) + " " + unit
+}
+
+// benchBaseline returns the file storing the baseline named name, by
+// default that of the working directory
+func benchBaseline(name string) (string, error) {
+	if name == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256([]byte(dir))
+		name = fmt.Sprintf("%s-%x", filepath.Base(dir), sum[:4])
+	}
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid baseline name %q", name)
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "bench")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".txt"), nil
+}
+
+// readBench reads a file of go test -bench output
+func readBench(path string) (*benchRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	run := newBenchRun()
+	for _, line := range strings.Split(string(data), "\n") {
+		run.read(strings.TrimRight(line, "\r"))
+	}
+	return run, nil
+}
+
+// runBench implements "notify bench", which compares go test -bench
+// results with the previous run and notifies when benchmarks regress
+func runBench(args []string) error {
+	opts := newNotifyOptions()
+	opts.Type = "warning"
+	threshold := 10.0
+	name, baseline := "", ""
+	update := false
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "threshold", Set: func(v string) (err error) { threshold, err = parsePercent(v); return }},
+		cliFlag{Name: "name", Set: func(v string) error { name = v; return nil }},
+		cliFlag{Name: "baseline", Set: func(v string) error { baseline = v; return nil }},
+		cliFlag{Name: "update", Bool: true, Set: func(v string) (err error) { update, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showBenchHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	stored, err := benchBaseline(name)
+	if err != nil {
+		return err
+	}
+	if baseline == "" {
+		baseline = stored
+	}
+
+	// Read the benchmarks from the command, or from stdin without one
+	current := newBenchRun()
+	var runErr error
+	if len(words) > 0 {
+		cmd := exec.Command(words[0], words[1:]...)
+		cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		if err := current.readFrom(stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: reading the benchmarks: %v\n", err)
+		}
+		runErr = cmd.Wait()
+	} else if err := current.readFrom(os.Stdin); err != nil {
+		return err
+	}
+	if len(current.results) == 0 {
+		if runErr != nil {
+			return runErr
+		}
+		return fmt.Errorf("no benchmark results were read, run go test with -bench")
+	}
+
+	old, err := readBench(baseline)
+	if errors.Is(err, os.ErrNotExist) && baseline == stored {
+		fmt.Fprintf(os.Stderr, "notify: saved %d benchmarks as the baseline for the next run\n", len(current.results))
+		return errors.Join(runErr, saveBench(stored, current))
+	}
+	if err != nil {
+		return err
+	}
+
+	worse, better, compared := compareBench(old, current, threshold)
+	fmt.Fprintf(os.Stderr, "notify: compared %d benchmarks with the baseline, %d changes for the worse and %d for the better beyond %g%%\n",
+		compared, len(worse), len(better), threshold)
+	for _, c := range worse {
+		fmt.Fprintf(os.Stderr, "  worse   %s\n", c)
+	}
+	for _, c := range better {
+		fmt.Fprintf(os.Stderr, "  better  %s\n", c)
+	}
+
+	// A regression stays the baseline's to beat until fixed or accepted
+	if (len(worse) == 0 && runErr == nil && baseline == stored) || update {
+		if err := saveBench(stored, current); err != nil {
+			return err
+		}
+	}
+	if len(worse) == 0 {
+		return runErr
+	}
+
+	regressed := map[string]bool{}
+	for _, c := range worse {
+		regressed[c.Name] = true
+	}
+	if opts.Title == "" {
+		opts.Title = "Benchmarks regressed"
+	}
+	lines := []string{fmt.Sprintf("%d of %d benchmarks regressed by more than %g%%", len(regressed), compared, threshold)}
+	for _, c := range worse[:min(len(worse), maxBenchLines)] {
+		lines = append(lines, c.String())
+	}
+	if len(worse) > maxBenchLines {
+		lines = append(lines, fmt.Sprintf("and %d more", len(worse)-maxBenchLines))
+	}
+	n, err := opts.build(strings.Join(lines, "\n"))
+	if err != nil {
+		return err
+	}
+	if err := sendNotification(n); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return runErr
+	}
+	return fmt.Errorf("%d benchmarks regressed", len(regressed))
+}
+
+// saveBench stores a run as the baseline
+func saveBench(path string, run *benchRun) error {
+	return os.WriteFile(path, []byte(strings.Join(run.lines, "\n")+"\n"), 0600)
+}
+
+func showBenchHelp() {
+	fmt.Print(`Notify when Go benchmarks regress
+
+Runs the command, or reads stdin without one, and compares the go test
+-bench results with the baseline saved by an earlier run. Benchmarks whose
+median time, memory, allocations or custom metric got worse by more than
+--threshold are notified; throughput units ending in /s are better higher.
+Use -count=5 or more for stable medians. Output is passed through.
+
+The baseline of each directory is saved by the first run and replaced by
+every run without regressions, so a regression stays reported until it is
+fixed or accepted with --update. Baselines are go test -bench output that
+benchstat also reads. notify bench exits with 1 when benchmarks regressed.
+
+Usage:
+  notify bench [OPTIONS] [--] COMMAND [ARGS]
+  COMMAND | notify bench [OPTIONS]
+
+Options:
+  --threshold PERCENT  Change counted as a regression (default: 10%)
+  --name NAME          Baseline to use (default: one per directory)
+  --baseline FILE      Compare with this file instead, e.g. saved from main
+  --update             Save this run as the baseline even if it regressed
+  Plus the notification options of 'notify --help', e.g. --title.
+
+Examples:
+  notify bench -- go test -run '^$' -bench . -count 5 ./...
+  go test -bench . -count 5 | notify bench --threshold 5%
+  notify bench --baseline main.txt -- go test -bench Parse -count 10
+`)
+}
@@ -8,7 +8,7 @@ set GOARCH=amd64
 :: Build with optimizations
 :: -ldflags "-s -w" strips debug information and DWARF symbol table
 :: -trimpath removes file system paths from binary
-go build -ldflags="-s -w" -trimpath -o notify.exe main.go
+go build -ldflags="-s -w" -trimpath -o notify.exe .
 
 if %ERRORLEVEL% EQU 0 (
     echo Build successful! notify.exe created.
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Bundle format version, stored in the manifest
+const bundleVersion = 1
+
+// PBKDF2 iterations for the credentials key
+const bundleIterations = 600000
+
+// bundleManifest describes an export bundle. It is the first entry of
+// the archive.
+type bundleManifest struct {
+	Version     int       `json:"version"`
+	Created     time.Time `json:"created"`
+	Host        string    `json:"host,omitempty"`
+	Files       []string  `json:"files"`
+	Credentials []string  `json:"credentials,omitempty"`
+	Encrypted   bool      `json:"encrypted,omitempty"` // credentials are encrypted with a password
+	Salt        []byte    `json:"salt,omitempty"`
+}
+
+// isCredential reports whether a data file holds a secret key
+func isCredential(name string) bool {
+	return strings.HasSuffix(name, ".key")
+}
+
+// isTransient reports whether a data file only matters while notify runs
+func isTransient(name string) bool {
+	return strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".tmp") ||
+		strings.HasPrefix(name, "clicked-")
+}
+
+// runExport implements "notify export FILE"
+func runExport(args []string) error {
+	credentials := false
+	history := true
+	password := os.Getenv("NOTIFY_EXPORT_PASSWORD")
+
+	flags := []cliFlag{
+		{Name: "credentials", Bool: true, Set: func(v string) (err error) { credentials, err = parseStrictBool(v); return }},
+		{Name: "history", Set: func(v string) (err error) { history, err = parseStrictBool(v); return }},
+		{Name: "password", Set: func(v string) error { password = v; return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showBundleHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return fmt.Errorf("usage: notify export FILE.tar.gz [--credentials] [--password PASSWORD]")
+	}
+
+	dir, err := dataDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	manifest := &bundleManifest{Version: bundleVersion, Created: time.Now()}
+	manifest.Host, _ = os.Hostname()
+
+	var key []byte
+	if credentials && password != "" {
+		manifest.Encrypted = true
+		manifest.Salt = make([]byte, 16)
+		rand.Read(manifest.Salt)
+		if key, err = bundleKey(password, manifest.Salt); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case !entry.Type().IsRegular() || isTransient(name):
+		case isCredential(name):
+			if credentials {
+				manifest.Credentials = append(manifest.Credentials, name)
+			}
+		case name == "history.jsonl" && !history:
+		default:
+			manifest.Files = append(manifest.Files, name)
+		}
+	}
+
+	out, err := os.Create(words[0])
+	if err != nil {
+		return err
+	}
+	if err := writeBundle(out, dir, manifest, key); err != nil {
+		out.Close()
+		os.Remove(words[0])
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d files to %s\n", len(manifest.Files)+len(manifest.Credentials), words[0])
+	if len(manifest.Credentials) > 0 && !manifest.Encrypted {
+		fmt.Fprintln(os.Stderr, "Warning: the bundle contains unencrypted keys, use --password to encrypt them")
+	}
+	return nil
+}
+
+// writeBundle writes the manifest and the files it lists as a tar.gz
+func writeBundle(w io.Writer, dir string, manifest *bundleManifest, key []byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := add("manifest.json", data); err != nil {
+		return err
+	}
+
+	for _, name := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := add("files/"+name, data); err != nil {
+			return err
+		}
+	}
+	for _, name := range manifest.Credentials {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			if data, err = bundleSeal(key, data); err != nil {
+				return err
+			}
+		}
+		if err := add("credentials/"+name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// runImport implements "notify import FILE"
+func runImport(args []string) error {
+	force := false
+	password := os.Getenv("NOTIFY_EXPORT_PASSWORD")
+
+	flags := []cliFlag{
+		{Name: "force", Bool: true, Set: func(v string) (err error) { force, err = parseStrictBool(v); return }},
+		{Name: "password", Set: func(v string) error { password = v; return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showBundleHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return fmt.Errorf("usage: notify import FILE.tar.gz [--force] [--password PASSWORD]")
+	}
+
+	manifest, files, err := readBundle(words[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", words[0], err)
+	}
+
+	// Decrypt everything before writing anything, so a wrong password
+	// leaves the current setup untouched
+	if manifest.Encrypted && len(manifest.Credentials) > 0 {
+		if password == "" {
+			return fmt.Errorf("the bundle's keys are encrypted, pass --password or set NOTIFY_EXPORT_PASSWORD")
+		}
+		key, err := bundleKey(password, manifest.Salt)
+		if err != nil {
+			return err
+		}
+		for _, name := range manifest.Credentials {
+			if files[name], err = bundleOpen(key, files[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	dir, err := dataDir()
+	if err != nil {
+		return err
+	}
+	names := append(append([]string(nil), manifest.Files...), manifest.Credentials...)
+	if !force {
+		var existing []string
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				existing = append(existing, name)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("%s already exist in %s, use --force to replace them", strings.Join(existing, ", "), dir)
+		}
+	}
+
+	unlock, err := lockData("state")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path+".tmp", files[name], 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Imported %d files from %s (exported on %s", len(names), words[0], manifest.Created.Local().Format("2006-01-02"))
+	if manifest.Host != "" {
+		fmt.Printf(" by %s", manifest.Host)
+	}
+	fmt.Println(")")
+	return nil
+}
+
+// readBundle reads the manifest and the files of a bundle, by name
+func readBundle(file string) (*bundleManifest, map[string][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *bundleManifest
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, 256<<20))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if header.Name == "manifest.json" {
+			manifest = &bundleManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+
+		// Only plain names are restored, never paths
+		dir, name := path.Split(header.Name)
+		if (dir == "files/" || dir == "credentials/") && name != "" && name == filepath.Base(name) {
+			files[name] = data
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, errors.New("not a notify bundle, manifest.json is missing")
+	}
+	if manifest.Version > bundleVersion {
+		return nil, nil, fmt.Errorf("bundle version %d is newer than this notify supports", manifest.Version)
+	}
+	for _, name := range append(append([]string(nil), manifest.Files...), manifest.Credentials...) {
+		if _, ok := files[name]; !ok || name != filepath.Base(name) || isTransient(name) {
+			return nil, nil, fmt.Errorf("bundle is missing or has an invalid %s", name)
+		}
+	}
+	return manifest, files, nil
+}
+
+// bundleKey derives the credentials key from a password
+func bundleKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, bundleIterations, 32)
+}
+
+// bundleSeal encrypts data with AES-GCM, prefixing the nonce
+func bundleSeal(key, data []byte) ([]byte, error) {
+	aead, err := bundleCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// bundleOpen decrypts data sealed by bundleSeal
+func bundleOpen(key, data []byte) ([]byte, error) {
+	aead, err := bundleCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted key is truncated")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong password or damaged bundle")
+	}
+	return plain, nil
+}
+
+func bundleCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func showBundleHelp() {
+	fmt.Print(`Move notify's setup between machines
+
+Export writes notify's configuration and state (config.yaml, mutes,
+pending acknowledgments, history and everything else in notify's config
+directory) to a tar.gz bundle; import restores it on another machine.
+
+Keys from 'notify keygen' are only exported with --credentials. Give a
+--password to encrypt them in the bundle (PBKDF2 and AES-GCM).
+
+Usage:
+  notify export FILE.tar.gz [--credentials] [--password PASSWORD] [--history false]
+  notify import FILE.tar.gz [--password PASSWORD] [--force]
+
+Options:
+  --credentials        Include keys
+  --password PASSWORD  Encrypt or decrypt the keys (default: $NOTIFY_EXPORT_PASSWORD)
+  --history BOOLEAN    Include the history (default: true)
+  --force              Replace existing files when importing
+
+Examples:
+  notify export notify-setup.tar.gz --credentials --password "correct horse"
+  notify import notify-setup.tar.gz --password "correct horse"
+`)
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Busy times are looked up this far ahead, so a refresh interval up to
+// this long keeps the cache useful
+const calendarWindow = 24 * time.Hour
+
+// calendarConfig chooses the calendar whose meetings defer notifications
+type calendarConfig struct {
+	ICS          string        `yaml:"ics"`                 // URL or path of an iCalendar feed
+	GraphCommand string        `yaml:"graph_token_command"` // prints a Microsoft Graph access token
+	Refresh      time.Duration `yaml:"refresh"`             // how long busy times are cached
+	Show         []string      `yaml:"show"`                // types shown even in meetings
+}
+
+// calendarCache holds the busy times last read from the calendar
+type calendarCache struct {
+	Source  string     `json:"source"` // the ICS URL or graph, to notice a changed config
+	Fetched time.Time  `json:"fetched"`
+	Busy    []busyTime `json:"busy"`
+}
+
+// busyTime is a meeting or other busy event
+type busyTime struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// enabled reports whether a calendar is configured
+func (c *calendarConfig) enabled() bool {
+	return c.ICS != "" || c.GraphCommand != ""
+}
+
+// source identifies the configured calendar
+func (c *calendarConfig) source() string {
+	if c.ICS != "" {
+		return c.ICS
+	}
+	return "graph"
+}
+
+// calendarBusy reports whether the calendar has a busy event at now. Busy
+// times are cached in the state for the refresh interval; when they can't
+// be read the stale ones are used, with a warning.
+func calendarBusy(c *calendarConfig, now time.Time) bool {
+	state, err := loadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return false
+	}
+
+	cache := state.Calendar
+	if cache == nil || cache.Source != c.source() || now.Sub(cache.Fetched) > c.Refresh {
+		busy, err := fetchBusyTimes(c, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read the calendar: %v\n", err)
+		} else {
+			cache = &calendarCache{Source: c.source(), Fetched: now, Busy: busy}
+			if err := updateState(func(s *State) error { s.Calendar = cache; return nil }); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+	if cache == nil || cache.Source != c.source() {
+		return false
+	}
+
+	for _, b := range cache.Busy {
+		if !now.Before(b.Start) && now.Before(b.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// showsDuringMeetings reports whether notifications of type t skip the
+// calendar
+func (c *calendarConfig) showsDuringMeetings(t string) bool {
+	return slices.Contains(c.Show, t)
+}
+
+// fetchBusyTimes reads the busy events from shortly before now until the
+// end of the calendar window
+func fetchBusyTimes(c *calendarConfig, now time.Time) ([]busyTime, error) {
+	from, to := now.Add(-calendarWindow), now.Add(calendarWindow)
+	if c.ICS != "" {
+		data, err := readICS(c.ICS)
+		if err != nil {
+			return nil, err
+		}
+		return parseICS(data, from, to)
+	}
+	return graphBusyTimes(c.GraphCommand, from, to)
+}
+
+// readICS downloads an iCalendar feed or reads it from a file
+func readICS(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+}
+
+// icsEvent is the part of a VEVENT needed to know when it is busy
+type icsEvent struct {
+	props    map[string]icsProp
+	exdates  []icsProp
+	uid      string
+	instance *icsProp // RECURRENCE-ID of a modified occurrence
+}
+
+// icsProp is a content line: NAME;PARAM=value:VALUE
+type icsProp struct {
+	Params map[string]string
+	Value  string
+}
+
+// parseICS returns the busy events of an iCalendar feed overlapping
+// from-to. Recurring events are expanded for daily, weekly (with BYDAY),
+// monthly and yearly rules; other rules only count their first occurrence.
+func parseICS(data []byte, from, to time.Time) ([]busyTime, error) {
+	return icsOccurrences(data, from, to, (*icsEvent).busy)
+}
+
+// icsOccurrences returns the occurrences of the events of a feed that
+// keep accepts, overlapping from-to
+func icsOccurrences(data []byte, from, to time.Time, keep func(*icsEvent) bool) ([]busyTime, error) {
+	var events []*icsEvent
+	var current *icsEvent
+
+	for _, line := range unfoldICS(string(data)) {
+		name, prop := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && prop.Value == "VEVENT":
+			current = &icsEvent{props: map[string]icsProp{}}
+		case name == "END" && prop.Value == "VEVENT" && current != nil:
+			events = append(events, current)
+			current = nil
+		case current == nil:
+		case name == "EXDATE":
+			current.exdates = append(current.exdates, prop)
+		case name == "UID":
+			current.uid = prop.Value
+		case name == "RECURRENCE-ID":
+			current.instance = &prop
+		default:
+			current.props[name] = prop
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("calendar ends inside an event")
+	}
+
+	// Modified occurrences replace the occurrence of their series
+	moved := map[string]bool{}
+	for _, e := range events {
+		if e.instance != nil {
+			if t, err := parseICSTime(*e.instance); err == nil {
+				moved[e.uid+"/"+strconv.FormatInt(t.Unix(), 10)] = true
+			}
+		}
+	}
+
+	var busy []busyTime
+	for _, e := range events {
+		if !keep(e) {
+			continue
+		}
+		start, err := parseICSTime(e.props["DTSTART"])
+		if err != nil {
+			return nil, fmt.Errorf("event %q: %w", e.props["SUMMARY"].Value, err)
+		}
+		length, err := e.length(start)
+		if err != nil {
+			return nil, fmt.Errorf("event %q: %w", e.props["SUMMARY"].Value, err)
+		}
+
+		skip := map[int64]bool{}
+		for _, ex := range e.exdates {
+			for _, value := range strings.Split(ex.Value, ",") {
+				if t, err := parseICSTime(icsProp{Params: ex.Params, Value: value}); err == nil {
+					skip[t.Unix()] = true
+				}
+			}
+		}
+
+		for _, occurrence := range expandRRule(e.props["RRULE"].Value, start, to) {
+			if skip[occurrence.Unix()] || (e.instance == nil && moved[e.uid+"/"+strconv.FormatInt(occurrence.Unix(), 10)]) {
+				continue
+			}
+			if end := occurrence.Add(length); end.After(from) && occurrence.Before(to) {
+				busy = append(busy, busyTime{Start: occurrence, End: end})
+			}
+		}
+	}
+	return busy, nil
+}
+
+// busy reports whether the event blocks time: not cancelled, and not
+// marked free or tentative
+func (e *icsEvent) busy() bool {
+	if e.props["STATUS"].Value == "CANCELLED" || e.props["TRANSP"].Value == "TRANSPARENT" {
+		return false
+	}
+	switch e.props["X-MICROSOFT-CDO-BUSYSTATUS"].Value {
+	case "FREE", "TENTATIVE":
+		return false
+	}
+	_, ok := e.props["DTSTART"]
+	return ok
+}
+
+// length returns how long each occurrence of the event lasts
+func (e *icsEvent) length(start time.Time) (time.Duration, error) {
+	if end, ok := e.props["DTEND"]; ok {
+		t, err := parseICSTime(end)
+		if err != nil {
+			return 0, err
+		}
+		return t.Sub(start), nil
+	}
+	if d, ok := e.props["DURATION"]; ok {
+		return parseICSDuration(d.Value)
+	}
+	if e.props["DTSTART"].Params["VALUE"] == "DATE" {
+		return 24 * time.Hour, nil
+	}
+	return 0, nil
+}
+
+// unfoldICS splits a feed into content lines, joining folded ones
+func unfoldICS(data string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseICSLine splits a content line into its upper case name and the
+// property
+func parseICSLine(line string) (string, icsProp) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	prop := icsProp{Params: map[string]string{}, Value: value}
+	for _, param := range parts[1:] {
+		if key, val, ok := strings.Cut(param, "="); ok {
+			prop.Params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), prop
+}
+
+// parseICSTime parses a DATE or DATE-TIME value. Times without a usable
+// TZID are taken as local time, as Outlook feeds use Windows zone names.
+func parseICSTime(p icsProp) (time.Time, error) {
+	value := strings.TrimSpace(p.Value)
+	loc := time.Local
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	switch {
+	case len(value) == 8:
+		return time.ParseInLocation("20060102", value, loc)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return t, nil
+}
+
+// parseICSDuration parses a duration like PT1H30M or P1D
+func parseICSDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(s, "+"), "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var total time.Duration
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case units[c] != 0 && number != "":
+			n, _ := strconv.Atoi(number)
+			total += time.Duration(n) * units[c]
+			number = ""
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+	return total, nil
+}
+
+// expandRRule returns the occurrences of an event starting at start,
+// until the first one after to
+func expandRRule(rule string, start, to time.Time) []time.Time {
+	if rule == "" {
+		return []time.Time{start}
+	}
+
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		if key, val, ok := strings.Cut(part, "="); ok {
+			parts[strings.ToUpper(key)] = val
+		}
+	}
+
+	interval, _ := strconv.Atoi(parts["INTERVAL"])
+	interval = max(interval, 1)
+	count, _ := strconv.Atoi(parts["COUNT"])
+	until := to
+	if parts["UNTIL"] != "" {
+		if t, err := parseICSTime(icsProp{Params: map[string]string{}, Value: parts["UNTIL"]}); err == nil && t.Before(to) {
+			until = t
+		}
+	}
+
+	// The weekdays of a weekly rule, in the order they occur in a week
+	// starting on Monday
+	var days []int
+	if parts["FREQ"] == "WEEKLY" && parts["BYDAY"] != "" {
+		for _, day := range strings.Split(parts["BYDAY"], ",") {
+			if i := slices.Index([]string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}, day); i >= 0 {
+				days = append(days, i)
+			}
+		}
+		slices.Sort(days)
+	}
+
+	// BY rules other than a weekly BYDAY aren't supported
+	for key := range parts {
+		if strings.HasPrefix(key, "BY") && (key != "BYDAY" || days == nil) {
+			return []time.Time{start}
+		}
+	}
+
+	var occurrences []time.Time
+	add := func(t time.Time) bool {
+		if t.After(until) || (count > 0 && len(occurrences) >= count) {
+			return false
+		}
+		occurrences = append(occurrences, t)
+		return true
+	}
+
+	// Bounded so a corrupt rule can't loop for long
+	for i := 0; i < 100000; i++ {
+		switch parts["FREQ"] {
+		case "DAILY":
+			if !add(start.AddDate(0, 0, i*interval)) {
+				return occurrences
+			}
+		case "WEEKLY":
+			if days == nil {
+				if !add(start.AddDate(0, 0, 7*i*interval)) {
+					return occurrences
+				}
+				continue
+			}
+			monday := start.AddDate(0, 0, 7*i*interval-(int(start.Weekday())+6)%7)
+			for _, day := range days {
+				if t := monday.AddDate(0, 0, day); !t.Before(start) && !add(t) {
+					return occurrences
+				}
+			}
+		case "MONTHLY":
+			if !add(start.AddDate(0, i*interval, 0)) {
+				return occurrences
+			}
+		case "YEARLY":
+			if !add(start.AddDate(i*interval, 0, 0)) {
+				return occurrences
+			}
+		default:
+			return []time.Time{start}
+		}
+	}
+	return occurrences
+}
+
+// graphBusyTimes reads the busy events of the signed in user's calendar
+// from Microsoft Graph, with a token printed by the configured command
+func graphBusyTimes(tokenCommand string, from, to time.Time) ([]busyTime, error) {
+	out, err := shellCommand(tokenCommand).Output()
+	if err != nil {
+		return nil, fmt.Errorf("graph_token_command: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return nil, fmt.Errorf("graph_token_command printed no token")
+	}
+
+	query := url.Values{
+		"startDateTime": {from.UTC().Format(time.RFC3339)},
+		"endDateTime":   {to.UTC().Format(time.RFC3339)},
+		"$select":       {"start,end,showAs,isCancelled"},
+		"$top":          {"200"},
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://graph.microsoft.com/v1.0/me/calendarView?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Prefer", `outlook.timezone="UTC"`)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Microsoft Graph: %s", resp.Status)
+	}
+
+	type graphTime struct {
+		DateTime string `json:"dateTime"`
+	}
+	var result struct {
+		Value []struct {
+			Start       graphTime `json:"start"`
+			End         graphTime `json:"end"`
+			ShowAs      string    `json:"showAs"`
+			IsCancelled bool      `json:"isCancelled"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Microsoft Graph: %w", err)
+	}
+
+	var busy []busyTime
+	for _, event := range result.Value {
+		if event.IsCancelled || (event.ShowAs != "busy" && event.ShowAs != "oof") {
+			continue
+		}
+		start, err1 := time.Parse("2006-01-02T15:04:05", event.Start.DateTime[:min(19, len(event.Start.DateTime))])
+		end, err2 := time.Parse("2006-01-02T15:04:05", event.End.DateTime[:min(19, len(event.End.DateTime))])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		busy = append(busy, busyTime{Start: start, End: end})
+	}
+	return busy, nil
+}
+
+// shellCommand runs a command line with the platform's shell
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/c", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Ways of handling notifications during calls
+var validCallModes = []string{"defer", "silent"}
+
+// callConfig chooses what happens to notifications during calls
+type callConfig struct {
+	Mode string   `yaml:"mode"` // defer, silent or empty to ignore calls
+	Show []string `yaml:"show"` // types shown as usual during calls
+	Apps []string `yaml:"apps"` // more programs that hold calls, e.g. obs64.exe
+}
+
+// meetingApp is a program whose use of the microphone means a call
+type meetingApp struct {
+	match string // part of the executable path or package name, lower case
+	name  string
+}
+
+// Programs recognized as calls when they use the microphone. Browsers
+// count too, for Google Meet and other calls in the browser.
+var meetingApps = []meetingApp{
+	{"zoom", "Zoom"},
+	{"teams", "Teams"},
+	{"webex", "Webex"},
+	{"slack", "Slack"},
+	{"skype", "Skype"},
+	{"discord", "Discord"},
+	{"chrome.exe", "a browser call"},
+	{"msedge.exe", "a browser call"},
+	{"firefox.exe", "a browser call"},
+	{"brave.exe", "a browser call"},
+}
+
+// validate checks the call settings
+func (c *callConfig) validate() error {
+	if c.Mode != "" && !slices.Contains(validCallModes, c.Mode) {
+		return fmt.Errorf("invalid calls mode %q.%s Valid modes are: %s",
+			c.Mode, suggest.DidYouMean(c.Mode, validCallModes, ""), strings.Join(validCallModes, ", "))
+	}
+	for _, t := range c.Show {
+		if !isValidType(t) {
+			return fmt.Errorf("invalid type %q in calls show.%s", t, suggest.DidYouMean(t, notify.Types, ""))
+		}
+	}
+	return nil
+}
+
+// inCall returns the program of a call that applies to notifications of
+// type t in the given mode, or "". An empty t asks about any
+// notification.
+func (c *callConfig) inCall(mode, t string) string {
+	if c.Mode != mode || slices.Contains(c.Show, t) {
+		return ""
+	}
+	apps := slices.Clone(meetingApps)
+	for _, app := range c.Apps {
+		apps = append(apps, meetingApp{match: strings.ToLower(app), name: app})
+	}
+	return activeCall(apps)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// activeCall is only available on Windows
+func activeCall(apps []meetingApp) string {
+	return ""
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Windows records which programs use the microphone; a program that
+// started and hasn't stopped using it has a zero LastUsedTimeStop
+const micConsentStore = `Software\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\microphone`
+
+// activeCall returns the name of the meeting app using the microphone,
+// or "" when there is no call
+func activeCall(apps []meetingApp) string {
+	var running map[string]bool
+	for _, user := range microphoneUsers() {
+		for _, app := range apps {
+			if !strings.Contains(user, app.match) {
+				continue
+			}
+
+			// Desktop programs are recorded by path with # for \, and a
+			// crashed one can leave its entry behind
+			if i := strings.LastIndex(user, "#"); i >= 0 {
+				if running == nil {
+					running = runningPrograms()
+				}
+				if !running[user[i+1:]] {
+					continue
+				}
+			}
+			return app.name
+		}
+	}
+	return ""
+}
+
+// microphoneUsers returns the lower case names of the apps and program
+// paths using the microphone right now
+func microphoneUsers() []string {
+	var users []string
+	for _, path := range []string{micConsentStore, micConsentStore + `\NonPackaged`} {
+		key, err := openKey(syscall.HKEY_CURRENT_USER, path)
+		if err != nil {
+			continue
+		}
+		for i := uint32(0); ; i++ {
+			name := make([]uint16, 1024)
+			length := uint32(len(name))
+			if syscall.RegEnumKeyEx(key, i, &name[0], &length, nil, nil, nil, nil) != nil {
+				break
+			}
+			sub := syscall.UTF16ToString(name[:length])
+			if microphoneInUse(key, sub) {
+				users = append(users, strings.ToLower(sub))
+			}
+		}
+		syscall.RegCloseKey(key)
+	}
+	return users
+}
+
+// microphoneInUse reads the usage times of one app
+func microphoneInUse(parent syscall.Handle, name string) bool {
+	key, err := openKey(parent, name)
+	if err != nil {
+		return false
+	}
+	defer syscall.RegCloseKey(key)
+	return queryUint64(key, "LastUsedTimeStart") != 0 && queryUint64(key, "LastUsedTimeStop") == 0
+}
+
+func openKey(parent syscall.Handle, path string) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var key syscall.Handle
+	err = syscall.RegOpenKeyEx(parent, p, 0, syscall.KEY_READ, &key)
+	return key, err
+}
+
+// queryUint64 reads a QWORD value, returning 0 when it is missing
+func queryUint64(key syscall.Handle, name string) uint64 {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return 0
+	}
+	var value uint64
+	var typ uint32
+	size := uint32(unsafe.Sizeof(value))
+	if syscall.RegQueryValueEx(key, p, nil, &typ, (*byte)(unsafe.Pointer(&value)), &size) != nil || typ != syscall.REG_QWORD {
+		return 0
+	}
+	return value
+}
+
+// runningPrograms returns the lower case executable names of the running
+// processes
+func runningPrograms() map[string]bool {
+	running := map[string]bool{}
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return running
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		running[strings.ToLower(syscall.UTF16ToString(entry.ExeFile[:]))] = true
+	}
+	return running
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Line added to notifications sent from CI jobs, unless ci.message is set
+const defaultCIMessage = `{{.Provider}}{{with .Workflow}} {{.}}{{end}}{{with .Number}} #{{.}}{{end}}` +
+	`{{with .Branch}} on {{.}}{{end}}{{with .Commit}} at {{short .}}{{end}}`
+
+// ciConfig adds the build a notification was sent from when notify runs
+// in a CI job
+type ciConfig struct {
+	Enabled bool   `yaml:"enabled"` // on by default
+	Message string `yaml:"message"` // template of the line added to the message
+}
+
+// ciBuild is the CI job notify runs in, read from the environment the CI
+// system sets. These are the fields of ci.message templates.
+type ciBuild struct {
+	Provider   string // GitHub Actions, GitLab CI, Jenkins, CircleCI or Buildkite
+	URL        string // of the run or job
+	Repository string
+	Branch     string
+	Commit     string // full hash, {{short .Commit}} abbreviates it
+	Workflow   string // workflow, job or pipeline name
+	Number     string // of the run or build
+}
+
+// Functions of ci.message templates, besides those of relay formats
+var ciFuncs = template.FuncMap{
+	"short": func(commit string) string { return commit[:min(len(commit), 7)] },
+}
+
+// detectCI returns the CI job notify runs in, or nil outside CI
+func detectCI() *ciBuild {
+	env := os.Getenv
+	switch {
+	case env("GITHUB_RUN_ID") != "":
+		return &ciBuild{
+			Provider:   "GitHub Actions",
+			URL:        cmp.Or(env("GITHUB_SERVER_URL"), "https://github.com") + "/" + env("GITHUB_REPOSITORY") + "/actions/runs/" + env("GITHUB_RUN_ID"),
+			Repository: env("GITHUB_REPOSITORY"),
+			Branch:     cmp.Or(env("GITHUB_HEAD_REF"), env("GITHUB_REF_NAME")),
+			Commit:     env("GITHUB_SHA"),
+			Workflow:   env("GITHUB_WORKFLOW"),
+			Number:     env("GITHUB_RUN_NUMBER"),
+		}
+	case env("CI_JOB_URL") != "":
+		return &ciBuild{
+			Provider:   "GitLab CI",
+			URL:        env("CI_JOB_URL"),
+			Repository: env("CI_PROJECT_PATH"),
+			Branch:     cmp.Or(env("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), env("CI_COMMIT_REF_NAME")),
+			Commit:     env("CI_COMMIT_SHA"),
+			Workflow:   env("CI_JOB_NAME"),
+			Number:     env("CI_PIPELINE_IID"),
+		}
+	case env("CIRCLE_BUILD_URL") != "":
+		return &ciBuild{
+			Provider:   "CircleCI",
+			URL:        env("CIRCLE_BUILD_URL"),
+			Repository: strings.Trim(env("CIRCLE_PROJECT_USERNAME")+"/"+env("CIRCLE_PROJECT_REPONAME"), "/"),
+			Branch:     env("CIRCLE_BRANCH"),
+			Commit:     env("CIRCLE_SHA1"),
+			Workflow:   env("CIRCLE_JOB"),
+			Number:     env("CIRCLE_BUILD_NUM"),
+		}
+	case env("BUILDKITE_BUILD_URL") != "":
+		return &ciBuild{
+			Provider:   "Buildkite",
+			URL:        env("BUILDKITE_BUILD_URL"),
+			Repository: env("BUILDKITE_PIPELINE_SLUG"),
+			Branch:     env("BUILDKITE_BRANCH"),
+			Commit:     env("BUILDKITE_COMMIT"),
+			Workflow:   env("BUILDKITE_PIPELINE_NAME"),
+			Number:     env("BUILDKITE_BUILD_NUMBER"),
+		}
+	case env("BUILD_URL") != "":
+		// Jenkins, whose multibranch pipelines name the branch themselves
+		return &ciBuild{
+			Provider: "Jenkins",
+			URL:      env("BUILD_URL"),
+			Branch:   cmp.Or(env("CHANGE_BRANCH"), env("BRANCH_NAME"), strings.TrimPrefix(env("GIT_BRANCH"), "origin/")),
+			Commit:   env("GIT_COMMIT"),
+			Workflow: env("JOB_NAME"),
+			Number:   env("BUILD_NUMBER"),
+		}
+	}
+	return nil
+}
+
+// template parses ci.message
+func (c *ciConfig) template() (*template.Template, error) {
+	t, err := template.New("ci").Funcs(formatFuncs).Funcs(ciFuncs).Parse(cmp.Or(c.Message, defaultCIMessage))
+	if err != nil {
+		return nil, fmt.Errorf("ci message: %w", err)
+	}
+	return t, nil
+}
+
+// validate checks the message template
+func (c *ciConfig) validate() error {
+	_, err := c.template()
+	return err
+}
+
+// apply links a notification sent from a CI job to the build and adds a
+// line about it to the message. Relayed notifications come from elsewhere,
+// and notifications updated in place already have the line.
+func (c *ciConfig) apply(n *Notification) {
+	if !c.Enabled || n.Source != "" {
+		return
+	}
+	build := detectCI()
+	if build == nil {
+		return
+	}
+	if n.Link == "" && n.OnClick == "" {
+		n.Link = build.URL
+	}
+
+	t, err := c.template()
+	var line strings.Builder
+	if err == nil {
+		err = t.Execute(&line, build)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	text := oneLine(line.String(), 200)
+	if text != "" && !strings.HasSuffix(n.Message, text) {
+		n.Message = strings.TrimRight(n.Message, "\n") + "\n" + text
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+)
+
+// runClear implements "notify clear", removing notify's toasts from Action Center
+func runClear(args []string) error {
+	app := ""
+
+	flags := []cliFlag{
+		{Name: "app", Set: func(v string) error { app = v; return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showClearHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	script := powershell.Prelude + appScript(app) + `
+[Windows.UI.Notifications.ToastNotificationManager]::History.Clear($APP_ID)
+`
+	if _, err := powershell.Run(script); err != nil {
+		return err
+	}
+
+	fmt.Println("Action Center cleared")
+	return nil
+}
+
+func showClearHelp() {
+	fmt.Print(`Remove all of notify's notifications from Action Center
+
+Usage:
+  notify clear [--app NAME]
+
+Options:
+  --app NAME   Clear the notifications of an app set with --app
+`)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// clipboardWatch reports text copied to the clipboard that matches a
+// pattern, like a one-time code. Only the matched snippet is shown and
+// it is kept out of history.
+type clipboardWatch struct {
+	Patterns []clipboardPattern `yaml:"patterns"`
+	Apps     []string           `yaml:"apps"` // only inspect copies made in these programs, like outlook.exe
+}
+
+// clipboardPattern is a kind of text worth a notification
+type clipboardPattern struct {
+	Name  string `yaml:"name"`  // title of the notification
+	Match string `yaml:"match"` // regular expression
+}
+
+// clipboardContent is the text on the clipboard and where it came from
+type clipboardContent struct {
+	Text     string
+	App      string // program that copied it, like outlook.exe, if known
+	Excluded bool   // the program asked clipboard monitors to ignore it
+}
+
+// validate checks the clipboard watch settings
+func (c *clipboardWatch) validate() error {
+	for i, p := range c.Patterns {
+		if _, err := regexp.Compile(p.Match); p.Match == "" || err != nil {
+			return fmt.Errorf("watch clipboard patterns[%d]: match must be a regular expression: %v", i, cmp.Or(err, fmt.Errorf("it is empty")))
+		}
+	}
+	return nil
+}
+
+// watchClipboard inspects each new copy, skipping those from programs
+// outside the allowlist and those password managers mark as excluded
+// from monitoring. Copies are checked as they happen rather than every
+// watch interval.
+func watchClipboard(c clipboardWatch, deliver func(*notify.RelayMessage)) error {
+	last, err := clipboardSequence()
+	if err != nil {
+		return err
+	}
+	patterns := make([]*regexp.Regexp, len(c.Patterns))
+	for i, p := range c.Patterns {
+		patterns[i] = regexp.MustCompile(p.Match)
+	}
+	apps := make([]string, len(c.Apps))
+	for i, app := range c.Apps {
+		apps[i] = strings.ToLower(strings.TrimSpace(app))
+	}
+
+	go func() {
+		for range time.Tick(500 * time.Millisecond) {
+			seq, err := clipboardSequence()
+			if err != nil || seq == last {
+				continue
+			}
+			last = seq
+
+			content, err := readClipboard()
+			if err != nil || content.Excluded || content.Text == "" {
+				continue
+			}
+			if len(apps) > 0 && !slices.Contains(apps, content.App) {
+				continue
+			}
+			for i, re := range patterns {
+				match := re.FindString(content.Text)
+				if match == "" {
+					continue
+				}
+				message := oneLine(match, 200)
+				if content.App != "" {
+					message += "\nCopied in " + content.App
+				}
+				deliver(&notify.RelayMessage{
+					App: "Watch", Category: "clipboard", Type: "info", Tag: "clipboard", Private: true,
+					Title:   oneLine(cmp.Or(c.Patterns[i].Name, "Copied text"), notify.MaxTitleLength),
+					Message: message,
+				})
+				break
+			}
+		}
+	}()
+	return nil
+}
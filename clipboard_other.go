@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// clipboardSequence is only available on Windows
+func clipboardSequence() (uint32, error) {
+	return 0, errors.New("watching the clipboard is only available on Windows")
+}
+
+func readClipboard() (clipboardContent, error) {
+	return clipboardContent{}, errors.New("watching the clipboard is only available on Windows")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procGetClipboardSequenceNumber = user32.NewProc("GetClipboardSequenceNumber")
+	procOpenClipboard              = user32.NewProc("OpenClipboard")
+	procCloseClipboard             = user32.NewProc("CloseClipboard")
+	procGetClipboardData           = user32.NewProc("GetClipboardData")
+	procGetClipboardOwner          = user32.NewProc("GetClipboardOwner")
+	procIsClipboardFormatAvailable = user32.NewProc("IsClipboardFormatAvailable")
+	procRegisterClipboardFormatW   = user32.NewProc("RegisterClipboardFormatW")
+	procGetWindowThreadProcessId   = user32.NewProc("GetWindowThreadProcessId")
+	procGlobalLock                 = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock               = kernel32.NewProc("GlobalUnlock")
+	procGlobalSize                 = kernel32.NewProc("GlobalSize")
+	procRtlMoveMemory              = kernel32.NewProc("RtlMoveMemory")
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+const (
+	cfUnicodeText                  = 13
+	processQueryLimitedInformation = 0x1000
+	maxClipboardText               = 1 << 20
+)
+
+// Formats that password managers and other programs add to ask clipboard
+// monitors and history to leave a copy alone
+var clipboardExclusions = []string{
+	"ExcludeClipboardContentFromMonitorProcessing",
+	"Clipboard Viewer Ignore",
+}
+
+// clipboardSequence returns the number Windows increments on every copy
+func clipboardSequence() (uint32, error) {
+	seq, _, _ := procGetClipboardSequenceNumber.Call()
+	if seq == 0 {
+		return 0, errors.New("the clipboard is not available, run 'notify watch' in your desktop session")
+	}
+	return uint32(seq), nil
+}
+
+// readClipboard returns the text on the clipboard and the program that
+// put it there
+func readClipboard() (clipboardContent, error) {
+	var content clipboardContent
+	if r, _, err := procOpenClipboard.Call(0); r == 0 {
+		return content, err
+	}
+	defer procCloseClipboard.Call()
+
+	for _, name := range clipboardExclusions {
+		format, _ := syscall.UTF16PtrFromString(name)
+		if id, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(format))); id != 0 {
+			if r, _, _ := procIsClipboardFormatAvailable.Call(id); r != 0 {
+				content.Excluded = true
+				return content, nil
+			}
+		}
+	}
+	if owner, _, _ := procGetClipboardOwner.Call(); owner != 0 {
+		content.App = windowProgram(owner)
+	}
+
+	h, _, _ := procGetClipboardData.Call(cfUnicodeText)
+	if h == 0 {
+		return content, nil // not text
+	}
+	size, _, _ := procGlobalSize.Call(h)
+	p, _, _ := procGlobalLock.Call(h)
+	if p == 0 || size < 2 {
+		return content, nil
+	}
+	defer procGlobalUnlock.Call(h)
+
+	text := make([]uint16, min(size, maxClipboardText)/2)
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&text[0])), p, uintptr(len(text)*2))
+	content.Text = syscall.UTF16ToString(text)
+	return content, nil
+}
+
+// windowProgram returns the lowercase file name of the program owning a
+// window, like outlook.exe
+func windowProgram(hwnd uintptr) string {
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	process, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(process)
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	size := uint32(len(buf))
+	if r, _, _ := procQueryFullProcessImageNameW.Call(uintptr(process), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); r == 0 {
+		return ""
+	}
+	return strings.ToLower(filepath.Base(syscall.UTF16ToString(buf[:size])))
+}
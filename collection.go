@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+var collectionTemplate = template.Must(template.New("collection").Funcs(template.FuncMap{
+	"ps":  powershell.Quote,
+	"app": appScript,
+}).Parse(powershell.Prelude + `
+{{app .App}}
+$manager = [Windows.UI.Notifications.ToastNotificationManager]::GetDefault().GetToastCollectionManager($APP_ID)
+{{if eq .Action "create"}}
+$collection = New-Object Windows.UI.Notifications.ToastCollection({{ps .ID}}, {{ps .Name}}, {{ps .ID}}, [Uri]{{ps .Icon}})
+AwaitAction ($manager.SaveToastCollectionAsync($collection))
+{{else if eq .Action "remove"}}
+AwaitAction ($manager.RemoveToastCollectionAsync({{ps .ID}}))
+{{else}}
+$collections = Await ($manager.FindAllToastCollectionsAsync()) ([System.Collections.Generic.IReadOnlyList[Windows.UI.Notifications.ToastCollection]])
+foreach ($c in $collections) { Write-Output ($c.Id + "` + "`t" + `" + $c.DisplayName) }
+{{end}}
+`))
+
+// collectionRequest describes a toast collection operation
+type collectionRequest struct {
+	Action string // create, remove or list
+	App    string // display name, see appUserModelID
+	ID     string
+	Name   string
+	Icon   string
+}
+
+// runCollection implements "notify collection create|list|remove"
+func runCollection(args []string) error {
+	req := &collectionRequest{}
+
+	flags := []cliFlag{
+		{Name: "name", Set: func(v string) error { req.Name = v; return nil }},
+		{Name: "icon", Set: func(v string) error { req.Icon = v; return nil }},
+		{Name: "app", Set: func(v string) error { req.App = v; return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showCollectionHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		showCollectionHelp()
+		return errors.New("missing collection command")
+	}
+
+	req.Action = words[0]
+	switch req.Action {
+	case "create":
+		if len(words) != 2 {
+			return errors.New("usage: notify collection create ID [--name NAME] [--icon PATH]")
+		}
+		req.ID = words[1]
+		if req.Name == "" {
+			req.Name = req.ID
+		}
+		if req.Icon, err = collectionIcon(req.Icon); err != nil {
+			return err
+		}
+	case "remove":
+		if len(words) != 2 {
+			return errors.New("usage: notify collection remove ID")
+		}
+		req.ID = words[1]
+	case "list":
+	default:
+		return fmt.Errorf("unknown collection command: %s.%s", req.Action,
+			suggest.DidYouMean(req.Action, []string{"create", "list", "remove"}, ""))
+	}
+
+	var script bytes.Buffer
+	if err := collectionTemplate.Execute(&script, req); err != nil {
+		return err
+	}
+
+	out, err := powershell.Run(script.String())
+	if err != nil {
+		return fmt.Errorf("toast collections need Windows 11: %w", err)
+	}
+
+	switch req.Action {
+	case "create":
+		fmt.Printf("Collection %q created. Send to it with --collection %s\n", req.Name, req.ID)
+	case "remove":
+		fmt.Printf("Collection %s removed\n", req.ID)
+	default:
+		list := strings.TrimSpace(string(out))
+		if list == "" {
+			fmt.Println("No collections")
+		} else {
+			fmt.Println(list)
+		}
+	}
+	return nil
+}
+
+// collectionIcon returns an absolute icon path for a collection. Action
+// Center keeps referring to the icon, so the default one is the copy
+// cached in the user cache directory.
+func collectionIcon(icon string) (string, error) {
+	if icon != "" {
+		return filepath.Abs(icon)
+	}
+	return notify.IconFile(notify.Info, notify.IconDefault)
+}
+
+// appScript returns the PowerShell lines that define $APP_ID for the app
+// name and register its display name
+func appScript(name string) string {
+	return powershell.AppScript(notify.AppUserModelID(name), notify.AppName(name))
+}
+
+func showCollectionHelp() {
+	fmt.Print(`Group notifications under named collections in Action Center (Windows 11)
+
+Usage:
+  notify collection create ID [--name NAME] [--icon PATH] [--app NAME]
+  notify collection list [--app NAME]
+  notify collection remove ID [--app NAME]
+
+Send to a collection:
+  notify "Tests passed" --type success --collection my-project
+`)
+}
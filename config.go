@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Config holds the settings from config.yaml in notify's config directory.
+// Settings missing from the file keep their defaults.
+type Config struct {
+	Backend       string                     `yaml:"backend"` // auto, toast, desktop or console
+	Inject        map[string]*faultInjection `yaml:"inject"`  // delays and failures by backend, for testing
+	Titles        map[string]string          `yaml:"titles"`  // default title by type, e.g. in the user's language
+	Icons         map[string]string          `yaml:"icons"`   // image file by type, instead of the drawn icon
+	Defaults      defaultsConfig             `yaml:"defaults"`
+	Stdin         stdinConfig                `yaml:"stdin"`
+	History       historyConfig              `yaml:"history"`
+	Defer         deferConfig                `yaml:"defer"`
+	Watch         watchConfig                `yaml:"watch"`
+	Schedule      scheduleConfig             `yaml:"schedule"`
+	Relay         relayConfig                `yaml:"relay"`
+	Preview       previewConfig              `yaml:"preview"`
+	Shorten       shortenConfig              `yaml:"shorten"`
+	Sounds        soundConfig                `yaml:"sounds"`
+	Accessibility accessibilityConfig        `yaml:"accessibility"`
+	CI            ciConfig                   `yaml:"ci"`
+}
+
+// defaultsConfig holds the options notifications get without the flag
+type defaultsConfig struct {
+	Type      string `yaml:"type"`
+	Timeout   int    `yaml:"timeout"` // seconds
+	AutoClose bool   `yaml:"autoclose"`
+	App       string `yaml:"app"`
+	Category  string `yaml:"category"`
+}
+
+// validate checks the defaults section of config.yaml
+func (d *defaultsConfig) validate() error {
+	d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+	if !isValidType(d.Type) {
+		return fmt.Errorf("defaults: invalid type %q.%s Valid types are: %s",
+			d.Type, suggest.DidYouMean(d.Type, notify.Types, ""), strings.Join(notify.Types, ", "))
+	}
+	if d.Timeout < 1 || d.Timeout > 3600 {
+		return errors.New("defaults: the timeout must be 1-3600 seconds")
+	}
+	d.App = strings.TrimSpace(d.App)
+	d.Category = normalizeCategory(d.Category)
+	return nil
+}
+
+// historyConfig is the retention policy of the history file
+type historyConfig struct {
+	MaxAge  time.Duration `yaml:"max_age"`  // older entries are pruned, 0 keeps them
+	MaxRows int           `yaml:"max_rows"` // newest entries kept, 0 for no limit
+	Privacy string        `yaml:"privacy"`  // full, hashed or omitted titles and messages, see historyPrivacy
+}
+
+// deferConfig chooses when notifications are held back for a catch-up
+// summary instead of being shown
+type deferConfig struct {
+	QuietHours   string `yaml:"quiet_hours"`   // daily range like 22:00-07:00
+	FocusAssist  bool   `yaml:"focus_assist"`  // while Focus Assist is on
+	FocusSession bool   `yaml:"focus_session"` // while a Windows 11 focus session runs
+	Locked       bool   `yaml:"locked"`        // while the screen is locked
+
+	Calendar calendarConfig `yaml:"calendar"` // during meetings
+	Calls    callConfig     `yaml:"calls"`    // during Zoom, Teams and other calls
+}
+
+// defaultConfig returns the settings used without a config file
+func defaultConfig() *Config {
+	return &Config{
+		Defaults: defaultsConfig{
+			Type:      "info",
+			Timeout:   5,
+			AutoClose: true,
+		},
+		History: historyConfig{
+			MaxAge:  90 * 24 * time.Hour,
+			MaxRows: 10000,
+		},
+		Defer: deferConfig{
+			Calendar: calendarConfig{
+				Refresh: 15 * time.Minute,
+				Show:    []string{"error"},
+			},
+			Calls: callConfig{
+				Show: []string{"error"},
+			},
+		},
+		Watch: watchConfig{
+			Interval: 10 * time.Second,
+			Network: networkWatch{
+				Probe: "http://www.msftconnecttest.com/connecttest.txt",
+			},
+			PublicIPURL:    "https://api.ipify.org",
+			LookupInterval: 5 * time.Minute,
+		},
+		Preview: previewConfig{
+			Timeout: 3 * time.Second,
+		},
+		Shorten: shortenConfig{
+			MinLength: 40,
+			Listen:    "127.0.0.1:8689",
+			Timeout:   3 * time.Second,
+		},
+		CI: ciConfig{
+			Enabled: true,
+		},
+		Stdin: stdinConfig{
+			MaxLength: 1000,
+			Keep:      "start",
+		},
+	}
+}
+
+// loadedConfig is the last config.yaml read, parsed again only when the
+// file changes, as a notification looks at it many times while being sent
+var loadedConfig struct {
+	sync.Mutex
+	modTime time.Time
+	size    int64
+	config  *Config
+	err     error
+}
+
+// loadConfig reads config.yaml, returning the defaults when it doesn't
+// exist. The result is shared and must not be changed.
+func loadConfig() (*Config, error) {
+	path, err := dataFile("config.yaml")
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loadedConfig.Lock()
+	defer loadedConfig.Unlock()
+	if loadedConfig.config == nil && loadedConfig.err == nil ||
+		!info.ModTime().Equal(loadedConfig.modTime) || info.Size() != loadedConfig.size {
+		loadedConfig.config, loadedConfig.err = readConfig(path)
+		loadedConfig.modTime, loadedConfig.size = info.ModTime(), info.Size()
+	}
+	return loadedConfig.config, loadedConfig.err
+}
+
+// readConfig parses and checks the config.yaml at path
+func readConfig(path string) (*Config, error) {
+	config := defaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalYAML(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.Backend, err = parseBackend(config.Backend); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateInjections(config.Inject); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for t, title := range config.Titles {
+		if !isValidType(t) {
+			return nil, fmt.Errorf("%s: invalid type %q in titles.%s", path, t, suggest.DidYouMean(t, notify.Types, ""))
+		}
+		if length := utf8.RuneCountInString(strings.TrimSpace(title)); length == 0 || length > notify.MaxTitleLength {
+			return nil, fmt.Errorf("%s: the title of %s must be 1 to %d characters long", path, t, notify.MaxTitleLength)
+		}
+	}
+	for t, icon := range config.Icons {
+		if !isValidType(t) {
+			return nil, fmt.Errorf("%s: invalid type %q in icons.%s", path, t, suggest.DidYouMean(t, notify.Types, ""))
+		}
+		if !filepath.IsAbs(icon) {
+			return nil, fmt.Errorf("%s: the icon of %s must be an absolute path", path, t)
+		}
+		if ext := strings.ToLower(filepath.Ext(icon)); ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" {
+			return nil, fmt.Errorf("%s: the icon of %s must be a png, jpg or gif file", path, t)
+		}
+	}
+	if err := config.Defaults.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.History.MaxAge < 0 || config.History.MaxRows < 0 {
+		return nil, fmt.Errorf("%s: history limits can't be negative", path)
+	}
+	if p := config.History.Privacy; p != "" && !slices.Contains(historyPrivacy, p) {
+		return nil, fmt.Errorf("%s: invalid history privacy %q.%s Valid levels are: %s",
+			path, p, suggest.DidYouMean(p, historyPrivacy, ""), strings.Join(historyPrivacy, ", "))
+	}
+	if config.Defer.QuietHours != "" {
+		if _, _, err := parseQuietHours(config.Defer.QuietHours); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	calendar := &config.Defer.Calendar
+	if calendar.ICS != "" && calendar.GraphCommand != "" {
+		return nil, fmt.Errorf("%s: use either ics or graph_token_command for the calendar", path)
+	}
+	if calendar.Refresh <= 0 || calendar.Refresh > calendarWindow {
+		return nil, fmt.Errorf("%s: the calendar refresh must be between 1s and %s", path, calendarWindow)
+	}
+	for _, t := range calendar.Show {
+		if !isValidType(t) {
+			return nil, fmt.Errorf("%s: invalid type %q in calendar show.%s", path, t, suggest.DidYouMean(t, notify.Types, ""))
+		}
+	}
+	if err := config.Defer.Calls.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Watch.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Relay.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Shorten.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Sounds.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Accessibility.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.CI.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.Stdin.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// runConfig implements "notify config"
+func runConfig(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showConfigHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 1 {
+		return fmt.Errorf("unexpected argument: %s", words[1])
+	}
+
+	actions := []string{"init", "path", "show"}
+	action := "show"
+	if len(words) == 1 {
+		action = words[0]
+	}
+	switch action {
+	case "init":
+		return initConfig()
+	case "path":
+		path, err := dataFile("config.yaml")
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	case "show":
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(marshalYAML(config))
+		return nil
+	}
+	return fmt.Errorf("unknown config command %q.%s Commands are: %s", action, suggest.DidYouMean(action, actions, ""), strings.Join(actions, ", "))
+}
+
+// initConfig writes config.yaml with the defaults, to start editing it from.
+// An existing config.yaml is left alone.
+func initConfig() error {
+	path, err := dataFile("config.yaml")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s exists already; see 'notify config show' for its settings", path)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(marshalYAML(defaultConfig()))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	fmt.Printf("Wrote the defaults to %s\n", path)
+	return nil
+}
+
+func showConfigHelp() {
+	fmt.Print(`Show where config.yaml is and the settings in effect, or create it
+
+config.yaml in notify's config directory (%APPDATA%\notify on Windows,
+~/.config/notify elsewhere) sets the defaults of notifications, such as
+their type, timeout, app, titles, icons, sounds and backend, and the
+settings of the other commands. Options on the command line override it.
+
+notify reads a subset of YAML: block mappings and lists, plain and quoted
+values, lists of values like [a, b], literal (|, |-) and folded (>, >-)
+multi-line values, and comments. Anchors, aliases, tags, {...} mappings,
+complex keys, directives and multiple documents are reported as errors;
+quote values starting with &, *, !, {, @ or a backquote.
+
+Usage:
+  notify config [show]   Print the settings in effect: config.yaml with
+                         the defaults of everything it leaves out
+  notify config path     Print the path of config.yaml, which may not
+                         exist yet
+  notify config init     Write config.yaml with the defaults, to edit;
+                         an existing config.yaml is kept
+`)
+}
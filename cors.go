@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Browsers may cache an allowed preflight for this many seconds
+const corsMaxAge = "600"
+
+// parseOrigin checks an --allow-origin value, an origin like
+// http://localhost:3000 or * for any
+func parseOrigin(s string) (string, error) {
+	if s == "*" {
+		return s, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+		return "", fmt.Errorf("invalid origin %q, use something like http://localhost:3000", s)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// cors lets pages from the allowed origins call the relay. Preflights are
+// answered here; other requests get the CORS headers and still need the
+// token like any client.
+func (s *relayServer) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (slices.Contains(s.origins, "*") || slices.Contains(s.origins, strings.ToLower(origin)))
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed {
+			http.Error(w, "origin not allowed, see --allow-origin", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, traceparent")
+		w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+		// Pages on public sites need this to reach a relay on localhost
+		if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+			w.Header().Set("Access-Control-Allow-Private-Network", "true")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// runCountdown implements "notify countdown DURATION MESSAGE"
+func runCountdown(args []string) error {
+	opts := newNotifyOptions()
+	opts.Title = "Countdown"
+	opts.AutoClose = false
+	every := 5 * time.Second
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "every", Set: func(v string) (err error) { every, err = parseDuration(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showCountdownHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) < 2 {
+		return errors.New("usage: notify countdown DURATION MESSAGE [OPTIONS]")
+	}
+
+	total, err := parseDuration(words[0])
+	if err != nil {
+		return err
+	}
+	if every < time.Second {
+		return errors.New("--every must be at least 1s")
+	}
+
+	message := strings.Join(words[1:], " ")
+	n, err := opts.build(message)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now().Add(total)
+	n.Tag = "countdown-" + newToken()
+	n.Progress = countdownProgress(total, end)
+
+	if err := sendNotification(n); err != nil {
+		return err
+	}
+	fmt.Printf("Countdown running until %s (Ctrl+C to cancel)\n", end.Format("15:04:05"))
+	if err := followCountdown(n, total, end, every); err != nil {
+		return err
+	}
+
+	// Replace the countdown with the alarm
+	n.Progress = nil
+	n.Message = "Time's up: " + n.Message
+	n.Sound = notify.SoundAlarm
+	return sendNotification(n)
+}
+
+// followCountdown updates the progress of a countdown toast every so
+// often until end. It keeps waiting when the toast was dismissed, so the
+// alarm after it still goes off on time.
+func followCountdown(n *Notification, total time.Duration, end time.Time, every time.Duration) error {
+	visible := true
+	sequence := uint32(1)
+	for {
+		remaining := time.Until(end)
+		if remaining <= 0 {
+			return nil
+		}
+		time.Sleep(min(every, remaining))
+
+		if visible && time.Until(end) > 0 {
+			sequence++
+			var err error
+			visible, err = updateToast(n, countdownProgress(total, end), sequence)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// countdownProgress describes the time left as a progress bar
+func countdownProgress(total time.Duration, end time.Time) *notify.Progress {
+	remaining := max(time.Until(end), 0)
+	return &notify.Progress{
+		Value:  float64(remaining) / float64(total),
+		Label:  formatDuration(remaining) + " left",
+		Status: "Ends at " + end.Format("15:04:05"),
+	}
+}
+
+// formatDuration renders d rounded to seconds, e.g. 1h02m or 4m05s
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm%02ds", m, s)
+	}
+	return fmt.Sprintf("%ds", s)
+}
+
+func showCountdownHelp() {
+	fmt.Print(`Show a toast that counts down and ends with an alarm
+
+Usage:
+  notify countdown DURATION MESSAGE [OPTIONS]
+
+Options:
+  --every DURATION   How often the remaining time is updated (default: 5s)
+  Plus the notification options of 'notify --help', e.g. --type and --title.
+
+Example:
+  notify countdown 5m "Deployment window closes" --type warning
+`)
+}
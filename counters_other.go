@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// counterQuery samples a set of performance counters
+type counterQuery struct{}
+
+// openCounters is only available on Windows
+func openCounters(paths []string) (*counterQuery, error) {
+	return nil, errors.New("performance counters are only available on Windows")
+}
+
+func (q *counterQuery) sample() ([]float64, error) {
+	return nil, errors.New("performance counters are only available on Windows")
+}
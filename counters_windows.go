@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	pdh                             = syscall.NewLazyDLL("pdh.dll")
+	procPdhOpenQueryW               = pdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounterW       = pdh.NewProc("PdhAddEnglishCounterW")
+	procPdhCollectQueryData         = pdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterValue = pdh.NewProc("PdhGetFormattedCounterValue")
+)
+
+const (
+	pdhFmtDouble   = 0x00000200
+	pdhFmtNoCap100 = 0x00008000 // percentages of several CPUs may exceed 100
+)
+
+// Friendlier messages for the PDH errors of mistyped counter paths
+var pdhErrors = map[uintptr]string{
+	0xC0000BB8: "no such counter object",
+	0xC0000BB9: "no such counter",
+	0xC0000BBA: "the counter can't be monitored, check its instance",
+	0xC0000BC0: "invalid counter path",
+	0x800007D0: "no such instance",
+}
+
+// pdhCounterValue is PDH_FMT_COUNTERVALUE holding a double
+type pdhCounterValue struct {
+	Status uint32
+	_      uint32 // the union is 8-byte aligned
+	Value  float64
+}
+
+// counterQuery samples a set of performance counters
+type counterQuery struct {
+	query    uintptr
+	counters []uintptr
+}
+
+// openCounters adds the counters to a PDH query. Rates like processor time
+// need two samples, so the first one is collected right away.
+func openCounters(paths []string) (*counterQuery, error) {
+	q := &counterQuery{}
+	if status, _, _ := procPdhOpenQueryW.Call(0, 0, uintptr(unsafe.Pointer(&q.query))); status != 0 {
+		return nil, fmt.Errorf("opening performance counters: %s", pdhError(status))
+	}
+	for _, path := range paths {
+		var counter uintptr
+		name, err := syscall.UTF16PtrFromString(path)
+		if err != nil {
+			return nil, err
+		}
+		if status, _, _ := procPdhAddEnglishCounterW.Call(q.query, uintptr(unsafe.Pointer(name)), 0, uintptr(unsafe.Pointer(&counter))); status != 0 {
+			return nil, fmt.Errorf("counter %s: %s", path, pdhError(status))
+		}
+		q.counters = append(q.counters, counter)
+	}
+	procPdhCollectQueryData.Call(q.query)
+	return q, nil
+}
+
+// sample returns the current value of each counter, NaN for those without
+// one, like the instance of a process that isn't running
+func (q *counterQuery) sample() ([]float64, error) {
+	if status, _, _ := procPdhCollectQueryData.Call(q.query); status != 0 {
+		return nil, fmt.Errorf("sampling performance counters: %s", pdhError(status))
+	}
+	values := make([]float64, len(q.counters))
+	for i, counter := range q.counters {
+		var value pdhCounterValue
+		status, _, _ := procPdhGetFormattedCounterValue.Call(counter, pdhFmtDouble|pdhFmtNoCap100, 0, uintptr(unsafe.Pointer(&value)))
+		if status != 0 || value.Status > 1 {
+			values[i] = math.NaN()
+			continue
+		}
+		values[i] = value.Value
+	}
+	return values, nil
+}
+
+// pdhError describes a PDH status code
+func pdhError(status uintptr) string {
+	if message, ok := pdhErrors[status]; ok {
+		return message
+	}
+	return fmt.Sprintf("PDH error 0x%08X", status)
+}
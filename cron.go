@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Output of "notify cron" commands kept for the failure notification, the
+// end of longer output
+const cronOutputLimit = 64 << 10
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+// Write keeps the end of the output, dropping what falls out of the limit
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if extra := len(b.data) - b.limit; extra > 0 {
+		b.data = append(b.data[:0], b.data[extra:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// lastLines returns the last n non-empty lines of the output
+func (b *tailBuffer) lastLines(n int) []string {
+	var lines []string
+	for _, line := range strings.Split(string(b.data), "\n") {
+		if line = strings.TrimRight(line, "\r \t"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines[max(len(lines)-n, 0):]
+}
+
+// takeCronLock creates the lock file of a job, holding the id of this
+// process. A lock whose process has ended is taken over; one held by a
+// running process is not, and its id is returned.
+func takeCronLock(path string) (release func(), holder int, err error) {
+	for range 2 {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, 0, err
+			}
+			return func() { os.Remove(path) }, 0, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, 0, err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, 0, err
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 && processRunning(pid) {
+			return nil, pid, nil
+		}
+		os.Remove(path)
+	}
+	return nil, 0, fmt.Errorf("can't take the lock %s", path)
+}
+
+// runCron implements "notify cron", which runs a scheduled job quietly and
+// notifies only when it fails, with the end of its output
+func runCron(args []string) error {
+	opts := newNotifyOptions()
+	opts.Type = "error"
+	checkin, lock := "", ""
+	lines := 10
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "checkin", Set: func(v string) (err error) { checkin, err = parseCheckName(v); return }},
+		cliFlag{Name: "lock", Set: func(v string) error { lock = v; return nil }},
+		cliFlag{Name: "lines", Set: func(v string) (err error) { lines, err = parseCount(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showCronHelp(); os.Exit(0); return nil }},
+	)
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("a command to run is required. Run 'notify cron --help' for usage.")
+	}
+	subject := orDefault(strings.TrimSpace(opts.Title), filepath.Base(words[0]))
+	if _, err := opts.build(subject); err != nil {
+		return err
+	}
+
+	if lock != "" {
+		release, holder, err := takeCronLock(lock)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			// The last run is still going, so this one is skipped
+			overlap := *opts
+			overlap.Type, overlap.Title = "warning", oneLine(subject+" skipped", notify.MaxTitleLength)
+			n, err := overlap.build(fmt.Sprintf("The previous run is still going (process %d), holding %s", holder, lock))
+			if err == nil {
+				err = sendNotification(n)
+			}
+			return errors.Join(fmt.Errorf("%s is still running as process %d", subject, holder), err)
+		}
+		defer release()
+	}
+
+	// Cron mails whatever a job prints, so output is only shown on failure
+	output := &tailBuffer{limit: cronOutputLimit}
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Stdout, cmd.Stderr = output, output
+
+	start := time.Now()
+	runErr := cmd.Run()
+	elapsed := formatDuration(time.Since(start))
+	if runErr == nil {
+		if checkin != "" {
+			return recordCheckin(checkin)
+		}
+		return nil
+	}
+
+	if output.truncated {
+		fmt.Fprintf(os.Stderr, "[the first part of the output was dropped, showing the last %s]\n", formatBytes(cronOutputLimit))
+	}
+	os.Stderr.Write(output.data)
+
+	var exit *exec.ExitError
+	message := fmt.Sprintf("%s failed after %s: %v", filepath.Base(words[0]), elapsed, runErr)
+	if errors.As(runErr, &exit) && exit.ExitCode() >= 0 {
+		message = fmt.Sprintf("%s exited with code %d after %s", filepath.Base(words[0]), exit.ExitCode(), elapsed)
+	}
+	if tail := output.lastLines(lines); len(tail) > 0 {
+		message += "\n" + strings.Join(tail, "\n")
+	}
+	opts.Title = oneLine(subject+" failed", notify.MaxTitleLength)
+	n, err := opts.build(message)
+	if err == nil {
+		err = sendNotification(n)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Exit like the command, so cron and scripts see the failure
+	if errors.As(runErr, &exit) {
+		flushTraces()
+		os.Exit(max(exit.ExitCode(), 1))
+	}
+	return runErr
+}
+
+func showCronHelp() {
+	fmt.Print(`Run a scheduled job, notifying only when it fails
+
+Made for crontab lines and scheduled tasks: the command's output is
+captured instead of printed, so nothing is shown or mailed when it
+succeeds. When it fails, an error notification says how it exited and
+shows the last lines of its output, which is also printed to stderr.
+notify cron exits with the command's exit code.
+
+With --checkin, a successful run also checks in for 'notify expect',
+which alerts when the job stops running at all. With --lock, a run that
+starts while the previous one is still going is skipped with a warning.
+
+Usage:
+  notify cron [OPTIONS] [--] COMMAND [ARGS]
+
+Options:
+  --checkin NAME     Run 'notify checkin NAME' when the command succeeds
+  --lock FILE        Lock file held while the command runs; a lock left
+                     by a process that ended is taken over
+  --lines N          Lines of output in the notification (default: 10)
+  --title TITLE      Name of the job in the title (default: the command)
+  Plus the notification options of 'notify --help', e.g. --urgent.
+
+Examples:
+  0 2 * * * notify cron --title Backup --checkin backup --lock /tmp/backup.lock -- /usr/local/bin/backup.sh
+  notify cron --remote https://desk:8080 -- ./sync-mirrors.sh
+`)
+}
@@ -0,0 +1,89 @@
+//go:build cshared
+
+// The notify library for native applications, built with
+//
+//	go build -tags cshared -buildmode=c-shared -o notify.dll .
+//
+// which also writes notify.h declaring the functions below.
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+var (
+	lastErrorMu sync.Mutex
+	lastError   *C.char // C copy of the last failure, freed when replaced
+)
+
+// notify_send shows a notification like 'notify MESSAGE --type TYPE
+// --title TITLE'; type and title may be NULL for the defaults. It returns
+// 0 when shown, 3 when Windows settings blocked it and 1 for other
+// failures, described by notify_last_error.
+//
+//export notify_send
+func notify_send(typ, title, message *C.char) C.int {
+	opts := newNotifyOptions()
+	if typ != nil {
+		opts.Type = C.GoString(typ)
+	}
+	if title != nil {
+		opts.Title = C.GoString(title)
+	}
+	if message == nil {
+		return setLastError(errors.New("message is required"))
+	}
+
+	trace := startSpan(nil, "notify")
+	n, err := opts.build(C.GoString(message))
+	if err == nil {
+		n.trace = trace
+		err = sendNotification(n)
+	}
+	trace.finish(err)
+	flushTraces()
+	return setLastError(err)
+}
+
+// notify_last_error returns the failure of the last notify_send on any
+// thread, or an empty string. The string stays valid until the next call
+// of notify_send.
+//
+//export notify_last_error
+func notify_last_error() *C.char {
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+	if lastError == nil {
+		lastError = C.CString("")
+	}
+	return lastError
+}
+
+// setLastError records err for notify_last_error and returns the result
+// code of notify_send
+func setLastError(err error) C.int {
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+	if lastError != nil {
+		C.free(unsafe.Pointer(lastError))
+	}
+	if err == nil {
+		lastError = C.CString("")
+		return 0
+	}
+	lastError = C.CString(err.Error())
+
+	var blocked *notify.BlockedError
+	if errors.As(err, &blocked) {
+		return exitBlocked
+	}
+	return 1
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// errDaemonRunning is returned when another daemon serves this user
+var errDaemonRunning = errors.New("a notify daemon is already running")
+
+// daemonRequest is a notification handed to 'notify daemon', one line of
+// JSON per connection
+type daemonRequest struct {
+	Notification *Notification `json:"notification"`
+}
+
+// daemonResponse is the daemon's answer once the notification was shown
+type daemonResponse struct {
+	Error       string            `json:"error,omitempty"`
+	Blocked     string            `json:"blocked,omitempty"` // setting of a notify.BlockedError
+	Skipped     bool              `json:"skipped,omitempty"` // repeated one shown within --dedup
+	Interaction *toastInteraction `json:"interaction,omitempty"`
+}
+
+// daemonServer shows the notifications of notify invocations, one at a
+// time like the relay, skipping repeats within dedup
+type daemonServer struct {
+	dedup time.Duration
+	mu    sync.Mutex
+	seen  map[string]time.Time // last shown, by type, title and message
+}
+
+// runDaemon implements "notify daemon"
+func runDaemon(args []string) error {
+	srv := &daemonServer{seen: map[string]time.Time{}}
+	status := false
+	flags := []cliFlag{
+		{Name: "dedup", Set: func(v string) (err error) { srv.dedup, err = parseRetention(v); return }},
+		{Name: "status", Bool: true, Set: func(v string) (err error) { status, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showDaemonHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	address, err := daemonAddress()
+	if err != nil {
+		return err
+	}
+	conn, err := dialDaemon()
+	running := err == nil
+	if running {
+		conn.Close()
+	}
+	if status {
+		if !running {
+			fmt.Println("No notify daemon is running")
+			os.Exit(1)
+		}
+		fmt.Printf("A notify daemon is running on %s\n", address)
+		return nil
+	}
+	if running {
+		return errDaemonRunning
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if config.Shorten.API == "" {
+		go serveShortLinks(config.Shorten.Listen)
+	}
+
+	if err := powershell.StartHost(); err != nil {
+		log.Printf("Warning: could not start PowerShell, each toast starts its own: %v", err)
+	}
+	log.Printf("notify daemon listening on %s", address)
+	return listenDaemon(srv.handle)
+}
+
+// handle answers the request of a connection
+func (s *daemonServer) handle(conn io.ReadWriter) {
+	var req daemonRequest
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+	if err == nil && req.Notification == nil {
+		err = errors.New("no notification")
+	}
+	if err == io.EOF && len(line) == 0 {
+		return // checking whether the daemon runs
+	}
+	if err != nil {
+		log.Printf("Invalid request: %v", err)
+		return
+	}
+
+	resp := s.deliver(req.Notification)
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	conn.Write(append(data, '\n'))
+}
+
+// deliver shows a notification, unless it repeats one shown within dedup
+func (s *daemonServer) deliver(n *Notification) *daemonResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	key := n.Type + "\x00" + n.Title + "\x00" + n.Message
+	for k, t := range s.seen {
+		if now.Sub(t) >= s.dedup {
+			delete(s.seen, k)
+		}
+	}
+	if _, ok := s.seen[key]; ok {
+		log.Printf("%q skipped, repeated within %v", n.Title, s.dedup)
+		return &daemonResponse{Skipped: true}
+	}
+
+	err := sendNotification(n)
+	if err == nil && s.dedup > 0 {
+		s.seen[key] = now
+	}
+	resp := &daemonResponse{Interaction: n.interaction}
+	var blocked *notify.BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		resp.Blocked = blocked.Setting
+	case err != nil:
+		resp.Error = err.Error()
+	}
+	if err != nil {
+		log.Printf("%q failed: %v", n.Title, err)
+	} else {
+		log.Printf("%q delivered in %v", n.Title, time.Since(now).Round(time.Millisecond))
+	}
+	return resp
+}
+
+// sendToDaemon hands a notification to a running 'notify daemon'. It
+// returns false, for this process to show it, when no daemon runs or the
+// notification needs this process: to print it here, to flash this
+// terminal or to reach another session or a relay.
+func sendToDaemon(n *Notification) (bool, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return false, nil
+	}
+	switch {
+	case n.Remote != "", n.Session != "",
+		backendName(cmp.Or(n.Backend, config.Backend)) == "console",
+		(n.Fallback == "flash" || n.Fallback == "badge") && n.Window == "":
+		return false, nil
+	}
+	conn, err := dialDaemon()
+	if err != nil {
+		return false, nil
+	}
+	defer conn.Close()
+
+	span := startSpan(n.trace, "daemon")
+	resp, err := askDaemon(conn, n)
+	span.finish(err)
+	if err != nil {
+		return true, fmt.Errorf("notify daemon: %w", err)
+	}
+
+	switch {
+	case resp.Skipped:
+		fmt.Println("Notification skipped, the daemon showed the same one recently")
+	case resp.Blocked != "":
+		return true, &notify.BlockedError{Setting: resp.Blocked}
+	case resp.Error != "":
+		err := errors.New(resp.Error)
+		if !printInstead(n, err) {
+			return true, err
+		}
+		if printErr := printNotification(n, span); printErr != nil {
+			return true, fmt.Errorf("%w; printing it failed: %v", err, printErr)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; printed the notification instead\n", err)
+	}
+	if resp.Interaction != nil {
+		fmt.Println(resp.Interaction.Action)
+	}
+	return true, nil
+}
+
+// askDaemon sends a notification over a daemon connection and reads the
+// answer
+func askDaemon(conn io.ReadWriter, n *Notification) (*daemonResponse, error) {
+	// The daemon runs elsewhere, so sound files are found from here
+	sent := *n
+	if strings.EqualFold(filepath.Ext(sent.Sound), ".wav") {
+		if abs, err := filepath.Abs(sent.Sound); err == nil {
+			sent.Sound = abs
+		}
+	}
+
+	data, err := json.Marshal(daemonRequest{Notification: &sent})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("no answer: %w", err)
+	}
+	var resp daemonResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func showDaemonHelp() {
+	fmt.Print(`Keep notify running so notifications show faster
+
+The daemon keeps PowerShell and the toast types loaded, so notifications
+don't wait for a new PowerShell to start, which takes most of the time of
+showing a toast. Later 'notify MESSAGE' invocations of this user hand
+their notifications to it over a named pipe on Windows or a unix socket
+in notify's config directory elsewhere, and show them themselves when no
+daemon is running or with --no-daemon.
+
+The daemon shows one notification at a time, records them in history
+like notify itself and can skip repeated notifications with --dedup.
+Notifications for --remote, --session or the console backend are still
+sent by the invocation itself.
+
+Usage:
+  notify daemon [--dedup DURATION]
+  notify daemon --status
+
+Options:
+  --dedup DURATION   Skip notifications repeating the type, title and message
+                     of one shown this recently, e.g. 30s (default: 0, off)
+  --status           Report whether a daemon is running, exit code 1 if not
+`)
+}
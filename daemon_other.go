@@ -0,0 +1,52 @@
+//go:build !windows
+
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// daemonAddress is the unix socket 'notify daemon' listens on, in the data
+// directory only this user can enter
+func daemonAddress() (string, error) {
+	return dataFile("daemon.sock")
+}
+
+// listenDaemon serves the daemon's socket, handing each connection to
+// handle. A socket left behind by a daemon that didn't exit cleanly is
+// replaced.
+func listenDaemon(handle func(io.ReadWriter)) error {
+	path, err := daemonAddress()
+	if err != nil {
+		return err
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			handle(conn)
+		}()
+	}
+}
+
+// dialDaemon connects to a running daemon
+func dialDaemon() (io.ReadWriteCloser, error) {
+	path, err := daemonAddress()
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", path, time.Second)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"cmp"
+	"io"
+	"os"
+)
+
+// daemonPipe is the pipe 'notify daemon' serves, one per user
+func daemonPipe() string {
+	return "notify-daemon-" + cmp.Or(os.Getenv("USERNAME"), "user")
+}
+
+// daemonAddress describes where the daemon listens
+func daemonAddress() (string, error) {
+	return `\\.\pipe\` + daemonPipe(), nil
+}
+
+// listenDaemon serves the daemon's pipe, handing each connection to handle
+func listenDaemon(handle func(io.ReadWriter)) error {
+	return listenDuplexPipe(daemonPipe(), handle)
+}
+
+// dialDaemon connects to a running daemon
+func dialDaemon() (io.ReadWriteCloser, error) {
+	return os.OpenFile(`\\.\pipe\`+daemonPipe(), os.O_RDWR, 0)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Deferred notifications kept for the catch-up summary, oldest dropped first
+const maxDeferred = 200
+
+// Lines listed in the catch-up summary, newest first
+const catchUpLines = 10
+
+// parseQuietHours parses a daily range like 22:00-07:00 into minutes
+// after midnight
+func parseQuietHours(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if ok {
+		start, err = parseClock(from)
+	}
+	if ok && err == nil {
+		end, err = parseClock(to)
+	}
+	if !ok || err != nil || start == end {
+		return 0, 0, fmt.Errorf("quiet hours %q are not a range like 22:00-07:00", s)
+	}
+	return start, end, nil
+}
+
+// parseClock parses a time of day like 7:30 or 22:00 into minutes
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inQuietHours reports whether now falls in the quiet hours, which may
+// span midnight
+func inQuietHours(spec string, now time.Time) bool {
+	if spec == "" {
+		return false
+	}
+	start, end, err := parseQuietHours(spec)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// deferReason returns why notifications of type t are held back right
+// now, or "" to show them. An empty t asks about any notification.
+func deferReason(config *Config, now time.Time, t string) string {
+	d := config.Defer
+	if inQuietHours(d.QuietHours, now) {
+		return "quiet hours"
+	}
+	if d.FocusAssist && focusAssist() != "" {
+		return "Focus Assist"
+	}
+	if d.FocusSession && focusSessionActive() {
+		return "focus session"
+	}
+	if d.Locked && sessionLocked() {
+		return "screen locked"
+	}
+	if d.Calendar.enabled() && !d.Calendar.showsDuringMeetings(t) && calendarBusy(&d.Calendar, now) {
+		return "in a meeting"
+	}
+	if app := d.Calls.inCall("defer", t); app != "" {
+		return "in a call on " + app
+	}
+	return ""
+}
+
+// deferNotification keeps n for the catch-up summary
+func deferNotification(n *Notification) error {
+	return updateState(func(s *State) error {
+		s.Deferred = append(s.Deferred, groupItem{
+			Type:    n.Type,
+			Title:   n.Title,
+			Message: n.storedMessage(),
+			Time:    time.Now(),
+		})
+		if len(s.Deferred) > maxDeferred {
+			s.Deferred = s.Deferred[len(s.Deferred)-maxDeferred:]
+		}
+		return nil
+	})
+}
+
+// catchUp shows the summary of the deferred notifications once they are
+// no longer held back, or right away with force. It returns the number
+// of notifications summarized.
+func catchUp(config *Config, parent *span, force bool) (int, error) {
+	if !force && deferReason(config, time.Now(), "") != "" {
+		return 0, nil
+	}
+
+	var items []groupItem
+	err := updateState(func(s *State) error {
+		items, s.Deferred = s.Deferred, nil
+		return nil
+	})
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+
+	summary := catchUpSummary(items)
+	config.Accessibility.apply(summary)
+	summary.Backend = config.Backend
+	if err := displayNotification(summary, parent); err != nil {
+		// Keep them for the next attempt
+		updateState(func(s *State) error {
+			s.Deferred = append(items, s.Deferred...)
+			return nil
+		})
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// catchUpSummary returns the toast summarizing deferred notifications,
+// which opens the history when clicked
+func catchUpSummary(items []groupItem) *Notification {
+	n := groupSummary(&Notification{Notification: notify.Notification{
+		Timeout: time.Duration(newNotifyOptions().Timeout) * time.Second,
+		Sticky:  true,
+		Group:   "While you were away",
+	}}, &groupRecord{Items: items})
+	n.Tag = "catch-up"
+
+	if lines := strings.Split(n.Message, "\n"); len(lines) > catchUpLines {
+		n.Message = strings.Join(lines[:catchUpLines], "\n") + fmt.Sprintf("\n+%d more", len(lines)-catchUpLines)
+	}
+	n.OnClick = activationURI("history", strconv.Itoa(len(items)))
+	n.ClickHint = "Click to open the history"
+	return n
+}
+
+// runCatchUp shows the catch-up summary now
+func runCatchUp(args []string) error {
+	force := false
+	flags := []cliFlag{
+		{Name: "force", Bool: true, Set: func(v string) (err error) { force, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showCatchUpHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if reason := deferReason(config, time.Now(), ""); reason != "" && !force {
+		state, err := loadState()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Still deferring (%s), %d notifications waiting; use --force to show them now\n", reason, len(state.Deferred))
+		return nil
+	}
+
+	count, err := catchUp(config, nil, true)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Println("No deferred notifications")
+	}
+	return nil
+}
+
+// showHistoryView lists the latest history in the console window Windows
+// opens for a clicked catch-up summary
+func showHistoryView(count string) error {
+	limit, err := parseCount(count)
+	if err != nil {
+		return err
+	}
+	if err := runHistory([]string{"--limit", strconv.Itoa(max(limit, 20))}); err != nil {
+		return err
+	}
+	fmt.Print("\nPress Enter to close")
+	fmt.Scanln()
+	return nil
+}
+
+func showCatchUpHelp() {
+	fmt.Print(`notify catch-up - Show the summary of deferred notifications
+
+Usage:
+  notify catch-up [--force]
+
+Notifications sent during quiet hours, with Focus Assist on, during a
+focus session, while the screen is locked or during meetings are
+deferred when config.yaml asks for it:
+
+  defer:
+    quiet_hours: 22:00-07:00
+    focus_assist: true
+    focus_session: true
+    locked: true
+    calendar:
+      ics: https://example.com/calendar.ics
+      show: [error]
+    calls:
+      mode: defer
+
+The next notification shown afterwards is preceded by one toast
+summarizing what was missed; clicking it opens the history. Run catch-up
+from a scheduled task (e.g. at logon or unlock) to show the summary
+without waiting for the next notification. A relay shows it by itself.
+--urgent notifications are never deferred.
+
+Options:
+  --force   Show the summary even while notifications are still deferred
+  --help    Show this help message
+`)
+}
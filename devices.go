@@ -0,0 +1,155 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// removableDrive is a mounted USB drive or memory card
+type removableDrive struct {
+	Root  string // like E:\
+	Label string
+	Free  uint64 // bytes
+	Size  uint64
+}
+
+// printJob is a job in a printer's queue
+type printJob struct {
+	ID       uint32
+	Printer  string
+	Document string
+	Pages    uint32
+	Problem  string // like "paper out", "" while the job is fine
+	Deleted  bool   // being cancelled
+}
+
+// watchDrives reports USB drives and memory cards being connected and
+// removed. The first round only records the drives already there.
+func watchDrives() (watchCheck, error) {
+	known, err := removableDrives()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(now time.Time) ([]*notify.RelayMessage, error) {
+		drives, err := removableDrives()
+		if err != nil {
+			return nil, err
+		}
+
+		var messages []*notify.RelayMessage
+		for root, drive := range drives {
+			if _, ok := known[root]; !ok {
+				messages = append(messages, &notify.RelayMessage{
+					App: "Watch", Category: "devices", Type: "info", Tag: "drive " + root,
+					Title:   "USB drive connected",
+					Message: fmt.Sprintf("%s\n%s free of %s", drive.name(), formatBytes(drive.Free), formatBytes(drive.Size)),
+				})
+			}
+		}
+		for root, drive := range known {
+			if _, ok := drives[root]; !ok {
+				messages = append(messages, &notify.RelayMessage{
+					App: "Watch", Category: "devices", Type: "info", Tag: "drive " + root,
+					Title:   "USB drive removed",
+					Message: drive.name(),
+				})
+			}
+		}
+		known = drives
+		return messages, nil
+	}, nil
+}
+
+// name describes a drive like "E: (BACKUP)"
+func (d removableDrive) name() string {
+	name := d.Root[:min(len(d.Root), 2)]
+	if d.Label != "" {
+		name += " (" + d.Label + ")"
+	}
+	return name
+}
+
+// watchPrinters reports print jobs running into problems, and finishing
+// or failing when they leave the queue. Jobs shorter than the watch
+// interval may be missed.
+func watchPrinters() (watchCheck, error) {
+	jobs, err := printJobs()
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]printJob{}
+	for _, job := range jobs {
+		known[job.key()] = job
+	}
+
+	return func(now time.Time) ([]*notify.RelayMessage, error) {
+		jobs, err := printJobs()
+		if err != nil {
+			return nil, err
+		}
+
+		var messages []*notify.RelayMessage
+		message := func(job printJob, typ, title, text string) {
+			messages = append(messages, &notify.RelayMessage{
+				App: "Watch", Category: "printers", Type: typ, Tag: oneLine("print "+job.key(), notify.MaxTagLength),
+				Title: title, Message: text,
+			})
+		}
+
+		current := map[string]printJob{}
+		for _, job := range jobs {
+			current[job.key()] = job
+			if job.Problem != "" && job.Problem != known[job.key()].Problem {
+				message(job, "error", "Print job needs attention", fmt.Sprintf("%s on %s: %s", job.document(), job.Printer, job.Problem))
+			}
+		}
+		for key, job := range known {
+			if _, ok := current[key]; ok {
+				continue
+			}
+			switch {
+			case job.Deleted:
+				message(job, "warning", "Print job cancelled", fmt.Sprintf("%s on %s", job.document(), job.Printer))
+			case job.Problem != "":
+				message(job, "error", "Print job failed", fmt.Sprintf("%s on %s: %s", job.document(), job.Printer, job.Problem))
+			default:
+				text := fmt.Sprintf("%s on %s", job.document(), job.Printer)
+				if job.Pages > 0 {
+					text += fmt.Sprintf(", %d pages", job.Pages)
+				}
+				message(job, "success", "Printed", text)
+			}
+		}
+		known = current
+		return messages, nil
+	}, nil
+}
+
+// key identifies a job across checks
+func (j printJob) key() string {
+	return j.Printer + " " + strconv.FormatUint(uint64(j.ID), 10)
+}
+
+// document returns the job's document name, shortened for a toast
+func (j printJob) document() string {
+	return oneLine(cmp.Or(j.Document, "Untitled document"), 60)
+}
+
+// formatBytes renders a size like 14.2 GB
+func formatBytes(n uint64) string {
+	units := []string{"bytes", "KB", "MB", "GB", "TB"}
+	size, unit := float64(n), 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// removableDrives is only available on Windows
+func removableDrives() (map[string]removableDrive, error) {
+	return nil, errors.New("watching USB drives is only available on Windows")
+}
+
+// printJobs is only available on Windows
+func printJobs() ([]printJob, error) {
+	return nil, errors.New("watching printers is only available on Windows")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procGetLogicalDrives      = kernel32.NewProc("GetLogicalDrives")
+	procGetDriveTypeW         = kernel32.NewProc("GetDriveTypeW")
+	procGetVolumeInformationW = kernel32.NewProc("GetVolumeInformationW")
+	procGetDiskFreeSpaceExW   = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+	winspool          = syscall.NewLazyDLL("winspool.drv")
+	procEnumPrintersW = winspool.NewProc("EnumPrintersW")
+	procOpenPrinterW  = winspool.NewProc("OpenPrinterW")
+	procEnumJobsW     = winspool.NewProc("EnumJobsW")
+	procClosePrinter  = winspool.NewProc("ClosePrinter")
+)
+
+const (
+	driveRemovable = 2
+	driveFixed     = 3
+
+	ioctlStorageQueryProperty = 0x2D1400
+
+	printerEnumLocal       = 0x2
+	printerEnumConnections = 0x4
+)
+
+// Storage buses of drives that come and go
+var removableBuses = map[uint32]bool{
+	7:  true, // USB
+	12: true, // SD
+	13: true, // MMC
+}
+
+// Job status bits that mean a print job is stuck, and how to describe them
+var printJobProblems = []struct {
+	bit  uint32
+	text string
+}{
+	{0x40, "paper out"},
+	{0x20, "printer offline"},
+	{0x400, "needs attention at the printer"},
+	{0x200, "blocked by an earlier job"},
+	{0x2, "printer error"},
+}
+
+const (
+	jobStatusDeleting = 0x4
+	jobStatusDeleted  = 0x100
+)
+
+// jobInfo1 is JOB_INFO_1W
+type jobInfo1 struct {
+	JobID        uint32
+	PrinterName  *uint16
+	MachineName  *uint16
+	UserName     *uint16
+	Document     *uint16
+	Datatype     *uint16
+	StatusText   *uint16
+	Status       uint32
+	Priority     uint32
+	Position     uint32
+	TotalPages   uint32
+	PagesPrinted uint32
+	Submitted    [8]uint16
+}
+
+// removableDrives returns the USB drives and memory cards with media,
+// by root. USB hard disks count too, though Windows reports them as
+// fixed drives.
+func removableDrives() (map[string]removableDrive, error) {
+	mask, _, _ := procGetLogicalDrives.Call()
+	drives := map[string]removableDrive{}
+	for i := range 26 {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		path, _ := syscall.UTF16PtrFromString(root)
+		kind, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(path)))
+		if kind != driveRemovable && (kind != driveFixed || !onRemovableBus(root[:2])) {
+			continue
+		}
+
+		// Card readers have drives without media
+		var label [261]uint16
+		if r, _, _ := procGetVolumeInformationW.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&label[0])), uintptr(len(label)), 0, 0, 0, 0, 0); r == 0 {
+			continue
+		}
+		drive := removableDrive{Root: root, Label: syscall.UTF16ToString(label[:])}
+		procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&drive.Free)), uintptr(unsafe.Pointer(&drive.Size)), 0)
+		drives[root] = drive
+	}
+	return drives, nil
+}
+
+// onRemovableBus reports whether a drive like E: is attached through USB
+// or a card reader
+func onRemovableBus(drive string) bool {
+	path, _ := syscall.UTF16PtrFromString(`\\.\` + drive)
+	h, err := syscall.CreateFile(path, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	// STORAGE_PROPERTY_QUERY for StorageDeviceProperty, answered with a
+	// STORAGE_DEVICE_DESCRIPTOR that has the bus type at offset 28
+	query := make([]byte, 12)
+	descriptor := make([]byte, 1024)
+	var n uint32
+	if err := syscall.DeviceIoControl(h, ioctlStorageQueryProperty, &query[0], uint32(len(query)), &descriptor[0], uint32(len(descriptor)), &n, nil); err != nil || n < 32 {
+		return false
+	}
+	return removableBuses[binary.LittleEndian.Uint32(descriptor[28:])]
+}
+
+// printJobs returns the jobs queued on the local and connected printers
+func printJobs() ([]printJob, error) {
+	var needed, count uint32
+	procEnumPrintersW.Call(printerEnumLocal|printerEnumConnections, 0, 4, 0, 0, uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)))
+	if needed == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, needed)
+	if r, _, err := procEnumPrintersW.Call(printerEnumLocal|printerEnumConnections, 0, 4, uintptr(unsafe.Pointer(&buf[0])), uintptr(needed),
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count))); r == 0 {
+		return nil, fmt.Errorf("listing printers: %w", err)
+	}
+
+	// PRINTER_INFO_4W starts with the printer name
+	type printerInfo4 struct {
+		Name       *uint16
+		Server     *uint16
+		Attributes uint32
+	}
+	var jobs []printJob
+	for _, printer := range unsafe.Slice((*printerInfo4)(unsafe.Pointer(&buf[0])), count) {
+		jobs = append(jobs, printerJobs(printer.Name)...)
+	}
+	return jobs, nil
+}
+
+// printerJobs returns the jobs of one printer
+func printerJobs(name *uint16) []printJob {
+	var h uintptr
+	if r, _, _ := procOpenPrinterW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&h)), 0); r == 0 {
+		return nil
+	}
+	defer procClosePrinter.Call(h)
+
+	var needed, count uint32
+	procEnumJobsW.Call(h, 0, 0xFFFF, 1, 0, 0, uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)))
+	if needed == 0 {
+		return nil
+	}
+	buf := make([]byte, needed)
+	if r, _, _ := procEnumJobsW.Call(h, 0, 0xFFFF, 1, uintptr(unsafe.Pointer(&buf[0])), uintptr(needed),
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count))); r == 0 {
+		return nil
+	}
+
+	printer := utf16PtrToString(name)
+	var jobs []printJob
+	for _, info := range unsafe.Slice((*jobInfo1)(unsafe.Pointer(&buf[0])), count) {
+		job := printJob{
+			ID:       info.JobID,
+			Printer:  printer,
+			Document: utf16PtrToString(info.Document),
+			Pages:    info.TotalPages,
+			Deleted:  info.Status&(jobStatusDeleting|jobStatusDeleted) != 0,
+		}
+		for _, problem := range printJobProblems {
+			if info.Status&problem.bit != 0 {
+				job.Problem = problem.text
+				break
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// utf16PtrToString converts a NUL terminated UTF-16 string
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; n++ {
+		ptr = unsafe.Add(ptr, 2)
+	}
+	return syscall.UTF16ToString(unsafe.Slice(p, n))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Record types "notify watch" can follow
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// dnsWatch follows the records of a name
+type dnsWatch struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`   // record type, A by default
+	Server string `yaml:"server"` // resolver like 1.1.1.1, the system's by default
+}
+
+// validate checks a DNS watch of config.yaml
+func (w *dnsWatch) validate(where string) error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("%s: name is required", where)
+	}
+	if w.Type != "" && !slices.Contains(dnsRecordTypes, strings.ToUpper(w.Type)) {
+		return fmt.Errorf("%s: invalid record type %q.%s Types are: %s",
+			where, w.Type, suggest.DidYouMean(strings.ToUpper(w.Type), dnsRecordTypes, ""), strings.Join(dnsRecordTypes, ", "))
+	}
+	return nil
+}
+
+// resolver returns the resolver for the watch's server
+func (w *dnsWatch) resolver() *net.Resolver {
+	if w.Server == "" {
+		return net.DefaultResolver
+	}
+	server := w.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// lookup returns the sorted values of the records, none when the name
+// doesn't exist
+func (w *dnsWatch) lookup(ctx context.Context) ([]string, error) {
+	r := w.resolver()
+	var values []string
+	var err error
+	switch strings.ToUpper(cmp.Or(w.Type, "A")) {
+	case "A", "AAAA":
+		network := "ip4"
+		if strings.EqualFold(w.Type, "AAAA") {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = r.LookupIP(ctx, network, w.Name)
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+	case "CNAME":
+		var cname string
+		cname, err = r.LookupCNAME(ctx, w.Name)
+		values = append(values, cname)
+	case "MX":
+		var records []*net.MX
+		records, err = r.LookupMX(ctx, w.Name)
+		for _, mx := range records {
+			values = append(values, strconv.Itoa(int(mx.Pref))+" "+mx.Host)
+		}
+	case "NS":
+		var records []*net.NS
+		records, err = r.LookupNS(ctx, w.Name)
+		for _, ns := range records {
+			values = append(values, ns.Host)
+		}
+	case "TXT":
+		values, err = r.LookupTXT(ctx, w.Name)
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(values)
+	return slices.Compact(values), nil
+}
+
+// watchLookups reports changes of DNS records and of the public IP
+// address, checked every interval. The first round only records the
+// current values.
+func watchLookups(records []dnsWatch, publicIP string, interval time.Duration) watchCheck {
+	client := &http.Client{Timeout: 10 * time.Second}
+	known := map[string][]string{}
+	var next time.Time
+
+	return func(now time.Time) ([]*notify.RelayMessage, error) {
+		if now.Before(next) {
+			return nil, nil
+		}
+		next = now.Add(interval)
+
+		var messages []*notify.RelayMessage
+		var errs []error
+		changed := func(key, title string, values []string) {
+			old, seen := known[key]
+			known[key] = values
+			if !seen || slices.Equal(old, values) {
+				return
+			}
+			messages = append(messages, &notify.RelayMessage{
+				App: "Watch", Category: "dns", Type: "info", Tag: oneLine(key, notify.MaxTagLength),
+				Title:   oneLine(title, notify.MaxTitleLength),
+				Message: fmt.Sprintf("Old: %s\nNew: %s", lookupValues(old), lookupValues(values)),
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, w := range records {
+			values, err := w.lookup(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("looking up %s: %w", w.Name, err))
+				continue
+			}
+			typ := strings.ToUpper(cmp.Or(w.Type, "A"))
+			changed("dns "+typ+" "+w.Name, fmt.Sprintf("%s %s record changed", w.Name, typ), values)
+		}
+
+		if publicIP != "" {
+			ip, err := fetchPublicIP(client, publicIP)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("checking the public IP address: %w", err))
+			} else {
+				changed("public ip", "Public IP address changed", []string{ip})
+			}
+		}
+		return messages, errors.Join(errs...)
+	}
+}
+
+// lookupValues formats record values for a notification
+func lookupValues(values []string) string {
+	if len(values) == 0 {
+		return "(none)"
+	}
+	return strings.Join(values, ", ")
+}
+
+// fetchPublicIP asks a service like api.ipify.org for this machine's
+// address as seen from the internet
+func fetchPublicIP(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("%s didn't answer with an IP address", url)
+	}
+	return ip.String(), nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Context string binding derived keys to this use
+const sealInfo = "notify sealed message v1"
+
+// sealMessage encrypts m so that only the holder of the private key for
+// recipient can read it
+func sealMessage(m *notify.RelayMessage, recipient *ecdh.PublicKey) (*notify.SealedBox, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := sealCipher(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &notify.SealedBox{
+		Key:   base64.StdEncoding.EncodeToString(ephemeral.PublicKey().Bytes()),
+		Nonce: base64.StdEncoding.EncodeToString(nonce),
+		Data:  base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, nil)),
+	}, nil
+}
+
+// open decrypts the box with the recipient's private key
+func openSealed(b *notify.SealedBox, key *ecdh.PrivateKey) (*notify.RelayMessage, error) {
+	ephemeral, err := parsePublicKey(b.Key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(b.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(b.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := sealCipher(shared, ephemeral, key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	plaintext, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.New("message was not encrypted to this relay's key or was modified")
+	}
+
+	var m notify.RelayMessage
+	if err := json.Unmarshal(plaintext, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// sealCipher derives the AES-GCM cipher from the X25519 shared secret,
+// bound to both public keys
+func sealCipher(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(ephemeral.Bytes(), recipient.Bytes()...)
+	key, err := hkdf.Key(sha256.New, shared, salt, sealInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// parsePublicKey decodes a base64 X25519 public key as printed by keygen
+func parsePublicKey(s string) (*ecdh.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return key, nil
+}
+
+// loadPrivateKey reads a private key file written by keygen
+func loadPrivateKey(path string) (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// defaultKeyFile returns where keygen stores the relay's private key
+func defaultKeyFile() (string, error) {
+	return dataFile("relay.key")
+}
+
+// loadOptionalKey loads the key for encrypted messages from keyFile, or
+// from the default key file when keyFile is empty. Without either it
+// returns nil, as the key is optional unless given.
+func loadOptionalKey(keyFile string) (*ecdh.PrivateKey, error) {
+	if keyFile == "" {
+		path, err := defaultKeyFile()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		keyFile = path
+	}
+	return loadPrivateKey(keyFile)
+}
+
+// runKeygen implements "notify keygen"
+func runKeygen(args []string) error {
+	force := false
+	signing := false
+	path := ""
+
+	flags := []cliFlag{
+		{Name: "out", Set: func(v string) error { path = v; return nil }},
+		{Name: "force", Bool: true, Set: func(v string) (err error) { force, err = parseStrictBool(v); return }},
+		{Name: "signing", Bool: true, Set: func(v string) (err error) { signing, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showKeygenHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	if signing {
+		if path == "" {
+			if path, err = defaultSigningKeyFile(); err != nil {
+				return err
+			}
+		}
+		return keygenSigning(path, force)
+	}
+
+	if path == "" {
+		if path, err = defaultKeyFile(); err != nil {
+			return err
+		}
+	}
+
+	// An existing key is shown rather than replaced, as senders use it
+	if key, err := loadPrivateKey(path); err == nil && !force {
+		fmt.Printf("Key %s already exists (use --force to replace it). Public key:\n", path)
+		fmt.Println(base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()))
+		return nil
+	}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Bytes()) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Private key written to %s. Public key for --encrypt-to:\n", path)
+	fmt.Println(base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()))
+	return nil
+}
+
+func showKeygenHelp() {
+	fmt.Print(`Create the key pair used for end-to-end encrypted notifications
+
+The private key stays with the relay that shows the toasts; senders
+encrypt to its public key with --encrypt-to, so relays in between can
+forward the notification but not read it.
+
+With --signing, create an Ed25519 key instead, used by senders to sign
+their requests with --sign-key and trusted by relays with --verify-key.
+
+Usage:
+  notify keygen [OPTIONS]
+
+Options:
+  --out FILE   Where to write the private key (default: relay.key or sign.key
+               in notify's config directory)
+  --signing    Create a signing key instead of an encryption key
+  --force      Replace an existing key
+
+Examples:
+  notify keygen
+  notify "Disk almost full" --remote hub.lan:8787 --encrypt-to PUBLIC_KEY
+  notify keygen --signing
+`)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+func TestSealedMessages(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &notify.RelayMessage{Type: notify.Warning, Title: "Disk", Message: "Almost full"}
+	box, err := sealMessage(sent, key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(box.Data, "Almost full") {
+		t.Fatalf("sealed data %q holds the message", box.Data)
+	}
+	got, err := openSealed(box, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != sent.Type || got.Title != sent.Title || got.Message != sent.Message {
+		t.Fatalf("opened %+v, want %+v", got, sent)
+	}
+
+	// Every flip, cut or substitution is detected by GCM or the decoding
+	flip := func(s string) string {
+		raw, _ := base64.StdEncoding.DecodeString(s)
+		raw[len(raw)-1] ^= 1
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+	cut := func(s string) string {
+		raw, _ := base64.StdEncoding.DecodeString(s)
+		return base64.StdEncoding.EncodeToString(raw[:len(raw)-1])
+	}
+	tests := []struct {
+		name string
+		box  notify.SealedBox
+		key  *ecdh.PrivateKey
+		err  string // part of the error
+	}{
+		{"wrong key", *box, other, "not encrypted to this relay's key"},
+		{"tampered data", notify.SealedBox{Key: box.Key, Nonce: box.Nonce, Data: flip(box.Data)}, key, "was modified"},
+		{"tampered nonce", notify.SealedBox{Key: box.Key, Nonce: flip(box.Nonce), Data: box.Data}, key, "was modified"},
+		{"other ephemeral key", notify.SealedBox{Key: base64.StdEncoding.EncodeToString(other.PublicKey().Bytes()), Nonce: box.Nonce, Data: box.Data}, key, "was modified"},
+		{"truncated data", notify.SealedBox{Key: box.Key, Nonce: box.Nonce, Data: cut(box.Data)}, key, "was modified"},
+		{"no data", notify.SealedBox{Key: box.Key, Nonce: box.Nonce}, key, "was modified"},
+		{"truncated nonce", notify.SealedBox{Key: box.Key, Nonce: cut(box.Nonce), Data: box.Data}, key, "invalid nonce length"},
+		{"truncated key", notify.SealedBox{Key: cut(box.Key), Nonce: box.Nonce, Data: box.Data}, key, "invalid public key"},
+		{"invalid base64", notify.SealedBox{Key: box.Key, Nonce: box.Nonce, Data: "not base64!"}, key, "invalid data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openSealed(&tt.box, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("openSealed() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
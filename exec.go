@@ -0,0 +1,333 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Environment of commands run by "notify exec", through which they and
+// their descendants update its status card
+const (
+	envSocket      = "NOTIFY_SOCKET"       // address of the status endpoint
+	envSocketToken = "NOTIFY_SOCKET_TOKEN" // token the endpoint requires
+	envTag         = "NOTIFY_TAG"          // tag of the status card
+)
+
+// statusEndpoint receives the status lines of a command run by "notify
+// exec" on the loopback interface, for as long as the command runs
+type statusEndpoint struct {
+	token   string
+	tracker *progressTracker
+}
+
+// handler serves POST /progress, whose body is a status line read like
+// those of "notify progress"
+func (e *statusEndpoint) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /progress", func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(e.token)) != 1 {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if line := strings.TrimSpace(string(body)); line != "" {
+			e.tracker.set(line, []*regexp.Regexp{percentPattern, ratioPattern})
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// runExec implements "notify exec", a live status card for a command that
+// it and its descendants update with "notify update"
+func runExec(args []string) error {
+	opts := newNotifyOptions()
+	opts.AutoClose = false
+	tag := ""
+	every := 5 * time.Second
+	quietOnSuccess := false
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "tag", Set: func(v string) error { tag = v; return nil }},
+		cliFlag{Name: "every", Set: func(v string) (err error) { every, err = parseDuration(v); return }},
+		cliFlag{Name: "quiet-on-success", Bool: true, Set: func(v string) (err error) { quietOnSuccess, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showExecHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("a command to run is required. Run 'notify exec --help' for usage.")
+	}
+	if every < time.Second {
+		return fmt.Errorf("--every must be at least 1s")
+	}
+	subject := strings.TrimSpace(opts.Title)
+	if opts.Title == "" {
+		opts.Title = "Running"
+	}
+
+	n, err := opts.build(strings.Join(words, " "))
+	if err != nil {
+		return err
+	}
+	if tag == "" {
+		tag = "exec-" + newToken()
+	}
+	n.Tag = tag
+
+	// The token keeps other local users from updating the card
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	tracker := &progressTracker{value: -1}
+	endpoint := &statusEndpoint{token: newToken(), tracker: tracker}
+	go http.Serve(listener, endpoint.handler())
+
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		envSocket+"="+listener.Addr().String(),
+		envSocketToken+"="+endpoint.token,
+		envTag+"="+tag,
+	)
+
+	// Ctrl+C reaches the command too, notify reports how it ended
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	start := time.Now()
+	n.Progress = stopwatchProgress(start, "Starting...", -1, "")
+	if err := sendNotification(n); err != nil {
+		return err
+	}
+	// Only the card stays until dismissed
+	n.Sticky = false
+	name := filepath.Base(words[0])
+	if err := cmd.Start(); err != nil {
+		finishExec(n, start, subject, name, "", err)
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		tracker.finish(cmd.Wait())
+		close(exited)
+	}()
+	if err := tracker.follow(n, start, every); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the status card is no longer updated: %v\n", err)
+		<-exited
+	}
+
+	line, _, _, _, runErr := tracker.get()
+	if runErr == nil && quietOnSuccess {
+		// Only failures need attention, so the card just goes away
+		if n.Remote == "" {
+			if err := removeToast(n); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		return nil
+	}
+	if err := finishExec(n, start, subject, name, line, runErr); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Exit like the command, so notify exec can stand in for it in scripts
+	var exit *exec.ExitError
+	if errors.As(runErr, &exit) {
+		flushTraces()
+		os.Exit(max(exit.ExitCode(), 1))
+	}
+	return runErr
+}
+
+// finishExec replaces the status card with how the command ended. The
+// title says what finished or failed when --title named it.
+func finishExec(n *Notification, start time.Time, subject, name, line string, runErr error) error {
+	elapsed := formatDuration(time.Since(start))
+
+	n.Progress = nil
+	n.Type, n.Title = "error", "Failed"
+	if subject != "" {
+		n.Title = oneLine(subject+" failed", notify.MaxTitleLength)
+	}
+	var exit *exec.ExitError
+	switch {
+	case runErr == nil:
+		n.Type, n.Title = "success", "Finished"
+		if subject != "" {
+			n.Title = oneLine(subject+" finished", notify.MaxTitleLength)
+		}
+		n.Message = fmt.Sprintf("%s finished after %s", name, elapsed)
+	case errors.As(runErr, &exit) && exit.ExitCode() >= 0:
+		n.Message = fmt.Sprintf("%s exited with code %d after %s", name, exit.ExitCode(), elapsed)
+	default:
+		n.Message = fmt.Sprintf("%s failed after %s: %v", name, elapsed, runErr)
+	}
+	if line != "" {
+		n.Message += "\n" + oneLine(line, 120)
+	}
+	return sendNotification(n)
+}
+
+// runRun implements "notify run", which runs a command and notifies
+// whether it succeeded and how long it took, without the status card of
+// "notify exec"
+func runRun(args []string) error {
+	opts := newNotifyOptions()
+	flags := append(opts.flags(),
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showRunHelp(); os.Exit(0); return nil }},
+	)
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("a command to run is required, e.g. 'notify run -- make build'")
+	}
+	subject := strings.TrimSpace(opts.Title)
+	n, err := opts.build(strings.Join(words, " "))
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// Ctrl+C reaches the command too, notify reports how it ended
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	start := time.Now()
+	runErr := cmd.Run()
+	if err := finishExec(n, start, subject, filepath.Base(words[0]), "", runErr); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	var exit *exec.ExitError
+	if errors.As(runErr, &exit) {
+		flushTraces()
+		os.Exit(max(exit.ExitCode(), 1))
+	}
+	return runErr
+}
+
+func showRunHelp() {
+	fmt.Print(`Run a command and notify when it finishes
+
+Shows a "Finished" notification with the elapsed time when the command
+succeeds, and a "Failed" one with its exit code when it fails. notify run
+exits with the command's exit code, so it can stand in for the command in
+scripts. For a live status card while the command runs, use 'notify exec'.
+
+Usage:
+  notify run [OPTIONS] [--] COMMAND [ARGS]
+
+Options:
+  --title TITLE   What runs, e.g. "Build" for "Build finished" and
+                  "Build failed" (default: Finished and Failed)
+  Plus the notification options of 'notify --help', e.g. --sound.
+
+Examples:
+  notify run -- make build
+  notify run --title "Backup" -- ./backup.sh --full
+`)
+}
+
+// runUpdate implements "notify update", which reports a status line to
+// the card of the "notify exec" running the caller
+func runUpdate(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showExecHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	line := strings.Join(words, " ")
+	if line == "" {
+		return fmt.Errorf("a status line is required, e.g. 'notify update 42%% Compiling'")
+	}
+	addr := os.Getenv(envSocket)
+	if addr == "" {
+		return fmt.Errorf("$%s is not set, notify update only works in commands run by notify exec", envSocket)
+	}
+
+	req, err := http.NewRequest("POST", "http://"+addr+"/progress", strings.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv(envSocketToken))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("status card: %s", resp.Status)
+	}
+	return nil
+}
+
+func showExecHelp() {
+	fmt.Print(`Run a command under a live status card that it can update
+
+The card shows the elapsed time and the latest status line, and is
+replaced by a "Finished" or "Failed" notification when the command exits.
+notify exec exits with the command's exit code. With --quiet-on-success
+the card is removed when the command succeeds, so only failures are shown;
+the snippets of 'notify integrations make|taskfile|npm' use it.
+
+The command and everything it starts get these environment variables:
+  NOTIFY_SOCKET        Address of the status endpoint, e.g. 127.0.0.1:50123
+  NOTIFY_SOCKET_TOKEN  Token the endpoint requires
+  NOTIFY_TAG           Tag of the card, for 'notify --tag' to replace it
+
+Status lines are sent with 'notify update LINE' or POSTed to
+http://$NOTIFY_SOCKET/progress with "Authorization: Bearer TOKEN". Lines
+like "42%" or "3 of 10" drive the progress bar.
+
+Usage:
+  notify exec [OPTIONS] [--] COMMAND [ARGS]
+  notify update LINE
+
+Options:
+  --tag TAG          Identifies the toast (default: generated)
+  --every DURATION   How often the toast is updated (default: 5s)
+  --quiet-on-success Remove the card instead of notifying when the command
+                     succeeds
+  Plus the notification options of 'notify --help', e.g. --title.
+
+Examples:
+  notify exec --title Backup -- ./backup.sh --full
+  notify update "Copied 3 of 10 folders"
+  curl -d 42% -H "Authorization: Bearer $NOTIFY_SOCKET_TOKEN" http://$NOTIFY_SOCKET/progress
+`)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// expectRecord tracks a "notify expect" waiting for check-ins
+type expectRecord struct {
+	Within  time.Duration `json:"within"`
+	Missed  time.Time     `json:"missed,omitzero"` // when the overdue check-in was due, zero if none is
+	PID     int           `json:"pid"`             // of the waiting process
+	Created time.Time     `json:"created"`
+}
+
+// due returns when the next check-in is due, counting from the last
+// check-in or else from when the expectation started
+func (r *expectRecord) due(last time.Time) time.Time {
+	if last.Before(r.Created) {
+		last = r.Created
+	}
+	return last.Add(r.Within)
+}
+
+// parseCheckName checks the name of a check-in, a word like backup
+func parseCheckName(name string) (string, error) {
+	if name == "" || strings.ContainsFunc(name, unicode.IsSpace) {
+		return "", fmt.Errorf("%q is not a valid check-in name, use a word like backup", name)
+	}
+	return name, nil
+}
+
+// runCheckin implements "notify checkin NAME", which tells the waiting
+// "notify expect NAME" that the job it watches ran
+func runCheckin(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showExpectHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return errors.New("usage: notify checkin NAME")
+	}
+	name, err := parseCheckName(words[0])
+	if err != nil {
+		return err
+	}
+	return recordCheckin(name)
+}
+
+// recordCheckin records that the job checking in as name just ran
+func recordCheckin(name string) error {
+	return updateState(func(s *State) error {
+		if s.Checkins == nil {
+			s.Checkins = map[string]time.Time{}
+		}
+		s.Checkins[name] = time.Now()
+		return nil
+	})
+}
+
+// runExpect implements "notify expect NAME --within DURATION", a dead
+// man's switch that alerts when "notify checkin NAME" doesn't come in time
+func runExpect(args []string) error {
+	opts := newNotifyOptions()
+	opts.Type = "error"
+	within := time.Duration(0)
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "within", Set: func(v string) (err error) { within, err = parseDuration(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showExpectHelp(); os.Exit(0); return nil }},
+	)
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return listExpectations()
+	}
+	if within < time.Second {
+		return errors.New("usage: notify expect NAME --within DURATION [MESSAGE] [OPTIONS], e.g. --within 1h")
+	}
+	name, err := parseCheckName(words[0])
+	if err != nil {
+		return err
+	}
+	message := strings.Join(words[1:], " ")
+	if _, err := opts.build(orDefault(message, name)); err != nil {
+		return err
+	}
+
+	if err := addExpectation(name, within); err != nil {
+		return err
+	}
+	defer removeExpectation(name)
+	removeExpectationOnInterrupt(name)
+	fmt.Printf("Expecting 'notify checkin %s' every %s (Ctrl+C to stop)\n", name, formatDuration(within))
+
+	for {
+		state, err := loadState()
+		if err != nil {
+			return err
+		}
+		record, ok := state.Expectations[name]
+		if !ok || record.PID != os.Getpid() {
+			return nil
+		}
+		last := state.Checkins[name]
+		due := record.due(last)
+
+		var n *Notification
+		switch now := time.Now(); {
+		case record.Missed.IsZero() && now.After(due):
+			n, err = opts.build(orDefault(message, expectOverdue(last, now)))
+			if err == nil && opts.Title == "" {
+				n.Title = name + " missed its check-in"
+			}
+		case !record.Missed.IsZero() && last.After(record.Missed):
+			// The alert is replaced, as the job is running again
+			recovered := *opts
+			recovered.Type, recovered.Title = "success", name+" checked in again"
+			n, err = recovered.build(fmt.Sprintf("%s late, next check-in due by %s", formatDuration(last.Sub(record.Missed)), describeWhen(due.Local())))
+			due = time.Time{}
+		default:
+			// The wall clock is read every poll because the monotonic clock
+			// stops while the machine sleeps
+			time.Sleep(min(max(time.Until(due), 0)+time.Millisecond, schedulePoll))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		n.Tag = oneLine("expect "+name, notify.MaxTagLength)
+		if err := sendNotification(n); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if err := setExpectationMissed(name, due); err != nil {
+			return err
+		}
+	}
+}
+
+// expectOverdue describes a missed check-in, like "No check-in for 1h5m,
+// the last was at Mon 2 Jan 09:00"
+func expectOverdue(last, now time.Time) string {
+	if last.IsZero() {
+		return "No check-in yet"
+	}
+	return fmt.Sprintf("No check-in for %s, the last was at %s", formatDuration(now.Sub(last)), describeWhen(last.Local()))
+}
+
+// addExpectation records that this process expects check-ins of name
+func addExpectation(name string, within time.Duration) error {
+	return updateState(func(s *State) error {
+		if record, taken := s.Expectations[name]; taken && processRunning(record.PID) {
+			return fmt.Errorf("'notify expect %s' is already running", name)
+		}
+		if s.Expectations == nil {
+			s.Expectations = map[string]*expectRecord{}
+		}
+		s.Expectations[name] = &expectRecord{Within: within, PID: os.Getpid(), Created: time.Now()}
+		return nil
+	})
+}
+
+// setExpectationMissed records when the overdue check-in was due, or that
+// none is when missed is zero
+func setExpectationMissed(name string, missed time.Time) error {
+	return updateState(func(s *State) error {
+		if record, ok := s.Expectations[name]; ok {
+			record.Missed = missed
+		}
+		return nil
+	})
+}
+
+// removeExpectationOnInterrupt forgets the expectation when Ctrl+C stops
+// the waiting process
+func removeExpectationOnInterrupt(name string) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		removeExpectation(name)
+		os.Exit(130)
+	}()
+}
+
+// removeExpectation forgets an expectation owned by this process
+func removeExpectation(name string) {
+	err := updateState(func(s *State) error {
+		if record, ok := s.Expectations[name]; ok && record.PID == os.Getpid() {
+			delete(s.Expectations, name)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// listExpectations prints the running expectations, dropping those whose
+// process has ended, e.g. at a restart
+func listExpectations() error {
+	var expectations map[string]*expectRecord
+	var checkins map[string]time.Time
+	err := updateState(func(s *State) error {
+		for name, record := range s.Expectations {
+			if !processRunning(record.PID) {
+				delete(s.Expectations, name)
+			}
+		}
+		expectations, checkins = s.Expectations, s.Checkins
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(expectations) == 0 {
+		fmt.Println("No check-ins expected, start one with 'notify expect NAME --within DURATION'")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tWITHIN\tLAST CHECK-IN\tDUE\tSTATE")
+	for _, name := range slices.Sorted(maps.Keys(expectations)) {
+		record, last := expectations[name], checkins[name]
+		lastText, state := "-", "waiting"
+		if !last.IsZero() {
+			lastText = last.Local().Format("2006-01-02 15:04")
+		}
+		if !record.Missed.IsZero() {
+			state = "overdue"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, formatDuration(record.Within), lastText,
+			record.due(last).Local().Format("2006-01-02 15:04"), state)
+	}
+	return w.Flush()
+}
+
+func showExpectHelp() {
+	fmt.Print(`Alert when a job doesn't check in on time (a dead man's switch)
+
+'notify expect NAME --within DURATION' waits for 'notify checkin NAME',
+which the watched job runs whenever it succeeds, e.g. at the end of a
+backup script or cron job. If no check-in arrives within DURATION of the
+last one (or of starting), it shows an error once; the next check-in
+replaces it with a success. Check-ins are recorded even while nothing
+expects them, so the job and the expectation can start in any order.
+
+Without arguments, 'notify expect' lists the running expectations.
+
+Usage:
+  notify expect NAME --within DURATION [MESSAGE] [OPTIONS]
+  notify expect
+  notify checkin NAME
+
+Options:
+  --within DURATION  How long after a check-in the next one is due,
+                     e.g. 1h, 25h or 7d
+  --type TYPE        Type of the alert (default: error)
+  --title TITLE      Title of the alert (default: NAME missed its check-in)
+  --help             Show this help
+
+The other options of notify, like --urgent, --app or --remote, apply to
+the alert. MESSAGE replaces the default, which says when the last
+check-in was.
+
+Examples:
+  notify expect backup --within 25h --urgent
+  rsync -a ~/docs /mnt/backup && notify checkin backup
+`)
+}
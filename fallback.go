@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// Ways to show a notification when Windows refuses the toast
+var validFallbacks = []string{"msgbox", "flash", "badge"}
+
+// parseFallback checks a --fallback value
+func parseFallback(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || slices.Contains(validFallbacks, s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid fallback %q.%s Valid fallbacks are: %s",
+		s, suggest.DidYouMean(s, validFallbacks, ""), strings.Join(validFallbacks, ", "))
+}
+
+// showFallback makes a notification visible after its toast failed
+func showFallback(n *Notification, parent *span) (err error) {
+	fallback := startSpan(parent, "deliver", "notify.target", n.Fallback)
+	defer func() { fallback.finish(err) }()
+
+	switch n.Fallback {
+	case "msgbox":
+		return showMessageBox(n)
+	case "flash":
+		return flashWindow(n)
+	case "badge":
+		return showBadge(n)
+	}
+	return fmt.Errorf("unknown fallback %q", n.Fallback)
+}
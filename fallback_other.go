@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// showMessageBox is only available on Windows
+func showMessageBox(n *Notification) error {
+	return errors.New("message boxes are only supported on Windows")
+}
+
+// flashWindow is only available on Windows
+func flashWindow(n *Notification) error {
+	return errors.New("flashing the taskbar is only supported on Windows")
+}
+
+// showBadge is only available on Windows
+func showBadge(n *Notification) error {
+	return errors.New("taskbar badges are only supported on Windows")
+}
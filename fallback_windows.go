@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+var (
+	wtsapi32           = syscall.NewLazyDLL("wtsapi32.dll")
+	procWTSSendMessage = wtsapi32.NewProc("WTSSendMessageW")
+)
+
+const wtsServerNil = 0 // WTS_CURRENT_SERVER_HANDLE
+
+// MessageBox styles
+const (
+	mbIconError       = 0x10
+	mbIconWarning     = 0x30
+	mbIconInformation = 0x40
+	mbSetForeground   = 0x10000
+	mbTopmost         = 0x40000
+)
+
+// showMessageBox shows the notification in a topmost message box. The
+// box is shown by the session itself, so it also works from session 0
+// and notify doesn't wait for it to be dismissed.
+func showMessageBox(n *Notification) error {
+	id := powershell.CurrentSessionID()
+	if n.Session != "" || id == 0 {
+		target := n.Session
+		if target == "" {
+			target = "active"
+		}
+		var err error
+		if id, err = powershell.ResolveSession(target); err != nil {
+			return err
+		}
+	}
+
+	icon := mbIconInformation
+	switch n.Type {
+	case "error":
+		icon = mbIconError
+	case "warning":
+		icon = mbIconWarning
+	}
+
+	title, err := syscall.UTF16FromString(n.Title)
+	if err != nil {
+		return err
+	}
+	message, err := syscall.UTF16FromString(n.Message)
+	if err != nil {
+		return err
+	}
+
+	// Lengths are in bytes without the terminating NUL; a zero timeout
+	// keeps the box until it is dismissed
+	var response uint32
+	r, _, err := procWTSSendMessage.Call(wtsServerNil, uintptr(id),
+		uintptr(unsafe.Pointer(&title[0])), uintptr((len(title)-1)*2),
+		uintptr(unsafe.Pointer(&message[0])), uintptr((len(message)-1)*2),
+		uintptr(icon|mbSetForeground|mbTopmost), 0, uintptr(unsafe.Pointer(&response)), 0)
+	if r == 0 {
+		return fmt.Errorf("showing a message box in session %d: %w", id, err)
+	}
+	return nil
+}
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	user32                       = syscall.NewLazyDLL("user32.dll")
+	procFlashWindowEx            = user32.NewProc("FlashWindowEx")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procIsWindowVisible          = user32.NewProc("IsWindowVisible")
+	procGetAncestor              = user32.NewProc("GetAncestor")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procCreateIconFromResourceEx = user32.NewProc("CreateIconFromResourceEx")
+	procDestroyIcon              = user32.NewProc("DestroyIcon")
+	procGetConsoleWindow         = kernel32.NewProc("GetConsoleWindow")
+
+	ole32                = syscall.NewLazyDLL("ole32.dll")
+	procCoInitializeEx   = ole32.NewProc("CoInitializeEx")
+	procCoUninitialize   = ole32.NewProc("CoUninitialize")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+)
+
+// Window and taskbar constants
+const (
+	flashAll          = 0x3 // FLASHW_ALL
+	flashUntilFocused = 0xC // FLASHW_TIMERNOFG
+	gaRootOwner       = 3
+	iconVersion       = 0x30000
+	clsctxInproc      = 0x1
+	comApartment      = 0x2 // COINIT_APARTMENTTHREADED
+
+	// ITaskbarList3 vtable slots
+	taskbarRelease        = 2
+	taskbarHrInit         = 3
+	taskbarSetOverlayIcon = 18
+)
+
+var (
+	clsidTaskbarList = syscall.GUID{Data1: 0x56FDF344, Data2: 0xFD6D, Data3: 0x11D0, Data4: [8]byte{0x95, 0x8A, 0x00, 0x60, 0x97, 0xC9, 0xA0, 0x90}}
+	iidTaskbarList3  = syscall.GUID{Data1: 0xEA1AFB91, Data2: 0x9E28, Data3: 0x4B86, Data4: [8]byte{0x90, 0xE9, 0x9E, 0x9F, 0x8A, 0x5E, 0xEF, 0xAF}}
+)
+
+// flashInfo is FLASHWINFO
+type flashInfo struct {
+	Size    uint32
+	Window  uintptr
+	Flags   uint32
+	Count   uint32
+	Timeout uint32
+}
+
+// flashWindow flashes the taskbar button of the chosen window until the
+// user switches to it
+func flashWindow(n *Notification) error {
+	hwnd, err := findWindow(n.Window)
+	if err != nil {
+		return err
+	}
+	info := flashInfo{Window: hwnd, Flags: flashAll | flashUntilFocused}
+	info.Size = uint32(unsafe.Sizeof(info))
+	procFlashWindowEx.Call(uintptr(unsafe.Pointer(&info)))
+	return nil
+}
+
+// showBadge puts the icon of the notification type on the taskbar button
+// of the chosen window, described by the title for screen readers
+func showBadge(n *Notification) error {
+	hwnd, err := findWindow(n.Window)
+	if err != nil {
+		return err
+	}
+
+	iconPath, err := cachedIcon(n.Type, n.iconStyle())
+	if err != nil {
+		return err
+	}
+	png, err := os.ReadFile(iconPath)
+	if err != nil {
+		return err
+	}
+	icon, _, err := procCreateIconFromResourceEx.Call(uintptr(unsafe.Pointer(&png[0])), uintptr(len(png)), 1, iconVersion, 16, 16, 0)
+	if icon == 0 {
+		return fmt.Errorf("creating the badge icon: %w", err)
+	}
+	defer procDestroyIcon.Call(icon)
+
+	// COM calls must stay on the thread that initialized it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	procCoInitializeEx.Call(0, comApartment)
+	defer procCoUninitialize.Call()
+
+	var taskbar *comObject
+	if hr, _, _ := procCoCreateInstance.Call(uintptr(unsafe.Pointer(&clsidTaskbarList)), 0, clsctxInproc,
+		uintptr(unsafe.Pointer(&iidTaskbarList3)), uintptr(unsafe.Pointer(&taskbar))); hr != 0 {
+		return fmt.Errorf("the taskbar is not available (0x%08X)", uint32(hr))
+	}
+	defer comCall(taskbar, taskbarRelease)
+
+	if hr := comCall(taskbar, taskbarHrInit); hr != 0 {
+		return fmt.Errorf("the taskbar is not available (0x%08X)", hr)
+	}
+	description, err := syscall.UTF16PtrFromString(n.Title)
+	if err != nil {
+		return err
+	}
+	if hr := comCall(taskbar, taskbarSetOverlayIcon, hwnd, icon, uintptr(unsafe.Pointer(description))); hr != 0 {
+		return fmt.Errorf("setting the taskbar badge (0x%08X)", hr)
+	}
+	return nil
+}
+
+// comObject is the layout of a COM interface pointer
+type comObject struct {
+	vtable *[taskbarSetOverlayIcon + 1]uintptr
+}
+
+// comCall calls a method of a COM object by its vtable slot
+func comCall(object *comObject, slot int, args ...uintptr) uint32 {
+	hr, _, _ := syscall.SyscallN(object.vtable[slot], append([]uintptr{uintptr(unsafe.Pointer(object))}, args...)...)
+	return uint32(hr)
+}
+
+// findWindow returns the visible window whose title contains the
+// --window text, or without it the window of the terminal notify runs in
+func findWindow(title string) (uintptr, error) {
+	if title == "" {
+		if console, _, _ := procGetConsoleWindow.Call(); console != 0 {
+			// Windows Terminal owns the hidden console window
+			root, _, _ := procGetAncestor.Call(console, gaRootOwner)
+			return root, nil
+		}
+		if hwnd, _, _ := procGetForegroundWindow.Call(); hwnd != 0 {
+			return hwnd, nil
+		}
+		return 0, fmt.Errorf("notify has no window to use, choose one with --window")
+	}
+
+	var found uintptr
+	var titles []string
+	want := strings.ToLower(title)
+	callback := syscall.NewCallback(func(hwnd, _ uintptr) uintptr {
+		if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+			return 1
+		}
+		buf := make([]uint16, 256)
+		length, _, _ := procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if length == 0 {
+			return 1
+		}
+		text := syscall.UTF16ToString(buf[:length])
+		if strings.Contains(strings.ToLower(text), want) {
+			found = hwnd
+			return 0
+		}
+		titles = append(titles, text)
+		return 1
+	})
+	procEnumWindows.Call(callback, 0)
+
+	if found == 0 {
+		return 0, fmt.Errorf("no window title contains %q.%s", title, suggest.DidYouMean(title, titles, ""))
+	}
+	return found, nil
+}
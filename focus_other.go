@@ -0,0 +1,36 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// focusAssist is only available on Windows
+func focusAssist() string {
+	return ""
+}
+
+// sessionLocked is only available on Windows
+func sessionLocked() bool {
+	return false
+}
+
+// errFocusUnsupported is returned as focus sessions are a Windows feature
+var errFocusUnsupported = errors.New("focus sessions are only supported on Windows 11")
+
+// focusSessionActive is only available on Windows
+func focusSessionActive() bool {
+	return false
+}
+
+// startFocusSession is only available on Windows
+func startFocusSession(d time.Duration) error {
+	return errFocusUnsupported
+}
+
+// stopFocusSession is only available on Windows
+func stopFocusSession() error {
+	return errFocusUnsupported
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+)
+
+var (
+	ntdll                   = syscall.NewLazyDLL("ntdll.dll")
+	procNtQueryWnfStateData = ntdll.NewProc("NtQueryWnfStateData")
+	procOpenInputDesktop    = user32.NewProc("OpenInputDesktop")
+	procCloseDesktop        = user32.NewProc("CloseDesktop")
+)
+
+// WNF_SHEL_QUIETHOURS_ACTIVE_PROFILE_CHANGED, the state Windows keeps
+// the current Focus Assist mode in
+const wnfQuietHours uint64 = 0x0D83063EA3BF1C75
+
+// focusAssist returns the active Focus Assist (Do Not Disturb) mode:
+// "priority only", "alarms only" or "" when it is off or unknown
+func focusAssist() string {
+	name := wnfQuietHours
+	var stamp, mode uint32
+	size := uint32(unsafe.Sizeof(mode))
+	status, _, _ := procNtQueryWnfStateData.Call(uintptr(unsafe.Pointer(&name)), 0, 0,
+		uintptr(unsafe.Pointer(&stamp)), uintptr(unsafe.Pointer(&mode)), uintptr(unsafe.Pointer(&size)))
+	if status != 0 {
+		return ""
+	}
+
+	switch mode {
+	case 1:
+		return "priority only"
+	case 2:
+		return "alarms only"
+	}
+	return ""
+}
+
+// sessionLocked reports whether the screen of notify's session is locked.
+// The input desktop is then the secure Winlogon desktop, which a user's
+// process can't open.
+func sessionLocked() bool {
+	if powershell.CurrentSessionID() == 0 {
+		return false
+	}
+	desktop, _, _ := procOpenInputDesktop.Call(0, 0, 0x100) // DESKTOP_SWITCHDESKTOP
+	if desktop == 0 {
+		return true
+	}
+	procCloseDesktop.Call(desktop)
+	return false
+}
+
+// Loads the focus session API of Windows 11 22H2 and later into $focus,
+// or stops the script when the Windows version has none
+const focusSessionPrelude = `
+$type = [Windows.UI.Shell.FocusSessionManager, Windows.UI.Shell, ContentType = WindowsRuntime]
+if (-not $type -or -not [Windows.UI.Shell.FocusSessionManager]::IsSupported) { 'unsupported'; exit }
+$focus = [Windows.UI.Shell.FocusSessionManager]::GetDefault()
+`
+
+// errFocusUnsupported is returned on Windows versions without focus sessions
+var errFocusUnsupported = errors.New("focus sessions need Windows 11 22H2 or later")
+
+// runFocusSession runs a script with $focus loaded and returns its
+// trimmed output
+func runFocusSession(script string) (string, error) {
+	out, err := powershell.Run(focusSessionPrelude + script)
+	text := strings.TrimSpace(string(out))
+	if err != nil {
+		return "", fmt.Errorf("focus session: %v: %s", err, text)
+	}
+	if text == "unsupported" {
+		return "", errFocusUnsupported
+	}
+	return text, nil
+}
+
+// focusSessionActive reports whether a focus session of the Clock app or
+// the notification center is running
+func focusSessionActive() bool {
+	out, err := runFocusSession(`$focus.IsFocusActive`)
+	return err == nil && strings.EqualFold(out, "True")
+}
+
+// startFocusSession starts a focus session, which turns on Do Not Disturb
+// and hides taskbar badges as the user set it up, ending after d
+func startFocusSession(d time.Duration) error {
+	_, err := runFocusSession(fmt.Sprintf(`$null = $focus.TryStartFocusSession([DateTimeOffset]::Now.AddSeconds(%d))`, int(d.Seconds())))
+	return err
+}
+
+// stopFocusSession ends the running focus session, if any
+func stopFocusSession() error {
+	_, err := runFocusSession(`$focus.DeactivateFocus()`)
+	return err
+}
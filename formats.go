@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// relayConfig holds the settings of "notify relay"
+type relayConfig struct {
+	Formats map[string]messageFormat `yaml:"formats"` // custom formatting profiles by name
+	Targets map[string]string        `yaml:"targets"` // profile of each --to relay, by URL
+}
+
+// messageFormat is a formatting profile, rewriting notifications for the
+// kind of device a target relay shows them on
+type messageFormat struct {
+	Title   string `yaml:"title"`   // template of the title, empty keeps it
+	Message string `yaml:"message"` // template of the message, empty keeps it
+	Plain   bool   `yaml:"plain"`   // drop the image and link
+
+	// send, link (to a copy hosted by the relay) or drop
+	Attachments string `yaml:"attachments"`
+}
+
+// Profiles available without configuring them
+var builtinFormats = map[string]messageFormat{
+	"full":  {},
+	"plain": {Plain: true, Attachments: "drop"},
+	"terse": {Title: `{{oneLine .Title 30}}`, Message: `{{oneLine .Message 140}}`, Plain: true, Attachments: "link"},
+}
+
+// Functions of format templates, besides the built-in ones
+var formatFuncs = template.FuncMap{
+	"oneLine": func(s string, n int) string { return oneLine(s, n) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+}
+
+// targetFormat is a profile ready to apply
+type targetFormat struct {
+	name           string
+	title, message *template.Template // nil keeps the field
+	plain          bool
+	attachments    string
+}
+
+// validate checks the profiles and the targets using them
+func (c *relayConfig) validate() error {
+	for name, f := range c.Formats {
+		if _, ok := builtinFormats[name]; ok {
+			return fmt.Errorf("relay format %q is built in, choose another name", name)
+		}
+		if _, err := f.compile(name); err != nil {
+			return err
+		}
+	}
+	for target, name := range c.Targets {
+		if _, err := notify.RelayEndpoint(target); err != nil {
+			return fmt.Errorf("relay targets: %w", err)
+		}
+		if _, ok := c.format(name); !ok {
+			names := slices.Sorted(maps.Keys(builtinFormats))
+			names = append(names, slices.Sorted(maps.Keys(c.Formats))...)
+			return fmt.Errorf("unknown relay format %q for %s.%s Formats are: %s", name, target, suggest.DidYouMean(name, names, ""), strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// format returns the profile called name, configured or built in
+func (c *relayConfig) format(name string) (messageFormat, bool) {
+	if f, ok := c.Formats[name]; ok {
+		return f, true
+	}
+	f, ok := builtinFormats[name]
+	return f, ok
+}
+
+// targetFormats returns the profile of each target relay configured with
+// one, by the --to value. The config was validated when loaded.
+func (c *relayConfig) targetFormats(targets []string) (map[string]*targetFormat, error) {
+	byEndpoint := map[string]string{}
+	for target, name := range c.Targets {
+		endpoint, _ := notify.RelayEndpoint(target)
+		byEndpoint[endpoint] = name
+	}
+
+	formats := map[string]*targetFormat{}
+	for _, target := range targets {
+		endpoint, _ := notify.RelayEndpoint(target)
+		name, ok := byEndpoint[endpoint]
+		if !ok {
+			continue
+		}
+		f, _ := c.format(name)
+		compiled, err := f.compile(name)
+		if err != nil {
+			return nil, err
+		}
+		formats[target] = compiled
+	}
+	return formats, nil
+}
+
+// compile parses the templates of a profile
+func (f messageFormat) compile(name string) (*targetFormat, error) {
+	compiled := &targetFormat{name: name, plain: f.Plain, attachments: orDefault(f.Attachments, "send")}
+	switch compiled.attachments {
+	case "send", "link", "drop":
+	default:
+		return nil, fmt.Errorf("relay format %q: attachments must be send, link or drop, got %q", name, f.Attachments)
+	}
+	for _, field := range []struct {
+		text string
+		to   **template.Template
+	}{{f.Title, &compiled.title}, {f.Message, &compiled.message}} {
+		if field.text == "" {
+			continue
+		}
+		t, err := template.New(name).Funcs(formatFuncs).Option("missingkey=error").Parse(field.text)
+		if err != nil {
+			return nil, fmt.Errorf("relay format %q: %w", name, err)
+		}
+		*field.to = t
+	}
+	return compiled, nil
+}
+
+// apply returns the message rewritten by the profile. Encrypted messages
+// can't be read, so they are passed on unchanged, as are all messages
+// when f is nil. host returns the link of an attachment, or is nil when
+// attachments can't be linked.
+func (f *targetFormat) apply(m *notify.RelayMessage, host func(*notify.Attachment) (string, error)) (*notify.RelayMessage, error) {
+	if f == nil || m.Sealed != nil {
+		return m, nil
+	}
+	out := *m
+	if f.title != nil {
+		var b strings.Builder
+		if err := f.title.Execute(&b, m); err != nil {
+			return nil, fmt.Errorf("relay format %q: %w", f.name, err)
+		}
+		out.Title = oneLine(b.String(), notify.MaxTitleLength)
+	}
+	if f.message != nil {
+		var b strings.Builder
+		if err := f.message.Execute(&b, m); err != nil {
+			return nil, fmt.Errorf("relay format %q: %w", f.name, err)
+		}
+		out.Message = strings.TrimSpace(b.String())
+		if out.Message == "" {
+			return nil, fmt.Errorf("relay format %q: the message is empty", f.name)
+		}
+	}
+	if f.plain {
+		out.Image, out.Link = "", ""
+	}
+	if out.Attachment != nil && f.attachments != "send" {
+		if f.attachments == "link" && host != nil {
+			link, err := host(out.Attachment)
+			if err != nil {
+				return nil, fmt.Errorf("hosting attachment %s: %w", out.Attachment.Name, err)
+			}
+			out.Message += "\n" + out.Attachment.Name + ": " + link
+		}
+		out.Attachment = nil
+	}
+	return &out, nil
+}
@@ -1,7 +1,3 @@
-module notify
+module github.com/sarfraznawaz2005/notify
 
 go 1.25.5
-
-require github.com/go-toast/toast v0.0.0-20190211030409-01e6764cf0a4
-
-require github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Groups that receive nothing for this long start over
+const groupIdle = time.Hour
+
+// groupRecord collects the notifications sent with the same --group
+type groupRecord struct {
+	Size    int         `json:"size,omitempty"` // expected number of items, 0 if unknown
+	Items   []groupItem `json:"items"`
+	Updated time.Time   `json:"updated"`
+}
+
+// groupItem is one notification summarized by a group
+type groupItem struct {
+	Type    string    `json:"type"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// addToGroup records n in its group and returns a copy of the group.
+// A group that was completed or has been idle starts over.
+func addToGroup(n *Notification) (*groupRecord, error) {
+	var result groupRecord
+	err := updateState(func(s *State) error {
+		if s.Groups == nil {
+			s.Groups = map[string]*groupRecord{}
+		}
+
+		g := s.Groups[n.Group]
+		if g == nil || time.Since(g.Updated) > groupIdle || g.complete() {
+			g = &groupRecord{}
+			s.Groups[n.Group] = g
+		}
+		if n.GroupSize > 0 {
+			g.Size = n.GroupSize
+		}
+
+		g.Updated = time.Now()
+		g.Items = append(g.Items, groupItem{
+			Type:    n.Type,
+			Title:   n.Title,
+			Message: n.storedMessage(),
+			Time:    g.Updated,
+		})
+
+		result = *g
+		result.Items = append([]groupItem(nil), g.Items...)
+		return nil
+	})
+	return &result, err
+}
+
+// complete reports whether all expected items have arrived
+func (g *groupRecord) complete() bool {
+	return g.Size > 0 && len(g.Items) >= g.Size
+}
+
+// groupSummary returns the toast that replaces the previous summary of
+// n's group: a count in the title and one line per item, newest first,
+// which Action Center shows in full when the toast is expanded
+func groupSummary(n *Notification, g *groupRecord) *Notification {
+	counts := map[string]int{}
+	for _, item := range g.Items {
+		counts[item.Type]++
+	}
+
+	summary := *n
+	summary.Tag = "summary"
+
+	switch {
+	case counts["error"] > 0:
+		summary.Type = "error"
+	case counts["warning"] > 0:
+		summary.Type = "warning"
+	case g.complete():
+		summary.Type = "success"
+	default:
+		summary.Type = "info"
+	}
+
+	if g.Size > 0 {
+		summary.Title = fmt.Sprintf("%s: %d of %d tasks complete", n.Group, len(g.Items), g.Size)
+	} else {
+		summary.Title = fmt.Sprintf("%s: %d notifications", n.Group, len(g.Items))
+	}
+	if failed := counts["error"]; failed > 0 {
+		summary.Title += fmt.Sprintf(", %d failed", failed)
+	}
+
+	lines := make([]string, 0, len(g.Items))
+	for i := len(g.Items) - 1; i >= 0; i-- {
+		item := g.Items[i]
+		lines = append(lines, fmt.Sprintf("%s %s: %s", notify.Symbol(item.Type), item.Title, oneLine(item.Message, 60)))
+	}
+	summary.Message = strings.Join(lines, "\n")
+	return &summary
+}
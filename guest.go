@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// guestToken lets a temporary script or a colleague send a limited class
+// of notifications to the relay, instead of its own --token. Only the hash
+// of the token is kept.
+type guestToken struct {
+	Hash    string    `json:"hash"`
+	Types   []string  `json:"types,omitempty"` // allowed types, all when empty
+	Expires time.Time `json:"expires"`
+	Uses    int       `json:"uses"` // notifications left, 0 for any number
+}
+
+// Guest tokens start with this, so they are told apart from relay tokens
+const guestTokenPrefix = "ng_"
+
+// errGuestUnknown is returned for guest tokens that can't be used at all
+var errGuestUnknown = errors.New("the token is unknown, expired or used up")
+
+// runToken implements "notify token"
+func runToken(args []string) error {
+	ttl, uses := time.Hour, 1
+	var types []string
+	flags := []cliFlag{
+		{Name: "ttl", Set: func(v string) (err error) { ttl, err = parseDuration(v); return }},
+		{Name: "types", Set: func(v string) (err error) { types, err = parseTypes(v); return }},
+		{Name: "uses", Set: func(v string) (err error) { uses, err = parseCount(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showTokenHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 || words[0] == "list" {
+		if len(words) > 1 {
+			return fmt.Errorf("unexpected argument: %s", words[1])
+		}
+		return listGuestTokens()
+	}
+
+	actions := []string{"list", "create", "revoke"}
+	action, names := words[0], words[1:]
+	switch action {
+	case "create":
+		if len(names) > 1 {
+			return fmt.Errorf("unexpected argument: %s", names[1])
+		}
+		name := ""
+		if len(names) == 1 {
+			name = names[0]
+		}
+		return createGuestToken(name, ttl, types, uses)
+	case "revoke":
+		if len(names) == 0 {
+			return errors.New("usage: notify token revoke NAME...")
+		}
+		return revokeGuestTokens(names)
+	}
+	return fmt.Errorf("unknown token command %q.%s Commands are: %s", action, suggest.DidYouMean(action, actions, ""), strings.Join(actions, ", "))
+}
+
+// parseTypes checks a comma separated list of notification types
+func parseTypes(s string) ([]string, error) {
+	var types []string
+	for t := range strings.SplitSeq(strings.ToLower(s), ",") {
+		t = strings.TrimSpace(t)
+		if !isValidType(t) {
+			return nil, fmt.Errorf("invalid type %q.%s Valid types are: %s",
+				t, suggest.DidYouMean(t, notify.Types, ""), strings.Join(notify.Types, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// createGuestToken mints a token and prints it, the only time it is shown
+func createGuestToken(name string, ttl time.Duration, types []string, uses int) error {
+	secret := make([]byte, 24)
+	rand.Read(secret)
+	token := guestTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	if name == "" {
+		name = newToken()
+	}
+
+	record := &guestToken{Hash: hashGuestToken(token), Types: types, Expires: time.Now().Add(ttl), Uses: uses}
+	err := updateState(func(s *State) error {
+		pruneGuestTokens(s)
+		if _, ok := s.GuestTokens[name]; ok {
+			return fmt.Errorf("a token named %q exists, revoke it first or pick another name", name)
+		}
+		if s.GuestTokens == nil {
+			s.GuestTokens = map[string]*guestToken{}
+		}
+		s.GuestTokens[name] = record
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	fmt.Fprintf(os.Stderr, "Token %q sends %s until %s. Send with --remote and --token, or as the bearer token of POST /notify.\n",
+		name, record.scope(), record.Expires.Format("2006-01-02 15:04"))
+	return nil
+}
+
+// scope describes what a token may send
+func (g *guestToken) scope() string {
+	types := "any type"
+	if len(g.Types) > 0 {
+		types = strings.Join(g.Types, ", ")
+	}
+	switch g.Uses {
+	case 0:
+		return "notifications of " + types
+	case 1:
+		return "one notification of " + types
+	}
+	return fmt.Sprintf("%d notifications of %s", g.Uses, types)
+}
+
+// listGuestTokens prints the tokens that can still be used
+func listGuestTokens() error {
+	var tokens map[string]*guestToken
+	err := updateState(func(s *State) error {
+		pruneGuestTokens(s)
+		tokens = s.GuestTokens
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No guest tokens")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tEXPIRES\tSENDS")
+	for _, name := range slices.Sorted(maps.Keys(tokens)) {
+		g := tokens[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, g.Expires.Format("2006-01-02 15:04"), g.scope())
+	}
+	return w.Flush()
+}
+
+// revokeGuestTokens removes tokens by name
+func revokeGuestTokens(names []string) error {
+	return updateState(func(s *State) error {
+		for _, name := range names {
+			if _, ok := s.GuestTokens[name]; !ok {
+				return fmt.Errorf("no guest token named %q", name)
+			}
+		}
+		for _, name := range names {
+			delete(s.GuestTokens, name)
+			fmt.Printf("Revoked %s\n", name)
+		}
+		return nil
+	})
+}
+
+// pruneGuestTokens removes expired tokens; used up ones are removed by
+// their last use
+func pruneGuestTokens(s *State) {
+	now := time.Now()
+	for name, g := range s.GuestTokens {
+		if now.After(g.Expires) {
+			delete(s.GuestTokens, name)
+		}
+	}
+}
+
+// hashGuestToken returns the hash a token is stored under
+func hashGuestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// guestBearer returns the guest token of a request, if it carries one
+func guestBearer(r *http.Request) string {
+	given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !strings.HasPrefix(given, guestTokenPrefix) {
+		return ""
+	}
+	return given
+}
+
+// useGuestToken checks that a guest token may send a notification of type
+// t, counting the use, and returns its name
+func useGuestToken(token, t string) (string, error) {
+	hash := hashGuestToken(token)
+	found := ""
+	err := updateState(func(s *State) error {
+		pruneGuestTokens(s)
+		for name, g := range s.GuestTokens {
+			if g.Hash != hash {
+				continue
+			}
+			found = name
+			if len(g.Types) > 0 && !slices.Contains(g.Types, t) {
+				return fmt.Errorf("the token may only send %s notifications", strings.Join(g.Types, ", "))
+			}
+			switch {
+			case g.Uses == 1:
+				delete(s.GuestTokens, name)
+			case g.Uses > 1:
+				g.Uses--
+			}
+			return nil
+		}
+		return errGuestUnknown
+	})
+	return found, err
+}
+
+func showTokenHelp() {
+	fmt.Print(`Manage guest tokens for sending to this machine's relay
+
+A guest token lets a temporary script or a colleague send notifications
+to 'notify relay' or 'notify serve' without the relay's --token, limited
+to some types, a number of notifications and a time. The token is only
+shown when created; notify keeps its hash.
+
+Usage:
+  notify token [list]                 List tokens that can still be used
+  notify token create [NAME] [OPTIONS]
+                                      Create a token and print it
+  notify token revoke NAME...         Revoke tokens
+
+Options of create:
+  --ttl DURATION   How long the token works (default: 1h)
+  --types LIST     Comma separated types it may send, e.g. info,warning
+                   (default: all)
+  --uses N         Notifications it may send, 0 for any number until it
+                   expires (default: 1)
+
+Example:
+  notify token create build-bot --ttl 2h --types info,success --uses 0
+  notify "Deployed" --type success --remote desktop.lan:8787 --token ng_...
+`)
+}
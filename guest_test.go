@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTestToken creates a guest token named name and returns it as
+// printed
+func createTestToken(t *testing.T, name string, types []string, uses int) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	err = createGuestToken(name, time.Hour, types, uses)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(r)
+	token, _, _ := strings.Cut(string(out), "\n")
+	if !strings.HasPrefix(token, guestTokenPrefix) {
+		t.Fatalf("createGuestToken printed %q, want a guest token first", out)
+	}
+	return token
+}
+
+func TestGuestTokenStoresOnlyItsHash(t *testing.T) {
+	isolate(t)
+	token := createTestToken(t, "bot", nil, 1)
+
+	path, err := dataFile("state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), strings.TrimPrefix(token, guestTokenPrefix)) {
+		t.Fatalf("state holds the token: %s", data)
+	}
+	if !strings.Contains(string(data), hashGuestToken(token)) {
+		t.Fatalf("state lacks the hash of the token: %s", data)
+	}
+}
+
+func TestUseGuestToken(t *testing.T) {
+	isolate(t)
+	token := createTestToken(t, "bot", []string{"info", "success"}, 2)
+
+	tests := []struct {
+		token string
+		typ   string
+		err   string // part of the error
+	}{
+		{"ng_unknown", "info", errGuestUnknown.Error()},
+		{token, "error", "may only send info, success"},
+		{token, "info", ""},
+		{token, "success", ""},
+		{token, "info", errGuestUnknown.Error()},
+	}
+	for i, tt := range tests {
+		name, err := useGuestToken(tt.token, tt.typ)
+		if tt.err == "" {
+			if err != nil || name != "bot" {
+				t.Fatalf("use %d: useGuestToken() = %q, %v, want bot", i, name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("use %d: useGuestToken() error = %v, want %q", i, err, tt.err)
+		}
+	}
+
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.GuestTokens["bot"]; ok {
+		t.Fatal("a used up token was kept")
+	}
+}
+
+func TestGuestTokenExpires(t *testing.T) {
+	isolate(t)
+	token := createTestToken(t, "bot", nil, 0)
+	if _, err := useGuestToken(token, "info"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := updateState(func(s *State) error {
+		s.GuestTokens["bot"].Expires = time.Now().Add(-time.Second)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := useGuestToken(token, "info"); !errors.Is(err, errGuestUnknown) {
+		t.Fatalf("useGuestToken() error = %v, want %v", err, errGuestUnknown)
+	}
+}
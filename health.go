@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// The relay reports not ready while this many notifications wait to be
+// shown, so load balancers send new ones elsewhere
+const maxDisplayQueue = 20
+
+// healthCheck is the result of one readiness check
+type healthCheck struct {
+	Status string `json:"status"` // ok or fail
+	Detail string `json:"detail,omitempty"`
+}
+
+// handleHealthz serves GET /healthz: the relay is alive if it answers
+func (s *relayServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]any{
+		"status": "ok",
+		"uptime": time.Since(s.started).Round(time.Second).String(),
+	})
+}
+
+// handleReadyz serves GET /readyz: the relay is ready if it can deliver
+// notifications, i.e. the toast backend (the notification server on
+// Linux) is available or, when forwarding, any target is healthy, and the
+// display queue isn't full
+func (s *relayServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]healthCheck{}
+
+	if len(s.targets) == 0 {
+		checks["backend"] = checkResult(checkBackend(s.backend))
+	} else {
+		checks["targets"] = s.checkTargets()
+	}
+
+	queued := s.queued.Load()
+	queue := healthCheck{Status: "ok", Detail: fmt.Sprintf("%d waiting", queued)}
+	if queued >= maxDisplayQueue {
+		queue.Status = "fail"
+	}
+	checks["queue"] = queue
+
+	status, code := "ok", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	writeHealth(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+// checkTargets probes the /healthz endpoint of every target in parallel
+func (s *relayServer) checkTargets() healthCheck {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.targets))
+	for i, target := range s.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.out.probe(target)
+		}()
+	}
+	wg.Wait()
+
+	healthy := 0
+	for _, err := range errs {
+		if err == nil {
+			healthy++
+		}
+	}
+	check := healthCheck{Status: "ok", Detail: fmt.Sprintf("%d of %d healthy", healthy, len(s.targets))}
+	if healthy == 0 {
+		check.Status = "fail"
+	}
+	return check
+}
+
+// probe checks the /healthz endpoint of a relay
+func (s *relaySender) probe(relay string) error {
+	endpoint, err := notify.RelayEndpoint(relay)
+	if err != nil {
+		return err
+	}
+	u, _ := url.Parse(endpoint)
+	u.Path = "/healthz"
+
+	client := *s.client
+	client.Timeout = 3 * time.Second
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", u, resp.Status)
+	}
+	return nil
+}
+
+func checkResult(err error) healthCheck {
+	if err != nil {
+		return healthCheck{Status: "fail", Detail: err.Error()}
+	}
+	return healthCheck{Status: "ok"}
+}
+
+func writeHealth(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
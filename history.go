@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// Delivery results recorded in history
+const (
+	statusDelivered = "delivered"
+	statusFailed    = "failed"
+	statusMuted     = "muted"
+	statusDeferred  = "deferred"
+
+	// Not a delivery: what the user did with a shown toast
+	statusInteraction = "interaction"
+)
+
+// Privacy levels of history: full records messages, hashed a keyed hash
+// of them that still tells repeated messages apart, omitted nothing. Typed
+// replies are treated the same.
+var historyPrivacy = []string{"full", "hashed", "omitted"}
+
+// Most interactions served by GET /interactions
+const maxInteractions = 100
+
+// historyEntry is one line of the history file
+type historyEntry struct {
+	Time     time.Time `json:"time"`
+	App      string    `json:"app,omitempty"`
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Category string    `json:"category,omitempty"`
+	Target   string    `json:"target,omitempty"` // relay URL, empty for a local toast
+	Source   string    `json:"source,omitempty"` // host a relayed notification came from
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	ID       string    `json:"id,omitempty"`       // of the shown toast, see Notification.ID
+	Redacted string    `json:"redacted,omitempty"` // hashed or omitted, as the privacy of history was then
+
+	Interaction *toastInteraction `json:"interaction,omitempty"` // set with statusInteraction
+}
+
+// toastInteraction is what the user did with a toast
+type toastInteraction struct {
+	Action string            `json:"action"`          // clicked, replied, dismissed or timed out
+	Input  map[string]string `json:"input,omitempty"` // typed text and choices by input id
+}
+
+// newHistoryEntry builds the history record for a notification
+func newHistoryEntry(n *Notification, status string, err error) historyEntry {
+	entry := historyEntry{
+		Time:     time.Now(),
+		App:      n.App,
+		Type:     n.Type,
+		Title:    n.Title,
+		Message:  n.storedMessage(),
+		Category: n.Category,
+		Target:   n.Remote,
+		Source:   n.Source,
+		Status:   status,
+		ID:       n.ID,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// storedMessage returns the message to keep in history and in the state
+// of deferred and grouped notifications
+func (n *Notification) storedMessage() string {
+	if n.Private {
+		return "(private)"
+	}
+	return n.Message
+}
+
+// recordInteraction adds what the user did with a toast to history,
+// described like the toast's own entry. Clicks on toasts without an id,
+// or whose entry was pruned, are recorded without the description.
+func recordInteraction(id string, in toastInteraction) error {
+	entry := historyEntry{ID: id}
+	if id != "" {
+		entries, err := readHistory()
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].ID == id && entries[i].Interaction == nil {
+				entry = entries[i]
+				break
+			}
+		}
+	}
+	// Replies to private toasts stay private like their message
+	if entry.Message == "(private)" {
+		in.Input = nil
+	}
+	if len(in.Input) > 0 {
+		input := make(map[string]string, len(in.Input))
+		for id, text := range in.Input {
+			var how string
+			if input[id], how = redactForHistory(text); how != "" {
+				entry.Redacted = how
+			}
+		}
+		in.Input = input
+	}
+	entry.Time, entry.Status, entry.Error, entry.Interaction = time.Now(), statusInteraction, "", &in
+	return appendHistory(entry)
+}
+
+// redactForHistory applies the privacy of history in config.yaml to text
+// about to be recorded, returning it with how it was redacted. Text is
+// omitted when the setting can't be read.
+func redactForHistory(text string) (string, string) {
+	if text == "" || text == "(private)" {
+		return text, ""
+	}
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: recording history without the message: %v\n", err)
+		return "(omitted)", "omitted"
+	}
+
+	switch config.History.Privacy {
+	case "hashed":
+		key, err := historyKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: recording history without the message: %v\n", err)
+			return "(omitted)", "omitted"
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(text))
+		return "hash:" + hex.EncodeToString(mac.Sum(nil)[:8]), "hashed"
+	case "omitted":
+		return "(omitted)", "omitted"
+	}
+	return text, ""
+}
+
+// historyKey returns the secret key of hashed history, created on first
+// use. Being keyed, the hashes of short messages like codes can't be
+// found by trying every message.
+func historyKey() ([]byte, error) {
+	state, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	if len(state.HistoryKey) > 0 {
+		return state.HistoryKey, nil
+	}
+
+	var key []byte
+	err = updateState(func(s *State) error {
+		if len(s.HistoryKey) == 0 {
+			s.HistoryKey = make([]byte, 32)
+			rand.Read(s.HistoryKey)
+		}
+		key = s.HistoryKey
+		return nil
+	})
+	return key, err
+}
+
+// inputText joins the typed text and choices for display
+func (in *toastInteraction) inputText() string {
+	var parts []string
+	for _, id := range slices.Sorted(maps.Keys(in.Input)) {
+		parts = append(parts, id+"="+in.Input[id])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleInteractions serves GET /interactions, the newest interactions
+// recorded on this machine
+func (s *relayServer) handleInteractions(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="notify"`)
+		http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+		return
+	}
+	if s.verify.enabled() {
+		if err := s.verify.verify(r, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	interactions := []historyEntry{}
+	for _, e := range entries {
+		if e.Status == statusInteraction {
+			interactions = append(interactions, e)
+		}
+	}
+	interactions = interactions[max(len(interactions)-maxInteractions, 0):]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(interactions)
+}
+
+// appendHistory adds an entry to the JSON lines history file
+func appendHistory(entry historyEntry) error {
+	path, err := dataFile("history.jsonl")
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Pruning rewrites the file, so appends must not happen meanwhile
+	unlock, err := lockData("history")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// readHistory returns the entries of the history file, oldest first.
+// Lines that can't be parsed are skipped.
+func readHistory() ([]historyEntry, error) {
+	path, err := dataFile("history.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry historyEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// pruneHistory removes entries older than maxAge and all but the newest
+// maxRows entries; zero disables either limit. Lines that can't be parsed
+// are removed as well. It returns the number of removed and kept entries.
+func pruneHistory(maxAge time.Duration, maxRows int, dryRun bool) (removed, kept int, err error) {
+	path, err := dataFile("history.jsonl")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	unlock, err := lockData("history")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Keep the raw lines so fields added by newer versions survive
+	var lines [][]byte
+	total := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		total++
+
+		var entry struct{ Time time.Time }
+		if json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		if maxAge > 0 && entry.Time.Before(cutoff) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if maxRows > 0 && len(lines) > maxRows {
+		lines = lines[len(lines)-maxRows:]
+	}
+
+	removed, kept = total-len(lines), len(lines)
+	if removed == 0 || dryRun {
+		return removed, kept, nil
+	}
+
+	var out bytes.Buffer
+	for _, line := range lines {
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0600); err != nil {
+		return 0, 0, err
+	}
+	return removed, kept, os.Rename(tmp, path)
+}
+
+// autoPruneHistory applies the retention policy from the config file
+// about once a day
+func autoPruneHistory() error {
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	if time.Since(state.HistoryPruned) < 24*time.Hour {
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, _, err := pruneHistory(config.History.MaxAge, config.History.MaxRows, false); err != nil {
+		return err
+	}
+	return updateState(func(s *State) error {
+		s.HistoryPruned = time.Now()
+		return nil
+	})
+}
+
+// runHistory implements "notify history" and "notify history prune"
+func runHistory(args []string) error {
+	if len(args) > 0 && args[0] == "prune" {
+		return runHistoryPrune(args[1:])
+	}
+
+	limit := 20
+	asJSON, interactions := false, false
+
+	flags := []cliFlag{
+		{Name: "limit", Set: func(v string) (err error) { limit, err = parseCount(v); return }},
+		{Name: "json", Bool: true, Set: func(v string) (err error) { asJSON, err = parseStrictBool(v); return }},
+		{Name: "interactions", Bool: true, Set: func(v string) (err error) { interactions, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showHistoryHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unknown history command %q.%s", words[0], suggest.DidYouMean(words[0], []string{"prune"}, ""))
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(e historyEntry) bool { return (e.Status == statusInteraction) != interactions })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if entries == nil {
+			entries = []historyEntry{}
+		}
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 && interactions {
+		fmt.Println("No interactions yet")
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if interactions {
+		fmt.Fprintln(w, "TIME\tACTION\tTYPE\tTITLE\tINPUT")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Interaction.Action,
+				orDefault(e.Type, "-"), oneLine(orDefault(e.Title, "-"), 30), oneLine(e.Interaction.inputText(), 50))
+		}
+		return w.Flush()
+	}
+	fmt.Fprintln(w, "TIME\tSTATUS\tTYPE\tCATEGORY\tTITLE\tMESSAGE")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Status,
+			e.Type, e.Category, oneLine(e.Title, 30), oneLine(e.Message, 50))
+	}
+	return w.Flush()
+}
+
+// runHistoryPrune implements "notify history prune"
+func runHistoryPrune(args []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	maxAge, maxRows := config.History.MaxAge, config.History.MaxRows
+	dryRun := false
+
+	flags := []cliFlag{
+		{Name: "older-than", Set: func(v string) (err error) { maxAge, err = parseRetention(v); return }},
+		{Name: "keep", Set: func(v string) (err error) { maxRows, err = parseCount(v); return }},
+		{Name: "dry-run", Bool: true, Set: func(v string) (err error) { dryRun, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showHistoryHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	removed, kept, err := pruneHistory(maxAge, maxRows, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("Would remove %d entries and keep %d (dry run)\n", removed, kept)
+		return nil
+	}
+	fmt.Printf("Removed %d entries, kept %d\n", removed, kept)
+	return updateState(func(s *State) error {
+		s.HistoryPruned = time.Now()
+		return nil
+	})
+}
+
+func showHistoryHelp() {
+	fmt.Print(`Show and prune the history of sent notifications
+
+Usage:
+  notify history [--limit N] [--json] [--interactions]
+  notify history prune [--older-than DURATION] [--keep N] [--dry-run]
+
+Options:
+  --limit N              Show the newest N entries, 0 for all (default: 20)
+  --json                 Print the entries as JSON
+  --interactions         Show what was done with toasts instead: clicked or
+                         replied to (with the typed text), or with --wait
+                         dismissed or timed out
+  --older-than DURATION  Remove entries older than this, 0 to keep them (default: history.max_age)
+  --keep N               Keep only the newest N entries, 0 for no limit (default: history.max_rows)
+  --dry-run              Show what would be removed without changing anything
+
+History is pruned automatically once a day using the retention policy in
+config.yaml in notify's config directory:
+
+  history:
+    max_age: 90d
+    max_rows: 10000
+
+Examples:
+  notify history --limit 5
+  notify history --interactions --json
+  notify history prune --older-than 30d
+`)
+}
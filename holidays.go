@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Holiday calendars are read again after this long
+const holidayRefresh = 24 * time.Hour
+
+// scheduleConfig holds the settings of "notify when"
+type scheduleConfig struct {
+	Holidays []string `yaml:"holidays"` // URLs or paths of iCalendar feeds, for --skip-holidays
+}
+
+// holidayCalendar is the set of days with an event in the configured
+// holiday calendars
+type holidayCalendar struct {
+	sources []string
+	days    map[string]bool // like 2026-12-25
+	fetched time.Time
+}
+
+// newHolidayCalendar reads the holiday calendars
+func newHolidayCalendar(sources []string, now time.Time) (*holidayCalendar, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("--skip-holidays needs holiday calendars in config.yaml (see 'notify when --help')")
+	}
+	h := &holidayCalendar{sources: sources}
+	if err := h.fetch(now); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// refresh reads the calendars again once a day, keeping the known
+// holidays when they can't be read
+func (h *holidayCalendar) refresh(now time.Time) {
+	if h == nil || now.Sub(h.fetched) < holidayRefresh {
+		return
+	}
+	if err := h.fetch(now); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read the holiday calendars: %v\n", err)
+	}
+}
+
+// fetch reads the holidays of the coming year. Every event counts, as
+// holiday feeds mark their days free.
+func (h *holidayCalendar) fetch(now time.Time) error {
+	days := map[string]bool{}
+	for _, source := range h.sources {
+		data, err := readICS(source)
+		if err != nil {
+			return err
+		}
+		events, err := icsOccurrences(data, now.AddDate(0, 0, -1), now.AddDate(1, 0, 1), (*icsEvent).holiday)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, e := range events {
+			// All-day events end at midnight after their last day
+			for day := e.Start; day.Before(e.End) || day.Equal(e.Start); day = day.AddDate(0, 0, 1) {
+				days[day.Format(time.DateOnly)] = true
+			}
+		}
+	}
+	h.days, h.fetched = days, now
+	return nil
+}
+
+// contains reports whether t falls on a holiday, going by the date on
+// t's clock
+func (h *holidayCalendar) contains(t time.Time) bool {
+	return h != nil && h.days[t.Format(time.DateOnly)]
+}
+
+// holiday reports whether the event can mark a holiday: not cancelled
+func (e *icsEvent) holiday() bool {
+	_, ok := e.props["DTSTART"]
+	return ok && e.props["STATUS"].Value != "CANCELLED"
+}
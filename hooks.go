@@ -0,0 +1,639 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// webhookParser turns the payload of a service's webhook into messages,
+// returning none for events not worth a notification
+type webhookParser func(r *http.Request, body []byte) ([]*notify.RelayMessage, error)
+
+// Services whose webhooks the relay understands, by the name in the
+// /hooks/{service} path
+var webhookParsers = map[string]webhookParser{
+	"github":      parseGitHubHook,
+	"gitlab":      parseGitLabHook,
+	"grafana":     parseGrafanaHook,
+	"uptime-kuma": parseUptimeKumaHook,
+	"sentry":      parseSentryHook,
+}
+
+// Names of the services in webhookParsers, for help and the OpenAPI document
+var webhookServices = []string{"github", "gitlab", "grafana", "sentry", "uptime-kuma"}
+
+// handleHook serves POST /hooks/{service}
+func (s *relayServer) handleHook(w http.ResponseWriter, r *http.Request) {
+	trace := startRemoteSpan(r.Header.Get("traceparent"), "relay.hook",
+		"client.address", remoteHost(r), "notify.service", r.PathValue("service"))
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.hook(rec, r, trace)
+
+	trace.set("http.response.status_code", strconv.Itoa(rec.status))
+	var err error
+	if rec.status >= 400 {
+		err = errors.New(http.StatusText(rec.status))
+	}
+	trace.finish(err)
+	go flushTraces()
+}
+
+// hook authenticates a webhook and shows the notification its payload
+// describes
+func (s *relayServer) hook(w *statusRecorder, r *http.Request, trace *span) {
+	service := r.PathValue("service")
+	if _, ok := webhookParsers[service]; !ok {
+		http.Error(w, fmt.Sprintf("unknown service %q.%s Supported services are: %s",
+			service, suggest.DidYouMean(service, webhookServices, ""), strings.Join(webhookServices, ", ")), http.StatusNotFound)
+		return
+	}
+
+	if s.limit != nil {
+		if ok, wait := s.limit.allow(remoteHost(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+			http.Error(w, "too many notifications, slow down", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	parse := startSpan(trace, "parse")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		parse.finish(err)
+		http.Error(w, fmt.Sprintf("request body is larger than %d bytes", s.maxBody), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		parse.finish(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Services can't sign requests the way notify does, so they prove
+	// themselves with the relay token
+	if !s.hookAuthorized(r, service, body) {
+		parse.finish(errors.New("unauthorized"))
+		message := "missing or invalid token, add ?token= to the webhook URL"
+		if service == "sentry" && len(s.sentrySecret) > 0 {
+			message = "missing or invalid Sentry-Hook-Signature, check --sentry-secret"
+		}
+		http.Error(w, message, http.StatusUnauthorized)
+		return
+	}
+
+	// Accepted payloads are kept with the response, for 'notify inbound'
+	if s.keepInbound > 0 {
+		payload := newInboundPayload(r, service, body)
+		defer func() {
+			payload.Status = w.status
+			if err := saveInbound(payload, s.keepInbound); err != nil {
+				log.Printf("Warning: could not store the %s payload: %v", service, err)
+			}
+		}()
+	}
+
+	messages, err := parseHook(service, r, body)
+	if err != nil {
+		parse.finish(err)
+		http.Error(w, fmt.Sprintf("invalid %s payload: %v", service, err), http.StatusBadRequest)
+		return
+	}
+	if len(messages) == 0 {
+		parse.finish(nil)
+		log.Printf("%s: %s event ignored", remoteHost(r), service)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	notifications := make([]*Notification, len(messages))
+	for i, m := range messages {
+		m.Source = remoteHost(r)
+		if notifications[i], err = receivedNotification(m); err != nil {
+			parse.finish(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		notifications[i].trace = trace
+	}
+	parse.finish(nil)
+
+	// One payload can describe several alerts
+	var errs []error
+	for i, m := range messages {
+		n := notifications[i]
+		if err := s.deliver(m, n); err != nil {
+			log.Printf("%s: %s %q failed: %v", m.Source, service, n.Title, err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("%s: %s %q delivered", m.Source, service, n.Title)
+	}
+	if err := errors.Join(errs...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseHook turns a webhook of a known service into messages ready to be
+// shown
+func parseHook(service string, r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	messages, err := webhookParsers[service](r, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		m.Title = oneLine(m.Title, notify.MaxTitleLength)
+		m.Message = cmp.Or(strings.TrimSpace(m.Message), m.Title)
+	}
+	return messages, nil
+}
+
+// hookAuthorized accepts the relay token as a bearer token, a token query
+// parameter or GitLab's X-Gitlab-Token header, and GitHub and Sentry
+// payloads signed with the token or the --verify-secret. Sentry generates
+// its own client secret; with --sentry-secret every Sentry payload must
+// be signed with it.
+func (s *relayServer) hookAuthorized(r *http.Request, service string, body []byte) bool {
+	if service == "sentry" && len(s.sentrySecret) > 0 {
+		return signedWith(r.Header.Get("Sentry-Hook-Signature"), body, s.sentrySecret)
+	}
+	if s.token == "" && !s.verify.enabled() {
+		return true
+	}
+
+	if s.token != "" {
+		for _, given := range []string{r.URL.Query().Get("token"), r.Header.Get("X-Gitlab-Token")} {
+			if given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1 {
+				return true
+			}
+		}
+		if r.Header.Get("Authorization") != "" && s.authorized(r) {
+			return true
+		}
+	}
+
+	signature := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	if signature == "" {
+		signature = r.Header.Get("Sentry-Hook-Signature")
+	}
+	return signedWith(signature, body, []byte(s.token), s.verify.secret)
+}
+
+// signedWith checks a hex HMAC-SHA256 signature of body against secrets,
+// skipping empty ones
+func signedWith(signature string, body []byte, secrets ...[]byte) bool {
+	given, err := hex.DecodeString(signature)
+	if signature == "" || err != nil {
+		return false
+	}
+	for _, secret := range secrets {
+		if len(secret) == 0 {
+			continue
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if hmac.Equal(given, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGitHubHook handles workflow runs, deployments, releases, pushes,
+// pull requests and issues
+func parseGitHubHook(r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	var p struct {
+		Action     string `json:"action"`
+		Ref        string `json:"ref"`
+		Zen        string `json:"zen"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+		Commits []struct {
+			Message string `json:"message"`
+		} `json:"commits"`
+		WorkflowRun struct {
+			Name       string `json:"name"`
+			HeadBranch string `json:"head_branch"`
+			Conclusion string `json:"conclusion"`
+		} `json:"workflow_run"`
+		PullRequest struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Merged bool   `json:"merged"`
+		} `json:"pull_request"`
+		Issue struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+		} `json:"issue"`
+		Release struct {
+			TagName string `json:"tag_name"`
+			Name    string `json:"name"`
+		} `json:"release"`
+		DeploymentStatus struct {
+			State       string `json:"state"`
+			Environment string `json:"environment"`
+		} `json:"deployment_status"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	repo := p.Repository.FullName
+	m := &notify.RelayMessage{App: "GitHub", Category: "github", Type: "info"}
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		m.Title = repo + ": webhook connected"
+		m.Message = p.Zen
+	case "workflow_run":
+		if p.Action != "completed" {
+			return nil, nil
+		}
+		m.Type = conclusionType(p.WorkflowRun.Conclusion)
+		m.Title = repo + ": " + p.WorkflowRun.Name
+		m.Message = fmt.Sprintf("%s on %s", p.WorkflowRun.Conclusion, p.WorkflowRun.HeadBranch)
+	case "deployment_status":
+		m.Type = conclusionType(p.DeploymentStatus.State)
+		if m.Type == "info" {
+			return nil, nil
+		}
+		m.Title = repo + ": deployment to " + p.DeploymentStatus.Environment
+		m.Message = p.DeploymentStatus.State
+	case "release":
+		if p.Action != "published" {
+			return nil, nil
+		}
+		m.Type = "success"
+		m.Title = repo + ": released " + p.Release.TagName
+		m.Message = cmp.Or(p.Release.Name, p.Release.TagName)
+	case "push":
+		if len(p.Commits) == 0 {
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s: %d commits pushed to %s", repo, len(p.Commits), strings.TrimPrefix(p.Ref, "refs/heads/"))
+		lines := make([]string, 0, len(p.Commits))
+		for _, c := range p.Commits {
+			lines = append(lines, oneLine(c.Message, 60))
+		}
+		m.Message = p.Sender.Login + ": " + strings.Join(lines, "\n")
+	case "pull_request":
+		action := p.Action
+		switch {
+		case action == "closed" && p.PullRequest.Merged:
+			action, m.Type = "merged", "success"
+		case action != "opened" && action != "closed" && action != "reopened" && action != "ready_for_review":
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s #%d %s", repo, p.PullRequest.Number, strings.ReplaceAll(action, "_", " "))
+		m.Message = p.Sender.Login + ": " + p.PullRequest.Title
+	case "issues":
+		if p.Action != "opened" && p.Action != "closed" && p.Action != "reopened" {
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s issue #%d %s", repo, p.Issue.Number, p.Action)
+		m.Message = p.Sender.Login + ": " + p.Issue.Title
+	default:
+		return nil, nil
+	}
+	return []*notify.RelayMessage{m}, nil
+}
+
+// parseGitLabHook handles pipelines, deployments, pushes, merge requests
+// and issues
+func parseGitLabHook(r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	var p struct {
+		ObjectKind string `json:"object_kind"`
+		Ref        string `json:"ref"`
+		UserName   string `json:"user_name"`
+		Status     string `json:"status"`
+		Env        string `json:"environment"`
+		TotalCount int    `json:"total_commits_count"`
+		User       struct {
+			Name string `json:"name"`
+		} `json:"user"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+		Attributes struct {
+			ID     int    `json:"id"`
+			IID    int    `json:"iid"`
+			Ref    string `json:"ref"`
+			Status string `json:"status"`
+			Title  string `json:"title"`
+			Action string `json:"action"`
+		} `json:"object_attributes"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	project := p.Project.PathWithNamespace
+	a := p.Attributes
+	m := &notify.RelayMessage{App: "GitLab", Category: "gitlab", Type: "info"}
+	switch p.ObjectKind {
+	case "pipeline":
+		if a.Status != "success" && a.Status != "failed" && a.Status != "canceled" {
+			return nil, nil
+		}
+		m.Type = conclusionType(a.Status)
+		m.Title = fmt.Sprintf("%s: pipeline #%d", project, a.ID)
+		m.Message = fmt.Sprintf("%s on %s", a.Status, a.Ref)
+	case "deployment":
+		if p.Status != "success" && p.Status != "failed" && p.Status != "canceled" {
+			return nil, nil
+		}
+		m.Type = conclusionType(p.Status)
+		m.Title = project + ": deployment to " + p.Env
+		m.Message = p.Status
+	case "push":
+		if p.TotalCount == 0 {
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s: %d commits pushed to %s", project, p.TotalCount, strings.TrimPrefix(p.Ref, "refs/heads/"))
+		m.Message = "by " + p.UserName
+	case "merge_request":
+		action := map[string]string{"open": "opened", "merge": "merged", "close": "closed", "reopen": "reopened"}[a.Action]
+		if action == "" {
+			return nil, nil
+		}
+		if action == "merged" {
+			m.Type = "success"
+		}
+		m.Title = fmt.Sprintf("%s !%d %s", project, a.IID, action)
+		m.Message = p.User.Name + ": " + a.Title
+	case "issue":
+		action := map[string]string{"open": "opened", "close": "closed", "reopen": "reopened"}[a.Action]
+		if action == "" {
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s issue #%d %s", project, a.IID, action)
+		m.Message = p.User.Name + ": " + a.Title
+	default:
+		return nil, nil
+	}
+	return []*notify.RelayMessage{m}, nil
+}
+
+// parseGrafanaHook handles Grafana alerting notifications, with one toast
+// per alert rule that the rule's next notification replaces
+func parseGrafanaHook(r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	var p struct {
+		Title  string `json:"title"`
+		Alerts []struct {
+			Status       string            `json:"status"`
+			Labels       map[string]string `json:"labels"`
+			Annotations  map[string]string `json:"annotations"`
+			ImageURL     string            `json:"imageURL"`
+			PanelURL     string            `json:"panelURL"`
+			DashboardURL string            `json:"dashboardURL"`
+			GeneratorURL string            `json:"generatorURL"`
+		} `json:"alerts"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Alerts) == 0 {
+		return nil, fmt.Errorf("no alerts")
+	}
+
+	var messages []*notify.RelayMessage
+	rules := map[string]*notify.RelayMessage{}
+	firing := map[string]int{}
+	for _, alert := range p.Alerts {
+		rule := cmp.Or(alert.Labels["alertname"], p.Title, "Grafana alert")
+		m := rules[rule]
+		if m == nil {
+			m = &notify.RelayMessage{App: "Grafana", Category: "grafana", Type: "success", Tag: oneLine("grafana "+rule, notify.MaxTagLength)}
+			rules[rule] = m
+			messages = append(messages, m)
+		}
+
+		// The most severe firing alert decides the type
+		if alert.Status == "firing" {
+			firing[rule]++
+			if t := severityType(alert.Labels["severity"]); typeRank(t) > typeRank(m.Type) || m.Type == "success" {
+				m.Type = t
+			}
+		}
+
+		if line := cmp.Or(alert.Annotations["summary"], alert.Annotations["description"], alert.Labels["instance"]); line != "" {
+			m.Message += oneLine(line, 80) + "\n"
+		}
+		m.Image = cmp.Or(m.Image, alert.ImageURL)
+		m.Link = cmp.Or(m.Link, alert.PanelURL, alert.DashboardURL, alert.GeneratorURL)
+	}
+
+	for rule, m := range rules {
+		if count := firing[rule]; count > 0 {
+			m.Title = fmt.Sprintf("%s: firing (%d)", rule, count)
+		} else {
+			m.Title = rule + ": resolved"
+		}
+	}
+	return messages, nil
+}
+
+// severityType maps an alert's severity label to a notification type.
+// Alerts without a known severity are errors.
+func severityType(severity string) string {
+	switch strings.ToLower(severity) {
+	case "warning", "warn", "medium", "minor", "p3":
+		return "warning"
+	case "info", "informational", "low", "none", "p4", "p5":
+		return "info"
+	}
+	return "error"
+}
+
+// typeRank orders notification types by urgency
+func typeRank(t string) int {
+	return map[string]int{"success": 0, "info": 1, "warning": 2, "error": 3}[t]
+}
+
+// parseUptimeKumaHook handles Uptime Kuma monitor status changes. A
+// monitor coming back up replaces the toast of it going down.
+func parseUptimeKumaHook(r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	var p struct {
+		Msg       string `json:"msg"`
+		Heartbeat *struct {
+			Status int      `json:"status"`
+			Msg    string   `json:"msg"`
+			Ping   *float64 `json:"ping"` // response time in milliseconds
+		} `json:"heartbeat"`
+		Monitor *struct {
+			Name     string `json:"name"`
+			URL      string `json:"url"`
+			Hostname string `json:"hostname"`
+		} `json:"monitor"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	m := &notify.RelayMessage{App: "Uptime Kuma", Category: "uptime-kuma", Type: "info", Title: "Uptime Kuma", Message: p.Msg}
+	if p.Heartbeat == nil || p.Monitor == nil {
+		// Test notifications only have a message
+		return []*notify.RelayMessage{m}, nil
+	}
+	name := cmp.Or(p.Monitor.Name, p.Monitor.Hostname, p.Monitor.URL, "Monitor")
+	switch p.Heartbeat.Status {
+	case 0:
+		m.Type, m.Title = "error", name+" is down"
+	case 1:
+		m.Type, m.Title = "success", name+" is up"
+	case 3:
+		m.Type, m.Title = "info", name+" is under maintenance"
+	default:
+		m.Type, m.Title = "warning", name+" is pending"
+	}
+	m.Tag = oneLine("uptime-kuma "+name, notify.MaxTagLength)
+
+	var lines []string
+	if p.Monitor.URL != "" && p.Monitor.URL != "https://" {
+		lines = append(lines, p.Monitor.URL)
+	} else if p.Monitor.Hostname != "" {
+		lines = append(lines, p.Monitor.Hostname)
+	}
+	if msg := cmp.Or(p.Heartbeat.Msg, p.Msg); msg != "" {
+		lines = append(lines, oneLine(msg, 120))
+	}
+	if p.Heartbeat.Ping != nil && p.Heartbeat.Status == 1 {
+		lines = append(lines, fmt.Sprintf("Response time: %.0f ms", *p.Heartbeat.Ping))
+	}
+	m.Message = cmp.Or(strings.Join(lines, "\n"), m.Title)
+
+	if u, err := url.Parse(p.Monitor.URL); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		m.Link = u.String()
+	}
+	return []*notify.RelayMessage{m}, nil
+}
+
+// parseSentryHook handles Sentry integration webhooks for issues and
+// alerts, and the payload of the legacy webhooks plugin. New issues and
+// regressions are errors linking to the issue.
+func parseSentryHook(r *http.Request, body []byte) ([]*notify.RelayMessage, error) {
+	var p struct {
+		Action string `json:"action"`
+		Data   struct {
+			Issue struct {
+				Title   string `json:"title"`
+				Culprit string `json:"culprit"`
+				ShortID string `json:"shortId"`
+				WebURL  string `json:"web_url"`
+				Project struct {
+					Name string `json:"name"`
+				} `json:"project"`
+			} `json:"issue"`
+			Event struct {
+				Title   string `json:"title"`
+				Culprit string `json:"culprit"`
+				Level   string `json:"level"`
+				WebURL  string `json:"web_url"`
+			} `json:"event"`
+			Title     string `json:"description_title"`
+			Text      string `json:"description_text"`
+			WebURL    string `json:"web_url"`
+			AlertRule string `json:"triggered_rule"`
+		} `json:"data"`
+
+		// Legacy webhooks plugin
+		ProjectName string `json:"project_name"`
+		Message     string `json:"message"`
+		Culprit     string `json:"culprit"`
+		Level       string `json:"level"`
+		URL         string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	m := &notify.RelayMessage{App: "Sentry", Category: "sentry"}
+	switch r.Header.Get("Sentry-Hook-Resource") {
+	case "issue":
+		issue := p.Data.Issue
+		var event string
+		switch p.Action {
+		case "created":
+			m.Type, event = "error", "new issue"
+		case "unresolved":
+			m.Type, event = "error", "regression"
+		case "resolved":
+			m.Type, event = "success", "resolved"
+		default:
+			return nil, nil
+		}
+		m.Title = fmt.Sprintf("%s: %s %s", cmp.Or(issue.Project.Name, "Sentry"), event, issue.ShortID)
+		m.Message = issue.Title + "\n" + issue.Culprit
+		m.Link = issue.WebURL
+	case "event_alert":
+		event := p.Data.Event
+		m.Type = levelType(event.Level)
+		m.Title = cmp.Or(p.Data.AlertRule, "Sentry alert")
+		m.Message = event.Title + "\n" + event.Culprit
+		m.Link = event.WebURL
+	case "metric_alert":
+		m.Type = map[string]string{"critical": "error", "warning": "warning", "resolved": "success"}[p.Action]
+		if m.Type == "" {
+			return nil, nil
+		}
+		m.Title = cmp.Or(p.Data.Title, "Sentry metric alert")
+		m.Message = cmp.Or(p.Data.Text, p.Action)
+		m.Link = p.Data.WebURL
+	case "":
+		if p.Message == "" {
+			return nil, fmt.Errorf("no message")
+		}
+		m.Type = levelType(p.Level)
+		m.Title = cmp.Or(p.ProjectName, "Sentry")
+		m.Message = p.Message + "\n" + p.Culprit
+		m.Link = p.URL
+	default:
+		return nil, nil
+	}
+	m.Message = strings.TrimSpace(m.Message)
+	return []*notify.RelayMessage{m}, nil
+}
+
+// conclusionType maps CI and deployment results to a notification type
+func conclusionType(result string) string {
+	switch result {
+	case "success":
+		return "success"
+	case "failure", "failed", "error", "timed_out", "startup_failure":
+		return "error"
+	case "cancelled", "canceled", "action_required":
+		return "warning"
+	}
+	return "info"
+}
+
+// levelType maps a log level to a notification type
+func levelType(level string) string {
+	switch level {
+	case "fatal", "error":
+		return "error"
+	case "warning":
+		return "warning"
+	}
+	return "info"
+}
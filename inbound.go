@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// Headers never stored with webhook payloads, as they carry credentials
+var inboundSecretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Gitlab-Token"}
+
+// Headers naming the event of a webhook, by service
+var inboundEventHeaders = map[string]string{
+	"github": "X-Github-Event",
+	"gitlab": "X-Gitlab-Event",
+	"sentry": "Sentry-Hook-Resource",
+}
+
+// inboundPayload is a webhook the relay received, kept so integrations
+// can be debugged without triggering the service again
+type inboundPayload struct {
+	ID      string            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Service string            `json:"service"`
+	Source  string            `json:"source"`
+	Status  int               `json:"status"` // of the relay's response
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// newInboundPayload records a webhook request and its body, leaving out
+// the query string and headers with credentials
+func newInboundPayload(r *http.Request, service string, body []byte) *inboundPayload {
+	p := &inboundPayload{
+		ID:      newToken()[:8],
+		Time:    time.Now(),
+		Service: service,
+		Source:  remoteHost(r),
+		Headers: map[string]string{},
+		Body:    body,
+	}
+	for name, values := range r.Header {
+		if !slices.Contains(inboundSecretHeaders, name) {
+			p.Headers[name] = strings.Join(values, ", ")
+		}
+	}
+	return p
+}
+
+// event returns the kind of event the service reported, if it says
+func (p *inboundPayload) event() string {
+	return p.Headers[inboundEventHeaders[p.Service]]
+}
+
+// request rebuilds the webhook request for the service's parser
+func (p *inboundPayload) request() *http.Request {
+	r, _ := http.NewRequest("POST", "/hooks/"+p.Service, bytes.NewReader(p.Body))
+	for name, value := range p.Headers {
+		r.Header.Set(name, value)
+	}
+	r.RemoteAddr = p.Source + ":0"
+	return r
+}
+
+// inboundDir returns the directory of stored webhook payloads, creating it
+func inboundDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "inbound")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// saveInbound stores a payload, removing the oldest beyond keep
+func saveInbound(p *inboundPayload, keep int) error {
+	dir, err := inboundDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockData("inbound")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.WriteFile(filepath.Join(dir, p.ID+".json"), data, 0600); err != nil {
+		return err
+	}
+
+	stored, err := readInbound()
+	if err != nil {
+		return err
+	}
+	for _, old := range stored[:max(len(stored)-keep, 0)] {
+		os.Remove(filepath.Join(dir, old.ID+".json"))
+	}
+	return nil
+}
+
+// readInbound returns the stored payloads, oldest first. Files that can't
+// be parsed are skipped.
+func readInbound() ([]*inboundPayload, error) {
+	dir, err := inboundDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var payloads []*inboundPayload
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		p := &inboundPayload{}
+		if json.Unmarshal(data, p) == nil && p.ID != "" {
+			payloads = append(payloads, p)
+		}
+	}
+	slices.SortFunc(payloads, func(a, b *inboundPayload) int { return a.Time.Compare(b.Time) })
+	return payloads, nil
+}
+
+// findInbound returns the stored payload whose id starts with id
+func findInbound(id string) (*inboundPayload, error) {
+	payloads, err := readInbound()
+	if err != nil {
+		return nil, err
+	}
+	var found []*inboundPayload
+	for _, p := range payloads {
+		if id != "" && strings.HasPrefix(p.ID, id) {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no webhook payload %q, see 'notify inbound list'", id)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%q matches %d webhook payloads, give more of the id", id, len(found))
+}
+
+// runInbound implements "notify inbound", which inspects and replays the
+// webhook payloads the relay received
+func runInbound(args []string) error {
+	commands := []string{"list", "show", "replay"}
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" {
+		showInboundHelp()
+		return nil
+	}
+	switch args[0] {
+	case "list":
+		return runInboundList(args[1:])
+	case "show":
+		return runInboundShow(args[1:])
+	case "replay":
+		return runInboundReplay(args[1:])
+	}
+	return fmt.Errorf("unknown inbound command %q.%s Valid commands are: %s",
+		args[0], suggest.DidYouMean(args[0], commands, ""), strings.Join(commands, ", "))
+}
+
+// runInboundList implements "notify inbound list"
+func runInboundList(args []string) error {
+	limit := 20
+	service := ""
+	asJSON := false
+
+	flags := []cliFlag{
+		{Name: "limit", Set: func(v string) (err error) { limit, err = parseCount(v); return }},
+		{Name: "service", Set: func(v string) error {
+			if _, ok := webhookParsers[v]; !ok {
+				return fmt.Errorf("invalid service %q.%s Valid services are: %s", v, suggest.DidYouMean(v, webhookServices, ""), strings.Join(webhookServices, ", "))
+			}
+			service = v
+			return nil
+		}},
+		{Name: "json", Bool: true, Set: func(v string) (err error) { asJSON, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showInboundHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	payloads, err := readInbound()
+	if err != nil {
+		return err
+	}
+	payloads = slices.DeleteFunc(payloads, func(p *inboundPayload) bool { return service != "" && p.Service != service })
+	if limit > 0 && len(payloads) > limit {
+		payloads = payloads[len(payloads)-limit:]
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if payloads == nil {
+			payloads = []*inboundPayload{}
+		}
+		return enc.Encode(payloads)
+	}
+	if len(payloads) == 0 {
+		fmt.Println("No webhook payloads yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSERVICE\tEVENT\tSOURCE\tSTATUS\tSIZE")
+	for _, p := range payloads {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", p.ID, p.Time.Local().Format("2006-01-02 15:04:05"),
+			p.Service, orDefault(p.event(), "-"), p.Source, p.Status, formatBytes(uint64(len(p.Body))))
+	}
+	return w.Flush()
+}
+
+// runInboundShow implements "notify inbound show"
+func runInboundShow(args []string) error {
+	bodyOnly := false
+	flags := []cliFlag{
+		{Name: "body", Bool: true, Set: func(v string) (err error) { bodyOnly, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showInboundHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return fmt.Errorf("one payload id is required, e.g. 'notify inbound show 3fa2c1d9'")
+	}
+	p, err := findInbound(words[0])
+	if err != nil {
+		return err
+	}
+
+	// The body as received, for piping into files or curl
+	if bodyOnly {
+		_, err := os.Stdout.Write(p.Body)
+		return err
+	}
+
+	fmt.Printf("ID:       %s\n", p.ID)
+	fmt.Printf("Time:     %s\n", p.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Service:  %s\n", p.Service)
+	fmt.Printf("Event:    %s\n", orDefault(p.event(), "-"))
+	fmt.Printf("Source:   %s\n", p.Source)
+	fmt.Printf("Status:   %d %s\n", p.Status, http.StatusText(p.Status))
+	fmt.Println("Headers:")
+	for _, name := range slices.Sorted(maps.Keys(p.Headers)) {
+		fmt.Printf("  %s: %s\n", name, p.Headers[name])
+	}
+	fmt.Println("Body:")
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, p.Body, "  ", "  ") == nil {
+		fmt.Printf("  %s\n", pretty.String())
+	} else {
+		fmt.Printf("  %s\n", strings.ReplaceAll(string(p.Body), "\n", "\n  "))
+	}
+	return nil
+}
+
+// runInboundReplay implements "notify inbound replay", which runs a
+// stored payload through its service again and shows or forwards the
+// notifications, without checking the token it was accepted with
+func runInboundReplay(args []string) error {
+	out := newListenerOptions()
+	dryRun := false
+	flags := append(out.flags(),
+		cliFlag{Name: "dry-run", Bool: true, Set: func(v string) (err error) { dryRun, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showInboundHelp(); os.Exit(0); return nil }},
+	)
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return fmt.Errorf("one payload id is required, e.g. 'notify inbound replay 3fa2c1d9'")
+	}
+	p, err := findInbound(words[0])
+	if err != nil {
+		return err
+	}
+
+	messages, err := parseHook(p.Service, p.request(), p.Body)
+	if err != nil {
+		return fmt.Errorf("invalid %s payload: %w", p.Service, err)
+	}
+	if len(messages) == 0 {
+		fmt.Printf("The %s event is ignored, no notifications are shown for it\n", orDefault(p.event(), p.Service))
+		return nil
+	}
+	if dryRun {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(messages)
+	}
+
+	if err := out.start(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, m := range messages {
+		m.Source = p.Source
+		n, err := receivedNotification(m)
+		if err == nil {
+			err = out.relay.deliver(m, n)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("%s: replayed %s %q", p.ID, p.Service, n.Title)
+	}
+	flushTraces()
+	return errors.Join(errs...)
+}
+
+func showInboundHelp() {
+	fmt.Print(`Inspect and replay the webhooks the relay received
+
+The relay keeps the newest webhook payloads it accepted on /hooks/SERVICE
+(see --keep-inbound of 'notify relay'), with their headers but without
+tokens, cookies or the query string. Replaying one runs it through the
+service's integration again, so changes to filters, formatting profiles or
+the relay's targets can be tried without triggering the service.
+
+Usage:
+  notify inbound list [--service SERVICE] [--limit N] [--json]
+  notify inbound show ID [--body]
+  notify inbound replay ID [--dry-run] [--to URL]
+
+IDs may be shortened to any unique prefix.
+
+Options:
+  --service SERVICE  List only the webhooks of github, gitlab, grafana,
+                     sentry or uptime-kuma
+  --limit N          List the newest N payloads, 0 for all (default: 20)
+  --json             List the payloads as JSON, bodies base64-encoded
+  --body             Print only the body, exactly as received
+  --dry-run          Print the notifications the payload turns into
+                     instead of showing them
+  --to URL           Forward the notifications to this relay instead of
+                     showing toasts (repeatable), with --to-token, --ca,
+                     --client-cert and --client-key as for 'notify relay'
+
+Examples:
+  notify inbound list --service github
+  notify inbound show 3fa2c1d9
+  notify inbound replay 3fa2 --dry-run
+  notify inbound show 3fa2 --body > push.json
+`)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// faultInjection slows down or fails the deliveries of a backend, so that
+// scripts and pipelines around notify can test their handling of slow and
+// failing notifications. It is set with the undocumented --inject-delay
+// and --inject-fail options or per backend in the inject section of
+// config.yaml.
+type faultInjection struct {
+	Delay time.Duration `yaml:"delay"` // before delivering or failing
+	Fail  string        `yaml:"fail"`  // error or blocked, empty to deliver
+}
+
+// Failures that can be injected: a failed delivery (exit code 1) or one
+// blocked by the notification settings (exit code 3)
+var injectedFailures = []string{"error", "blocked"}
+
+// errInjected is wrapped by injected delivery failures, which are never
+// printed to the console instead so scripts see them fail
+var errInjected = errors.New("injected failure")
+
+// parseInjectedFailure checks an --inject-fail value
+func parseInjectedFailure(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || slices.Contains(injectedFailures, s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid injected failure %q.%s Valid failures are: %s",
+		s, suggest.DidYouMean(s, injectedFailures, ""), strings.Join(injectedFailures, ", "))
+}
+
+// validateInjections checks the inject section of config.yaml
+func validateInjections(injections map[string]*faultInjection) error {
+	for name, f := range injections {
+		if _, ok := notify.Backends[name]; !ok {
+			names := slices.Sorted(maps.Keys(notify.Backends))
+			return fmt.Errorf("inject: unknown backend %q.%s Valid backends are: %s",
+				name, suggest.DidYouMean(name, names, ""), strings.Join(names, ", "))
+		}
+		if f == nil {
+			continue
+		}
+		if f.Delay < 0 {
+			return fmt.Errorf("inject: the delay of %s can't be negative", name)
+		}
+		var err error
+		if f.Fail, err = parseInjectedFailure(f.Fail); err != nil {
+			return fmt.Errorf("inject: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// apply waits for the delay and returns the injected failure, if any, of
+// a delivery by the backend
+func (f *faultInjection) apply(backend string, parent *span) error {
+	if f == nil {
+		return nil
+	}
+	inject := startSpan(parent, "inject", "notify.delay", f.Delay.String(), "notify.fail", f.Fail)
+	time.Sleep(f.Delay)
+
+	var err error
+	switch f.Fail {
+	case "error":
+		err = fmt.Errorf("%w of the %s backend", errInjected, backend)
+	case "blocked":
+		err = &notify.BlockedError{Setting: "Injected"}
+	}
+	inject.finish(err)
+	return err
+}
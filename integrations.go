@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Generators of "notify integrations", by language or build tool
+var integrations = map[string]func(*integrationOptions) ([]byte, error){
+	"browser":    browserClient,
+	"make":       makeSnippet,
+	"node":       nodeClient,
+	"npm":        npmSnippet,
+	"powershell": powerShellModule,
+	"python":     pythonClient,
+	"taskfile":   taskfileSnippet,
+}
+
+// Relay clients post to without --target
+const defaultIntegrationTarget = "http://localhost:8787/notify"
+
+// integrationOptions are the settings baked into a generated client
+type integrationOptions struct {
+	Targets []string // relay endpoints, the first is the default
+}
+
+// runIntegrations implements "notify integrations LANGUAGE", which prints
+// a client of the relay API for scripts in other languages
+func runIntegrations(args []string) error {
+	options := &integrationOptions{}
+	out := ""
+
+	flags := []cliFlag{
+		{Name: "target", Set: func(v string) error {
+			endpoint, err := notify.RelayEndpoint(v)
+			options.Targets = append(options.Targets, endpoint)
+			return err
+		}},
+		{Name: "out", Set: func(v string) error { out = v; return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showIntegrationsHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	languages := slices.Sorted(maps.Keys(integrations))
+	if len(words) != 1 {
+		return fmt.Errorf("usage: notify integrations %s [OPTIONS]", strings.Join(languages, "|"))
+	}
+	generate, ok := integrations[words[0]]
+	if !ok {
+		return fmt.Errorf("unknown integration %q.%s Integrations are: %s", words[0], suggest.DidYouMean(words[0], languages, ""), strings.Join(languages, ", "))
+	}
+
+	code, err := generate(options)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	if err := os.WriteFile(out, code, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", out)
+	return nil
+}
+
+// apiField is a field of the notification body as a client parameter
+type apiField struct {
+	JSON string   // name in the request body, like group_size
+	Name string   // parameter name, like GroupSize
+	Kind string   // string, int, bool or optional bool
+	Doc  string   // from the doc tag
+	Enum []string // allowed values, if limited
+}
+
+// notifyFields returns the fields a client sets when posting to /notify,
+// taken from the route so clients follow the API. Message comes first;
+// fields set by relays and sealed messages are left out.
+func notifyFields() ([]apiField, error) {
+	var body reflect.Type
+	for _, route := range relayRoutes {
+		if route.Method == "POST" && route.Path == "/notify" {
+			body = reflect.TypeOf(route.Body)
+		}
+	}
+	if body == nil {
+		return nil, errors.New("the relay API has no POST /notify")
+	}
+
+	var fields []apiField
+	for i := 0; i < body.NumField(); i++ {
+		field := body.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "", "-", "source", "hops", "sealed", "attachment":
+			continue
+		}
+
+		f := apiField{JSON: name, Doc: field.Tag.Get("doc"), Enum: schemaEnums[name]}
+		for _, word := range strings.Split(name, "_") {
+			f.Name += strings.ToUpper(word[:1]) + word[1:]
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			f.Kind = "string"
+		case reflect.Int:
+			f.Kind = "int"
+		case reflect.Bool:
+			f.Kind = "bool"
+		case reflect.Pointer:
+			// Optional ints are sent whenever given, like ints
+			f.Kind = map[reflect.Kind]string{reflect.Bool: "optional bool", reflect.Int: "int"}[field.Type.Elem().Kind()]
+		}
+		if f.Kind == "" {
+			return nil, fmt.Errorf("field %s of the relay API has an unsupported type", name)
+		}
+
+		if name == "message" {
+			f.Doc = "The notification message"
+			fields = append([]apiField{f}, fields...)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+var powerShellTemplate = template.Must(template.New("powershell").Funcs(template.FuncMap{
+	"ps": powershell.Quote,
+	"list": func(values []string) string {
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = powershell.Quote(v)
+		}
+		return strings.Join(quoted, ", ")
+	},
+}).Parse(`# Notify PowerShell module, generated by 'notify integrations powershell'.
+# Import-Module .\Notify.psm1, then: Send-Notification "Backup done" -Type success
+
+$NotifyTargets = @({{list .Targets}})
+
+function Send-Notification {
+    <#
+    .SYNOPSIS
+    Shows a notification through a notify relay.
+{{- range .Fields}}
+    .PARAMETER {{.Name}}
+    {{.Doc}}
+{{- end}}
+    .PARAMETER Target
+    Relay endpoint to post to (default: {{index .Targets 0}})
+    .PARAMETER Token
+    Token the relay requires (default: $env:NOTIFY_TOKEN)
+    .EXAMPLE
+    Send-Notification "Disk full" -Type error -Title "Backup"
+    .EXAMPLE
+    Get-Content errors.txt | Send-Notification -Type warning
+    #>
+    [CmdletBinding()]
+    param(
+{{- range $f := .Fields}}
+{{- if eq $f.JSON "message"}}
+        [Parameter(Mandatory, Position = 0, ValueFromPipeline)]
+{{- end}}
+{{- if $f.Enum}}
+        [ValidateSet({{list $f.Enum}})]
+{{- end}}
+        {{if eq $f.Kind "string"}}[string]{{else if eq $f.Kind "int"}}[int]{{else if eq $f.Kind "bool"}}[switch]{{else}}[bool]{{end}}${{$f.Name}},
+{{- end}}
+        [ArgumentCompleter({
+            param($command, $parameter, $word)
+            @({{list .Targets}}) | Where-Object { $_ -like "$word*" }
+        })]
+        [string]$Target = $NotifyTargets[0],
+        [string]$Token = $env:NOTIFY_TOKEN
+    )
+
+    process {
+        $fields = @{
+{{- range .Fields}}
+            {{ps .Name}} = {{ps .JSON}}
+{{- end}}
+        }
+        $body = @{}
+        foreach ($name in $fields.Keys) {
+            if ($PSBoundParameters.ContainsKey($name)) {
+                $value = $PSBoundParameters[$name]
+                if ($value -is [switch]) { $value = $value.IsPresent }
+                $body[$fields[$name]] = $value
+            }
+        }
+
+        $headers = @{}
+        if ($Token) { $headers['Authorization'] = "Bearer $Token" }
+        $json = [Text.Encoding]::UTF8.GetBytes(($body | ConvertTo-Json -Compress))
+        Invoke-RestMethod -Method Post -Uri $Target -Headers $headers -ContentType 'application/json; charset=utf-8' -Body $json | Out-Null
+    }
+}
+
+Export-ModuleMember -Function Send-Notification -Variable NotifyTargets
+`))
+
+// powerShellModule generates a module with a Send-Notification cmdlet
+// completing types and targets
+func powerShellModule(options *integrationOptions) ([]byte, error) {
+	return executeClient(powerShellTemplate, options)
+}
+
+// Functions of the Python and Node templates
+var clientFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"names": func(fields []apiField) []string {
+		var names []string
+		for _, f := range fields {
+			if f.JSON != "message" {
+				names = append(names, f.JSON)
+			}
+		}
+		return names
+	},
+	"enums": func(fields []apiField) map[string][]string {
+		enums := map[string][]string{}
+		for _, f := range fields {
+			if f.Enum != nil {
+				enums[f.JSON] = f.Enum
+			}
+		}
+		return enums
+	},
+	"pytype": func(kind string) string {
+		return map[string]string{"string": "str", "int": "int", "bool": "bool", "optional bool": "bool"}[kind]
+	},
+	"jstype": func(kind string) string {
+		return map[string]string{"string": "string", "int": "number", "bool": "boolean", "optional bool": "boolean"}[kind]
+	},
+}
+
+var pythonTemplate = template.Must(template.New("python").Funcs(clientFuncs).Parse(`"""Notify relay client, generated by 'notify integrations python'.
+
+    import notify
+    notify.send("Backup done", type="success", title="Backup")
+
+Needs only the standard library. The token is read from $NOTIFY_TOKEN
+unless passed to send().
+"""
+
+import json
+import os
+import urllib.request
+
+TARGETS = {{json .Targets}}
+
+# Allowed values of limited fields
+ENUMS = {{json (enums .Fields)}}
+
+
+def send(
+    message,
+    *,
+{{- range .Fields}}{{if ne .JSON "message"}}
+    {{.JSON}}=None,
+{{- end}}{{end}}
+    target=None,
+    token=None,
+):
+    """Show a notification through a notify relay.
+
+    message ({{pytype "string"}}): The notification message
+{{- range .Fields}}{{if ne .JSON "message"}}
+    {{.JSON}} ({{pytype .Kind}}): {{.Doc}}{{if .Enum}}, one of {{range $i, $v := .Enum}}{{if $i}}, {{end}}{{$v}}{{end}}{{end}}
+{{- end}}{{end}}
+    target (str): Relay endpoint to post to (default: TARGETS[0])
+    token (str): Token the relay requires (default: $NOTIFY_TOKEN)
+
+    Raises urllib.error.HTTPError when the relay refuses the notification.
+    """
+    body = {
+{{- range .Fields}}
+        "{{.JSON}}": {{.JSON}},
+{{- end}}
+    }
+    body = {name: value for name, value in body.items() if value is not None}
+    for name, allowed in ENUMS.items():
+        if name in body and body[name] not in allowed:
+            raise ValueError(f"{name} must be one of {', '.join(allowed)}")
+
+    request = urllib.request.Request(target or TARGETS[0], data=json.dumps(body).encode(), method="POST")
+    request.add_header("Content-Type", "application/json")
+    token = os.environ.get("NOTIFY_TOKEN") if token is None else token
+    if token:
+        request.add_header("Authorization", "Bearer " + token)
+    with urllib.request.urlopen(request, timeout=10):
+        pass
+`))
+
+// The Node and browser clients, which differ in how they are loaded and
+// where the token comes from
+const javaScriptClient = `{{if browser -}}
+// Notify relay client for web pages, generated by 'notify integrations browser'.
+//
+//   import * as notify from './notify.js';
+//   notify.configure({ token: 'secret' });
+//   await notify.send('Build done', { type: 'success', title: 'Dev server' });
+//
+// The relay must allow the page's origin with --allow-origin. Anyone who
+// can read the page can read the token, so use one only for local pages.
+{{- else -}}
+// Notify relay client, generated by 'notify integrations node'.
+//
+//   const notify = require('./notify');
+//   await notify.send('Backup done', { type: 'success', title: 'Backup' });
+//
+// Needs Node 18 or later and no packages. The token is read from
+// $NOTIFY_TOKEN unless passed to send().
+'use strict';
+{{- end}}
+
+const TARGETS = {{json .Targets}};
+{{- if browser}}
+
+// Defaults of send, see configure
+const defaults = { target: TARGETS[0], token: null };
+{{- end}}
+
+// Fields of a notification besides the message
+const FIELDS = {{json (names .Fields)}};
+
+// Allowed values of limited fields
+const ENUMS = {{json (enums .Fields)}};
+
+/**
+ * Shows a notification through a notify relay.
+ *
+ * @param {string} message The notification message
+ * @param {object} [options]
+{{- range .Fields}}{{if ne .JSON "message"}}
+ * @param { {{- jstype .Kind}}} [options.{{.JSON}}] {{.Doc}}{{if .Enum}}, one of {{range $i, $v := .Enum}}{{if $i}}, {{end}}{{$v}}{{end}}{{end}}
+{{- end}}{{end}}
+ * @param {string} [options.target] Relay endpoint to post to (default: {{if browser}}see configure{{else}}TARGETS[0]{{end}})
+ * @param {string} [options.token] Token the relay requires (default: {{if browser}}see configure{{else}}$NOTIFY_TOKEN{{end}})
+ */
+{{if browser}}export {{end}}async function send(message, options = {}) {
+  const body = { message };
+  for (const name of FIELDS) {
+    if (options[name] !== undefined && options[name] !== null) {
+      body[name] = options[name];
+    }
+  }
+  for (const [name, allowed] of Object.entries(ENUMS)) {
+    if (name in body && !allowed.includes(body[name])) {
+      throw new Error(` + "`${name} must be one of ${allowed.join(', ')}`" + `);
+    }
+  }
+
+  const headers = { 'Content-Type': 'application/json' };
+  const token = options.token ?? {{if browser}}defaults.token{{else}}process.env.NOTIFY_TOKEN{{end}};
+  if (token) {
+    headers.Authorization = ` + "`Bearer ${token}`" + `;
+  }
+  const response = await fetch(options.target ?? {{if browser}}defaults.target{{else}}TARGETS[0]{{end}}, {
+    method: 'POST',
+    headers,
+    body: JSON.stringify(body),
+    signal: AbortSignal.timeout(10000),
+  });
+  if (!response.ok) {
+    throw new Error(` + "`notify relay answered ${response.status}: ${(await response.text()).trim()}`" + `);
+  }
+}
+{{if browser}}
+/**
+ * Sets the relay and token used when send isn't given them.
+ *
+ * @param {object} options
+ * @param {string} [options.target] Relay endpoint, one of TARGETS or another
+ * @param {string} [options.token] Token the relay requires
+ */
+export function configure({ target, token } = {}) {
+  if (target !== undefined) {
+    defaults.target = target;
+  }
+  if (token !== undefined) {
+    defaults.token = token;
+  }
+}
+
+export { TARGETS };
+{{- else}}
+module.exports = { send, TARGETS };
+{{- end}}
+`
+
+var (
+	nodeTemplate    = template.Must(template.New("node").Funcs(clientFuncs).Funcs(template.FuncMap{"browser": func() bool { return false }}).Parse(javaScriptClient))
+	browserTemplate = template.Must(template.New("browser").Funcs(clientFuncs).Funcs(template.FuncMap{"browser": func() bool { return true }}).Parse(javaScriptClient))
+)
+
+// pythonClient generates a Python module with a send function
+func pythonClient(options *integrationOptions) ([]byte, error) {
+	return executeClient(pythonTemplate, options)
+}
+
+// nodeClient generates a CommonJS module with an async send function
+func nodeClient(options *integrationOptions) ([]byte, error) {
+	return executeClient(nodeTemplate, options)
+}
+
+// browserClient generates an ES module for web pages with an async send
+// function
+func browserClient(options *integrationOptions) ([]byte, error) {
+	return executeClient(browserTemplate, options)
+}
+
+// executeClient renders a client template with the notification fields
+func executeClient(t *template.Template, options *integrationOptions) ([]byte, error) {
+	fields, err := notifyFields()
+	if err != nil {
+		return nil, err
+	}
+	targets := options.Targets
+	if len(targets) == 0 {
+		targets = []string{defaultIntegrationTarget}
+	}
+	var b bytes.Buffer
+	err = t.Execute(&b, map[string]any{"Targets": targets, "Fields": fields})
+	return b.Bytes(), err
+}
+
+// buildToolCommand returns the notify exec command the build tool snippets
+// run their commands with. Successes stay quiet, so only failures and
+// long runs get attention; with --target the relay shows them.
+func buildToolCommand(options *integrationOptions) string {
+	command := "notify exec --quiet-on-success"
+	if len(options.Targets) > 0 {
+		command += " --remote " + options.Targets[0]
+	}
+	return command
+}
+
+var makeTemplate = template.Must(template.New("make").Parse(`# Notifications for make, from 'notify integrations make'. Start recipe
+# lines with $(NOTIFY) for a status card while they run and a notification
+# when they fail. 'make NOTIFY=' runs them without.
+NOTIFY ?= {{.}} --title "make $@" --
+
+build:
+	$(NOTIFY) go build ./...
+
+test:
+	$(NOTIFY) go test ./...
+`))
+
+// Task expands {{.VAR}} itself, so this template uses [[ ]]
+var taskfileTemplate = template.Must(template.New("taskfile").Delims("[[", "]]").Parse(`# Notifications for Task, from 'notify integrations taskfile'. Commands
+# run through NOTIFY get a status card while they run and a notification
+# when they fail.
+version: '3'
+
+vars:
+  NOTIFY: [[.]]
+
+tasks:
+  build:
+    cmds:
+      - '{{.NOTIFY}} --title "task {{.TASK}}" -- go build ./...'
+
+  test:
+    cmds:
+      - '{{.NOTIFY}} --title "task {{.TASK}}" -- go test ./...'
+`))
+
+// makeSnippet generates Makefile rules running their recipes with notify
+func makeSnippet(options *integrationOptions) ([]byte, error) {
+	var b bytes.Buffer
+	err := makeTemplate.Execute(&b, buildToolCommand(options))
+	return b.Bytes(), err
+}
+
+// taskfileSnippet generates a Taskfile.yml running its commands with notify
+func taskfileSnippet(options *integrationOptions) ([]byte, error) {
+	var b bytes.Buffer
+	err := taskfileTemplate.Execute(&b, buildToolCommand(options))
+	return b.Bytes(), err
+}
+
+// npmSnippet generates the scripts of a package.json running with notify.
+// JSON has no comments, so it is only the scripts to paste.
+func npmSnippet(options *integrationOptions) ([]byte, error) {
+	command := buildToolCommand(options)
+	scripts := map[string]map[string]string{"scripts": {
+		"build": command + ` --title "npm run build" -- tsc`,
+		"test":  command + ` --title "npm test" -- node --test`,
+	}}
+	b, err := json.MarshalIndent(scripts, "", "  ")
+	return append(b, '\n'), err
+}
+
+func showIntegrationsHelp() {
+	fmt.Print(`Generate clients of the relay API for scripts in other languages, and
+snippets for build tools
+
+The generated clients post notifications to a 'notify relay', so scripts
+don't have to run notify or reimplement its API. The build tool snippets
+run commands with 'notify exec --quiet-on-success': a status card while
+they run, a notification when they fail and nothing when they succeed.
+
+Usage:
+  notify integrations LANGUAGE|TOOL [OPTIONS]
+
+Languages:
+  powershell         A module with a Send-Notification cmdlet that completes
+                     notification types and targets
+  python             A module with send(message, type=..., ...), standard
+                     library only
+  node               A CommonJS module with async send(message, {type, ...}),
+                     for Node 18 or later without packages
+  browser            An ES module with the same send for local web pages such
+                     as dev dashboards; start the relay with --allow-origin
+
+Build tools:
+  make               Makefile rules whose recipes start with $(NOTIFY)
+  taskfile           A Taskfile.yml for Task (taskfile.dev)
+  npm                The scripts of a package.json
+
+Options:
+  --target URL       Relay to post to, offered for completion (repeatable,
+                     default: http://localhost:8787); the first is the default.
+                     Build tool snippets send to it with --remote instead of
+                     showing notifications on this machine
+  --out FILE         Write to FILE instead of printing
+  --help             Show this help
+
+The token is read from $NOTIFY_TOKEN or passed when sending, so it isn't
+written into the generated code.
+
+Example:
+  notify integrations powershell --target hub.lan:8787 --target https://desktop.lan:8787 --out Notify.psm1
+  Import-Module .\Notify.psm1
+  Send-Notification "Backup done" -Type success -Target <Tab>
+  notify integrations python --target hub.lan:8787 --out notify.py
+  notify integrations make >> Makefile
+`)
+}
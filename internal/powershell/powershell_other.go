@@ -0,0 +1,44 @@
+//go:build !windows
+
+package powershell
+
+import (
+	"errors"
+
+	"github.com/sarfraznawaz2005/notify/internal/wsl"
+)
+
+// errToastsUnsupported is returned outside Windows and WSL
+var errToastsUnsupported = errors.New("toast notifications are only supported on Windows and inside WSL")
+
+// Check reports whether the Windows host can show toasts, which it can
+// inside WSL
+func Check() error {
+	if !wsl.Detected() {
+		return errToastsUnsupported
+	}
+	return nil
+}
+
+// Run runs script on the Windows host, inside WSL
+func Run(script string) ([]byte, error) {
+	return RunIn("", script)
+}
+
+// RunIn runs script like Run; sessions are only available on Windows
+// itself
+func RunIn(session, script string) ([]byte, error) {
+	if !wsl.Detected() {
+		return nil, errToastsUnsupported
+	}
+	if session != "" {
+		return nil, errors.New("--session is only supported on Windows")
+	}
+	return wsl.RunPowerShell(script)
+}
+
+// StartHost keeps a PowerShell running on Windows itself; inside WSL each
+// script starts the host's PowerShell
+func StartHost() error {
+	return nil
+}
@@ -0,0 +1,200 @@
+package powershell
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Check reports whether PowerShell, which shows the toasts, can be found
+func Check() error {
+	_, err := exec.LookPath("PowerShell")
+	return err
+}
+
+// Run writes script to a temporary file, runs it with a hidden window and
+// returns its standard output
+func Run(script string) ([]byte, error) {
+	return RunIn("", script)
+}
+
+// RunIn runs script like Run, in the session of the --session target
+// (see openSession)
+func RunIn(session, script string) ([]byte, error) {
+	user, err := openSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if user != nil {
+		defer user.close()
+		cmd = exec.Command("PowerShell", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-EncodedCommand", encodeCommand(script))
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, Token: user.token}
+		cmd.Env = user.env
+	} else {
+		file, err := writeScriptFile(script)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file)
+
+		if out, ran, err := warmHost.run(file); ran {
+			return out, err
+		}
+		cmd = exec.Command("PowerShell", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", file)
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("%v: %s", err, msg)
+		}
+		return stdout.Bytes(), err
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeScriptFile writes script to a temporary file PowerShell runs
+func writeScriptFile(script string) (string, error) {
+	id := make([]byte, 8)
+	rand.Read(id)
+	file := filepath.Join(os.TempDir(), "notify_"+hex.EncodeToString(id)+".ps1")
+
+	// PowerShell 5 needs the BOM to read the script as UTF-8
+	content := append([]byte{0xEF, 0xBB, 0xBF}, []byte(script)...)
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// hostScript runs the script files named on standard input, one per line,
+// answering each with a line of JSON holding its output, its errors and
+// whether it failed, as the exit code of its own PowerShell would tell.
+// Event subscriptions and queued events are dropped after each script, as
+// its own PowerShell would have exited.
+const hostScript = `
+[Console]::InputEncoding = [Console]::OutputEncoding = New-Object Text.UTF8Encoding $false
+$null = [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]
+$null = [Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime]
+while ($null -ne ($file = [Console]::In.ReadLine())) {
+    $global:LASTEXITCODE = 0
+    $errors = @()
+    $failed = $false
+    try {
+        $out = & $file 2>&1 | ForEach-Object {
+            if ($_ -is [Management.Automation.ErrorRecord]) { $errors += $_.ToString() } else { $_ }
+        } | Out-String
+    } catch {
+        $errors += $_.ToString()
+        $failed = $true
+    } finally {
+        # Events of one script never reach the next
+        Get-EventSubscriber | ForEach-Object { Unregister-Event -SubscriptionId $_.SubscriptionId }
+        Get-Event | Remove-Event
+    }
+    [Console]::Out.WriteLine((ConvertTo-Json -Compress -InputObject ([PSCustomObject]@{
+        Out    = [string]$out
+        Errors = $errors -join "` + "`n" + `"
+        Failed = $failed -or $LASTEXITCODE -ne 0
+    })))
+}
+`
+
+// psHost is a PowerShell process kept running by 'notify daemon' to run
+// scripts without the startup of a new PowerShell, which takes most of
+// the time of showing a toast
+type psHost struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// warmHost runs the scripts of this process once StartHost started it
+var warmHost = &psHost{}
+
+// StartHost starts the PowerShell process scripts run in, loading the
+// toast types up front
+func StartHost() error {
+	h := warmHost
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cmd := exec.Command("PowerShell", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-EncodedCommand", encodeCommand(hostScript))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	h.cmd, h.stdin, h.stdout = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// run runs a script file in the host. ran is false when there is no host,
+// or it is busy with another script, e.g. a toast waiting for a click.
+func (h *psHost) run(file string) (out []byte, ran bool, err error) {
+	if !h.mu.TryLock() {
+		return nil, false, nil
+	}
+	defer h.mu.Unlock()
+	if h.cmd == nil {
+		return nil, false, nil
+	}
+
+	var result struct {
+		Out    string
+		Errors string
+		Failed bool
+	}
+	_, err = fmt.Fprintln(h.stdin, file)
+	var line []byte
+	if err == nil {
+		line, err = h.stdout.ReadBytes('\n')
+	}
+	if err == nil {
+		err = json.Unmarshal(line, &result)
+	}
+	if err != nil {
+		// Scripts run in a new PowerShell from now on
+		fmt.Fprintf(os.Stderr, "Warning: the PowerShell host stopped: %v\n", err)
+		h.stdin.Close()
+		h.cmd.Process.Kill()
+		h.cmd.Wait()
+		h.cmd = nil
+		return nil, false, nil
+	}
+
+	out = []byte(result.Out)
+	if result.Failed {
+		if msg := strings.TrimSpace(result.Errors); msg != "" {
+			return out, true, errors.New(msg)
+		}
+		return out, true, errors.New("the script failed")
+	}
+	return out, true, nil
+}
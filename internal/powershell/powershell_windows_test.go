@@ -0,0 +1,46 @@
+package powershell
+
+import (
+	"strings"
+	"testing"
+)
+
+// waitScript registers a timer event under a fixed source identifier and
+// waits for it, like toast scripts wait for clicks, leaving the subscriber
+// and a second event behind
+const waitScript = `
+$before = @(Get-Event).Count
+$timer = New-Object Timers.Timer 100
+Register-ObjectEvent -InputObject $timer -EventName Elapsed -SourceIdentifier notify.test -ErrorAction Stop | Out-Null
+$timer.Start()
+$event = Wait-Event -SourceIdentifier notify.test -Timeout 10
+Start-Sleep -Milliseconds 300
+Write-Output ("before:" + $before + " waited:" + ($null -ne $event))
+`
+
+func TestHostRunsWaitsBackToBack(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Skip("PowerShell is not available:", err)
+	}
+	if err := StartHost(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		warmHost.stdin.Close()
+		warmHost.cmd.Wait()
+		warmHost.cmd = nil
+	})
+
+	for i := range 2 {
+		out, err := Run(waitScript)
+		if err != nil {
+			t.Fatalf("wait %d: %v", i+1, err)
+		}
+		if got := strings.TrimSpace(string(out)); got != "before:0 waited:True" {
+			t.Fatalf("wait %d printed %q, want no earlier events and the timer's", i+1, got)
+		}
+	}
+	if warmHost.cmd == nil {
+		t.Fatal("the scripts ran outside the host")
+	}
+}
@@ -0,0 +1,55 @@
+// Package powershell runs the PowerShell scripts that show and manage
+// toasts, on Windows or on the Windows host of WSL
+package powershell
+
+import "strings"
+
+// Prelude loads the WinRT types used by the scripts and defines helpers
+// for waiting on WinRT async operations from PowerShell
+const Prelude = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.UI.Notifications.ToastNotification, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.UI.Notifications.NotificationData, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+Add-Type -AssemblyName System.Runtime.WindowsRuntime
+
+$asTaskMethods = [System.WindowsRuntimeSystemExtensions].GetMethods() | Where-Object { $_.Name -eq 'AsTask' -and $_.GetParameters().Count -eq 1 }
+
+function Await($op, [Type]$resultType) {
+    $method = ($asTaskMethods | Where-Object { $_.GetParameters()[0].ParameterType.Name -eq 'IAsyncOperation` + "`" + `1' })[0]
+    $task = $method.MakeGenericMethod($resultType).Invoke($null, @($op))
+    $task.Wait(-1) | Out-Null
+    $task.Result
+}
+
+function AwaitAction($op) {
+    $method = ($asTaskMethods | Where-Object { $_.GetParameters()[0].ParameterType.Name -eq 'IAsyncAction' })[0]
+    $task = $method.Invoke($null, @($op))
+    $task.Wait(-1) | Out-Null
+}
+`
+
+// psRegisterApp registers the AUMID with its display name, so Action
+// Center shows the name instead of the raw id. The name is checked on
+// every use, as a key created before, e.g. by the activator registration,
+// may hold another one.
+const psRegisterApp = `
+$appKey = 'HKCU:\Software\Classes\AppUserModelId\' + $APP_ID
+if (-not (Test-Path $appKey)) {
+    New-Item -Path $appKey -Force | Out-Null
+}
+if ((Get-ItemProperty -Path $appKey).DisplayName -ne $APP_NAME) {
+    New-ItemProperty -Path $appKey -Name DisplayName -Value $APP_NAME -PropertyType String -Force | Out-Null
+}
+`
+
+// AppScript returns the PowerShell lines that define $APP_ID as the AUMID
+// id and register name as its display name
+func AppScript(id, name string) string {
+	return "$APP_ID = " + Quote(id) + "\n" + "$APP_NAME = " + Quote(name) + "\n" + psRegisterApp
+}
+
+// Quote returns s as a single-quoted PowerShell string literal
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,197 @@
+package powershell
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+var (
+	wtsapi32                       = syscall.NewLazyDLL("wtsapi32.dll")
+	procWTSQueryUserToken          = wtsapi32.NewProc("WTSQueryUserToken")
+	procWTSEnumerateSessions       = wtsapi32.NewProc("WTSEnumerateSessionsW")
+	procWTSQuerySessionInformation = wtsapi32.NewProc("WTSQuerySessionInformationW")
+	procWTSFreeMemory              = wtsapi32.NewProc("WTSFreeMemory")
+
+	kernel32                         = syscall.NewLazyDLL("kernel32.dll")
+	procWTSGetActiveConsoleSessionId = kernel32.NewProc("WTSGetActiveConsoleSessionId")
+	procProcessIdToSessionId         = kernel32.NewProc("ProcessIdToSessionId")
+
+	userenv                     = syscall.NewLazyDLL("userenv.dll")
+	procCreateEnvironmentBlock  = userenv.NewProc("CreateEnvironmentBlock")
+	procDestroyEnvironmentBlock = userenv.NewProc("DestroyEnvironmentBlock")
+)
+
+// WTS constants
+const (
+	wtsActive    = 0
+	wtsUserName  = 5
+	noSession    = 0xFFFFFFFF
+	wtsServerNil = 0 // WTS_CURRENT_SERVER_HANDLE
+)
+
+// wtsSessionInfo is WTS_SESSION_INFOW
+type wtsSessionInfo struct {
+	SessionID      uint32
+	WinStationName *uint16
+	State          uint32
+}
+
+// userSession is a process context in another user's session
+type userSession struct {
+	id    uint32
+	token syscall.Token
+	env   []string
+}
+
+// CurrentSessionID returns the session notify runs in
+func CurrentSessionID() uint32 {
+	var id uint32
+	procProcessIdToSessionId.Call(uintptr(os.Getpid()), uintptr(unsafe.Pointer(&id)))
+	return id
+}
+
+// openSession resolves the --session target and returns the context to
+// run PowerShell in, or nil to run it in notify's own session. Services
+// and SYSTEM tasks run in session 0, where toasts are never seen, so
+// they target the active console session by default.
+func openSession(target string) (*userSession, error) {
+	current := CurrentSessionID()
+	if target == "" {
+		if current != 0 {
+			return nil, nil
+		}
+		target = "active"
+	}
+
+	id, err := ResolveSession(target)
+	if err != nil {
+		return nil, err
+	}
+	if id == current {
+		return nil, nil
+	}
+
+	var token syscall.Token
+	if r, _, err := procWTSQueryUserToken.Call(uintptr(id), uintptr(unsafe.Pointer(&token))); r == 0 {
+		return nil, fmt.Errorf("showing the toast in session %d needs notify to run as SYSTEM, e.g. from a service or scheduled task: %w", id, err)
+	}
+
+	env, err := userEnvironment(token)
+	if err != nil {
+		token.Close()
+		return nil, err
+	}
+	return &userSession{id: id, token: token, env: env}, nil
+}
+
+func (s *userSession) close() {
+	s.token.Close()
+}
+
+// ResolveSession turns "active", a session id or a user name into the id
+// of a session with a logged on user
+func ResolveSession(target string) (uint32, error) {
+	if target == "active" {
+		r, _, _ := procWTSGetActiveConsoleSessionId.Call()
+		if uint32(r) == noSession {
+			return 0, fmt.Errorf("no user is logged on at the console")
+		}
+		return uint32(r), nil
+	}
+	if id, err := strconv.ParseUint(target, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+
+	sessions, err := activeSessions()
+	if err != nil {
+		return 0, err
+	}
+	var users []string
+	for id, user := range sessions {
+		// Accept both "name" and "DOMAIN\name"
+		_, short, _ := strings.Cut(target, `\`)
+		if strings.EqualFold(user, target) || strings.EqualFold(user, short) {
+			return id, nil
+		}
+		users = append(users, user)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("user %q is not logged on, and no other user is", target)
+	}
+	return 0, fmt.Errorf("user %q is not logged on.%s Logged on: %s", target, suggest.DidYouMean(target, users, ""), strings.Join(users, ", "))
+}
+
+// activeSessions returns the user names of the active sessions by id
+func activeSessions() (map[uint32]string, error) {
+	var info *wtsSessionInfo
+	var count uint32
+	r, _, err := procWTSEnumerateSessions.Call(wtsServerNil, 0, 1, uintptr(unsafe.Pointer(&info)), uintptr(unsafe.Pointer(&count)))
+	if r == 0 {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	defer procWTSFreeMemory.Call(uintptr(unsafe.Pointer(info)))
+
+	sessions := map[uint32]string{}
+	for _, s := range unsafe.Slice(info, count) {
+		if s.State != wtsActive {
+			continue
+		}
+		var name *uint16
+		var size uint32
+		r, _, _ := procWTSQuerySessionInformation.Call(wtsServerNil, uintptr(s.SessionID), wtsUserName,
+			uintptr(unsafe.Pointer(&name)), uintptr(unsafe.Pointer(&size)))
+		if r == 0 {
+			continue
+		}
+		user := syscall.UTF16ToString(unsafe.Slice(name, size/2))
+		procWTSFreeMemory.Call(uintptr(unsafe.Pointer(name)))
+		if user != "" {
+			sessions[s.SessionID] = user
+		}
+	}
+	return sessions, nil
+}
+
+// userEnvironment returns the environment variables of the user owning
+// token, so PowerShell gets the user's profile and temp directories
+func userEnvironment(token syscall.Token) ([]string, error) {
+	var block *uint16
+	if r, _, err := procCreateEnvironmentBlock.Call(uintptr(unsafe.Pointer(&block)), uintptr(token), 0); r == 0 {
+		return nil, fmt.Errorf("reading the user's environment: %w", err)
+	}
+	defer procDestroyEnvironmentBlock.Call(uintptr(unsafe.Pointer(block)))
+
+	// The block is a list of NUL terminated strings ending with an empty one
+	var env []string
+	for p := unsafe.Pointer(block); ; {
+		var n uintptr
+		for *(*uint16)(unsafe.Add(p, n*2)) != 0 {
+			n++
+		}
+		if n == 0 {
+			break
+		}
+		env = append(env, syscall.UTF16ToString(unsafe.Slice((*uint16)(p), n)))
+		p = unsafe.Add(p, (n+1)*2)
+	}
+	return env, nil
+}
+
+// encodeCommand encodes a script for PowerShell's -EncodedCommand, which
+// avoids a script file the other user might not be able to read
+func encodeCommand(script string) string {
+	units := utf16.Encode([]rune(script))
+	raw := make([]byte, len(units)*2)
+	for i, u := range units {
+		raw[i*2], raw[i*2+1] = byte(u), byte(u>>8)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
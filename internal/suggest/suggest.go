@@ -0,0 +1,60 @@
+// Package suggest proposes the valid value closest to a mistyped one
+package suggest
+
+import "strings"
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// closestMatch returns the candidate nearest to s, or "" when nothing is
+// close enough to be a likely typo
+func closestMatch(s string, candidates []string) string {
+	s = strings.ToLower(s)
+	best := ""
+	bestDistance := 0
+
+	for _, c := range candidates {
+		d := levenshtein(s, c)
+		if best == "" || d < bestDistance {
+			best = c
+			bestDistance = d
+		}
+	}
+
+	// Allow roughly one typo per three characters, but at least two
+	limit := max(2, len(s)/3)
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// DidYouMean formats a suggestion sentence, or returns "" without a match
+func DidYouMean(s string, candidates []string, prefix string) string {
+	if match := closestMatch(s, candidates); match != "" {
+		return " Did you mean " + prefix + match + "?"
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+// Package wsl runs PowerShell on the Windows host from inside WSL, which
+// shows the toasts of Linux builds there
+package wsl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Detected reports whether notify runs inside WSL with interop, so that the
+// powershell.exe of the Windows host can show toasts
+var Detected = sync.OnceValue(func() bool {
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil || !strings.Contains(strings.ToLower(string(release)), "microsoft") {
+		return false
+	}
+	_, err = exec.LookPath("powershell.exe")
+	return err == nil
+})
+
+// hostDir is a directory of the Windows host, as WSL and Windows see it
+type hostDir struct {
+	Linux   string
+	Windows string
+}
+
+// hostTemp returns notify's directory in the temporary directory of the
+// Windows user, where scripts and the files toasts show are put for the
+// host to read
+var hostTemp = sync.OnceValues(func() (hostDir, error) {
+	cmd := exec.Command("cmd.exe", "/c", "echo %TEMP%")
+	// Windows programs started in a Linux directory warn about UNC paths
+	cmd.Dir = "/mnt/c"
+	out, err := cmd.Output()
+	if err != nil {
+		return hostDir{}, fmt.Errorf("cannot find the temporary directory of Windows: %w", err)
+	}
+	windows := strings.TrimSpace(string(out))
+	out, err = exec.Command("wslpath", "-u", windows).Output()
+	if err != nil {
+		return hostDir{}, fmt.Errorf("cannot convert %s to a WSL path: %w", windows, err)
+	}
+
+	dir := hostDir{
+		Linux:   filepath.Join(strings.TrimSpace(string(out)), "notify"),
+		Windows: windows + `\notify`,
+	}
+	return dir, os.MkdirAll(dir.Linux, 0700)
+})
+
+// RunPowerShell runs script with the powershell.exe of the Windows
+// host and returns its standard output
+func RunPowerShell(script string) ([]byte, error) {
+	dir, err := hostTemp()
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	name := "notify_" + hex.EncodeToString(id) + ".ps1"
+	file := filepath.Join(dir.Linux, name)
+	defer os.Remove(file)
+
+	// PowerShell 5 needs the BOM to read the script as UTF-8
+	content := append([]byte{0xEF, 0xBB, 0xBF}, []byte(script)...)
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", dir.Windows+`\`+name)
+	cmd.Dir = dir.Linux
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("%v: %s", err, msg)
+		}
+		return stdout.Bytes(), err
+	}
+	return stdout.Bytes(), nil
+}
+
+// HostPath returns the path the Windows host reads a file of a toast
+// from: files on Windows drives are converted, others are copied to
+// hostTemp. Windows paths and URLs are returned as they are.
+func HostPath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return path, nil
+	}
+	if strings.HasPrefix(path, "/mnt/") {
+		out, err := exec.Command("wslpath", "-w", path).Output()
+		if err != nil {
+			return "", fmt.Errorf("cannot convert %s to a Windows path: %w", path, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	dir, err := hostTemp()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Named by content, so each file is copied once
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:8]) + filepath.Ext(path)
+	if _, err := os.Stat(filepath.Join(dir.Linux, name)); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(filepath.Join(dir.Linux, name), data, 0600); err != nil {
+			return "", err
+		}
+	}
+	return dir.Windows + `\` + name, nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package wsl
+
+import "errors"
+
+// Detected reports false, as WSL runs Linux builds
+func Detected() bool {
+	return false
+}
+
+// RunPowerShell is only available inside WSL
+func RunPowerShell(script string) ([]byte, error) {
+	return nil, errors.New("not running inside WSL")
+}
+
+// HostPath returns path, which Windows reads itself
+func HostPath(path string) (string, error) {
+	return path, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+var listTemplate = template.Must(template.New("list").Parse(powershell.Prelude + `
+[Windows.UI.Notifications.Management.UserNotificationListener, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$listener = [Windows.UI.Notifications.Management.UserNotificationListener]::Current
+$access = $listener.GetAccessStatus()
+if ($access -eq 'Unspecified') {
+    $access = Await ($listener.RequestAccessAsync()) ([Windows.UI.Notifications.Management.UserNotificationListenerAccessStatus])
+}
+if ($access -ne 'Allowed') {
+    [Console]::Error.WriteLine("notification access is $access")
+    exit 3
+}
+
+$items = Await ($listener.GetNotificationsAsync([Windows.UI.Notifications.NotificationKinds]::Toast)) ([System.Collections.Generic.IReadOnlyList[Windows.UI.Notifications.UserNotification]])
+$result = foreach ($n in $items) {
+    $texts = @()
+    $binding = $n.Notification.Visual.GetBinding([Windows.UI.Notifications.KnownNotificationBindings]::ToastGeneric)
+    if ($binding) { $texts = @($binding.GetTextElements() | ForEach-Object { $_.Text }) }
+    [PSCustomObject]@{
+        Id      = $n.Id
+        App     = $n.AppInfo.DisplayInfo.DisplayName
+        AppId   = $n.AppInfo.AppUserModelId
+        Created = $n.CreationTime.ToString('o')
+        Title   = ($texts | Select-Object -First 1)
+        Message = (($texts | Select-Object -Skip 1) -join "` + "`n" + `")
+    }
+}
+ConvertTo-Json -InputObject @($result) -Compress
+`))
+
+// listedNotification is a notification currently shown or kept in Action Center
+type listedNotification struct {
+	ID      uint32    `json:"id"`
+	App     string    `json:"app"`
+	AppID   string    `json:"app_id"`
+	Created time.Time `json:"created"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+}
+
+// runList implements "notify list"
+func runList(args []string) error {
+	all := false
+	asJSON := false
+	app := ""
+
+	flags := []cliFlag{
+		{Name: "all", Bool: true, Set: func(v string) (err error) { all, err = parseStrictBool(v); return }},
+		{Name: "app", Set: func(v string) error { app = v; return nil }},
+		{Name: "json", Bool: true, Set: func(v string) (err error) { asJSON, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showListHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	items, err := listNotifications()
+	if err != nil {
+		return err
+	}
+
+	if !all {
+		id, name := notify.AppUserModelID(app), notify.AppName(app)
+
+		var own []listedNotification
+		for _, item := range items {
+			if item.AppID == id || item.App == name {
+				own = append(own, item)
+			}
+		}
+		items = own
+	}
+
+	if asJSON {
+		if items == nil {
+			items = []listedNotification{}
+		}
+		data, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(items) == 0 {
+		fmt.Println("No notifications")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tAPP\tTITLE\tMESSAGE")
+	for _, item := range items {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", item.ID, item.Created.Local().Format("2006-01-02 15:04:05"),
+			item.App, oneLine(item.Title, 30), oneLine(item.Message, 50))
+	}
+	return w.Flush()
+}
+
+// listNotifications queries the UserNotificationListener for toasts
+func listNotifications() ([]listedNotification, error) {
+	var script bytes.Buffer
+	if err := listTemplate.Execute(&script, nil); err != nil {
+		return nil, err
+	}
+
+	out, err := powershell.Run(script.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot read notifications (allow access under Settings > Privacy > Notifications): %w", err)
+	}
+
+	var raw []struct {
+		Id      uint32
+		App     string
+		AppId   string
+		Created string
+		Title   string
+		Message string
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &raw); err != nil {
+		return nil, fmt.Errorf("unexpected listener output: %w", err)
+	}
+
+	items := make([]listedNotification, 0, len(raw))
+	for _, r := range raw {
+		created, _ := time.Parse(time.RFC3339Nano, r.Created)
+		items = append(items, listedNotification{
+			ID:      r.Id,
+			App:     r.App,
+			AppID:   r.AppId,
+			Created: created,
+			Title:   r.Title,
+			Message: r.Message,
+		})
+	}
+	return items, nil
+}
+
+// oneLine flattens s onto a single line and shortens it to at most n runes
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func showListHelp() {
+	fmt.Print(`List notifications currently shown or kept in Action Center
+
+Usage:
+  notify list [--all] [--app NAME] [--json]
+
+Options:
+  --all        Include notifications from other apps
+  --app NAME   List the notifications of an app set with --app
+  --json       Print the list as JSON for scripts
+
+Windows asks once for permission to read notifications.
+`)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// listenerOptions are the options shared by the commands that turn other
+// protocols into notifications, like "notify syslog". They show the
+// notifications or forward them the way the relay does.
+type listenerOptions struct {
+	relay   *relayServer
+	toToken string
+	files   tlsFiles
+	rate    int
+	burst   int
+}
+
+func newListenerOptions() *listenerOptions {
+	return &listenerOptions{
+		relay: &relayServer{started: time.Now()},
+		rate:  30,
+		burst: 10,
+	}
+}
+
+// flags returns the command line options for delivering notifications
+func (o *listenerOptions) flags() []cliFlag {
+	return []cliFlag{
+		{Name: "to", Set: func(v string) error {
+			if _, err := notify.RelayEndpoint(v); err != nil {
+				return err
+			}
+			o.relay.targets = append(o.relay.targets, v)
+			return nil
+		}},
+		{Name: "to-token", Set: func(v string) error { o.toToken = v; return nil }},
+		{Name: "ca", Set: func(v string) error { o.files.CA = v; return nil }},
+		{Name: "client-cert", Set: func(v string) error { o.files.Cert = v; return nil }},
+		{Name: "client-key", Set: func(v string) error { o.files.Key = v; return nil }},
+		{Name: "rate", Set: func(v string) (err error) { o.rate, err = parseCount(v); return }},
+		{Name: "burst", Set: func(v string) (err error) { o.burst, err = parseGroupSize(v); return }},
+	}
+}
+
+// start prepares the relay for delivering, and shows catch-up summaries
+// when notifications are shown on this machine
+func (o *listenerOptions) start() error {
+	o.relay.limit = newRateLimiter(o.rate, o.burst)
+	var err error
+	if o.relay.out, err = newRelaySender(o.toToken, "", "", o.files); err != nil {
+		return err
+	}
+	if len(o.relay.targets) == 0 {
+		go o.relay.catchUpLoop()
+	}
+	return nil
+}
+
+// deliverEvent shows or forwards the notification for an event received
+// from a device, within the sender's rate limit
+func (s *relayServer) deliverEvent(kind, from string, m *notify.RelayMessage, trace *span) {
+	defer func() { go flushTraces() }()
+
+	if s.limit != nil {
+		if ok, _ := s.limit.allow(from); !ok {
+			trace.finish(errors.New("rate limited"))
+			log.Printf("%s: too many %s messages, dropped %q", from, kind, m.Title)
+			return
+		}
+	}
+
+	m.Source = from
+	n, err := receivedNotification(m)
+	if err != nil {
+		trace.finish(err)
+		log.Printf("%s: invalid %s notification: %v", from, kind, err)
+		return
+	}
+	n.trace = trace
+
+	err = s.deliver(m, n)
+	trace.finish(err)
+	if err != nil {
+		log.Printf("%s: %s %q failed: %v", from, kind, n.Title, err)
+		return
+	}
+	log.Printf("%s: %s %q delivered", from, kind, n.Title)
+}
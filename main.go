@@ -1,312 +1,588 @@
 package main
 
 import (
+	"cmp"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"os"
 	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/go-toast/toast"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
 )
 
-// Notification represents a notification with type, message, and options
+// Notification is a notification of the notify package with the options
+// of the command, which decide how it is delivered
 type Notification struct {
-	Type      string
-	Title     string
-	Message   string
-	Timeout   int
-	AutoClose bool
+	notify.Notification
+
+	Collection string
+	AckID      string
+	OnClick    string // notify: URI launched when the toast is clicked
+	ClickHint  string // shown below the message when OnClick is set
+	Attachment *notify.Attachment
+	Preview    *bool  // add a preview of the first link in the message, nil for config.yaml's setting
+	Shorten    *bool  // shorten long links in the message, nil for config.yaml's setting
+	Volume     *int   // sound volume in percent, nil for the system volume
+	Duck       bool   // lower other audio while the sound plays
+	Speak      bool   // also read the notification aloud
+	Accessible bool   // high-contrast icon, the type in the title and a long toast
+	Palette    string // icon colors: colorblind, or empty for the default
+	Sound      string // overrides the sound chosen by type: silent, a Windows sound or a wav file
+	GroupSize  int    // number of notifications expected in Group
+	Remote     string // relay that shows the notification instead of this machine
+	Token      string // token for Remote
+	EncryptTo  string // public key of the relay that may read the notification
+	SignSecret string // HMAC secret signing requests to Remote
+	SignKey    string // Ed25519 key file signing requests to Remote
+	TLS        tlsFiles
+	Source     string          // host a relayed notification came from
+	Session    string          // Windows session showing the toast: active, a user name or id
+	Fallback   string          // shown instead when Windows refuses the toast, e.g. msgbox
+	Backend    string          // shows the notification: toast, desktop or console, empty or auto for the platform's
+	Inject     *faultInjection // delays or fails the delivery for testing, nil for config.yaml's
+	Window     string          // title of the window a flash or badge fallback uses
+	Wake       time.Duration   // keep the display on this long after showing the toast
+	Wait       bool            // wait until the toast is clicked, dismissed or times out
+	ID         string          // identifies the shown toast in history, for its interactions
+	Progress   *notify.Progress
+
+	trace       *span             // parent of the spans traced while sending
+	interaction *toastInteraction // what the user did with the toast, with Wait
 }
 
-// Icon data for each notification type (colored circle icons)
-var iconData = map[string]struct {
-	Color    color.RGBA
-	Symbol   string
-}{
-	"success": {Color: color.RGBA{R: 46, G: 204, B: 113, A: 255}, Symbol: "✓"},   // Green
-	"error":   {Color: color.RGBA{R: 231, G: 76, B: 60, A: 255}, Symbol: "✗"},    // Red
-	"info":    {Color: color.RGBA{R: 52, G: 152, B: 219, A: 255}, Symbol: "ℹ"},   // Blue
-	"warning": {Color: color.RGBA{R: 241, G: 196, B: 15, A: 255}, Symbol: "⚠"},   // Yellow
+// exitBlocked is the exit code when Windows settings keep the toast from
+// showing, so scripts can tell it apart from other failures
+const exitBlocked = 3
+
+// Subcommands, selected by the first argument. A message starting with
+// one of their names is sent with "notify send" or after "--".
+var commands = map[string]func(args []string) error{
+	"ack":          runAck,
+	"bench":        runBench,
+	"catch-up":     runCatchUp,
+	"checkin":      runCheckin,
+	"clear":        runClear,
+	"collection":   runCollection,
+	"config":       runConfig,
+	"countdown":    runCountdown,
+	"cron":         runCron,
+	"daemon":       runDaemon,
+	"exec":         runExec,
+	"expect":       runExpect,
+	"export":       runExport,
+	"history":      runHistory,
+	"import":       runImport,
+	"inbound":      runInbound,
+	"integrations": runIntegrations,
+	"keygen":       runKeygen,
+	"list":         runList,
+	"mute":         runMute,
+	"pending":      runPending,
+	"pomodoro":     runPomodoro,
+	"progress":     runProgress,
+	"relay":        runRelay,
+	"run":          runRun,
+	"schedule":     runSchedule,
+	"sequence":     runSequence,
+	"serve":        runRelay,
+	"settings":     runSettings,
+	"snmp":         runSNMP,
+	"sounds":       runSounds,
+	"spool":        runSpool,
+	"stats":        runStats,
+	"stream":       runStream,
+	"subscribe":    runSubscribe,
+	"syslog":       runSyslog,
+	"test":         runTest,
+	"token":        runToken,
+	"unmute":       runUnmute,
+	"update":       runUpdate,
+	"watch":        runWatch,
+	"when":         runWhen,
 }
 
 func main() {
 	args := os.Args[1:]
 
+	// Clicking a toast launches notify with --activate and a notify: URI
+	if len(args) == 2 && args[0] == activateArg {
+		if err := handleActivation(args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Windows starts the toast activator for clicks, also after the
+	// sending notify has exited
+	if isActivatorLaunch(args) {
+		err := serveActivator(func(a activation) {
+			if !isActivation(a.Args) {
+				fmt.Printf("Error: unknown activation %q\n", a.Args)
+				return
+			}
+			in := toastInteraction{Action: "clicked", Input: a.Input}
+			if len(a.Input) > 0 {
+				in.Action = "replied"
+			}
+			if err := recordInteraction(activationToast(a.Args), in); err != nil {
+				fmt.Printf("Warning: could not record history: %v\n", err)
+			}
+			if err := handleActivation(a.Args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// --follow is 'notify stream' for those looking for it among the
+	// options, e.g. 'tail -f log | notify --follow --match ERROR'
+	end := len(args)
+	if i := slices.Index(args, "--"); i >= 0 {
+		end = i
+	}
+	if i := slices.Index(args[:end], "--follow"); i >= 0 {
+		args = slices.Concat([]string{"stream"}, args[:i], args[i+1:])
+	}
+
+	// "notify send" always sends its words, e.g. 'notify send test passed'
+	// rather than running 'notify test'
+	if len(args) > 0 && args[0] == "send" {
+		args = args[1:]
+	} else if len(args) > 0 {
+		if command, ok := commands[args[0]]; ok {
+			err := command(args[1:])
+			flushTraces()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Default values
-	notificationType := "info"
-	timeout := 5
-	autoClose := true
-	customTitle := ""
-	var message string
+	opts := newNotifyOptions()
+	dryRun, noDaemon := false, false
+	var stdin stdinConfig // overrides of config.yaml's
+
+	showUsage := func(string) error {
+		showHelp()
+		os.Exit(0)
+		return nil
+	}
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "help", Bool: true, Set: showUsage},
+		cliFlag{Name: "h", Bool: true, Set: showUsage},
+		cliFlag{Name: "dry-run", Bool: true, Set: func(v string) (err error) { dryRun, err = parseStrictBool(v); return }},
+		cliFlag{Name: "no-daemon", Bool: true, Set: func(v string) (err error) { noDaemon, err = parseStrictBool(v); return }},
+		cliFlag{Name: "max-length", Set: func(v string) (err error) { stdin.MaxLength, err = parseMaxLength(v); return }},
+		cliFlag{Name: "keep", Set: func(v string) (err error) { stdin.Keep, err = parseKeep(v); return }},
+	)
 
 	// Parse arguments
-	i := 0
-	for i < len(args) {
-		arg := args[i]
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("Run 'notify --help' for usage.")
+		os.Exit(1)
+	}
 
-		if arg == "--help" || arg == "-help" || arg == "-h" {
+	// All positional arguments form the message, so quoting is optional.
+	// Without any, or with -, it is read from standard input.
+	message := strings.Join(words, " ")
+	if message == "-" || (message == "" && stdinPiped()) {
+		config, err := loadConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		stdin.MaxLength = cmp.Or(stdin.MaxLength, config.Stdin.MaxLength)
+		stdin.Keep = cmp.Or(stdin.Keep, config.Stdin.Keep)
+		if message, err = readStdinMessage(stdin); err != nil {
+			fmt.Printf("Error reading the message from standard input: %v\n", err)
+			os.Exit(1)
+		}
+		if message == "" {
+			fmt.Println("Error: no message was given and standard input was empty")
+			os.Exit(1)
+		}
+	}
+	if message == "" {
+		if !isInteractive() {
+			fmt.Println("Message is required as a positional argument")
 			showHelp()
-			os.Exit(0)
+			os.Exit(1)
 		}
 
-		if strings.HasPrefix(arg, "--type=") {
-			notificationType = strings.TrimPrefix(arg, "--type=")
-			i++
-			continue
+		// No message on a terminal - ask for the details instead
+		message, err = runWizard(opts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
+	}
 
-		if arg == "--type" || arg == "-type" {
-			if i+1 < len(args) {
-				notificationType = args[i+1]
-				i += 2
-				continue
-			}
-		}
+	// Create notification
+	trace := startSpan(nil, "notify")
+	parse := startSpan(trace, "parse")
+	notification, err := opts.build(message)
+	parse.finish(err)
+	if err != nil {
+		trace.finish(err)
+		flushTraces()
+		fmt.Printf("Invalid notification: %v\n", err)
+		os.Exit(1)
+	}
+	notification.trace = trace
 
-		if strings.HasPrefix(arg, "--title=") {
-			customTitle = strings.TrimPrefix(arg, "--title=")
-			i++
-			continue
-		}
+	if dryRun {
+		printDryRun(notification)
+		return
+	}
 
-		if arg == "--title" || arg == "-title" {
-			if i+1 < len(args) {
-				customTitle = args[i+1]
-				i += 2
-				continue
-			}
+	sent := false
+	if !noDaemon {
+		sent, err = sendToDaemon(notification)
+	}
+	if !sent {
+		err = sendNotification(notification)
+	}
+	trace.finish(err)
+	flushTraces()
+	if err != nil {
+		var blocked *notify.BlockedError
+		if errors.As(err, &blocked) {
+			fmt.Printf("Error: the notification was not shown, %v\n", err)
+			os.Exit(exitBlocked)
 		}
+		fmt.Printf("Error displaying notification: %v\n", err)
+		os.Exit(1)
+	}
+	awake.Wait()
+}
 
-		if strings.HasPrefix(arg, "--timeout=") {
-			if val, err := strconv.Atoi(strings.TrimPrefix(arg, "--timeout=")); err == nil {
-				timeout = val
-			}
-			i++
-			continue
+// sendNotification delivers a validated notification, honoring muted
+// categories and recording the outcome in history
+func sendNotification(n *Notification) (err error) {
+	send := startSpan(n.trace, "send", "notify.type", n.Type, "notify.app", notify.AppName(n.App))
+	defer func() { send.finish(err) }()
+
+	route := startSpan(send, "route")
+	if muted, until := isMuted(n.Category); muted {
+		route.set("notify.route", "muted")
+		route.finish(nil)
+		recordHistory(n, statusMuted, nil)
+		if until.IsZero() {
+			fmt.Printf("Category %q is muted, notification suppressed\n", n.Category)
+		} else {
+			fmt.Printf("Category %q is muted until %s, notification suppressed\n", n.Category, until.Format("15:04"))
 		}
+		return nil
+	}
 
-		if arg == "--timeout" || arg == "-timeout" {
-			if i+1 < len(args) {
-				if val, err := strconv.Atoi(args[i+1]); err == nil {
-					timeout = val
-				}
-				i += 2
-				continue
-			}
-		}
+	config, err := loadConfig()
+	if err != nil {
+		route.finish(err)
+		return err
+	}
+	config.CI.apply(n)
+	previewLink(n, config, route)
+	shortenLinks(n, config, route)
+	n.Backend = cmp.Or(n.Backend, config.Backend)
+	if n.Inject == nil {
+		n.Inject = config.Inject[backendName(n.Backend)]
+	}
 
-		if strings.HasPrefix(arg, "--autoclose=") {
-			autoClose = parseBool(strings.TrimPrefix(arg, "--autoclose="))
-			i++
-			continue
+	if n.Remote != "" {
+		route.set("notify.route", "remote")
+		route.finish(nil)
+		if err := sendRemote(n, send); err != nil {
+			recordHistory(n, statusFailed, err)
+			return err
 		}
+		recordHistory(n, statusDelivered, nil)
+		return nil
+	}
 
-		if arg == "--autoclose" || arg == "-autoclose" {
-			if i+1 < len(args) {
-				autoClose = parseBool(args[i+1])
-				i += 2
-				continue
-			}
+	if n.AckID != "" {
+		if err := addPendingAck(n.AckID, n); err != nil {
+			route.finish(err)
+			return err
 		}
+	}
 
-		if !strings.HasPrefix(arg, "-") {
-			message = arg
-			i++
-			continue
+	if reason := deferReason(config, time.Now(), n.Type); reason != "" && !n.Urgent {
+		route.set("notify.route", "deferred")
+		route.finish(nil)
+		if err := deferNotification(n); err != nil {
+			return err
 		}
+		recordHistory(n, statusDeferred, nil)
+		fmt.Printf("Notification deferred (%s), it will be summarized later\n", reason)
+		return nil
+	}
 
-		i++
+	if n.Sound == "" {
+		n.Sound = config.Sounds.sound(n)
 	}
+	config.Accessibility.apply(n)
 
-	if message == "" {
-		fmt.Println("Message is required as a positional argument")
-		showHelp()
-		os.Exit(1)
+	// Calls can also just silence notifications
+	if app := config.Defer.Calls.inCall("silent", n.Type); app != "" && !n.Urgent {
+		n.Sound = notify.SoundSilent
+		route.set("notify.silenced", app)
 	}
 
-	// Validate notification type
-	validTypes := []string{"success", "error", "info", "warning"}
-	isValidType := false
-	for _, t := range validTypes {
-		if notificationType == t {
-			isValidType = true
-			break
+	// Interactions with the toast are recorded under its id
+	n.ID = newToken()
+
+	// Grouped notifications replace their group's summary toast
+	display := n
+	if n.Group != "" && n.Tag == "" {
+		group, err := addToGroup(n)
+		if err != nil {
+			route.finish(err)
+			return err
 		}
+		display = groupSummary(n, group)
+		route.set("notify.group", n.Group)
 	}
+	route.set("notify.route", "local")
+	route.finish(nil)
 
-	if !isValidType {
-		fmt.Printf("Invalid notification type: %s. Valid types are: success, error, info, warning\n", notificationType)
-		os.Exit(1)
+	// Notifications missed while deferring are summarized first
+	if _, err := catchUp(config, send, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not show the catch-up summary: %v\n", err)
 	}
 
-	// Determine title
-	title := customTitle
-	if title == "" {
-		title = strings.Title(notificationType)
+	// Display the notification
+	if err := displayNotification(display, send); err != nil {
+		switch {
+		case n.Fallback != "":
+			if fallbackErr := showFallback(display, send); fallbackErr != nil {
+				err = fmt.Errorf("%w; %s fallback failed: %v", err, n.Fallback, fallbackErr)
+				recordHistory(n, statusFailed, err)
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v; used the %s fallback\n", err, n.Fallback)
+		case printInstead(n, err):
+			if printErr := printNotification(display, send); printErr != nil {
+				err = fmt.Errorf("%w; printing it failed: %v", err, printErr)
+				recordHistory(n, statusFailed, err)
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v; printed the notification instead\n", err)
+		default:
+			recordHistory(n, statusFailed, err)
+			return err
+		}
 	}
-
-	// Create notification
-	notification := &Notification{
-		Type:      notificationType,
-		Title:     title,
-		Message:   message,
-		Timeout:   timeout,
-		AutoClose: autoClose,
+	if n.Wake > 0 {
+		keepAwake(n.Wake)
 	}
+	recordHistory(n, statusDelivered, nil)
+	if in := display.interaction; in != nil {
+		fmt.Println(in.Action)
+		if err := recordInteraction(n.ID, *in); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record history: %v\n", err)
+		}
+	}
+	return nil
+}
 
-	// Display the notification
-	if err := displayNotification(notification); err != nil {
-		fmt.Printf("Error displaying notification: %v\n", err)
-		os.Exit(1)
+// recordHistory saves the outcome of a notification. History is best
+// effort, so failures only produce a warning.
+func recordHistory(n *Notification, status string, deliveryErr error) {
+	entry := newHistoryEntry(n, status, deliveryErr)
+	var titleRedacted string
+	entry.Title, titleRedacted = redactForHistory(entry.Title)
+	entry.Message, entry.Redacted = redactForHistory(entry.Message)
+	entry.Redacted = cmp.Or(entry.Redacted, titleRedacted)
+	if err := appendHistory(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record history: %v\n", err)
+	}
+	if err := autoPruneHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not prune history: %v\n", err)
 	}
 }
 
-func parseBool(s string) bool {
-	return strings.ToLower(s) == "true"
+// isValidType reports whether t is one of the supported notification types
+func isValidType(t string) bool {
+	return slices.Contains(notify.Types, t)
 }
 
 func showHelp() {
-	fmt.Println(`notify - A CLI notification utility
+	fmt.Print(`notify - A CLI notification utility
 
 Usage:
   notify MESSAGE [OPTIONS]
+  notify send MESSAGE [OPTIONS]
+  notify COMMAND [ARGS]
 
 Arguments:
-  MESSAGE             The notification message (positional argument)
-
-Options:
+  MESSAGE             The notification message (positional arguments are joined with spaces;
+                      words after -- are never options)
+                      A first word naming a command, e.g. 'notify test passed',
+                      runs that command; use 'notify send test passed' or
+                      'notify -- test passed' to send it as the message
+                      When omitted, or -, it is read from a pipe or file on standard
+                      input; on an interactive terminal notify asks for it
+
+Options (the defaults section of config.yaml changes their defaults, see 'notify config'):
   --title TITLE       Custom title for the notification (default: based on type)
   --type TYPE         Type of notification: success, error, info, warning (default: info)
-  --timeout SECONDS   Timeout in seconds (default: 5)
+  --timeout SECONDS   Timeout in seconds, 1-3600 (default: 5)
   --autoclose BOOLEAN Auto close after timeout (default: true)
+  --app NAME          Sender name shown in Action Center (default: Notify CLI)
+  --category NAME     Category used for muting, e.g. ci or chat (see 'notify mute')
+  --require-ack ID    Keep the notification pending until 'notify ack ID' or a click
+  --group NAME        Summarize related notifications in one toast, e.g. "3 of 5 tasks complete"
+  --group-size N      Number of notifications expected in the --group
+  --remote URL        Send to a 'notify relay' instead of showing the toast here,
+                      or publish to an ntfy topic given as ntfy://host/topic
+  --token TOKEN       Token for --remote (default: $NOTIFY_TOKEN)
+  --encrypt-to KEY    Encrypt for the relay with this public key (see 'notify keygen')
+  --sign-secret S     Sign --remote requests with this HMAC secret (default: $NOTIFY_SIGNING_SECRET)
+  --sign-key FILE     Sign --remote requests with an Ed25519 key from 'notify keygen --signing'
+  --ca FILE           Trust this CA for an https --remote
+  --client-cert FILE  Client certificate for relays requiring mutual TLS
+  --client-key FILE   Private key of --client-cert
+  --session USER      Show the toast in this user's session: active (default for
+                      services and SYSTEM tasks), a user name or a session id
+  --fallback KIND     When Windows refuses the toast, show a topmost message box
+                      (msgbox), flash the taskbar button (flash) or put a badge
+                      on it (badge)
+  --backend NAME      Show the notification as a toast (toast), on the Linux or
+                      macOS desktop (desktop) or print it in the terminal
+                      (console) (default: auto, the platform's, which prints
+                      it when it can't be shown, e.g. over SSH)
+  --window TITLE      Window to flash or badge (default: the terminal)
+  --urgent            Break through Focus Assist (Windows 11) and never defer
+  --preview           Use the title and image of the first link in the message
+                      and open it on click; --preview=false turns off the
+                      preview setting of config.yaml
+  --shorten           Shorten long links in the message, through 'notify daemon'
+                      or the shortener of config.yaml; --shorten=false turns
+                      off the shorten setting of config.yaml
+  --attach FILE       Send a file with the notification, at most 8MB; images are
+                      shown in the toast and clicking shows the file in Explorer
+  --volume PERCENT    Play the sound at this volume, 0-100 (0 for none)
+  --duck              Lower other audio while the sound plays, e.g. for alarms
+  --speak             Also read the notification aloud
+  --qr VALUE          Show VALUE, e.g. a link or otpauth: URI, as a QR code to
+                      scan with a phone (at most 213 bytes)
+  --wait              Wait until the toast is clicked, dismissed or times out,
+                      print which and record it in history
+  --wake DURATION     With --urgent, turn the display on and keep it on this
+                      long, e.g. 2m; notify waits until then
+  --private           Keep the message out of history, catch-up and group
+                      summaries, e.g. for one-time codes
+  --collection ID     Show the toast in a collection created with 'notify collection'
+  --follow            Stay attached to standard input and show a notification
+                      per line, with the options of 'notify stream', e.g.
+                      --match REGEX and --rate N
+  --max-length N      Characters kept of a message read from standard input
+                      (default: 1000, or stdin.max_length of config.yaml)
+  --keep start|end    Which end of a longer message from standard input is kept
+                      (default: start)
+  --dry-run           Validate the options and print the result without notifying
+  --no-daemon         Show the notification from this process even when a
+                      'notify daemon' is running
   --help              Show this help message
 
+Commands:
+  clear               Remove notify's notifications from Action Center
+  ack, pending        Acknowledge and list --require-ack notifications
+  collection          Create, list and remove toast collections (Windows 11)
+  list                List notify's notifications in Action Center (--all for every app)
+  config [path|show|init]
+                      Print where config.yaml is or the settings in effect,
+                      or write one with the defaults
+  settings            Show whether Windows settings let notify's toasts pop up, with
+                      --open to change them
+  countdown DURATION MESSAGE
+                      Show a live countdown toast that ends with an alarm
+  pomodoro [TASK]     Work periods and breaks as countdown toasts, with --focus
+                      as Windows 11 focus sessions
+  when --at TIME|--in DELAY MESSAGE
+                      Show a notification later, e.g. '--at "tomorrow 9am"' or
+                      in another time zone: '--tz Asia/Karachi --at 09:00'
+  schedule            List, show, cancel, pause and resume waiting 'notify when's
+  expect NAME --within DURATION
+                      Alert when 'notify checkin NAME' doesn't run in time, e.g.
+                      after backups or cron jobs
+  sounds              List and preview sounds, chosen per type and category in config.yaml
+  catch-up            Show the summary of notifications deferred by quiet hours,
+                      Focus Assist, a locked screen, meetings or calls (see config.yaml)
+  history             Show sent notifications; 'history prune' applies the retention policy
+  stats               Summarize the history by type, target, hour, sender and category
+  export, import      Move configuration, state and optionally keys to another machine
+  keygen              Create the key pair for --encrypt-to
+  relay, serve        Accept notifications from other machines and show or forward them
+  token               Create expiring guest tokens for the relay, limited to some
+                      types and a number of notifications
+  inbound             List, show and replay the webhooks the relay received
+  integrations LANGUAGE
+                      Generate a relay client for powershell, python, node or
+                      web pages (browser)
+  syslog              Notify on syslog messages from routers and other appliances
+  snmp                Notify on SNMP traps, e.g. 'notify snmp --oid linkDown'
+  subscribe TOPIC     Show the messages of ntfy topics, e.g. ntfy://ntfy.sh/alerts
+  spool DIR           Turn text and JSON files dropped in a directory into
+                      notifications, archiving them afterwards
+  watch               Alert on performance counters crossing thresholds, network,
+                      DNS and public IP changes, USB drives, print jobs and
+                      copied codes (see config.yaml)
+  daemon              Keep notify running so later notifications show faster
+  progress            Live status card fed from stdin, e.g. 'job | notify progress'
+  run -- COMMAND      Run a command and notify when it finishes or fails, with
+                      the elapsed time
+  exec -- COMMAND     Live status card for a command, which it and its children
+                      update with 'notify update 42%'
+  test -- COMMAND     Notify the results of go test -json, jest --json or TAP
+                      tests, once or on every change with --watch; without a
+                      command, send a sample of each type to check delivery
+  cron -- COMMAND     Run a scheduled job quietly, notifying with its output only
+                      when it fails; --lock skips overlapping runs
+  bench -- COMMAND    Notify when go test -bench results regress from the last run
+  stream              A notification per stdin line, with dedup and a rate limit,
+                      e.g. 'tail -f log | grep ERROR | notify stream --type error'
+  sequence FILE       Play a series of notifications from a YAML file
+  mute, unmute        Silence a --category for a while, e.g. 'notify mute ci --for 2h'
+
+Use 'notify -- WORDS' for a message that starts with a command name.
+
+Exits with 3 when Windows settings block the toast (notifications turned
+off for the app or user, or by group policy) and no --fallback was shown,
+and 1 for other failures.
+
 Examples:
   notify "Operation completed successfully" --type success
+  notify Build finished successfully --type success
   notify "An error occurred" --type error --timeout 10
   notify "Build done" --title "My App" --type success
+  notify "Nightly backup done" --app "Backup Jobs" --type success
   notify "Download started" --title "Downloader" --type info --autoclose false
 `)
 }
 
-// createIcon creates a colored icon PNG and returns the path
-func createIcon(nType string) (string, error) {
-	data, ok := iconData[nType]
-	if !ok {
-		data = iconData["info"]
-	}
-
-	// Create a 64x64 image
-	size := 64
-	img := image.NewRGBA(image.Rect(0, 0, size, size))
-
-	// Draw a filled circle with the color
-	center := size / 2
-	radius := size / 2 - 4
-
-	for y := 0; y < size; y++ {
-		for x := 0; x < size; x++ {
-			// Calculate distance from center
-			dx := x - center
-			dy := y - center
-			distance := dx*dx + dy*dy
-
-			if distance <= radius*radius {
-				// Inside circle - draw color
-				img.Set(x, y, data.Color)
-			} else {
-				// Outside circle - transparent
-				img.Set(x, y, color.RGBA{R: 0, G: 0, B: 0, A: 0})
-			}
-		}
-	}
-
-	// Get temp directory
-	tempDir := os.TempDir()
-	iconPath := filepath.Join(tempDir, fmt.Sprintf("notify_icon_%s.png", nType))
-
-	// Create the file
-	file, err := os.Create(iconPath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	// Encode as PNG
-	err = png.Encode(file, img)
-	if err != nil {
-		return "", err
-	}
-
-	return iconPath, nil
-}
-
-// getIconPath returns the path to an icon file for the notification type
-func getIconPath(nType string) (string, error) {
-	// Try to create icon in temp directory
-	iconPath, err := createIcon(nType)
-	if err != nil {
-		return "", err
-	}
-	return iconPath, nil
-}
-
-func displayNotification(n *Notification) error {
-	// Create icon for this notification type
-	iconPath, err := getIconPath(n.Type)
-	if err != nil {
-		// Continue without icon if there's an error
-		iconPath = ""
-	}
-
-	// Build toast notification
-	notification := toast.Notification{
-		AppID:               "Notify CLI",
-		Title:               n.Title,
-		Message:             n.Message,
-		Icon:                iconPath,
-		Duration:            toast.Short,
-		ActivationType:      "protocol",
-		ActivationArguments: "dismiss",
+// cachedIcon returns the path of the icon for the notification type, the
+// one of config.yaml when it sets one
+func cachedIcon(nType, style string) (string, error) {
+	if icon := typeIcon(nType); icon != "" {
+		return icon, nil
 	}
-
-	// We can't easily add custom XML attributes through the go-toast library
-	// The library generates PowerShell code that creates the toast
-	// By default, clicking a toast dismisses it from the Action Center
-
-	// Set audio based on type
-	switch n.Type {
-	case "success", "error", "warning":
-		notification.Audio = toast.Default
-	default:
-		notification.Audio = toast.Silent
-	}
-
-	if !n.AutoClose {
-		notification.Duration = toast.Long
-	}
-
-	// Show the notification - it will dismiss when clicked
-	err = notification.Push()
-	if err != nil {
-		return err
-	}
-
-	// Small delay to ensure notification is sent before program exits
-	time.Sleep(500 * time.Millisecond)
-
-	// Clean up icon file
-	if iconPath != "" {
-		os.Remove(iconPath)
-	}
-
-	return nil
+	return notify.IconFile(nType, style)
 }
 
 // Embedded icon as base64 (fallback)
@@ -337,4 +613,4 @@ func getEmbeddedIconPath(nType string) (string, error) {
 	}
 
 	return iconPath, nil
-}
\ No newline at end of file
+}
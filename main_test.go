@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+	"github.com/sarfraznawaz2005/notify/pkg/notify/notifytest"
+)
+
+// isolate points the config, data and cache directories of notify at
+// empty directories for the test, so config.yaml, state and history
+// start out empty
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	t.Setenv("NOTIFY_TOKEN", "")
+	t.Setenv("NOTIFY_SIGNING_SECRET", "")
+}
+
+// fakeBackend registers a fake backend under name for the test
+func fakeBackend(t *testing.T, name string) *notifytest.Backend {
+	t.Helper()
+	backend := &notifytest.Backend{}
+	notify.Backends[name] = backend
+	t.Cleanup(func() { delete(notify.Backends, name) })
+	return backend
+}
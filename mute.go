@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runMute implements "notify mute [CATEGORY] [--for DURATION]"
+func runMute(args []string) error {
+	var duration time.Duration
+
+	flags := []cliFlag{
+		{Name: "for", Set: func(v string) (err error) { duration, err = parseDuration(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showMuteHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+
+	if len(words) == 0 {
+		return listMutes()
+	}
+	if len(words) > 1 {
+		return fmt.Errorf("unexpected argument: %s", words[1])
+	}
+
+	category := normalizeCategory(words[0])
+	var until time.Time
+	if duration > 0 {
+		until = time.Now().Add(duration)
+	}
+
+	err = updateState(func(s *State) error {
+		if s.Mutes == nil {
+			s.Mutes = map[string]time.Time{}
+		}
+		s.Mutes[category] = until
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if until.IsZero() {
+		fmt.Printf("Category %q muted until 'notify unmute %s'\n", category, category)
+	} else {
+		fmt.Printf("Category %q muted until %s\n", category, until.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// runUnmute implements "notify unmute CATEGORY"
+func runUnmute(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showMuteHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return fmt.Errorf("usage: notify unmute CATEGORY")
+	}
+
+	category := normalizeCategory(words[0])
+	found := false
+	err = updateState(func(s *State) error {
+		_, found = s.Mutes[category]
+		delete(s.Mutes, category)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		fmt.Printf("Category %q was not muted\n", category)
+	} else {
+		fmt.Printf("Category %q unmuted\n", category)
+	}
+	return nil
+}
+
+// listMutes prints the active mutes
+func listMutes() error {
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	var categories []string
+	for category, until := range state.Mutes {
+		if until.IsZero() || until.After(time.Now()) {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		fmt.Println("No muted categories")
+		return nil
+	}
+
+	sort.Strings(categories)
+	for _, category := range categories {
+		if until := state.Mutes[category]; until.IsZero() {
+			fmt.Printf("%s\tuntil unmuted\n", category)
+		} else {
+			fmt.Printf("%s\tuntil %s\n", category, until.Format("2006-01-02 15:04"))
+		}
+	}
+	return nil
+}
+
+// isMuted reports whether notifications in the category are silenced,
+// returning when the mute ends (zero for indefinite mutes)
+func isMuted(category string) (bool, time.Time) {
+	if category == "" {
+		return false, time.Time{}
+	}
+
+	state, err := loadState()
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	until, ok := state.Mutes[category]
+	if !ok || (!until.IsZero() && until.Before(time.Now())) {
+		return false, time.Time{}
+	}
+	return true, until
+}
+
+// normalizeCategory makes category names case-insensitive
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+func showMuteHelp() {
+	fmt.Print(`Temporarily silence notifications sent with --category
+
+Usage:
+  notify mute CATEGORY [--for DURATION]
+  notify mute                      List muted categories
+  notify unmute CATEGORY
+
+Options:
+  --for DURATION   Mute only for a while, e.g. 30m, 2h or 1d (default: until unmuted)
+
+Example:
+  notify mute ci --for 2h
+`)
+}
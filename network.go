@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// networkWatch chooses the network changes "notify watch" reports
+type networkWatch struct {
+	Internet bool   `yaml:"internet"` // connectivity drops and recoveries
+	WiFi     bool   `yaml:"wifi"`     // joining, leaving and switching Wi-Fi networks
+	VPN      bool   `yaml:"vpn"`      // VPNs connecting and disconnecting
+	Probe    string `yaml:"probe"`    // URL fetched to check internet access
+}
+
+// networkState is what is known about the connection at one moment
+type networkState struct {
+	Gateway  string   // default gateway, "" without one
+	Internet bool     // the probe URL could be fetched
+	SSID     string   // Wi-Fi network, "" when not on Wi-Fi
+	VPNs     []string // connected VPN adapters
+}
+
+// watchNetwork reports connectivity, Wi-Fi and VPN changes. The first
+// round only records the current state.
+func watchNetwork(w networkWatch) (watchCheck, error) {
+	if w.WiFi {
+		if _, err := wifiSSID(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Captive portals answer with a redirect, which doesn't count as online
+	client := &http.Client{
+		Timeout:       5 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	var last *networkState
+	var offlineSince time.Time
+	failures := 0
+
+	return func(now time.Time) ([]*notify.RelayMessage, error) {
+		state := &networkState{}
+		state.Gateway, state.VPNs = networkAdapters()
+		if w.WiFi {
+			state.SSID, _ = wifiSSID()
+		}
+		if w.Internet {
+			failures++
+			if probeInternet(client, w.Probe) {
+				failures = 0
+			} else if failures == 1 {
+				offlineSince = now
+			}
+			// One failed probe is often a blip, so wait for a second one
+			state.Internet = failures == 0 || (failures < 2 && last != nil && last.Internet)
+		}
+
+		previous := last
+		last = state
+		if previous == nil {
+			return nil, nil
+		}
+
+		var messages []*notify.RelayMessage
+		message := func(tag, typ, title, text string) {
+			messages = append(messages, &notify.RelayMessage{
+				App: "Watch", Category: "network", Tag: oneLine("network "+tag, notify.MaxTagLength),
+				Type: typ, Title: oneLine(title, notify.MaxTitleLength), Message: text,
+			})
+		}
+
+		if w.Internet && previous.Internet != state.Internet {
+			if !state.Internet {
+				text := "No network connection"
+				if state.Gateway != "" {
+					text = fmt.Sprintf("The local network is up (gateway %s) but the internet can't be reached", state.Gateway)
+				}
+				message("internet", "warning", "Internet connection lost", text)
+			} else {
+				message("internet", "success", "Back online", "Offline for "+formatDuration(now.Sub(offlineSince)))
+			}
+		}
+
+		if w.WiFi && previous.SSID != state.SSID {
+			switch {
+			case previous.SSID == "":
+				message("wifi", "info", "Connected to Wi-Fi", state.SSID)
+			case state.SSID == "":
+				message("wifi", "warning", "Wi-Fi disconnected", "Left "+previous.SSID)
+			default:
+				message("wifi", "info", "Switched Wi-Fi network", previous.SSID+" → "+state.SSID)
+			}
+		}
+
+		if w.VPN {
+			for _, vpn := range state.VPNs {
+				if !slices.Contains(previous.VPNs, vpn) {
+					message("vpn "+vpn, "success", "VPN connected", vpn)
+				}
+			}
+			for _, vpn := range previous.VPNs {
+				if !slices.Contains(state.VPNs, vpn) {
+					message("vpn "+vpn, "warning", "VPN disconnected", vpn)
+				}
+			}
+		}
+		return messages, nil
+	}, nil
+}
+
+// probeInternet fetches the probe URL, which must answer without a
+// redirect
+func probeInternet(client *http.Client, probe string) bool {
+	resp, err := client.Get(probe)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// Words in the names and descriptions of VPN adapters
+var vpnAdapterWords = []string{
+	"vpn", "wireguard", "openvpn", "tap-windows", "wintun", "anyconnect",
+	"fortinet", "fortissl", "pangp", "globalprotect", "juniper", "pulse secure",
+	"tailscale", "zerotier", "nordlynx",
+}
+
+// isVPNAdapter reports whether an adapter name or description looks like
+// a VPN's
+func isVPNAdapter(name string) bool {
+	name = strings.ToLower(name)
+	return slices.ContainsFunc(vpnAdapterWords, func(word string) bool {
+		return strings.Contains(name, word)
+	})
+}
+
+// validate checks the network watch settings
+func (w *networkWatch) validate() error {
+	if w.Internet && !strings.HasPrefix(w.Probe, "http://") && !strings.HasPrefix(w.Probe, "https://") {
+		return fmt.Errorf("watch network probe: %q is not an http or https URL", cmp.Or(w.Probe, "(empty)"))
+	}
+	return nil
+}
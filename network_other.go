@@ -0,0 +1,49 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// wifiSSID is only available on Windows
+func wifiSSID() (string, error) {
+	return "", errors.New("watching Wi-Fi networks is only available on Windows")
+}
+
+// networkAdapters returns the default gateway, known on Linux, and the
+// tunnel interfaces that are up
+func networkAdapters() (gateway string, vpns []string) {
+	// Columns: Iface Destination Gateway ..., in little endian hex
+	if data, err := os.ReadFile("/proc/net/route"); err == nil {
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[1] != "00000000" {
+				continue
+			}
+			if ip, err := strconv.ParseUint(fields[2], 16, 32); err == nil && ip != 0 {
+				gateway = net.IPv4(byte(ip), byte(ip>>8), byte(ip>>16), byte(ip>>24)).String()
+				break
+			}
+		}
+	}
+
+	interfaces, _ := net.Interfaces()
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		tunnel := isVPNAdapter(iface.Name)
+		for _, prefix := range []string{"tun", "tap", "wg", "ppp", "ipsec"} {
+			tunnel = tunnel || strings.HasPrefix(iface.Name, prefix)
+		}
+		if addrs, _ := iface.Addrs(); tunnel && len(addrs) > 0 {
+			vpns = append(vpns, iface.Name)
+		}
+	}
+	return gateway, vpns
+}
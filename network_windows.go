@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	wlanapi                = syscall.NewLazyDLL("wlanapi.dll")
+	procWlanOpenHandle     = wlanapi.NewProc("WlanOpenHandle")
+	procWlanCloseHandle    = wlanapi.NewProc("WlanCloseHandle")
+	procWlanEnumInterfaces = wlanapi.NewProc("WlanEnumInterfaces")
+	procWlanQueryInterface = wlanapi.NewProc("WlanQueryInterface")
+	procWlanFreeMemory     = wlanapi.NewProc("WlanFreeMemory")
+)
+
+const (
+	mibIfTypePPP                    = 23
+	wlanInterfaceStateConnected     = 1
+	wlanIntfOpcodeCurrentConnection = 7
+)
+
+// wlanInterfaceInfo is WLAN_INTERFACE_INFO
+type wlanInterfaceInfo struct {
+	GUID        [16]byte
+	Description [256]uint16
+	State       uint32
+}
+
+// wlanInterfaceList is WLAN_INTERFACE_INFO_LIST
+type wlanInterfaceList struct {
+	Count uint32
+	Index uint32
+	Items [1]wlanInterfaceInfo
+}
+
+// wlanConnection is the start of WLAN_CONNECTION_ATTRIBUTES, up to the
+// SSID of the association
+type wlanConnection struct {
+	State      uint32
+	Mode       uint32
+	Profile    [256]uint16
+	SSIDLength uint32
+	SSID       [32]byte
+}
+
+// wifiSSID returns the network of the first connected Wi-Fi adapter, or ""
+func wifiSSID() (string, error) {
+	var version uint32
+	var handle uintptr
+	if r, _, _ := procWlanOpenHandle.Call(2, 0, uintptr(unsafe.Pointer(&version)), uintptr(unsafe.Pointer(&handle))); r != 0 {
+		// Machines without Wi-Fi may not run the WLAN service
+		return "", nil
+	}
+	defer procWlanCloseHandle.Call(handle, 0)
+
+	var list *wlanInterfaceList
+	if r, _, _ := procWlanEnumInterfaces.Call(handle, 0, uintptr(unsafe.Pointer(&list))); r != 0 {
+		return "", syscall.Errno(r)
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(list)))
+
+	for _, info := range unsafe.Slice(&list.Items[0], list.Count) {
+		if info.State != wlanInterfaceStateConnected {
+			continue
+		}
+		var size uint32
+		var conn *wlanConnection
+		r, _, _ := procWlanQueryInterface.Call(handle, uintptr(unsafe.Pointer(&info.GUID)), wlanIntfOpcodeCurrentConnection, 0,
+			uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&conn)), 0)
+		if r != 0 {
+			continue
+		}
+		ssid := string(conn.SSID[:min(conn.SSIDLength, uint32(len(conn.SSID)))])
+		procWlanFreeMemory.Call(uintptr(unsafe.Pointer(conn)))
+		return ssid, nil
+	}
+	return "", nil
+}
+
+// networkAdapters returns the default gateway and the connected VPN
+// adapters
+func networkAdapters() (gateway string, vpns []string) {
+	size := uint32(16 << 10)
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+		err := syscall.GetAdaptersInfo((*syscall.IpAdapterInfo)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != syscall.ERROR_BUFFER_OVERFLOW {
+			return "", nil
+		}
+	}
+
+	for ai := (*syscall.IpAdapterInfo)(unsafe.Pointer(&buf[0])); ai != nil; ai = ai.Next {
+		address := cString(ai.IpAddressList.IpAddress.String[:])
+		if address == "" || address == "0.0.0.0" {
+			continue
+		}
+		description := cString(ai.Description[:])
+		if ai.Type == mibIfTypePPP || isVPNAdapter(description) {
+			if !slices.Contains(vpns, description) {
+				vpns = append(vpns, description)
+			}
+		}
+		if gw := cString(ai.GatewayList.IpAddress.String[:]); gateway == "" && gw != "" && gw != "0.0.0.0" {
+			gateway = gw
+		}
+	}
+	return gateway, vpns
+}
+
+// cString converts a NUL terminated byte array
+func cString(b []byte) string {
+	if i := slices.Index(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// relayRoute is an endpoint of the relay. The routes registered with the
+// server and the OpenAPI document are both built from relayRoutes, so the
+// document always matches what the relay serves.
+type relayRoute struct {
+	Method    string
+	Path      string
+	Summary   string
+	Body      any               // request body type, nil for none
+	Params    map[string]string // path parameters and their meaning
+	Responses map[int]string    // status codes and their meaning
+	Public    bool              // served without authentication
+	Handler   func(*relayServer) http.HandlerFunc
+}
+
+var relayRoutes = []relayRoute{
+	{
+		Method:  "POST",
+		Path:    "/notify",
+		Summary: "Show or forward a notification",
+		Body:    notify.RelayMessage{},
+		Responses: map[int]string{
+			204: "The notification was shown or forwarded",
+			400: "The notification is invalid",
+			401: "Missing or invalid token or signature",
+			413: "The request body is too large",
+			429: "Too many notifications from this client, see Retry-After",
+			502: "Showing or forwarding the notification failed",
+			508: "The notification passed through too many relays",
+		},
+		Handler: func(s *relayServer) http.HandlerFunc { return s.handleNotify },
+	},
+	{
+		Method:  "POST",
+		Path:    "/hooks/{service}",
+		Summary: "Show a notification for a webhook of GitHub, GitLab, Grafana, Uptime Kuma or Sentry",
+		Body:    map[string]any{},
+		Params:  map[string]string{"service": "The service sending the webhook"},
+		Responses: map[int]string{
+			204: "The notification was shown or forwarded, or the event was ignored",
+			400: "The payload is invalid",
+			401: "Missing or invalid token or signature",
+			404: "The service is not supported",
+			413: "The request body is too large",
+			429: "Too many notifications from this client, see Retry-After",
+			502: "Showing or forwarding the notification failed",
+		},
+		Handler: func(s *relayServer) http.HandlerFunc { return s.handleHook },
+	},
+	{
+		Method:  "POST",
+		Path:    "/sentry",
+		Summary: "Show an error for new and regressed Sentry issues, same as /hooks/sentry",
+		Body:    map[string]any{},
+		Responses: map[int]string{
+			204: "The notification was shown or forwarded, or the event was ignored",
+			400: "The payload is invalid",
+			401: "Missing or invalid Sentry-Hook-Signature or token",
+			413: "The request body is too large",
+			429: "Too many notifications from this client, see Retry-After",
+			502: "Showing or forwarding the notification failed",
+		},
+		Handler: func(s *relayServer) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				r.SetPathValue("service", "sentry")
+				s.handleHook(w, r)
+			}
+		},
+	},
+	{
+		Method:  "GET",
+		Path:    "/interactions",
+		Summary: "What was done with the toasts this relay showed, newest last: clicked, replied, dismissed or timed out",
+		Responses: map[int]string{
+			200: "The newest 100 interactions as a JSON array of history entries",
+			401: "Missing or invalid token or signature",
+		},
+		Handler: func(s *relayServer) http.HandlerFunc { return s.handleInteractions },
+	},
+	{
+		Method:  "GET",
+		Path:    "/attachments/{id}/{name}",
+		Summary: "Download an attachment the relay linked instead of forwarding, see --public-url",
+		Params:  map[string]string{"id": "Random id from the link", "name": "File name of the attachment"},
+		Responses: map[int]string{
+			200: "The attachment",
+			404: "The attachment doesn't exist or has expired",
+		},
+		Public:  true,
+		Handler: func(s *relayServer) http.HandlerFunc { return s.handleAttachment },
+	},
+	{
+		Method:    "GET",
+		Path:      "/healthz",
+		Summary:   "Liveness probe",
+		Responses: map[int]string{200: "The relay is running"},
+		Public:    true,
+		Handler:   func(s *relayServer) http.HandlerFunc { return s.handleHealthz },
+	},
+	{
+		Method:  "GET",
+		Path:    "/readyz",
+		Summary: "Readiness probe checking the toast backend or targets and the display queue",
+		Responses: map[int]string{
+			200: "The relay can deliver notifications",
+			503: "The backend or all targets are unavailable, or the display queue is full",
+		},
+		Public:  true,
+		Handler: func(s *relayServer) http.HandlerFunc { return s.handleReadyz },
+	},
+	{
+		Method:    "GET",
+		Path:      "/openapi.json",
+		Summary:   "This OpenAPI document",
+		Responses: map[int]string{200: "The OpenAPI document"},
+		Public:    true,
+		Handler:   func(s *relayServer) http.HandlerFunc { return s.handleOpenAPI },
+	},
+}
+
+// Allowed values of string fields, by JSON name
+var schemaEnums = map[string][]string{
+	"type":     notify.Types,
+	"fallback": validFallbacks,
+	"service":  webhookServices,
+}
+
+// openAPIDocument builds the OpenAPI 3 description of the relay API
+func openAPIDocument() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+
+	for _, route := range relayRoutes {
+		responses := map[string]any{}
+		for code, description := range route.Responses {
+			responses[strconv.Itoa(code)] = map[string]any{"description": description}
+		}
+
+		operation := map[string]any{
+			"summary":   route.Summary,
+			"responses": responses,
+		}
+		if route.Public {
+			operation["security"] = []any{}
+		}
+		if route.Body != nil {
+			// Bodies that aren't structs are free-form JSON objects
+			schema := map[string]any{"type": "object"}
+			if t := reflect.TypeOf(route.Body); t.Kind() == reflect.Struct {
+				schema = schemaRef(t, schemas)
+			}
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schema},
+				},
+			}
+		}
+		var params []any
+		for _, name := range slices.Sorted(maps.Keys(route.Params)) {
+			schema := map[string]any{"type": "string"}
+			if enum, ok := schemaEnums[name]; ok {
+				schema["enum"] = enum
+			}
+			params = append(params, map[string]any{
+				"name":        name,
+				"in":          "path",
+				"required":    true,
+				"description": route.Params[name],
+				"schema":      schema,
+			})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		item, _ := paths[route.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "notify relay",
+			"description": "Send desktop notifications through a notify relay.",
+			"version":     "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []any{}}},
+	}
+}
+
+// schemaRef adds the schema of a struct type to schemas and returns a
+// reference to it
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	name := schemaName(t)
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+	schemas[name] = nil // reserve the name for recursive types
+
+	properties := map[string]any{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		property := schemaOf(field.Type, schemas)
+		if doc := field.Tag.Get("doc"); doc != "" {
+			property["description"] = doc
+		}
+		if enum, ok := schemaEnums[name]; ok {
+			property["enum"] = enum
+		}
+		properties[name] = property
+	}
+
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+	return ref
+}
+
+// schemaOf returns the schema of a field type
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		// References can't have siblings in OpenAPI 3.0, so wrap them
+		return map[string]any{"allOf": []any{schemaRef(t, schemas)}}
+	}
+	return map[string]any{}
+}
+
+// schemaName turns a Go type name into a schema name like RelayMessage
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// handleOpenAPI serves GET /openapi.json. The document is rendered when
+// the relay starts, as it never changes while running.
+func (s *relayServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.openAPI)
+}
+
+// writeOpenAPI writes the indented OpenAPI document
+func writeOpenAPI(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(openAPIDocument())
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// notifyOptions are the notification options shared by the main command
+// and the subcommands that send notifications
+type notifyOptions struct {
+	Type       string
+	Title      string
+	Timeout    int
+	AutoClose  bool
+	Collection string
+	App        string
+	Category   string
+	AckID      string
+	Group      string
+	GroupSize  int
+	Remote     string
+	Token      string
+	EncryptTo  string
+	SignSecret string
+	SignKey    string
+	TLS        tlsFiles
+	Session    string
+	Fallback   string
+	Backend    string
+	Inject     faultInjection
+	Window     string
+	Urgent     bool
+	Private    bool
+	Wake       time.Duration
+	Wait       bool
+	Attach     string // file sent with the notification
+	QR         string // value shown as a QR code image
+	Preview    *bool
+	Shorten    *bool
+	Volume     *int
+	Duck       bool
+	Speak      bool
+}
+
+// newNotifyOptions returns the default options, from the defaults section
+// of config.yaml where it sets them. A config.yaml that can't be read is
+// reported when sending.
+func newNotifyOptions() *notifyOptions {
+	o := &notifyOptions{
+		Type:       "info",
+		Timeout:    5,
+		AutoClose:  true,
+		Token:      os.Getenv("NOTIFY_TOKEN"),
+		SignSecret: os.Getenv("NOTIFY_SIGNING_SECRET"),
+	}
+	if config, err := loadConfig(); err == nil {
+		d := config.Defaults
+		o.Type, o.Timeout, o.AutoClose = d.Type, d.Timeout, d.AutoClose
+		o.App, o.Category = d.App, d.Category
+	}
+	return o
+}
+
+// flags returns the command line options that set o
+func (o *notifyOptions) flags() []cliFlag {
+	return []cliFlag{
+		{Name: "type", Set: func(v string) error { o.Type = v; return nil }},
+		{Name: "title", Set: func(v string) error { o.Title = v; return nil }},
+		{Name: "timeout", Set: func(v string) (err error) { o.Timeout, err = parseTimeout(v); return }},
+		{Name: "autoclose", Set: func(v string) (err error) { o.AutoClose, err = parseStrictBool(v); return }},
+		{Name: "collection", Set: func(v string) error { o.Collection = v; return nil }},
+		{Name: "require-ack", Set: func(v string) error { o.AckID = strings.TrimSpace(v); return nil }},
+		{Name: "app", Set: func(v string) error { o.App = strings.TrimSpace(v); return nil }},
+		{Name: "category", Set: func(v string) error { o.Category = normalizeCategory(v); return nil }},
+		{Name: "session", Set: func(v string) error { o.Session = strings.TrimSpace(v); return nil }},
+		{Name: "group", Set: func(v string) error { o.Group = strings.TrimSpace(v); return nil }},
+		{Name: "remote", Set: func(v string) error { o.Remote = strings.TrimSpace(v); return nil }},
+		{Name: "token", Set: func(v string) error { o.Token = v; return nil }},
+		{Name: "sign-secret", Set: func(v string) error { o.SignSecret = v; return nil }},
+		{Name: "sign-key", Set: func(v string) error { o.SignKey = v; return nil }},
+		{Name: "ca", Set: func(v string) error { o.TLS.CA = v; return nil }},
+		{Name: "client-cert", Set: func(v string) error { o.TLS.Cert = v; return nil }},
+		{Name: "client-key", Set: func(v string) error { o.TLS.Key = v; return nil }},
+		{Name: "encrypt-to", Set: func(v string) error { o.EncryptTo = strings.TrimSpace(v); return nil }},
+		{Name: "fallback", Set: func(v string) (err error) { o.Fallback, err = parseFallback(v); return }},
+		{Name: "backend", Set: func(v string) (err error) { o.Backend, err = parseBackend(v); return }},
+		// Undocumented, for testing the handling of slow and failing deliveries
+		{Name: "inject-delay", Set: func(v string) (err error) { o.Inject.Delay, err = parseDuration(v); return }},
+		{Name: "inject-fail", Set: func(v string) (err error) { o.Inject.Fail, err = parseInjectedFailure(v); return }},
+		{Name: "window", Set: func(v string) error { o.Window = strings.TrimSpace(v); return nil }},
+		{Name: "urgent", Bool: true, Set: func(v string) (err error) { o.Urgent, err = parseStrictBool(v); return }},
+		{Name: "private", Bool: true, Set: func(v string) (err error) { o.Private, err = parseStrictBool(v); return }},
+		{Name: "preview", Bool: true, Set: func(v string) error {
+			preview, err := parseStrictBool(v)
+			o.Preview = &preview
+			return err
+		}},
+		{Name: "shorten", Bool: true, Set: func(v string) error {
+			shorten, err := parseStrictBool(v)
+			o.Shorten = &shorten
+			return err
+		}},
+		{Name: "attach", Set: func(v string) error { o.Attach = v; return nil }},
+		{Name: "volume", Set: func(v string) (err error) { o.Volume, err = parseVolume(v); return }},
+		{Name: "duck", Bool: true, Set: func(v string) (err error) { o.Duck, err = parseStrictBool(v); return }},
+		{Name: "speak", Bool: true, Set: func(v string) (err error) { o.Speak, err = parseStrictBool(v); return }},
+		{Name: "qr", Set: func(v string) error { o.QR = v; return nil }},
+		{Name: "wait", Bool: true, Set: func(v string) (err error) { o.Wait, err = parseStrictBool(v); return }},
+		{Name: "wake", Set: func(v string) (err error) { o.Wake, err = parseDuration(v); return }},
+		{Name: "group-size", Set: func(v string) (err error) { o.GroupSize, err = parseGroupSize(v); return }},
+	}
+}
+
+// typeTitle returns the title of notifications of type t without one, from
+// the titles section of config.yaml or else the type's name
+func typeTitle(t string) string {
+	if config, err := loadConfig(); err == nil && config.Titles[t] != "" {
+		return strings.TrimSpace(config.Titles[t])
+	}
+	return notify.DefaultTitle(t)
+}
+
+// typeIcon returns the icon file config.yaml sets for type t, or ""
+func typeIcon(t string) string {
+	if config, err := loadConfig(); err == nil {
+		return config.Icons[t]
+	}
+	return ""
+}
+
+// build creates and validates the notification for a message
+func (o *notifyOptions) build(message string) (*Notification, error) {
+	if !isValidType(o.Type) {
+		return nil, fmt.Errorf("invalid type %q.%s Valid types are: %s",
+			o.Type, suggest.DidYouMean(o.Type, notify.Types, ""), strings.Join(notify.Types, ", "))
+	}
+
+	// Determine title
+	title := strings.TrimSpace(o.Title)
+	switch {
+	case o.Title == "":
+		title = typeTitle(o.Type)
+	case title == "":
+		title = o.Title // only whitespace, which validate rejects
+	}
+
+	n := &Notification{
+		Notification: notify.Notification{
+			Type:     o.Type,
+			Title:    title,
+			Message:  strings.TrimSpace(message),
+			Timeout:  time.Duration(o.Timeout) * time.Second,
+			Sticky:   !o.AutoClose,
+			App:      o.App,
+			Category: o.Category,
+			Group:    o.Group,
+			Urgent:   o.Urgent,
+			Private:  o.Private,
+		},
+		Collection: o.Collection,
+		AckID:      o.AckID,
+		GroupSize:  o.GroupSize,
+		Remote:     o.Remote,
+		Token:      o.Token,
+		EncryptTo:  o.EncryptTo,
+		SignSecret: o.SignSecret,
+		SignKey:    o.SignKey,
+		TLS:        o.TLS,
+		Session:    o.Session,
+		Fallback:   o.Fallback,
+		Backend:    o.Backend,
+		Window:     o.Window,
+		Wake:       o.Wake,
+		Wait:       o.Wait,
+		Preview:    o.Preview,
+		Shorten:    o.Shorten,
+		Volume:     o.Volume,
+		Duck:       o.Duck,
+		Speak:      o.Speak,
+	}
+	if o.Inject != (faultInjection{}) {
+		inject := o.Inject
+		n.Inject = &inject
+	}
+	if o.Attach != "" && o.QR != "" {
+		return nil, fmt.Errorf("--attach and --qr can't be used together")
+	}
+	if o.Attach != "" {
+		a, err := readAttachment(o.Attach)
+		if err != nil {
+			return nil, err
+		}
+		n.Attachment = a
+	}
+	if o.QR != "" {
+		image, err := qrImage([]byte(o.QR))
+		if err != nil {
+			return nil, err
+		}
+		n.Attachment = &notify.Attachment{Name: "qr.png", Type: "image/png", Data: image}
+	}
+	return n, n.validate()
+}
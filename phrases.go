@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Days named in --at phrases
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// Units of --in phrases
+var delayUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// Days without a time of day are at this hour, like "next monday"
+const defaultWhenHour = 9
+
+// parseAt reads --at: a time like 09:00 or 9am, a day like tomorrow,
+// friday, next monday or 2026-03-08, both in either order, or a delay
+// like "in 2 hours"
+func (w *whenSchedule) parseAt(s string, now time.Time) error {
+	phrase := strings.ToLower(strings.TrimSpace(s))
+	if delay, ok := strings.CutPrefix(phrase, "in "); ok {
+		return w.parseIn(delay, now)
+	}
+	invalid := fmt.Errorf("invalid --at %q, use a time like 09:00 or 9am, a day like tomorrow, next monday or 2026-03-08, or both", s)
+
+	now = now.In(w.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, w.Location)
+	var day time.Time
+	hour, minute := -1, 0
+	weekday := false // a bare day name, which means next week once it has passed today
+
+	words := strings.Fields(strings.ReplaceAll(phrase, ",", " "))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if wd, ok := weekdayNames[word]; ok {
+			day, weekday = nextWeekday(today, wd, false), true
+			continue
+		}
+		switch word {
+		case "at", "on":
+		case "today", "tonight":
+			day = today
+		case "tomorrow":
+			day = today.AddDate(0, 0, 1)
+		case "next", "this":
+			if i+1 == len(words) {
+				return invalid
+			}
+			wd, ok := weekdayNames[words[i+1]]
+			if !ok {
+				return invalid
+			}
+			day, weekday = nextWeekday(today, wd, word == "next"), false
+			i++
+		case "noon":
+			hour, minute = 12, 0
+		case "midnight":
+			hour, minute = 0, 0
+		default:
+			if d, err := time.Parse("2006-01-02", word); err == nil {
+				day = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, w.Location)
+				continue
+			}
+			// "9 am" as well as "9am"
+			if i+1 < len(words) && (words[i+1] == "am" || words[i+1] == "pm") {
+				word += words[i+1]
+				i++
+			}
+			t, err := parseTimeOfDay(word)
+			if err != nil {
+				return invalid
+			}
+			hour, minute = t.Hour(), t.Minute()
+		}
+	}
+	if hour < 0 && day.IsZero() {
+		return invalid
+	}
+	if hour < 0 {
+		hour = defaultWhenHour
+	}
+	if weekday && !time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, w.Location).After(now) {
+		day = day.AddDate(0, 0, 7)
+	}
+	w.Date, w.Hour, w.Minute = day, hour, minute
+	return nil
+}
+
+// parseIn reads --in, a delay like 90m, 2h30m or "2 hours 30 minutes"
+func (w *whenSchedule) parseIn(s string, now time.Time) error {
+	d, err := parseDelay(s)
+	if err != nil {
+		return err
+	}
+	w.At = now.Add(d).Round(0)
+	return nil
+}
+
+// parseTimeOfDay reads a time of day like 17:30, 9am or 9:30pm
+func parseTimeOfDay(s string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"15:04", "3pm", "3:04pm"} {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// nextWeekday returns the first day from today falling on wd, after today
+// when strict
+func nextWeekday(today time.Time, wd time.Weekday, strict bool) time.Time {
+	days := (int(wd) - int(today.Weekday()) + 7) % 7
+	if days == 0 && strict {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// parseDelay reads a delay written like parseDuration or in words, like
+// "2 hours 30 minutes", "an hour and 15 minutes" or "in 3 days"
+func parseDelay(s string) (time.Duration, error) {
+	phrase := strings.ToLower(strings.TrimSpace(s))
+	phrase = strings.TrimSpace(strings.TrimPrefix(phrase, "in "))
+	if d, err := parseDuration(phrase); err == nil {
+		return d, nil
+	}
+	invalid := fmt.Errorf("invalid delay %q, use something like 90m, 2h30m or \"2 hours 30 minutes\"", s)
+
+	var total time.Duration
+	words := strings.Fields(strings.ReplaceAll(phrase, ",", " "))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "and" {
+			continue
+		}
+
+		// The number may be joined to its unit, like "2hours"
+		split := strings.IndexFunc(word, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if split < 0 {
+			split = len(word)
+		}
+		number, unit := word[:split], word[split:]
+		var count float64
+		switch {
+		case number != "":
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, invalid
+			}
+			count = n
+		case word == "a" || word == "an":
+			count, unit = 1, ""
+		case word == "half" && i+1 < len(words) && (words[i+1] == "a" || words[i+1] == "an"):
+			count, unit = 0.5, ""
+			i++
+		default:
+			return 0, invalid
+		}
+		if unit == "" {
+			if i+1 == len(words) {
+				return 0, invalid
+			}
+			i++
+			unit = words[i]
+		}
+		size, ok := delayUnits[unit]
+		if !ok {
+			return 0, invalid
+		}
+		total += time.Duration(count * float64(size))
+	}
+	if total < time.Second {
+		return 0, invalid
+	}
+	return total, nil
+}
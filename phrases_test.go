@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"90m", 90 * time.Minute, false},
+		{"2h30m", 150 * time.Minute, false},
+		{"1.5h", 90 * time.Minute, false},
+		{"3d", 72 * time.Hour, false},
+		{"2 hours 30 minutes", 150 * time.Minute, false},
+		{"2 Hours, 30 Mins", 150 * time.Minute, false},
+		{"an hour and 15 minutes", 75 * time.Minute, false},
+		{"half an hour", 30 * time.Minute, false},
+		{"a week", 7 * 24 * time.Hour, false},
+		{"in 3 days", 72 * time.Hour, false},
+		{"2hours", 2 * time.Hour, false},
+		{"1.5 days", 36 * time.Hour, false},
+		{"2 hours and", 2 * time.Hour, false},
+		{"", 0, true},
+		{"0s", 0, true},
+		{"0.5 seconds", 0, true},
+		{"-2 hours", 0, true},
+		{"2", 0, true},
+		{"hours", 0, true},
+		{"2 fortnights", 0, true},
+		{"half", 0, true},
+		{"half hour", 0, true},
+		{"an", 0, true},
+		{"1..5 hours", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDelay(tt.in)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("parseDelay(%q) = %s, want an error", tt.in, got)
+		case tt.err && !strings.Contains(err.Error(), "invalid delay"):
+			t.Errorf("parseDelay(%q) error = %v, want an invalid delay", tt.in, err)
+		case !tt.err && (err != nil || got != tt.want):
+			t.Errorf("parseDelay(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	// A Wednesday morning
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		in     string
+		date   time.Time // zero for the next occurrence
+		hour   int
+		minute int
+		err    bool
+	}{
+		{"09:00", time.Time{}, 9, 0, false},
+		{"9am", time.Time{}, 9, 0, false},
+		{"9 PM", time.Time{}, 21, 0, false},
+		{"9:30pm", time.Time{}, 21, 30, false},
+		{"noon", time.Time{}, 12, 0, false},
+		{"midnight", time.Time{}, 0, 0, false},
+		{"tomorrow", day(5), 9, 0, false},
+		{"tomorrow 17:30", day(5), 17, 30, false},
+		{"17:30 tomorrow", day(5), 17, 30, false},
+		{"today, 18:00", day(4), 18, 0, false},
+		{"tonight at 8pm", day(4), 20, 0, false},
+		{"friday", day(6), 9, 0, false},
+		{"Fri at noon", day(6), 12, 0, false},
+		{"wednesday 11:00", day(4), 11, 0, false},
+		{"wednesday 10:00", day(11), 10, 0, false},
+		{"wednesday", day(11), 9, 0, false},
+		{"this wednesday", day(4), 9, 0, false},
+		{"next wednesday", day(11), 9, 0, false},
+		{"next friday at 5pm", day(6), 17, 0, false},
+		{"on 2026-03-08 at 9:15", day(8), 9, 15, false},
+		{"", time.Time{}, 0, 0, true},
+		{"at", time.Time{}, 0, 0, true},
+		{"next", time.Time{}, 0, 0, true},
+		{"next tomorrow", time.Time{}, 0, 0, true},
+		{"25:00", time.Time{}, 0, 0, true},
+		{"13pm", time.Time{}, 0, 0, true},
+		{"2026-02-30", time.Time{}, 0, 0, true},
+		{"someday", time.Time{}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		w := &whenSchedule{Location: time.UTC}
+		err := w.parseAt(tt.in, now)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("parseAt(%q) = %+v, want an error", tt.in, w)
+		case tt.err && !strings.Contains(err.Error(), "invalid --at"):
+			t.Errorf("parseAt(%q) error = %v, want an invalid --at", tt.in, err)
+		case !tt.err && (err != nil || !w.Date.Equal(tt.date) || w.Hour != tt.hour || w.Minute != tt.minute):
+			t.Errorf("parseAt(%q) = %s %02d:%02d, %v, want %s %02d:%02d",
+				tt.in, w.Date.Format(time.DateOnly), w.Hour, w.Minute, err, tt.date.Format(time.DateOnly), tt.hour, tt.minute)
+		}
+	}
+
+	w := &whenSchedule{Location: time.UTC}
+	if err := w.parseAt("in 2 hours", now); err != nil || !w.At.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("parseAt(\"in 2 hours\") = %s, %v, want %s", w.At, err, now.Add(2*time.Hour))
+	}
+	if err := w.parseAt("in 2 fortnights", now); err == nil || !strings.Contains(err.Error(), "invalid delay") {
+		t.Errorf("parseAt(\"in 2 fortnights\") error = %v, want an invalid delay", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// servePipe shows or forwards the notifications written to a named pipe,
+// one line each: MESSAGE, TYPE|MESSAGE or TYPE|TITLE|MESSAGE
+func (s *relayServer) servePipe(name string) {
+	err := listenPipe(name, func(r io.Reader) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 4096), 64<<10)
+		for scanner.Scan() {
+			m := parsePipeLine(scanner.Text())
+			if m == nil {
+				continue
+			}
+			if s.limit != nil {
+				if ok, _ := s.limit.allow("pipe"); !ok {
+					log.Printf("pipe: too many messages, dropped %q", m.Message)
+					continue
+				}
+			}
+			if err := s.deliverLocal("pipe.message", "pipe", m); err != nil {
+				log.Printf("pipe: %q failed: %v", m.Message, err)
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Warning: pipe %s: %v", name, err)
+	}
+}
+
+// parsePipeLine reads a line of the pipe protocol. A line whose first
+// field isn't a type, or a level like warn, is all message, so messages
+// may contain |. Blank lines give nil.
+func parsePipeLine(line string) *notify.RelayMessage {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+	if line == "" {
+		return nil
+	}
+	fields := strings.SplitN(line, "|", 3)
+	typ, ok := logType(fields[0])
+	switch {
+	case !ok || len(fields) == 1:
+		return &notify.RelayMessage{Message: line}
+	case len(fields) == 2:
+		return &notify.RelayMessage{Type: typ, Message: strings.TrimSpace(fields[1])}
+	}
+	return &notify.RelayMessage{Type: typ, Title: strings.TrimSpace(fields[1]), Message: strings.TrimSpace(fields[2])}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// listenPipe is only available on Windows
+func listenPipe(name string, handle func(io.Reader)) error {
+	return errors.New("named pipes are only available on Windows")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procCreateNamedPipeW    = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = kernel32.NewProc("ConnectNamedPipe")
+	procDisconnectNamedPipe = kernel32.NewProc("DisconnectNamedPipe")
+)
+
+const (
+	pipeAccessInbound       = 0x1
+	pipeAccessDuplex        = 0x3
+	fileFlagFirstInstance   = 0x80000
+	pipeRejectRemoteClients = 0x8
+	pipeUnlimitedInstances  = 255
+	errorPipeConnected      = syscall.Errno(535)
+)
+
+// listenPipe serves \\.\pipe\NAME, handing each connection to handle. The
+// pipe only accepts local clients, and Windows' default security lets
+// only this user, administrators and SYSTEM write to it.
+func listenPipe(name string, handle func(io.Reader)) error {
+	return createPipeInstances(name, pipeAccessInbound, func(f *os.File) { handle(f) })
+}
+
+// listenDuplexPipe serves a pipe like listenPipe, whose clients also read
+// the answers written to them
+func listenDuplexPipe(name string, handle func(io.ReadWriter)) error {
+	return createPipeInstances(name, pipeAccessDuplex, func(f *os.File) { handle(f) })
+}
+
+// createPipeInstances creates the instances of a pipe, one per connection
+func createPipeInstances(name string, access uintptr, handle func(*os.File)) error {
+	path := `\\.\pipe\` + name
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	// The first instance fails when another process owns the name
+	flags := access | fileFlagFirstInstance
+	for {
+		h, _, err := procCreateNamedPipeW.Call(uintptr(unsafe.Pointer(p)), flags, pipeRejectRemoteClients,
+			pipeUnlimitedInstances, 64<<10, 64<<10, 0, 0)
+		if syscall.Handle(h) == syscall.InvalidHandle {
+			return err
+		}
+		flags = access
+
+		if r, _, err := procConnectNamedPipe.Call(h, 0); r == 0 && err != errorPipeConnected {
+			syscall.CloseHandle(syscall.Handle(h))
+			continue
+		}
+		go func() {
+			f := os.NewFile(h, path)
+			handle(f)
+			procDisconnectNamedPipe.Call(h)
+			f.Close()
+		}()
+	}
+}
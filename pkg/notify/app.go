@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+)
+
+// DefaultApp is the sender name of notifications without an App
+const DefaultApp = "Notify CLI"
+
+// AppName returns the display name for an app, defaulting to notify's own
+func AppName(name string) string {
+	if name == "" {
+		return DefaultApp
+	}
+	return name
+}
+
+// AppUserModelID returns the AUMID Windows shows the toasts of an app
+// under: the letters and digits of its display name, for reading the id,
+// and a hash of the exact name, so names differing only in other
+// characters or case get their own ids. The default app keeps its historic
+// id so existing Action Center entries stay grouped together.
+func AppUserModelID(name string) string {
+	if name == "" || name == DefaultApp {
+		return DefaultApp
+	}
+
+	var b strings.Builder
+	for _, word := range strings.Fields(name) {
+		for i, r := range word {
+			if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				continue
+			}
+			if i == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	if b.Len() == 0 {
+		return fmt.Sprintf("Notify.App%08x", h.Sum32())
+	}
+	return fmt.Sprintf("Notify.%s.%08x", b.String(), h.Sum32())
+}
+
+// appScript returns the PowerShell lines that define $APP_ID for the app
+// name and register its display name
+func appScript(name string) string {
+	return powershell.AppScript(AppUserModelID(name), AppName(name))
+}
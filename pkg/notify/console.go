@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// consoleBackend prints notifications to standard output instead of
+// showing them, with the icon's symbol and color; sounds, images and
+// clicks are left out
+type consoleBackend struct{}
+
+func (consoleBackend) Show(t *Toast) (string, error) {
+	icon := iconData[t.Type]
+	heading := icon.Symbol + " " + t.Title
+	if consoleColors() {
+		heading = fmt.Sprintf("\x1b[1;38;2;%d;%d;%dm%s\x1b[0m", icon.Color.R, icon.Color.G, icon.Color.B, heading)
+	}
+	if t.App != "" {
+		heading += consoleDim(" · " + t.App)
+	}
+	fmt.Println(heading)
+	for line := range strings.SplitSeq(t.Message, "\n") {
+		fmt.Println("  " + line)
+	}
+	if t.Progress != nil {
+		fmt.Println("  " + consoleProgress(t.Progress.data()))
+	}
+	if t.Link != "" {
+		fmt.Println("  " + consoleDim(t.Link))
+	}
+	if t.Source != "" {
+		fmt.Println("  " + consoleDim("via "+t.Source))
+	}
+	return "", nil
+}
+
+func (consoleBackend) Update(u *Update) (bool, error) {
+	fmt.Println("  " + consoleDim(consoleProgress(u.Progress.data())))
+	return true, nil
+}
+
+func (consoleBackend) Remove(t *Toast) error { return nil }
+func (consoleBackend) Check() error          { return nil }
+
+// consoleColors reports whether the console backend colors its output:
+// standard output is a terminal that supports it and NO_COLOR isn't set
+var consoleColors = sync.OnceValue(func() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !stdoutTerminal() {
+		return false
+	}
+	return enableColors()
+})
+
+// consoleDim returns s shown faint, when the output is colored
+func consoleDim(s string) string {
+	if !consoleColors() {
+		return s
+	}
+	return "\x1b[2m" + s + "\x1b[0m"
+}
+
+// stdoutTerminal reports whether standard output is a terminal
+func stdoutTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleProgress formats the progress of data-bound toasts as a line of
+// text
+func consoleProgress(data map[string]string) string {
+	var status []string
+	if value, err := strconv.ParseFloat(data["progressValue"], 64); err == nil {
+		status = append(status, fmt.Sprintf("%.0f%%", min(value, 1)*100))
+	}
+	for _, s := range []string{data["progressValueString"], data["progressStatus"]} {
+		if s != "" {
+			status = append(status, s)
+		}
+	}
+	if len(status) == 0 {
+		return "..."
+	}
+	return strings.Join(status, " · ")
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package notify
+
+// enableColors reports whether the terminal of standard output supports
+// escape sequences, which all terminals of Linux and macOS do
+func enableColors() bool {
+	return true
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"os"
+	"syscall"
+)
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// ENABLE_VIRTUAL_TERMINAL_PROCESSING, which makes the console interpret
+// ANSI escape sequences (Windows 10 and later)
+const enableVirtualTerminalProcessing = 0x0004
+
+// enableColors turns on escape sequences in the console of standard
+// output, reporting whether it supports them
+func enableColors() bool {
+	handle := syscall.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
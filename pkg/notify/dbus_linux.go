@@ -0,0 +1,496 @@
+//go:build linux
+
+package notify
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of D-Bus messages
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// Header fields of D-Bus messages
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSignature   = 8
+)
+
+// Largest message accepted from the bus, far above any reply notify reads
+const maxDBusMessage = 1 << 20
+
+// dbusConn is a connection to the session bus, speaking just enough of the
+// D-Bus protocol to call methods and receive signals
+type dbusConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	serial  uint32
+	pending []*dbusMessage // signals that arrived while waiting for a reply
+}
+
+// dbusMessage is a message received from the bus
+type dbusMessage struct {
+	Type        byte
+	ReplySerial uint32
+	Interface   string
+	Member      string
+	ErrorName   string
+	Signature   string
+	Body        *dbusDecoder
+}
+
+// dbusVariant is a value of type v, marshaled with its signature
+type dbusVariant struct {
+	Signature string
+	Value     any
+}
+
+// dialSessionBus connects to the session bus of $DBUS_SESSION_BUS_ADDRESS,
+// or the user's bus under /run/user, and authenticates
+func dialSessionBus() (*dbusConn, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if address == "" {
+		address = fmt.Sprintf("unix:path=/run/user/%d/bus", os.Getuid())
+	}
+
+	var errs []error
+	for _, entry := range strings.Split(address, ";") {
+		transport, params, _ := strings.Cut(entry, ":")
+		if transport != "unix" {
+			continue
+		}
+		keys := map[string]string{}
+		for _, param := range strings.Split(params, ",") {
+			k, v, _ := strings.Cut(param, "=")
+			keys[k], _ = url.PathUnescape(v)
+		}
+		path := keys["path"]
+		if keys["abstract"] != "" {
+			path = "@" + keys["abstract"]
+		}
+		if path == "" {
+			continue
+		}
+		conn, err := net.DialTimeout("unix", path, 2*time.Second)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		bus := &dbusConn{conn: conn, reader: bufio.NewReader(conn)}
+		if err := bus.auth(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("session bus: %w", err)
+		}
+		return bus, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no unix session bus in %q", address)
+	}
+	return nil, fmt.Errorf("session bus: %w", errors.Join(errs...))
+}
+
+// auth authenticates as the user running notify and says hello to the
+// bus, which must be the first call
+func (c *dbusConn) auth() error {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer c.conn.SetDeadline(time.Time{})
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := io.WriteString(c.conn, "\x00AUTH EXTERNAL "+uid+"\r\n"); err != nil {
+		return err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("authentication rejected: %s", strings.TrimSpace(line))
+	}
+	if _, err := io.WriteString(c.conn, "BEGIN\r\n"); err != nil {
+		return err
+	}
+	_, err = c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", "")
+	return err
+}
+
+// Close closes the connection
+func (c *dbusConn) Close() error {
+	return c.conn.Close()
+}
+
+// call calls a method and returns its reply. signature
+// describes args, with the types s, o, g, y, b, u, i, as and a{sv}.
+func (c *dbusConn) call(dest, path, iface, member, signature string, args ...any) (*dbusMessage, error) {
+	c.serial++
+	serial := c.serial
+
+	body := &dbusEncoder{}
+	for i, t := range dbusSignatureTypes(signature) {
+		if err := body.value(t, args[i]); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", iface, member, err)
+		}
+	}
+
+	header := &dbusEncoder{}
+	header.bytes([]byte{'l', dbusMethodCall, 0, 1})
+	header.uint32(uint32(len(body.buf)))
+	header.uint32(serial)
+	type field struct {
+		code  byte
+		value dbusVariant
+	}
+	fields := []field{
+		{dbusFieldPath, dbusVariant{"o", path}},
+		{dbusFieldInterface, dbusVariant{"s", iface}},
+		{dbusFieldMember, dbusVariant{"s", member}},
+		{dbusFieldDestination, dbusVariant{"s", dest}},
+	}
+	if signature != "" {
+		fields = append(fields, field{dbusFieldSignature, dbusVariant{"g", signature}})
+	}
+	header.array(8, func() {
+		for _, f := range fields {
+			header.align(8)
+			header.bytes([]byte{f.code})
+			header.value("v", f.value)
+		}
+	})
+	header.align(8)
+
+	if _, err := c.conn.Write(append(header.buf, body.buf...)); err != nil {
+		return nil, err
+	}
+	for {
+		m, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case m.Type == dbusSignal:
+			c.pending = append(c.pending, m)
+		case m.ReplySerial != serial:
+		case m.Type == dbusError:
+			detail, _ := m.Body.string()
+			return nil, fmt.Errorf("%s.%s: %s: %s", iface, member, m.ErrorName, detail)
+		case m.Type == dbusMethodReturn:
+			return m, nil
+		}
+	}
+}
+
+// signal returns the next signal received, waiting at most until deadline
+func (c *dbusConn) signal(deadline time.Time) (*dbusMessage, error) {
+	if len(c.pending) > 0 {
+		m := c.pending[0]
+		c.pending = c.pending[1:]
+		return m, nil
+	}
+	c.conn.SetReadDeadline(deadline)
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		m, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if m.Type == dbusSignal {
+			return m, nil
+		}
+	}
+}
+
+// read reads the next message from the bus
+func (c *dbusConn) read() (*dbusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(c.reader, fixed); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if fixed[0] == 'B' {
+		order = binary.BigEndian
+	}
+	bodyLen, fieldsLen := order.Uint32(fixed[4:]), order.Uint32(fixed[12:])
+	headerLen := (16 + fieldsLen + 7) &^ 7
+	if uint64(headerLen)+uint64(bodyLen) > maxDBusMessage {
+		return nil, fmt.Errorf("message of %d bytes from the bus is too large", uint64(headerLen)+uint64(bodyLen))
+	}
+	rest := make([]byte, headerLen-16+bodyLen)
+	if _, err := io.ReadFull(c.reader, rest); err != nil {
+		return nil, err
+	}
+
+	m := &dbusMessage{Type: fixed[1]}
+	fields := &dbusDecoder{order: order, buf: append(fixed, rest[:headerLen-16]...), pos: 16}
+	for fields.pos < 16+int(fieldsLen) {
+		fields.align(8)
+		code, err := fields.byte()
+		if err != nil {
+			return nil, err
+		}
+		value, err := fields.variant()
+		if err != nil {
+			return nil, err
+		}
+		switch code {
+		case dbusFieldInterface:
+			m.Interface, _ = value.(string)
+		case dbusFieldMember:
+			m.Member, _ = value.(string)
+		case dbusFieldErrorName:
+			m.ErrorName, _ = value.(string)
+		case dbusFieldReplySerial:
+			m.ReplySerial, _ = value.(uint32)
+		case dbusFieldSignature:
+			m.Signature, _ = value.(string)
+		}
+	}
+	m.Body = &dbusDecoder{order: order, buf: rest[headerLen-16:]}
+	return m, nil
+}
+
+// dbusSignatureTypes splits a signature into its complete types
+func dbusSignatureTypes(signature string) []string {
+	var types []string
+	for i := 0; i < len(signature); {
+		end := i + 1
+		for signature[end-1] == 'a' {
+			end++
+		}
+		if signature[end-1] == '{' || signature[end-1] == '(' {
+			depth := 1
+			for depth > 0 {
+				switch signature[end] {
+				case '{', '(':
+					depth++
+				case '}', ')':
+					depth--
+				}
+				end++
+			}
+		}
+		types = append(types, signature[i:end])
+		i = end
+	}
+	return types
+}
+
+// dbusEncoder marshals values in little-endian D-Bus wire format. Offsets
+// are relative to the start of the message or body, both 8-byte aligned.
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) bytes(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+// array writes the length of the elements that fill writes, which start
+// aligned to elemAlign
+func (e *dbusEncoder) array(elemAlign int, fill func()) {
+	e.uint32(0)
+	at := len(e.buf)
+	e.align(elemAlign)
+	start := len(e.buf)
+	fill()
+	binary.LittleEndian.PutUint32(e.buf[at-4:], uint32(len(e.buf)-start))
+}
+
+// value marshals v as the complete type t
+func (e *dbusEncoder) value(t string, v any) error {
+	switch t {
+	case "s", "o":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%T is not a string", v)
+		}
+		e.uint32(uint32(len(s)))
+		e.bytes(append([]byte(s), 0))
+	case "g":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%T is not a signature", v)
+		}
+		e.bytes(append([]byte{byte(len(s))}, append([]byte(s), 0)...))
+	case "y":
+		b, ok := v.(byte)
+		if !ok {
+			return fmt.Errorf("%T is not a byte", v)
+		}
+		e.bytes([]byte{b})
+	case "b":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%T is not a boolean", v)
+		}
+		e.uint32(map[bool]uint32{false: 0, true: 1}[b])
+	case "u":
+		u, ok := v.(uint32)
+		if !ok {
+			return fmt.Errorf("%T is not a uint32", v)
+		}
+		e.uint32(u)
+	case "i":
+		i, ok := v.(int32)
+		if !ok {
+			return fmt.Errorf("%T is not an int32", v)
+		}
+		e.uint32(uint32(i))
+	case "v":
+		variant, ok := v.(dbusVariant)
+		if !ok {
+			return fmt.Errorf("%T is not a variant", v)
+		}
+		e.value("g", variant.Signature)
+		return e.value(variant.Signature, variant.Value)
+	case "as":
+		list, ok := v.([]string)
+		if !ok {
+			return fmt.Errorf("%T is not a string array", v)
+		}
+		e.array(4, func() {
+			for _, s := range list {
+				e.value("s", s)
+			}
+		})
+	case "a{sv}":
+		dict, ok := v.(map[string]dbusVariant)
+		if !ok {
+			return fmt.Errorf("%T is not a dictionary of variants", v)
+		}
+		var err error
+		e.array(8, func() {
+			for k, variant := range dict {
+				e.align(8)
+				e.value("s", k)
+				if verr := e.value("v", variant); err == nil {
+					err = verr
+				}
+			}
+		})
+		return err
+	default:
+		return fmt.Errorf("unsupported D-Bus type %q", t)
+	}
+	return nil
+}
+
+// dbusDecoder unmarshals the header fields and bodies notify reads
+type dbusDecoder struct {
+	order binary.ByteOrder
+	buf   []byte
+	pos   int
+}
+
+var errDBusShort = errors.New("truncated D-Bus message")
+
+func (d *dbusDecoder) align(n int) {
+	d.pos = (d.pos + n - 1) / n * n
+}
+
+func (d *dbusDecoder) byte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, errDBusShort
+	}
+	d.pos++
+	return d.buf[d.pos-1], nil
+}
+
+func (d *dbusDecoder) uint32() (uint32, error) {
+	d.align(4)
+	if d.pos+4 > len(d.buf) {
+		return 0, errDBusShort
+	}
+	d.pos += 4
+	return d.order.Uint32(d.buf[d.pos-4:]), nil
+}
+
+func (d *dbusDecoder) string() (string, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return "", err
+	}
+	if d.pos+int(n)+1 > len(d.buf) {
+		return "", errDBusShort
+	}
+	d.pos += int(n) + 1
+	return string(d.buf[d.pos-int(n)-1 : d.pos-1]), nil
+}
+
+func (d *dbusDecoder) signature() (string, error) {
+	n, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+	if d.pos+int(n)+1 > len(d.buf) {
+		return "", errDBusShort
+	}
+	d.pos += int(n) + 1
+	return string(d.buf[d.pos-int(n)-1 : d.pos-1]), nil
+}
+
+// strings reads an array of strings
+func (d *dbusDecoder) strings() ([]string, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	end := d.pos + int(n)
+	var list []string
+	for d.pos < end {
+		s, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// variant reads a variant of the basic types used in header fields
+func (d *dbusDecoder) variant() (any, error) {
+	signature, err := d.signature()
+	if err != nil {
+		return nil, err
+	}
+	switch signature {
+	case "s", "o":
+		return d.string()
+	case "g":
+		return d.signature()
+	case "u":
+		return d.uint32()
+	case "y":
+		return d.byte()
+	}
+	return nil, fmt.Errorf("unexpected header field type %q", signature)
+}
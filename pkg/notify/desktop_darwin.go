@@ -0,0 +1,209 @@
+//go:build darwin
+
+package notify
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sounds of /System/Library/Sounds standing in for Windows' notification
+// sounds; the default sound depends on the type
+var macSounds = map[string]string{"IM": "Pop", "SMS": "Pop", "Mail": "Blow", "Reminder": "Tink", "Looping.Alarm": "Sosumi", "Looping.Call": "Submarine"}
+
+// macTypeSounds are the default sounds of each type
+var macTypeSounds = map[string]string{"success": "Glass", "error": "Basso", "warning": "Funk", "info": "Ping"}
+
+// Notifications this process showed with a tag, by group and tag, which
+// progress updates show again with new values. The mutex guards the map
+// and the notifications in it, as updates change them.
+var (
+	shownDesktopMu sync.Mutex
+	shownDesktop   = map[string]*macNotification{}
+)
+
+// macNotification is a notification as Notification Center shows it
+type macNotification struct {
+	Group    string // terminal-notifier group, from the group and tag
+	Title    string
+	Subtitle string
+	Message  string
+	Status   string // of the progress, below the message
+	Icon     string // path of the type icon
+	Image    string // shown in the notification, with terminal-notifier
+	Link     string // opened by clicking, with terminal-notifier
+	Sound    string // name of a system sound, empty for none
+	Play     string // file played with afplay instead, at Volume
+	Volume   int
+}
+
+// newMacNotification maps a toast to Notification Center. The app is the
+// subtitle, as notifications can't be shown in another app's name.
+func newMacNotification(t *Toast) *macNotification {
+	m := &macNotification{
+		Title:    t.Title,
+		Subtitle: t.App,
+		Message:  t.Message,
+		Icon:     t.Icon,
+		Image:    t.Image,
+		Link:     t.Link,
+		Volume:   100,
+	}
+	if t.Tag != "" {
+		m.Group = "notify/" + t.Group + "/" + t.Tag
+	}
+	if t.Progress != nil {
+		m.Status = consoleProgress(t.Progress.data())
+	}
+	if t.Source != "" {
+		m.Message += "\nvia " + t.Source
+	}
+
+	sound := cmp.Or(t.Sound, DefaultSound(t.Type))
+	if t.Volume != nil {
+		m.Volume = *t.Volume
+	}
+	switch name := SoundName(sound); {
+	case sound == SoundSilent || m.Volume == 0:
+	case soundFile(sound):
+		m.Play = sound
+	case strings.HasPrefix(name, "Looping."):
+		m.Sound = macSounds[strings.TrimRight(name, "0123456789")]
+	default:
+		m.Sound = cmp.Or(macSounds[name], macTypeSounds[t.Type])
+	}
+	// Notification Center plays sounds at the alert volume, afplay at any
+	if m.Sound != "" && m.Volume < 100 {
+		m.Play = filepath.Join("/System/Library/Sounds", m.Sound+".aiff")
+		m.Sound = ""
+	}
+	return m
+}
+
+// body returns the message with the progress below it
+func (m *macNotification) body() string {
+	return strings.TrimSpace(m.Message + "\n" + m.Status)
+}
+
+// Show shows a toast in Notification Center, through terminal-notifier
+// when it is installed and osascript otherwise. Only terminal-notifier
+// replaces notifications with the same tag, shows images and opens links
+// when clicked. Clicks can't be reported, so Wait is ignored.
+func (desktopBackend) Show(t *Toast) (string, error) {
+	if t.Session != "" {
+		return "", errors.New("--session is only supported on Windows")
+	}
+	m := newMacNotification(t)
+	if _, err := m.show(); err != nil {
+		return "", err
+	}
+	if m.Group != "" {
+		shownDesktopMu.Lock()
+		shownDesktop[m.Group] = m
+		shownDesktopMu.Unlock()
+	}
+	return "", nil
+}
+
+// show delivers the notification and plays its sound file, returning the
+// program that showed it
+func (m *macNotification) show() (string, error) {
+	if m.Play != "" {
+		// afplay takes a volume of 0 to 1, and keeps playing after notify exits
+		exec.Command("afplay", "-v", strconv.FormatFloat(float64(m.Volume)/100, 'f', 2, 64), m.Play).Start()
+	}
+	if path, err := exec.LookPath("terminal-notifier"); err == nil {
+		return "terminal-notifier", m.terminalNotifier(path)
+	}
+	return "osascript", m.osascript()
+}
+
+// terminalNotifier shows the notification with terminal-notifier
+func (m *macNotification) terminalNotifier(path string) error {
+	args := []string{"-title", m.Title, "-message", cmp.Or(m.body(), " ")}
+	if m.Subtitle != "" {
+		args = append(args, "-subtitle", m.Subtitle)
+	}
+	if m.Group != "" {
+		args = append(args, "-group", m.Group)
+	}
+	if m.Icon != "" {
+		args = append(args, "-appIcon", m.Icon)
+	}
+	// Recent macOS versions ignore -appIcon, so the type icon is also the
+	// image when there is no other
+	if image := cmp.Or(m.Image, m.Icon); image != "" {
+		args = append(args, "-contentImage", image)
+	}
+	if m.Link != "" {
+		args = append(args, "-open", m.Link)
+	}
+	if m.Sound != "" {
+		args = append(args, "-sound", m.Sound)
+	}
+	return runMacCommand(path, args...)
+}
+
+// osascript shows the notification with AppleScript, which can't replace,
+// remove or open anything, so the link is part of the message
+func (m *macNotification) osascript() error {
+	body := m.body()
+	if m.Link != "" {
+		body = strings.TrimSpace(body + "\n" + m.Link)
+	}
+	// Values are passed as arguments, which need no quoting
+	script := `display notification (item 2 of argv) with title (item 1 of argv) subtitle (item 3 of argv)`
+	if m.Sound != "" {
+		script += ` sound name (item 4 of argv)`
+	}
+	return runMacCommand("osascript", "-e", "on run argv", "-e", script, "-e", "end run", m.Title, body, m.Subtitle, m.Sound)
+}
+
+// runMacCommand runs a program, returning its output with any failure
+func runMacCommand(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %v: %s", filepath.Base(name), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Update shows a toast this process showed again with the progress of an
+// update, silently. It returns false when there is none.
+func (desktopBackend) Update(u *Update) (bool, error) {
+	shownDesktopMu.Lock()
+	defer shownDesktopMu.Unlock()
+	m, ok := shownDesktop["notify/"+u.Group+"/"+u.Tag]
+	if !ok {
+		return false, nil
+	}
+	m.Status = consoleProgress(u.Progress.data())
+	m.Sound, m.Play = "", ""
+	_, err := m.show()
+	return err == nil, err
+}
+
+// Remove removes the notification last shown with the tag of t from
+// Notification Center, which takes terminal-notifier
+func (desktopBackend) Remove(t *Toast) error {
+	path, err := exec.LookPath("terminal-notifier")
+	if err != nil {
+		return nil
+	}
+	return runMacCommand(path, "-remove", "notify/"+t.Group+"/"+t.Tag)
+}
+
+// Check checks that notifications can be shown
+func (desktopBackend) Check() error {
+	if _, err := exec.LookPath("terminal-notifier"); err == nil {
+		return nil
+	}
+	_, err := exec.LookPath("osascript")
+	return err
+}
@@ -0,0 +1,410 @@
+//go:build linux
+
+package notify
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The notification server of the desktop, per the Desktop Notifications
+// Specification that GNOME, KDE, XFCE, dunst and mako implement
+const (
+	notificationsName  = "org.freedesktop.Notifications"
+	notificationsPath  = "/org/freedesktop/Notifications"
+	notificationsMatch = "type='signal',interface='org.freedesktop.Notifications'"
+)
+
+// Bodies of servers with body-markup are a subset of HTML
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Ids of tagged notifications are forgotten after this long, as servers
+// restart with the session
+const desktopIDLifetime = 24 * time.Hour
+
+// Why the server closed a notification, by the reason it reports
+var closeReasons = map[uint32]string{1: "timed out", 2: "dismissed", 3: "dismissed"}
+
+// Notifications this process showed with a tag, by group and tag, which
+// progress updates show again with new values. The mutex guards the map
+// and the notifications in it, as updates change them.
+var (
+	shownDesktopMu sync.Mutex
+	shownDesktop   = map[string]*desktopNotification{}
+)
+
+// desktopNotification is a notification in the terms of the specification
+type desktopNotification struct {
+	Key     string // group and tag, empty without a tag
+	App     string
+	Icon    string // path of the type icon
+	Summary string
+	Body    string
+	Status  string // of the progress, below the message
+	Link    string
+	Actions []string // keys and labels, only reported while waiting
+	Hints   map[string]dbusVariant
+	Timeout int32 // in milliseconds, -1 for the server's default and 0 for never
+}
+
+// newDesktopNotification maps a toast to the specification: info is low
+// urgency, urgent toasts critical, and sticky toasts never expire. Long
+// toasts stay at least 25 seconds.
+func newDesktopNotification(t *Toast) *desktopNotification {
+	d := &desktopNotification{
+		App:     AppName(t.App),
+		Icon:    t.Icon,
+		Summary: t.Title,
+		Body:    t.Message,
+		Link:    t.Link,
+		Hints:   map[string]dbusVariant{},
+		Timeout: -1,
+	}
+	if t.Tag != "" {
+		d.Key = t.Group + "/" + t.Tag
+	}
+	if t.Wait {
+		d.Actions = []string{"default", cmp.Or(t.ClickHint, "Open")}
+	}
+
+	urgency := byte(1)
+	switch {
+	case t.Urgent:
+		urgency = 2
+	case t.Type == Info:
+		urgency = 0
+	}
+	d.Hints["urgency"] = dbusVariant{"y", urgency}
+	if t.Category != "" {
+		d.Hints["x-notify-category"] = dbusVariant{"s", t.Category}
+	}
+	if t.Image != "" {
+		d.Hints["image-path"] = dbusVariant{"s", t.Image}
+	}
+
+	if t.Progress != nil {
+		d.setProgress(t.Progress.data())
+	}
+	if t.Source != "" {
+		d.Body += "\nvia " + t.Source
+	}
+
+	// The freedesktop sound theme has sounds for errors and warnings,
+	// files are played by the server itself
+	sound := cmp.Or(t.Sound, DefaultSound(t.Type))
+	switch {
+	case sound == SoundSilent || (t.Volume != nil && *t.Volume == 0):
+		d.Hints["suppress-sound"] = dbusVariant{"b", true}
+	case soundFile(sound):
+		d.Hints["sound-file"] = dbusVariant{"s", sound}
+	case t.Type == Error:
+		d.Hints["sound-name"] = dbusVariant{"s", "dialog-error"}
+	case t.Type == Warning:
+		d.Hints["sound-name"] = dbusVariant{"s", "dialog-warning"}
+	case t.Type == Success:
+		d.Hints["sound-name"] = dbusVariant{"s", "complete"}
+	default:
+		d.Hints["sound-name"] = dbusVariant{"s", "message-new-instant"}
+	}
+
+	if t.Timeout > 0 {
+		d.Timeout = int32(t.Timeout.Milliseconds())
+	}
+	if t.Long {
+		d.Timeout = max(d.Timeout, 25000)
+	}
+	if t.Sticky {
+		d.Timeout = 0
+	}
+	return d
+}
+
+// setProgress shows the progress bar and status of data-bound toasts
+func (d *desktopNotification) setProgress(data map[string]string) {
+	delete(d.Hints, "value")
+	if value, err := strconv.ParseFloat(data["progressValue"], 64); err == nil {
+		d.Hints["value"] = dbusVariant{"i", int32(min(value, 1) * 100)}
+	}
+	d.Status = strings.TrimSpace(data["progressStatus"] + "\n" + data["progressValueString"])
+}
+
+// Show shows a toast through the desktop's notification server, or with
+// notify-send when the session bus can't be reached. Toasts with a tag
+// replace the last one shown with it.
+func (desktopBackend) Show(t *Toast) (string, error) {
+	if t.Session != "" {
+		return "", errors.New("--session is only supported on Windows")
+	}
+	d := newDesktopNotification(t)
+
+	bus, err := dialSessionBus()
+	if err != nil {
+		if sendErr := notifySend(d); sendErr != nil {
+			return "", fmt.Errorf("%w, and notify-send failed: %v", err, sendErr)
+		}
+		return "", nil
+	}
+	defer bus.Close()
+
+	// Clicks and closes are only reported to a connection that listens
+	if t.Wait {
+		if _, err := bus.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "AddMatch", "s", notificationsMatch); err != nil {
+			return "", err
+		}
+	}
+	id, err := d.show(bus)
+	if err != nil {
+		return "", err
+	}
+	if d.Key != "" {
+		shownDesktopMu.Lock()
+		shownDesktop[d.Key] = d
+		shownDesktopMu.Unlock()
+	}
+	if t.Wait {
+		return waitDesktop(bus, id, d.Link), nil
+	}
+	return "", nil
+}
+
+// show sends the notification to the server, replacing the one last shown
+// with its tag, and returns its id
+func (d *desktopNotification) show(bus *dbusConn) (uint32, error) {
+	body := strings.TrimSpace(d.Body + "\n" + d.Status)
+	reply, err := bus.call(notificationsName, notificationsPath, notificationsName, "GetCapabilities", "")
+	var caps []string
+	if err == nil {
+		caps, _ = reply.Body.strings()
+	}
+	switch {
+	case !slices.Contains(caps, "body-markup"):
+		if d.Link != "" {
+			body += "\n" + d.Link
+		}
+	case d.Link != "" && slices.Contains(caps, "body-hyperlinks"):
+		link := html.EscapeString(d.Link)
+		body = markupEscaper.Replace(body) + fmt.Sprintf("\n<a href=\"%s\">%s</a>", link, link)
+	case d.Link != "":
+		body = markupEscaper.Replace(body + "\n" + d.Link)
+	default:
+		body = markupEscaper.Replace(body)
+	}
+
+	replaces := uint32(0)
+	if d.Key != "" {
+		if ids, err := loadDesktopIDs(); err == nil && ids[d.Key] != nil {
+			replaces = ids[d.Key].ID
+		}
+	}
+	reply, err = bus.call(notificationsName, notificationsPath, notificationsName, "Notify", "susssasa{sv}i",
+		d.App, replaces, d.Icon, d.Summary, body, d.Actions, d.Hints, d.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	id, err := reply.Body.uint32()
+	if err != nil || d.Key == "" || id == replaces {
+		return id, err
+	}
+	err = updateDesktopIDs(func(ids map[string]*desktopID) {
+		for key, shown := range ids {
+			if time.Since(shown.Shown) > desktopIDLifetime {
+				delete(ids, key)
+			}
+		}
+		ids[d.Key] = &desktopID{ID: id, Shown: time.Now()}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the notification can't be replaced later: %v\n", err)
+	}
+	return id, nil
+}
+
+// Update shows a toast this process showed again with the progress of an
+// update, silently. It returns false when there is none.
+func (desktopBackend) Update(u *Update) (bool, error) {
+	shownDesktopMu.Lock()
+	defer shownDesktopMu.Unlock()
+	d, ok := shownDesktop[u.Group+"/"+u.Tag]
+	if !ok {
+		return false, nil
+	}
+	d.setProgress(u.Progress.data())
+	delete(d.Hints, "sound-name")
+	delete(d.Hints, "sound-file")
+	d.Hints["suppress-sound"] = dbusVariant{"b", true}
+
+	bus, err := dialSessionBus()
+	if err != nil {
+		return false, err
+	}
+	defer bus.Close()
+	_, err = d.show(bus)
+	return err == nil, err
+}
+
+// waitDesktop waits until the notification is clicked or closed, opening
+// its link when clicked, and returns what happened to it
+func waitDesktop(bus *dbusConn, id uint32, link string) string {
+	wait, _ := strconv.Atoi(waitSeconds)
+	deadline := time.Now().Add(time.Duration(wait) * time.Second)
+	for {
+		m, err := bus.signal(deadline)
+		if err != nil {
+			return "timed out"
+		}
+		if m.Interface != notificationsName {
+			continue
+		}
+		if signalID, err := m.Body.uint32(); err != nil || signalID != id {
+			continue
+		}
+		switch m.Member {
+		case "ActionInvoked":
+			if link != "" {
+				exec.Command("xdg-open", link).Start()
+			}
+			bus.call(notificationsName, notificationsPath, notificationsName, "CloseNotification", "u", id)
+			return "clicked"
+		case "NotificationClosed":
+			reason, _ := m.Body.uint32()
+			return cmp.Or(closeReasons[reason], "dismissed")
+		}
+	}
+}
+
+// notifySend shows a notification with libnotify's notify-send, for
+// sessions whose bus notify can't reach by itself
+func notifySend(d *desktopNotification) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return err
+	}
+	urgency := map[byte]string{0: "low", 1: "normal", 2: "critical"}[d.Hints["urgency"].Value.(byte)]
+	args := []string{"--app-name", d.App, "--urgency", urgency, "--expire-time", strconv.Itoa(int(d.Timeout))}
+	if d.Icon != "" {
+		args = append(args, "--icon", d.Icon)
+	}
+	if image, ok := d.Hints["image-path"].Value.(string); ok {
+		args = append(args, "--hint", "string:image-path:"+image)
+	}
+	body := strings.TrimSpace(d.Body + "\n" + d.Status)
+	if d.Link != "" {
+		body += "\n" + d.Link
+	}
+	args = append(args, "--", d.Summary, body)
+	out, err := exec.Command(path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Remove closes the notification last shown with the tag of t
+func (desktopBackend) Remove(t *Toast) error {
+	key := t.Group + "/" + t.Tag
+	ids, err := loadDesktopIDs()
+	if err != nil {
+		return err
+	}
+	shown, ok := ids[key]
+	if !ok {
+		return nil
+	}
+	bus, err := dialSessionBus()
+	if err != nil {
+		return err
+	}
+	defer bus.Close()
+	if _, err := bus.call(notificationsName, notificationsPath, notificationsName, "CloseNotification", "u", shown.ID); err != nil {
+		return err
+	}
+	return updateDesktopIDs(func(ids map[string]*desktopID) {
+		delete(ids, key)
+	})
+}
+
+// Check checks that the desktop's notification server is running, or that
+// notify-send can reach it
+func (desktopBackend) Check() error {
+	bus, err := dialSessionBus()
+	if err != nil {
+		if _, lookErr := exec.LookPath("notify-send"); lookErr == nil {
+			return nil
+		}
+		return err
+	}
+	defer bus.Close()
+	_, err = bus.call(notificationsName, notificationsPath, notificationsName, "GetServerInformation", "")
+	return err
+}
+
+// desktopID is the id the notification server gave a notification
+type desktopID struct {
+	ID    uint32    `json:"id"`
+	Shown time.Time `json:"shown"`
+}
+
+// desktopIDsFile returns the file keeping the ids of toasts shown with a
+// tag, by group and tag, so the next one replaces them
+func desktopIDsFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notify", "desktop_ids.json"), nil
+}
+
+// loadDesktopIDs reads the ids of toasts shown with a tag
+func loadDesktopIDs() (map[string]*desktopID, error) {
+	path, err := desktopIDsFile()
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]*desktopID{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// updateDesktopIDs changes the ids of toasts shown with a tag, replacing
+// the file so that readers never see half of it
+func updateDesktopIDs(change func(map[string]*desktopID)) error {
+	ids, err := loadDesktopIDs()
+	if err != nil {
+		return err
+	}
+	change(ids)
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	path, _ := desktopIDsFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
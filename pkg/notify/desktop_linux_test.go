@@ -0,0 +1,31 @@
+//go:build linux
+
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDesktopNotificationTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		toast Toast
+		want  int32
+	}{
+		{"server default", Toast{}, -1},
+		{"timeout", Toast{Timeout: 10 * time.Second}, 10000},
+		{"long", Toast{Long: true}, 25000},
+		{"long keeps a longer timeout", Toast{Timeout: time.Minute, Long: true}, 60000},
+		{"long extends a shorter timeout", Toast{Timeout: 5 * time.Second, Long: true}, 25000},
+		{"sticky", Toast{Timeout: 10 * time.Second, Sticky: true}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.toast.Type, tt.toast.Message = Info, "x"
+			if got := newDesktopNotification(&tt.toast).Timeout; got != tt.want {
+				t.Errorf("timeout %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package notify
+
+import "errors"
+
+// errNoDesktop is returned by the desktop backend where there is none
+var errNoDesktop = errors.New("desktop notifications are only supported on Linux and macOS")
+
+func (desktopBackend) Show(t *Toast) (string, error)  { return "", errNoDesktop }
+func (desktopBackend) Update(u *Update) (bool, error) { return false, errNoDesktop }
+func (desktopBackend) Remove(t *Toast) error          { return errNoDesktop }
+func (desktopBackend) Check() error                   { return errNoDesktop }
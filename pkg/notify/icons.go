@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Colors of the icons of each type, and the symbols standing in for them
+// in text
+var iconData = map[string]struct {
+	Color  color.RGBA
+	Symbol string
+}{
+	"success": {Color: color.RGBA{R: 46, G: 204, B: 113, A: 255}, Symbol: "✓"}, // Green
+	"error":   {Color: color.RGBA{R: 231, G: 76, B: 60, A: 255}, Symbol: "✗"},  // Red
+	"info":    {Color: color.RGBA{R: 52, G: 152, B: 219, A: 255}, Symbol: "ℹ"}, // Blue
+	"warning": {Color: color.RGBA{R: 241, G: 196, B: 15, A: 255}, Symbol: "⚠"}, // Yellow
+}
+
+// Symbol returns the symbol of a notification type, e.g. ✓ for Success
+func Symbol(nType string) string {
+	return iconData[nType].Symbol
+}
+
+// Styles of Icon: high contrast, and shapes telling the types apart
+// without their colors
+const (
+	IconDefault      = ""
+	IconHighContrast = "hc"
+	IconColorblind   = "colorblind"
+)
+
+// iconName tells the icon styles of a type apart in file names
+func iconName(nType, style string) string {
+	if style != IconDefault {
+		return style + "_" + nType
+	}
+	return nType
+}
+
+// Icon draws the icon of a notification type in a style: a circle of the
+// type's color by default
+func Icon(nType, style string) image.Image {
+	if style != IconDefault {
+		return styledIcon(nType, style)
+	}
+
+	data, ok := iconData[nType]
+	if !ok {
+		data = iconData["info"]
+	}
+
+	// Create a 64x64 image
+	size := 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	// Draw a filled circle with the color
+	center := size / 2
+	radius := size/2 - 4
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			// Calculate distance from center
+			dx := x - center
+			dy := y - center
+			distance := dx*dx + dy*dy
+
+			if distance <= radius*radius {
+				// Inside circle - draw color
+				img.Set(x, y, data.Color)
+			} else {
+				// Outside circle - transparent
+				img.Set(x, y, color.RGBA{R: 0, G: 0, B: 0, A: 0})
+			}
+		}
+	}
+	return img
+}
+
+// Strokes of the symbols as x1, y1, x2, y2 from the center of the 64x64
+// icon; a stroke with equal ends is a dot
+var iconSymbols = map[string][][4]float64{
+	"success": {{-12, 1, -4, 9}, {-4, 9, 12, -8}},
+	"error":   {{-9, -9, 9, 9}, {9, -9, -9, 9}},
+	"info":    {{0, -12, 0, -12}, {0, -3, 0, 12}},
+	"warning": {{0, -14, 0, 3}, {0, 12, 0, 12}},
+}
+
+// The warning symbol sits lower in its triangle
+var triangleWarning = [][4]float64{{0, -8, 0, 8}, {0, 16, 0, 16}}
+
+// iconShapes tell the types apart without color in the colorblind palette.
+// Each reports whether a point, relative to the center, is inside.
+var iconShapes = map[string]func(x, y float64) bool{
+	// Circle
+	"success": func(x, y float64) bool { return math.Hypot(x, y) <= 28 },
+	// Octagon, like a stop sign
+	"error": func(x, y float64) bool {
+		return max(math.Abs(x), math.Abs(y), (math.Abs(x)+math.Abs(y))/math.Sqrt2) <= 27
+	},
+	// Diamond
+	"info": func(x, y float64) bool { return math.Abs(x)+math.Abs(y) <= 30 },
+	// Triangle pointing up, like a warning sign
+	"warning": func(x, y float64) bool { return y <= 25 && math.Abs(x)*52 <= (y+27)*28 },
+}
+
+// colorblindColors are the Okabe-Ito colors, which stay apart with every
+// kind of color blindness, and the symbol colors that contrast with them
+var colorblindColors = map[string][2]color.RGBA{
+	"success": {{R: 0, G: 158, B: 115, A: 255}, {R: 255, G: 255, B: 255, A: 255}}, // bluish green
+	"error":   {{R: 213, G: 94, B: 0, A: 255}, {R: 255, G: 255, B: 255, A: 255}},  // vermillion
+	"info":    {{R: 0, G: 114, B: 178, A: 255}, {R: 255, G: 255, B: 255, A: 255}}, // blue
+	"warning": {{R: 240, G: 228, B: 66, A: 255}, {A: 255}},                        // yellow
+}
+
+// styledIcon draws the icon of the notification type with its symbol. The
+// high-contrast style is white on black inside a white ring, which stays
+// visible in high contrast themes; the colorblind one uses the type's
+// shape and color.
+func styledIcon(nType, style string) *image.RGBA {
+	if _, ok := iconSymbols[nType]; !ok {
+		nType = "info"
+	}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	fill, symbol := color.RGBA{A: 255}, white
+	inside, strokes := iconShapes["success"], iconSymbols[nType]
+	if style == IconColorblind {
+		fill, symbol = colorblindColors[nType][0], colorblindColors[nType][1]
+		inside = iconShapes[nType]
+		if nType == "warning" {
+			strokes = triangleWarning
+		}
+	}
+
+	size := 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			px, py := float64(x)+0.5-32, float64(y)+0.5-32
+			if !inside(px, py) {
+				// Outside the shape - transparent
+				continue
+			}
+			c := fill
+			if style == IconHighContrast && math.Hypot(px, py) > 23 {
+				c = white
+			}
+			for _, s := range strokes {
+				if segmentDistance(px, py, s) <= 3.5 {
+					c = symbol
+					break
+				}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// segmentDistance returns the distance of a point from a stroke
+func segmentDistance(x, y float64, s [4]float64) float64 {
+	dx, dy := s[2]-s[0], s[3]-s[1]
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = max(0, min(1, ((x-s[0])*dx+(y-s[1])*dy)/length))
+	}
+	return math.Hypot(x-(s[0]+t*dx), y-(s[1]+t*dy))
+}
+
+// writeIcon saves an icon as a PNG file
+func writeIcon(iconPath string, img image.Image) error {
+	// Create the file
+	file, err := os.Create(iconPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Encode as PNG
+	return png.Encode(file, img)
+}
+
+// IconFile returns the path of a PNG file of Icon, drawn once into the
+// cache directory, where it stays for the toasts that read it later
+func IconFile(nType, style string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "notify")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	iconPath := filepath.Join(dir, fmt.Sprintf("icon_%s.png", iconName(nType, style)))
+	if _, err := os.Stat(iconPath); err == nil {
+		return iconPath, nil
+	}
+	return iconPath, writeIcon(iconPath, Icon(nType, style))
+}
+
+// ImageFile downloads the image of a toast, which can only show local
+// files, into the cache directory and returns its path
+func ImageFile(url string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "notify")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	imagePath := filepath.Join(dir, fmt.Sprintf("image_%x", sum[:8]))
+	if _, err := os.Stat(imagePath); err == nil {
+		return imagePath, nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return "", fmt.Errorf("%s is not an image", url)
+	}
+
+	// Toasts don't show images larger than 3MB
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 3<<20+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > 3<<20 {
+		return "", fmt.Errorf("%s is larger than 3MB", url)
+	}
+	if err := os.WriteFile(imagePath, data, 0644); err != nil {
+		return "", err
+	}
+	return imagePath, nil
+}
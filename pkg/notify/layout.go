@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters of a toast's text line at the default display scale. Toasts
+// only wrap text at spaces and clip what doesn't fit, so longer words need
+// break opportunities.
+const toastLineWidth = 38
+
+// Characters after which a long word, e.g. a URL, path or hash, may break
+const wordBreakAfter = `/\.-_?&=,;:@`
+
+// Zero-width space, an invisible break opportunity, and the no-break space
+// keeping two words on a line
+const (
+	zeroWidthSpace = "\u200b"
+	noBreakSpace   = "\u00a0"
+)
+
+var longWordPattern = regexp.MustCompile(fmt.Sprintf(`\S{%d,}`, toastLineWidth+1))
+
+// layoutToastText prepares the title or message of a toast for wrapping:
+// long words get break opportunities so they wrap instead of being clipped,
+// and the last word of a line that wraps is kept with the one before it
+// rather than left alone on the last line
+func layoutToastText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = longWordPattern.ReplaceAllStringFunc(balanceLine(line), breakLongWord)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// breakLongWord inserts zero-width spaces after the punctuation of a word,
+// and within runs of toastLineWidth characters without any
+func breakLongWord(word string) string {
+	var b strings.Builder
+	run := 0 // characters since the last break opportunity
+	for _, r := range word {
+		if run == toastLineWidth {
+			b.WriteString(zeroWidthSpace)
+			run = 0
+		}
+		b.WriteRune(r)
+		run++
+		if strings.ContainsRune(wordBreakAfter, r) {
+			b.WriteString(zeroWidthSpace)
+			run = 0
+		}
+	}
+	return strings.TrimSuffix(b.String(), zeroWidthSpace)
+}
+
+// balanceLine joins the last two words of a line with a no-break space when
+// wrapping it at toastLineWidth would leave the last word alone, and both
+// fit on half a line
+func balanceLine(line string) string {
+	words := strings.Split(line, " ")
+	if len(words) < 3 {
+		return line
+	}
+
+	// Wrap greedily, as the toast does, to find the words of the last line
+	width, count, wrapped := 0, 0, false
+	for _, w := range words {
+		n := utf8.RuneCountInString(w)
+		if count > 0 && width+1+n > toastLineWidth {
+			width, count, wrapped = n, 1, true
+			continue
+		}
+		if count > 0 {
+			width++
+		}
+		width += n
+		count++
+	}
+
+	last, prev := words[len(words)-1], words[len(words)-2]
+	if !wrapped || count != 1 || prev == "" ||
+		utf8.RuneCountInString(prev)+1+utf8.RuneCountInString(last) > toastLineWidth/2 {
+		return line
+	}
+	return strings.Join(words[:len(words)-2], " ") + " " + prev + noBreakSpace + last
+}
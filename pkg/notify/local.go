@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+)
+
+// Local shows notifications on this machine with the backend of its
+// platform: toasts on Windows and WSL, the desktop's notification server
+// on Linux and Notification Center on macOS. Muting, quiet hours and
+// history are the notify command's; send through a Relay for them.
+type Local struct {
+	Backend string // name of one of Backends (default: PlatformBackend)
+
+	// Warn is called with the problems that left parts of a notification
+	// out, like an image that couldn't be downloaded (default: ignored)
+	Warn func(error)
+}
+
+// Send shows the notification and returns once it is shown
+func (l *Local) Send(ctx context.Context, n Notification) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t, warnings := n.Toast(l.name())
+	if l.Warn != nil {
+		for _, err := range warnings {
+			l.Warn(err)
+		}
+	}
+	_, err := l.Show(t)
+	return err
+}
+
+// Show shows a toast with the backend and returns what the user did with
+// it, like Backend.Show. It is for toasts completed beyond what a
+// Notification sets, e.g. with a sound or a progress bar.
+func (l *Local) Show(t *Toast) (string, error) {
+	name := l.name()
+	backend, ok := Backends[name]
+	if !ok {
+		return "", fmt.Errorf("unknown backend %q", name)
+	}
+	return backend.Show(t)
+}
+
+// name returns the name of the backend
+func (l *Local) name() string {
+	return cmp.Or(l.Backend, PlatformBackend())
+}
+
+// Toast returns the toast showing the notification with the named
+// backend, with the icon of its type and its image downloaded, except for
+// the console backend, which shows neither. An image that can't be
+// downloaded is left out, and returned as a warning with the toast.
+func (n *Notification) Toast(backend string) (*Toast, []error) {
+	nType := cmp.Or(n.Type, Info)
+	t := &Toast{
+		Type:     nType,
+		App:      n.App,
+		Title:    cmp.Or(n.Title, DefaultTitle(nType)),
+		Message:  n.Message,
+		IconAlt:  DefaultTitle(nType),
+		Timeout:  n.Timeout,
+		Sticky:   n.Sticky,
+		Urgent:   n.Urgent,
+		Category: n.Category,
+		Link:     n.Link,
+		Tag:      n.Tag,
+		Group:    n.Group,
+	}
+	if backend == "console" {
+		return t, nil
+	}
+
+	t.Icon = n.Icon
+	if t.Icon == "" {
+		if icon, err := IconFile(nType, n.IconStyle); err == nil {
+			t.Icon = icon
+		}
+	}
+	var warnings []error
+	if n.Image != "" {
+		if image, err := ImageFile(n.Image); err != nil {
+			warnings = append(warnings, fmt.Errorf("showing the notification without its image: %w", err))
+		} else {
+			t.Image, t.ImageAlt = image, "Image"
+		}
+	}
+	return t, warnings
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// RelayMessage is a notification as notify relays accept it, as JSON. The
+// doc tags describe the fields in the relay's OpenAPI document.
+type RelayMessage struct {
+	Type       string      `json:"type,omitempty" doc:"Notification type (default: info)"`
+	Title      string      `json:"title,omitempty" doc:"Title, at most 64 characters (default: based on type)"`
+	Message    string      `json:"message" doc:"The notification message, required unless sealed is set"`
+	Timeout    int         `json:"timeout,omitempty" doc:"Timeout in seconds, 1-3600 (default: 5)"`
+	AutoClose  *bool       `json:"autoclose,omitempty" doc:"Close after the timeout (default: true)"`
+	App        string      `json:"app,omitempty" doc:"Sender name shown in Action Center"`
+	Category   string      `json:"category,omitempty" doc:"Category used for muting"`
+	Group      string      `json:"group,omitempty" doc:"Summarize with other notifications of this group"`
+	GroupSize  int         `json:"group_size,omitempty" doc:"Number of notifications expected in the group"`
+	Fallback   string      `json:"fallback,omitempty" doc:"Shown instead when Windows refuses the toast"`
+	Urgent     bool        `json:"urgent,omitempty" doc:"Break through Focus Assist"`
+	Wake       int         `json:"wake,omitempty" doc:"Keep the display on for this many seconds, needs urgent"`
+	Private    bool        `json:"private,omitempty" doc:"Keep the message out of history and stored summaries"`
+	Link       string      `json:"link,omitempty" doc:"http or https URL opened when the toast is clicked"`
+	Image      string      `json:"image,omitempty" doc:"http or https URL of an image shown above the message"`
+	Tag        string      `json:"tag,omitempty" doc:"Replaces the earlier toast with the same tag"`
+	Volume     *int        `json:"volume,omitempty" doc:"Sound volume in percent, 0-100 (default: the system volume)"`
+	Duck       bool        `json:"duck,omitempty" doc:"Lower other audio while the sound plays"`
+	Speak      bool        `json:"speak,omitempty" doc:"Also read the notification aloud"`
+	Attachment *Attachment `json:"attachment,omitempty" doc:"File sent with the notification, shown as the image when it is one"`
+	Source     string      `json:"source,omitempty" doc:"Ignored, relays record the client address and guest token name"`
+	Hops       int         `json:"hops,omitempty" doc:"Relays the notification passed through"`
+
+	// Set instead of the fields above for end-to-end encrypted messages
+	Sealed *SealedBox `json:"sealed,omitempty" doc:"End-to-end encrypted message, set instead of the other fields"`
+}
+
+// Attachment is a file sent with a notification
+type Attachment struct {
+	Name string `json:"name" doc:"File name without directories"`
+	Type string `json:"type,omitempty" doc:"Content type (default: detected from the name and contents)"`
+	Data []byte `json:"data" doc:"File contents, base64 encoded"`
+}
+
+// Toasts show PNG, JPEG and GIF images of at most 3MB
+var toastImageTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// Validate checks the name of an attachment, which becomes a file name on
+// the machine showing it, and fills in its content type
+func (a *Attachment) Validate() error {
+	name := strings.TrimSpace(a.Name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return fmt.Errorf("attachment name %q is not a file name", a.Name)
+	}
+	a.Name = name
+
+	if a.Type == "" {
+		a.Type = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if a.Type == "" {
+		a.Type = http.DetectContentType(a.Data)
+	}
+	if _, _, err := mime.ParseMediaType(a.Type); err != nil {
+		return fmt.Errorf("attachment type %q is invalid", a.Type)
+	}
+	return nil
+}
+
+// ToastImage reports whether a toast can show the attachment as its image
+func (a *Attachment) ToastImage() bool {
+	mediaType, _, _ := mime.ParseMediaType(a.Type)
+	for _, t := range toastImageTypes {
+		if mediaType == t {
+			return len(a.Data) <= 3<<20
+		}
+	}
+	return false
+}
+
+// SealedBox is a RelayMessage encrypted to the X25519 key of the relay
+// that shows it. Relays in between forward it without being able to read it.
+type SealedBox struct {
+	Key   string `json:"key" doc:"Base64 ephemeral X25519 public key of the sender"`
+	Nonce string `json:"nonce" doc:"Base64 AES-GCM nonce"`
+	Data  string `json:"data" doc:"Base64 encrypted JSON of the message"`
+}
@@ -0,0 +1,119 @@
+// Package notify shows desktop notifications from Go programs with the
+// backends of the notify command: toasts on Windows, the notification
+// server of Linux desktops and Notification Center on macOS. It shows them
+// on this machine, or on another through a notify relay.
+//
+//	err := notify.Send(ctx, notify.Notification{
+//		Type:    notify.Success,
+//		Message: "Backup finished",
+//	})
+//
+// Notifications sent through a relay honor muting, quiet hours and history
+// on the machine that shows them; Local shows them as they are.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Notification types, which choose the icon, sound and default title
+const (
+	Success = "success"
+	Error   = "error"
+	Info    = "info"
+	Warning = "warning"
+)
+
+// Types lists the notification types
+var Types = []string{Success, Error, Info, Warning}
+
+// Limits of notification fields
+const (
+	MaxTitleLength = 64        // longer titles are clipped by Action Center
+	MaxTagLength   = 64        // toast tags and groups are limited to 64 characters
+	MaxTimeout     = time.Hour // of Timeout
+)
+
+// ErrBlocked is returned, wrapped, when the notification settings of the
+// machine kept a notification from being shown
+var ErrBlocked = errors.New("blocked by the notification settings")
+
+// Notification is a notification to show. Only Message is required.
+type Notification struct {
+	Type     string // Success, Error, Info or Warning (default: Info)
+	Title    string // default: based on the type
+	Message  string
+	Timeout  time.Duration // whole seconds up to MaxTimeout (default: 5s)
+	Sticky   bool          // stay until dismissed instead of closing after Timeout
+	App      string        // sender name shown in Action Center (default: Notify CLI)
+	Category string        // category used for muting
+	Group    string        // summarize with other notifications of this group
+	Tag      string        // replaces the earlier notification with the same tag
+	Link     string        // http or https URL opened by clicking
+	Image    string        // http or https URL of an image shown above the message
+	Urgent   bool          // break through Focus Assist and never defer
+	Private  bool          // keep the message out of history and summaries
+
+	// Shown by Local only; relays use their own icons
+	Icon      string // PNG file replacing the drawn icon of the type
+	IconStyle string // IconDefault, IconHighContrast or IconColorblind
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Default is the Notifier of Send, showing notifications on this machine
+var Default Notifier = &Local{}
+
+// Send delivers a notification with Default
+func Send(ctx context.Context, n Notification) error {
+	return Default.Send(ctx, n)
+}
+
+// DefaultTitle returns the title of notifications of type t without one
+func DefaultTitle(t string) string {
+	if t == "" {
+		return ""
+	}
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+// Validate checks a notification, returning an error for the first
+// problem found
+func (n *Notification) Validate() error {
+	if n.Type != "" && !slices.Contains(Types, n.Type) {
+		return fmt.Errorf("invalid type %q, valid types are: %s", n.Type, strings.Join(Types, ", "))
+	}
+	if strings.TrimSpace(n.Message) == "" {
+		return errors.New("message is empty after trimming whitespace")
+	}
+	if n.Title != "" && strings.TrimSpace(n.Title) == "" {
+		return errors.New("title is empty after trimming whitespace; leave it out to use the default")
+	}
+	if length := utf8.RuneCountInString(n.Title); length > MaxTitleLength {
+		return fmt.Errorf("title is %d characters long, the maximum is %d; move the details into the message", length, MaxTitleLength)
+	}
+	if n.Timeout < 0 || n.Timeout > MaxTimeout || n.Timeout%time.Second != 0 {
+		return fmt.Errorf("timeout must be whole seconds up to %s, got %s", MaxTimeout, n.Timeout)
+	}
+	for name, value := range map[string]string{"group": n.Group, "tag": n.Tag} {
+		if length := utf8.RuneCountInString(value); length > MaxTagLength {
+			return fmt.Errorf("%s is %d characters long, the maximum is %d", name, length, MaxTagLength)
+		}
+	}
+	for _, link := range []string{n.Link, n.Image} {
+		if u, err := url.Parse(link); link != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https")) {
+			return fmt.Errorf("%q is not an http or https URL", link)
+		}
+	}
+	return nil
+}
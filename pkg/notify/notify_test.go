@@ -0,0 +1,257 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+	"github.com/sarfraznawaz2005/notify/pkg/notify/notifytest"
+)
+
+// fakeBackend registers a fake backend under name for the test
+func fakeBackend(t *testing.T, name string) *notifytest.Backend {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	backend := &notifytest.Backend{}
+	notify.Backends[name] = backend
+	t.Cleanup(func() { delete(notify.Backends, name) })
+	return backend
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		n    notify.Notification
+		err  string // part of the error, empty when valid
+	}{
+		{"message only", notify.Notification{Message: "Done"}, ""},
+		{"every field", notify.Notification{Type: notify.Warning, Title: "Disk", Message: "Low", Timeout: time.Minute,
+			Group: "g", Tag: "t", Link: "https://example.com", Image: "http://example.com/a.png"}, ""},
+		{"unknown type", notify.Notification{Type: "fatal", Message: "x"}, "invalid type"},
+		{"empty message", notify.Notification{Message: " \n\t"}, "message is empty"},
+		{"blank title", notify.Notification{Title: "   ", Message: "x"}, "title is empty"},
+		{"long title", notify.Notification{Title: strings.Repeat("é", notify.MaxTitleLength+1), Message: "x"}, "title is 65 characters"},
+		{"longest title", notify.Notification{Title: strings.Repeat("é", notify.MaxTitleLength), Message: "x"}, ""},
+		{"negative timeout", notify.Notification{Message: "x", Timeout: -time.Second}, "timeout"},
+		{"long timeout", notify.Notification{Message: "x", Timeout: notify.MaxTimeout + time.Second}, "timeout"},
+		{"fractional timeout", notify.Notification{Message: "x", Timeout: 1500 * time.Millisecond}, "timeout"},
+		{"long tag", notify.Notification{Message: "x", Tag: strings.Repeat("t", notify.MaxTagLength+1)}, "tag is 65"},
+		{"long group", notify.Notification{Message: "x", Group: strings.Repeat("g", notify.MaxTagLength+1)}, "group is 65"},
+		{"file link", notify.Notification{Message: "x", Link: "file:///etc/passwd"}, "not an http or https URL"},
+		{"relative image", notify.Notification{Message: "x", Image: "a.png"}, "not an http or https URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.n.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Validate() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Validate() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLocalSendShowsWithBackend(t *testing.T) {
+	backend := fakeBackend(t, "fake")
+	other := fakeBackend(t, "other")
+
+	local := &notify.Local{Backend: "fake"}
+	err := local.Send(context.Background(), notify.Notification{
+		Type:    notify.Error,
+		Message: "Build failed",
+		Sticky:  true,
+		Link:    "https://ci.example.com/1",
+		Tag:     "build",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(other.Shown()) != 0 {
+		t.Fatalf("other backend showed %d toasts", len(other.Shown()))
+	}
+	shown := backend.Shown()
+	if len(shown) != 1 {
+		t.Fatalf("backend showed %d toasts, want 1", len(shown))
+	}
+	toast := shown[0]
+	if toast.Type != notify.Error || toast.Title != "Error" || toast.Message != "Build failed" {
+		t.Errorf("toast = %q %q %q, want the type, default title and message", toast.Type, toast.Title, toast.Message)
+	}
+	if !toast.Sticky || toast.Link != "https://ci.example.com/1" || toast.Tag != "build" {
+		t.Errorf("toast = %+v, want it sticky with the link and tag", toast)
+	}
+	if toast.Icon == "" {
+		t.Error("toast has no icon")
+	}
+}
+
+func TestLocalSendErrors(t *testing.T) {
+	backend := fakeBackend(t, "fake")
+	valid := notify.Notification{Message: "x"}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		local   *notify.Local
+		ctx     context.Context
+		n       notify.Notification
+		showErr error
+		want    error  // matched with errors.Is when set
+		err     string // part of the error otherwise
+	}{
+		{"invalid", &notify.Local{Backend: "fake"}, context.Background(), notify.Notification{}, nil, nil, "message is empty"},
+		{"unknown backend", &notify.Local{Backend: "pager"}, context.Background(), valid, nil, nil, `unknown backend "pager"`},
+		{"canceled", &notify.Local{Backend: "fake"}, canceled, valid, nil, context.Canceled, ""},
+		{"blocked", &notify.Local{Backend: "fake"}, context.Background(), valid,
+			&notify.BlockedError{Setting: "DisabledForUser"}, notify.ErrBlocked, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.Err = tt.showErr
+			before := len(backend.Shown())
+			err := tt.local.Send(tt.ctx, tt.n)
+			switch {
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Fatalf("Send() = %v, want %v", err, tt.want)
+			case tt.want == nil && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Send() = %v, want an error containing %q", err, tt.err)
+			}
+			if len(backend.Shown()) != before {
+				t.Fatal("a failed notification was shown")
+			}
+		})
+	}
+}
+
+func TestLocalSendWarnsOfMissingImage(t *testing.T) {
+	backend := fakeBackend(t, "fake")
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	var warnings []error
+	local := &notify.Local{Backend: "fake", Warn: func(err error) { warnings = append(warnings, err) }}
+	if err := local.Send(context.Background(), notify.Notification{Message: "x", Image: server.URL + "/a.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "without its image") {
+		t.Fatalf("warnings = %v, want one about the image", warnings)
+	}
+	if shown := backend.Shown(); len(shown) != 1 || shown[0].Image != "" {
+		t.Fatalf("shown = %v, want one toast without an image", shown)
+	}
+}
+
+func TestToastForConsole(t *testing.T) {
+	n := notify.Notification{Type: notify.Success, Title: "Backup", Message: "Done", Image: "http://192.0.2.1/a.png",
+		Timeout: 10 * time.Second}
+	toast, warnings := n.Toast("console")
+	if toast.Icon != "" || toast.Image != "" || len(warnings) != 0 {
+		t.Fatalf("console toast = %+v, %v, want neither icon nor image", toast, warnings)
+	}
+	if toast.Title != "Backup" || toast.IconAlt != "Success" || toast.Timeout != 10*time.Second {
+		t.Fatalf("console toast = %+v, want the title, type and timeout", toast)
+	}
+}
+
+func TestToastUsesIconFile(t *testing.T) {
+	n := notify.Notification{Message: "x", Icon: "/icons/mine.png"}
+	toast, _ := n.Toast("toast")
+	if toast.Icon != "/icons/mine.png" {
+		t.Fatalf("Icon = %q, want the notification's", toast.Icon)
+	}
+}
+
+func TestSendUsesDefault(t *testing.T) {
+	fake := &notifytest.Notifier{}
+	saved := notify.Default
+	notify.Default = fake
+	defer func() { notify.Default = saved }()
+
+	if err := notify.Send(context.Background(), notify.Notification{Message: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.Sent(); len(sent) != 1 || sent[0].Message != "Hello" {
+		t.Fatalf("sent = %v, want the notification", sent)
+	}
+}
+
+func TestRelaySend(t *testing.T) {
+	var got notify.RelayMessage
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	relay := &notify.Relay{URL: server.URL, Token: "secret"}
+	err := relay.Send(context.Background(), notify.Notification{
+		Type: notify.Warning, Title: "Disk", Message: "Low", Timeout: 10 * time.Second, Sticky: true, Private: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/notify" || auth != "Bearer secret" {
+		t.Errorf("request to %s with %q, want /notify with the bearer token", path, auth)
+	}
+	if got.Type != notify.Warning || got.Title != "Disk" || got.Message != "Low" || got.Timeout != 10 || !got.Private {
+		t.Errorf("message = %+v, want the notification's fields", got)
+	}
+	if got.AutoClose == nil || *got.AutoClose {
+		t.Errorf("autoclose = %v, want false for a sticky notification", got.AutoClose)
+	}
+}
+
+func TestRelaySendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	relay := &notify.Relay{URL: server.URL}
+	err := relay.Send(context.Background(), notify.Notification{Message: "x"})
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid token") {
+		t.Fatalf("Send() = %v, want the relay's answer", err)
+	}
+	if err := relay.Send(context.Background(), notify.Notification{}); err == nil {
+		t.Fatal("Send() of an invalid notification succeeded")
+	}
+}
+
+func TestRelayEndpoint(t *testing.T) {
+	tests := []struct {
+		relay, want string
+	}{
+		{"desk:8787", "http://desk:8787/notify"},
+		{"https://desk:8787", "https://desk:8787/notify"},
+		{"https://desk:8787/", "https://desk:8787/notify"},
+		{"https://hub.example.com/notify/desk", "https://hub.example.com/notify/desk"},
+		{"ftp://desk", ""},
+	}
+	for _, tt := range tests {
+		got, err := notify.RelayEndpoint(tt.relay)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("RelayEndpoint(%q) = %q, want an error", tt.relay, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("RelayEndpoint(%q) = %q, %v, want %q", tt.relay, got, err, tt.want)
+		}
+	}
+}
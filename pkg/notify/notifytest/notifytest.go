@@ -0,0 +1,114 @@
+// Package notifytest provides fakes of the notify package's interfaces
+// for tests: a Backend recording the toasts it is asked to show, and a
+// Notifier recording the notifications sent through it.
+//
+//	backend := &notifytest.Backend{}
+//	notify.Backends["fake"] = backend
+//	err := (&notify.Local{Backend: "fake"}).Send(ctx, n)
+package notifytest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Backend is a notify.Backend recording what it is asked to do instead of
+// showing toasts
+type Backend struct {
+	Err    error  // returned by every method when set
+	Action string // returned by Show, e.g. clicked
+
+	mu      sync.Mutex
+	shown   []*notify.Toast
+	updates []*notify.Update
+	removed []*notify.Toast
+}
+
+func (b *Backend) Show(t *notify.Toast) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return "", b.Err
+	}
+	b.shown = append(b.shown, t)
+	return b.Action, nil
+}
+
+func (b *Backend) Update(u *notify.Update) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return false, b.Err
+	}
+	b.updates = append(b.updates, u)
+	return true, nil
+}
+
+func (b *Backend) Remove(t *notify.Toast) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return b.Err
+	}
+	b.removed = append(b.removed, t)
+	return nil
+}
+
+func (b *Backend) Check() error {
+	return b.Err
+}
+
+// Shown returns the toasts shown so far
+func (b *Backend) Shown() []*notify.Toast {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*notify.Toast(nil), b.shown...)
+}
+
+// Updates returns the updates applied so far
+func (b *Backend) Updates() []*notify.Update {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*notify.Update(nil), b.updates...)
+}
+
+// Removed returns the toasts removed so far
+func (b *Backend) Removed() []*notify.Toast {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*notify.Toast(nil), b.removed...)
+}
+
+// Notifier is a notify.Notifier recording the notifications sent through
+// it, after validating them like the real ones
+type Notifier struct {
+	Err error // returned by Send when set
+
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (f *Notifier) Send(ctx context.Context, n notify.Notification) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+// Sent returns the notifications sent so far
+func (f *Notifier) Sent() []notify.Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]notify.Notification(nil), f.sent...)
+}
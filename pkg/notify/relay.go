@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Relay sends notifications to a notify relay ('notify relay'), which
+// shows them on its machine or forwards them
+type Relay struct {
+	URL   string // of the relay, like https://desk:8080; /notify is added to a bare address
+	Token string // presented as a bearer token, when the relay requires one
+
+	// Client sends the requests, e.g. with the relay's CA or a client
+	// certificate (default: a client with a 15s timeout)
+	Client *http.Client
+}
+
+// RelayEndpoint returns the URL notifications are posted to, adding http://
+// to an address without a scheme and the default /notify path to a bare
+// relay address
+func RelayEndpoint(relay string) (string, error) {
+	if !strings.Contains(relay, "://") {
+		relay = "http://" + relay
+	}
+	u, err := url.Parse(relay)
+	if err != nil {
+		return "", fmt.Errorf("invalid relay URL %q: %w", relay, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid relay URL %q: use http or https", relay)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/notify"
+	}
+	return u.String(), nil
+}
+
+// Send posts a notification to the relay
+func (r *Relay) Send(ctx context.Context, n Notification) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	endpoint, err := RelayEndpoint(r.URL)
+	if err != nil {
+		return err
+	}
+
+	autoClose := !n.Sticky
+	body, err := json.Marshal(&RelayMessage{
+		Type:      n.Type,
+		Title:     n.Title,
+		Message:   n.Message,
+		Timeout:   int(n.Timeout / time.Second),
+		AutoClose: &autoClose,
+		App:       n.App,
+		Category:  n.Category,
+		Group:     n.Group,
+		Tag:       n.Tag,
+		Link:      n.Link,
+		Image:     n.Image,
+		Urgent:    n.Urgent,
+		Private:   n.Private,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("relay %s answered %s: %s", endpoint, resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
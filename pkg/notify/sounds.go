@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sounds of toasts
+const (
+	SoundDefault = "ms-winsoundevent:Notification.Default"
+	SoundSilent  = "silent"
+	SoundAlarm   = "ms-winsoundevent:Notification.Looping.Alarm"
+)
+
+// DefaultSound returns the sound of toasts of type t
+func DefaultSound(t string) string {
+	switch t {
+	case "success", "error", "warning":
+		return SoundDefault
+	}
+	return SoundSilent
+}
+
+// SoundName returns the short name of a sound, e.g. IM for
+// ms-winsoundevent:Notification.IM
+func SoundName(sound string) string {
+	if soundFile(sound) {
+		return sound
+	}
+	return strings.TrimPrefix(sound, "ms-winsoundevent:Notification.")
+}
+
+// mediaFile returns the wav file of a sound; Windows' sounds are files in
+// %WINDIR%\Media
+func mediaFile(sound string) string {
+	if soundFile(sound) {
+		return sound
+	}
+	name := SoundName(sound)
+	file := map[string]string{
+		"Default":  "Windows Notify System Generic.wav",
+		"IM":       "Windows Notify Messaging.wav",
+		"Mail":     "Windows Notify Email.wav",
+		"Reminder": "Windows Notify Calendar.wav",
+		"SMS":      "Windows Notify Messaging.wav",
+	}[name]
+	for kind, prefix := range map[string]string{"Looping.Alarm": "Alarm", "Looping.Call": "Ring"} {
+		if rest, ok := strings.CutPrefix(name, kind); ok {
+			n, _ := strconv.Atoi(cmp.Or(rest, "1"))
+			file = fmt.Sprintf("%s%02d.wav", prefix, n)
+		}
+	}
+	return filepath.Join(cmp.Or(os.Getenv("WINDIR"), `C:\Windows`), "Media", cmp.Or(file, "Windows Notify System Generic.wav"))
+}
+
+// soundFile reports whether a resolved sound is a wav file, which toasts
+// of unpackaged apps can't play themselves
+func soundFile(sound string) bool {
+	return strings.EqualFold(filepath.Ext(sound), ".wav")
+}
+
+// Other audio plays at this share of its volume while ducked
+const duckLevel = "0.2"
+
+// duckingSource lowers the volume of other programs' audio sessions on the
+// default output device through Core Audio, and restores it afterwards
+const duckingSource = `using System;
+using System.Collections.Generic;
+using System.Runtime.InteropServices;
+
+namespace NotifyAudio {
+    [ComImport, Guid("BCDE0395-E52F-467C-8E3D-C4579291692E")]
+    class MMDeviceEnumerator {}
+
+    [ComImport, Guid("A95664D2-9614-4F35-A746-DE8DB63617E6"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface IMMDeviceEnumerator {
+        int EnumAudioEndpoints();
+        [PreserveSig] int GetDefaultAudioEndpoint(int dataFlow, int role, out IMMDevice device);
+    }
+
+    [ComImport, Guid("D666063F-1587-4E43-81F1-B948E807363F"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface IMMDevice {
+        [PreserveSig] int Activate(ref Guid iid, int context, IntPtr parameters, [MarshalAs(UnmanagedType.IUnknown)] out object result);
+    }
+
+    [ComImport, Guid("77AA99A0-1BD6-484F-8BC7-2C654C9A9B6F"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface IAudioSessionManager2 {
+        int GetAudioSessionControl();
+        int GetSimpleAudioVolume();
+        [PreserveSig] int GetSessionEnumerator(out IAudioSessionEnumerator sessions);
+    }
+
+    [ComImport, Guid("E2F5BB11-0570-40CA-ACDD-3AA01277DEE8"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface IAudioSessionEnumerator {
+        [PreserveSig] int GetCount(out int count);
+        [PreserveSig] int GetSession(int index, out IAudioSessionControl2 session);
+    }
+
+    [ComImport, Guid("BFB7FF88-7239-4FC9-8FA2-07C950BE9C6D"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface IAudioSessionControl2 {
+        int GetState(); int GetDisplayName(); int SetDisplayName(); int GetIconPath(); int SetIconPath();
+        int GetGroupingParam(); int SetGroupingParam(); int RegisterAudioSessionNotification();
+        int UnregisterAudioSessionNotification(); int GetSessionIdentifier(); int GetSessionInstanceIdentifier();
+        [PreserveSig] int GetProcessId(out uint pid);
+    }
+
+    [ComImport, Guid("87CE5498-68D6-44E5-9215-6DA47EF883D8"), InterfaceType(ComInterfaceType.InterfaceIsIUnknown)]
+    interface ISimpleAudioVolume {
+        [PreserveSig] int SetMasterVolume(float level, ref Guid context);
+        [PreserveSig] int GetMasterVolume(out float level);
+    }
+
+    public static class Ducking {
+        static List<KeyValuePair<ISimpleAudioVolume, float>> ducked = new List<KeyValuePair<ISimpleAudioVolume, float>>();
+
+        public static void Duck(float share) {
+            IMMDevice device;
+            var devices = (IMMDeviceEnumerator)new MMDeviceEnumerator();
+            if (devices.GetDefaultAudioEndpoint(0, 1, out device) != 0) return; // eRender, eMultimedia
+            Guid iid = typeof(IAudioSessionManager2).GUID;
+            object manager;
+            if (device.Activate(ref iid, 23, IntPtr.Zero, out manager) != 0) return; // CLSCTX_ALL
+            IAudioSessionEnumerator sessions;
+            if (((IAudioSessionManager2)manager).GetSessionEnumerator(out sessions) != 0) return;
+
+            int count;
+            sessions.GetCount(out count);
+            uint self = (uint)System.Diagnostics.Process.GetCurrentProcess().Id;
+            for (int i = 0; i < count; i++) {
+                IAudioSessionControl2 session;
+                uint pid;
+                if (sessions.GetSession(i, out session) != 0 || session.GetProcessId(out pid) < 0 || pid == self || pid == 0) continue;
+                var volume = session as ISimpleAudioVolume;
+                float level;
+                if (volume == null || volume.GetMasterVolume(out level) != 0) continue;
+                Guid context = Guid.Empty;
+                if (volume.SetMasterVolume(level * share, ref context) == 0) {
+                    ducked.Add(new KeyValuePair<ISimpleAudioVolume, float>(volume, level));
+                }
+            }
+        }
+
+        public static void Restore() {
+            Guid context = Guid.Empty;
+            foreach (var d in ducked) d.Key.SetMasterVolume(d.Value, ref context);
+            ducked.Clear();
+        }
+    }
+}`
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/wsl"
+)
+
+// Toast is a notification as a Backend shows it, with its icon, image and
+// clicks resolved. Local builds them from Notifications; the notify
+// command adds its settings, attachments and click actions.
+type Toast struct {
+	Type       string // Success, Error, Info or Warning, for the urgency and default sound
+	App        string // sender name (default: DefaultApp)
+	Title      string
+	Message    string
+	Icon       string // path of the icon file
+	IconAlt    string // describes the icon to screen readers
+	Image      string // path of an image shown above the message
+	ImageAlt   string
+	Sound      string        // SoundSilent, a Windows sound like SoundDefault or a wav file (default: DefaultSound of Type)
+	Volume     *int          // of Sound in percent, nil for the system volume
+	Duck       bool          // lower other audio while Sound plays
+	Speech     string        // read aloud once the toast is shown, on Windows
+	Timeout    time.Duration // how long the toast stays, on Linux (default: the server's)
+	Long       bool          // stay long enough to be read, e.g. with a screen reader
+	Sticky     bool          // stay until dismissed
+	Urgent     bool          // break through Focus Assist and Do Not Disturb
+	Category   string
+	Link       string // web page opened by clicking
+	Launch     string // passed to Handler when the toast is clicked, instead of opening Link, on Windows
+	Handler    string // PowerShell lines registering the program handling Launch
+	ClickHint  string // shown below the message with Launch, and the action of toasts waited for
+	Source     string // host the notification came from, shown below the message
+	Collection string // Windows toast collection id
+	Tag        string // identifies the toast for updates and replacement
+	Group      string
+	Progress   *Progress // shows a progress bar that Update changes
+	Wait       bool      // wait until the toast is clicked, dismissed or times out
+	Session    string    // Windows session showing the toast: active, a user name or id
+}
+
+// Progress is the live progress bar of a toast that can be updated
+type Progress struct {
+	Title  string  // shown above the bar
+	Value  float64 // 0 to 1, or negative for an indeterminate bar
+	Label  string  // replaces the percentage, e.g. "3 of 10"
+	Status string  // shown below the bar
+}
+
+// data returns the values bound to the progress bar fields
+func (p *Progress) data() map[string]string {
+	value := "indeterminate"
+	if p.Value >= 0 {
+		value = strconv.FormatFloat(min(p.Value, 1), 'f', 3, 64)
+	}
+	return map[string]string{
+		"progressTitle":       p.Title,
+		"progressValue":       value,
+		"progressValueString": p.Label,
+		"progressStatus":      p.Status,
+	}
+}
+
+// Update changes the progress of a toast shown with a Tag
+type Update struct {
+	App      string
+	Tag      string
+	Group    string
+	Progress *Progress
+	Sequence uint32 // must increase with every update
+	Session  string // see Toast.Session
+}
+
+// Backend shows toasts on this machine
+type Backend interface {
+	// Show shows a toast and returns what the user did with it when it
+	// was shown with Wait: clicked, dismissed or timed out, or "" when
+	// that isn't known
+	Show(t *Toast) (string, error)
+
+	// Update applies an update, returning false when the toast no longer
+	// exists because it was dismissed or expired
+	Update(u *Update) (bool, error)
+
+	// Remove removes the toast with the App, Tag, Group and Session of t
+	Remove(t *Toast) error
+
+	// Check reports whether toasts can be shown
+	Check() error
+}
+
+// Backends by name: toast is Windows' toast notifications, desktop the
+// D-Bus notification server of Linux desktops or Notification Center on
+// macOS, and console prints them in the terminal, e.g. over SSH
+var Backends = map[string]Backend{
+	"toast":   toastBackend{},
+	"desktop": desktopBackend{},
+	"console": consoleBackend{},
+}
+
+// desktopBackend shows toasts on Linux desktops and macOS
+type desktopBackend struct{}
+
+// PlatformBackend returns the name of the backend of this platform
+func PlatformBackend() string {
+	// Inside WSL the Windows host shows toasts
+	if (runtime.GOOS == "linux" && !wsl.Detected()) || runtime.GOOS == "darwin" {
+		return "desktop"
+	}
+	return "toast"
+}
+
+// --wait gives up after this many seconds, as long toasts time out after
+// about 25
+const waitSeconds = "60"
+
+// BlockedError reports a toast that the notification settings keep from
+// showing. It wraps ErrBlocked.
+type BlockedError struct {
+	Setting string // NotificationSetting reported by the notifier
+}
+
+func (e *BlockedError) Error() string {
+	switch e.Setting {
+	case "DisabledForApplication":
+		return "notifications are turned off for this app in Settings > System > Notifications"
+	case "DisabledForUser":
+		return "notifications are turned off in Settings > System > Notifications"
+	case "DisabledByGroupPolicy":
+		return "notifications are disabled by group policy"
+	case "DisabledByManifest":
+		return "notifications are disabled by the app's manifest"
+	case "Injected":
+		return "notifications are blocked by an injected failure"
+	}
+	return "notifications are blocked by Windows settings (" + e.Setting + ")"
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
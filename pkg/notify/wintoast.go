@@ -0,0 +1,379 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/internal/wsl"
+)
+
+// toastRequest holds everything needed to render the toast script
+type toastRequest struct {
+	App            string // display name, see AppUserModelID
+	Title          string
+	Message        string
+	Icon           string
+	IconAlt        string // describes the icon to screen readers
+	Hero           string // image shown above the message
+	HeroAlt        string
+	Audio          string
+	SoundFile      string // wav file played once the toast is shown, with Audio silent
+	Volume         int    // of SoundFile in percent
+	Duck           bool   // lower other audio while SoundFile plays
+	Speech         string // read aloud once the toast is shown
+	Duration       string // short or long
+	Scenario       string // optional, urgent breaks through Focus Assist on Windows 11
+	ActivationType string
+	Launch         string
+	Collection     string // optional toast collection id
+	Attribution    string // small text below the message
+	Handler        string // script registering the click handlers, see Toast.Handler
+	Loop           bool   // repeat the sound while the toast is shown
+	Tag            string // identifies the toast for updates and replacement
+	Group          string
+	Progress       bool              // show a data-bound progress bar
+	Data           map[string]string // initial values for data-bound fields
+	Wait           bool              // report whether the toast was clicked, dismissed or timed out
+	Events         string            // prefix of the source identifiers of the toast's events, unique per toast
+}
+
+// hostFiles replaces the paths of the files the toast shows and plays with
+// ones the Windows host reads, inside WSL
+func (r *toastRequest) hostFiles() error {
+	var errs []error
+	for _, path := range []*string{&r.Icon, &r.Hero, &r.SoundFile} {
+		host, err := wsl.HostPath(*path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		*path = host
+	}
+	return errors.Join(errs...)
+}
+
+// newToastRequest maps a toast to the toast script
+func newToastRequest(t *Toast) *toastRequest {
+	req := &toastRequest{
+		App:            t.App,
+		Title:          t.Title,
+		Message:        t.Message,
+		Icon:           t.Icon,
+		IconAlt:        t.IconAlt,
+		Hero:           t.Image,
+		HeroAlt:        t.ImageAlt,
+		Speech:         t.Speech,
+		Duration:       "short",
+		ActivationType: "protocol",
+		Launch:         "dismiss",
+		Collection:     t.Collection,
+		Tag:            t.Tag,
+		Group:          t.Group,
+		Wait:           t.Wait,
+	}
+	if t.Urgent {
+		req.Scenario = "urgent"
+	}
+	if t.Progress != nil {
+		req.Progress = true
+		req.Data = t.Progress.data()
+	}
+
+	if t.Launch != "" {
+		req.Launch = t.Launch
+		req.Attribution = t.ClickHint
+		// Clicks go to the toast activator, which works after notify exits
+		req.ActivationType = "foreground"
+		req.Handler = t.Handler
+	} else {
+		if t.Link != "" {
+			req.Launch = t.Link
+		}
+		if t.Source != "" {
+			req.Attribution = "via " + t.Source
+		}
+	}
+
+	req.Audio = cmp.Or(t.Sound, DefaultSound(t.Type))
+	if t.Volume != nil && *t.Volume == 0 {
+		req.Audio = SoundSilent
+	}
+	req.Loop = strings.Contains(req.Audio, ".Looping.")
+
+	// Toasts play Windows' sounds at the system volume and no files, so
+	// the script plays the sound itself for anything else
+	if req.Audio != SoundSilent && (soundFile(req.Audio) || t.Volume != nil || t.Duck) {
+		req.SoundFile = mediaFile(req.Audio)
+		req.Volume = 100
+		if t.Volume != nil {
+			req.Volume = *t.Volume
+		}
+		req.Duck = t.Duck
+		req.Audio = SoundSilent
+	}
+
+	if t.Long || t.Sticky {
+		req.Duration = "long"
+	}
+	if req.Wait || req.SoundFile != "" && req.Loop {
+		id := make([]byte, 8)
+		rand.Read(id)
+		req.Events = "notify." + hex.EncodeToString(id)
+	}
+	req.Title, req.Message = layoutToastText(req.Title), layoutToastText(req.Message)
+	return req
+}
+
+var toastTemplate = template.Must(template.New("toast").Funcs(template.FuncMap{
+	"xml":  xmlEscape,
+	"ps":   powershell.Quote,
+	"app":  appScript,
+	"data": dataScript,
+}).Parse(powershell.Prelude + `
+{{app .App}}
+{{.Handler}}
+
+$template = @'
+<toast activationType="{{xml .ActivationType}}" launch="{{xml .Launch}}" duration="{{xml .Duration}}"{{if .Scenario}} scenario="{{xml .Scenario}}"{{end}}>
+    <visual>
+        <binding template="ToastGeneric">
+            {{if .Icon}}<image placement="appLogoOverride" src="{{xml .Icon}}" alt="{{xml .IconAlt}}" />{{end}}
+            {{if .Hero}}<image placement="hero" src="{{xml .Hero}}" alt="{{xml .HeroAlt}}" />{{end}}
+            {{if .Title}}<text>{{xml .Title}}</text>{{end}}
+            {{if .Message}}<text>{{xml .Message}}</text>{{end}}
+            {{if .Progress}}<progress title="{progressTitle}" value="{progressValue}" valueStringOverride="{progressValueString}" status="{progressStatus}" />{{end}}
+            {{if .Attribution}}<text placement="attribution">{{xml .Attribution}}</text>{{end}}
+        </binding>
+    </visual>
+    {{if eq .Audio "silent"}}<audio silent="true" />{{else}}<audio src="{{xml .Audio}}"{{if .Loop}} loop="true"{{end}} />{{end}}
+</toast>
+'@
+
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($template)
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+{{if .Tag}}$toast.Tag = {{ps .Tag}}{{end}}
+{{if .Group}}$toast.Group = {{ps .Group}}{{end}}
+{{if .Data}}{{data .Data 1}}
+$toast.Data = $data{{end}}
+{{if .Collection}}
+$notifier = Await ([Windows.UI.Notifications.ToastNotificationManager]::GetDefault().GetToastNotifierForToastCollectionIdAsync({{ps .Collection}})) ([Windows.UI.Notifications.ToastNotifier])
+{{else}}
+$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($APP_ID)
+{{end}}
+{{if .Events}}
+$events = {{ps .Events}}
+Register-ObjectEvent -InputObject $toast -EventName Activated -SourceIdentifier ($events + '.activated') -ErrorAction Stop | Out-Null
+Register-ObjectEvent -InputObject $toast -EventName Dismissed -SourceIdentifier ($events + '.dismissed') -ErrorAction Stop | Out-Null
+function Get-ToastEvent { Get-Event | Where-Object { $_.SourceIdentifier -like ($events + '.*') } | Select-Object -First 1 }
+{{end}}
+try {
+# Reported so notify can tell when settings keep the toast from showing
+Write-Output ("setting:" + $notifier.Setting)
+$notifier.Show($toast)
+{{if .SoundFile}}
+# Toasts of unpackaged apps can't play files, set the volume or duck
+# other audio themselves. Looping sounds repeat until the toast is gone.
+if ($notifier.Setting -eq 'Enabled') {
+{{- if .Duck}}
+    Add-Type -TypeDefinition @'
+` + duckingSource + `
+'@
+    [NotifyAudio.Ducking]::Duck(` + duckLevel + `)
+{{- end}}
+{{- if eq .Volume 100}}
+    $player = New-Object System.Media.SoundPlayer {{ps .SoundFile}}
+{{- else}}
+    Add-Type -AssemblyName PresentationCore
+    $player = New-Object System.Windows.Media.MediaPlayer
+    $player.Volume = {{.Volume}} / 100
+    $player.Open([Uri]{{ps .SoundFile}})
+{{- end}}
+    $stop = [DateTime]::Now.AddSeconds(` + waitSeconds + `)
+    try {
+        do {
+{{- if eq .Volume 100}}
+            $player.PlaySync()
+{{- else}}
+            $player.Position = [TimeSpan]::Zero
+            $player.Play()
+            while (-not $player.NaturalDuration.HasTimeSpan -and [DateTime]::Now -lt $stop) { Start-Sleep -Milliseconds 50 }
+            if (-not $player.NaturalDuration.HasTimeSpan) { break }
+            Start-Sleep -Milliseconds ([int]$player.NaturalDuration.TimeSpan.TotalMilliseconds)
+{{- end}}
+        } while ({{if .Loop}}$true{{else}}$false{{end}} -and {{if .Events}}$null -eq (Get-ToastEvent){{else}}$true{{end}} -and [DateTime]::Now -lt $stop)
+    } finally {
+{{- if .Duck}}
+        [NotifyAudio.Ducking]::Restore()
+{{- end}}
+    }
+}
+{{end}}
+{{if .Speech}}
+if ($notifier.Setting -eq 'Enabled') {
+    Add-Type -AssemblyName System.Speech
+    $voice = New-Object System.Speech.Synthesis.SpeechSynthesizer
+    $voice.Speak({{ps .Speech}})
+    $voice.Dispose()
+}
+{{end}}
+{{if .Wait}}
+# Windows only reports dismissals to a running process
+$stop = [DateTime]::Now.AddSeconds(` + waitSeconds + `)
+while ($null -eq ($event = Get-ToastEvent) -and [DateTime]::Now -lt $stop) {
+    Wait-Event -Timeout 1 | Out-Null
+}
+if ($event.SourceIdentifier -eq ($events + '.activated')) {
+    Write-Output 'interaction:clicked'
+} elseif ($event.SourceEventArgs.Reason -eq [Windows.UI.Notifications.ToastDismissalReason]::UserCanceled) {
+    Write-Output 'interaction:dismissed'
+} elseif ($event.SourceEventArgs.Reason -eq [Windows.UI.Notifications.ToastDismissalReason]::TimedOut) {
+    Write-Output 'interaction:timed out'
+}
+{{end}}
+} finally {
+{{- if .Events}}
+    # 'notify daemon' runs the next toast in the same PowerShell
+    Unregister-Event -SourceIdentifier ($events + '.activated') -ErrorAction SilentlyContinue
+    Unregister-Event -SourceIdentifier ($events + '.dismissed') -ErrorAction SilentlyContinue
+    Get-Event | Where-Object { $_.SourceIdentifier -like ($events + '.*') } | Remove-Event
+{{- end}}
+}
+`))
+
+var updateTemplate = template.Must(template.New("update").Funcs(template.FuncMap{
+	"ps":   powershell.Quote,
+	"app":  appScript,
+	"data": dataScript,
+}).Parse(powershell.Prelude + `
+{{app .App}}
+{{data .Data .Sequence}}
+$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($APP_ID)
+{{if .Group}}$notifier.Update($data, {{ps .Tag}}, {{ps .Group}}){{else}}$notifier.Update($data, {{ps .Tag}}){{end}}
+`))
+
+// toastBackend shows toasts through PowerShell, also on the Windows host
+// of WSL
+type toastBackend struct{}
+
+func (toastBackend) Show(t *Toast) (string, error) {
+	req := newToastRequest(t)
+	if wsl.Detected() {
+		if err := req.hostFiles(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: showing the toast without its files: %v\n", err)
+		}
+	}
+	script, err := buildToastScript(req)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := powershell.RunIn(t.Session, script)
+	if err == nil {
+		err = toastSetting(out)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Small delay to ensure notification is sent before program exits
+	time.Sleep(500 * time.Millisecond)
+	return toastOutcome(out), nil
+}
+
+func (toastBackend) Update(u *Update) (bool, error) {
+	var script bytes.Buffer
+	err := updateTemplate.Execute(&script, map[string]any{
+		"App":      u.App,
+		"Tag":      u.Tag,
+		"Group":    u.Group,
+		"Data":     u.Progress.data(),
+		"Sequence": u.Sequence,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	out, err := powershell.RunIn(u.Session, script.String())
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "Succeeded", nil
+}
+
+func (toastBackend) Remove(t *Toast) error {
+	script := powershell.Prelude + appScript(t.App) + fmt.Sprintf(`
+[Windows.UI.Notifications.ToastNotificationManager]::History.Remove(%s, %s, $APP_ID)
+`, powershell.Quote(t.Tag), powershell.Quote(t.Group))
+	_, err := powershell.RunIn(t.Session, script)
+	return err
+}
+
+func (toastBackend) Check() error {
+	return powershell.Check()
+}
+
+// dataScript returns PowerShell lines that create $data holding values
+func dataScript(values map[string]string, sequence uint32) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("$values = New-Object 'System.Collections.Generic.Dictionary[String, String]'\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "$values.Add(%s, %s)\n", powershell.Quote(k), powershell.Quote(values[k]))
+	}
+	fmt.Fprintf(&b, "$data = New-Object Windows.UI.Notifications.NotificationData($values, [uint32]%d)\n", sequence)
+	return b.String()
+}
+
+// toastSetting returns the error for the notifier setting in the output
+// of the toast script, or nil when toasts are enabled
+func toastSetting(out []byte) error {
+	for _, line := range strings.Split(string(out), "\n") {
+		if setting, ok := strings.CutPrefix(strings.TrimSpace(line), "setting:"); ok && setting != "Enabled" {
+			return &BlockedError{Setting: setting}
+		}
+	}
+	return nil
+}
+
+// toastOutcome returns what the user did with a toast shown with Wait,
+// "" when it isn't known
+func toastOutcome(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if action, ok := strings.CutPrefix(strings.TrimSpace(line), "interaction:"); ok {
+			return action
+		}
+	}
+	return ""
+}
+
+// buildToastScript renders the PowerShell script that shows the toast
+func buildToastScript(req *toastRequest) (string, error) {
+	var out bytes.Buffer
+	if err := toastTemplate.Execute(&out, req); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// xmlEscape escapes text for use in XML content and attributes
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToastScriptEvents(t *testing.T) {
+	first := newToastRequest(&Toast{Type: Info, Message: "x", Wait: true})
+	second := newToastRequest(&Toast{Type: Info, Message: "x", Wait: true})
+	if first.Events == "" || first.Events == second.Events {
+		t.Fatalf("events %q and %q, want a unique source identifier per toast", first.Events, second.Events)
+	}
+
+	script, err := buildToastScript(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"$events = '" + first.Events + "'",
+		"-SourceIdentifier ($events + '.activated')",
+		"} finally {",
+		"Unregister-Event -SourceIdentifier ($events + '.activated')",
+		"Unregister-Event -SourceIdentifier ($events + '.dismissed')",
+		"| Remove-Event",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("the toast script lacks %q", want)
+		}
+	}
+	if strings.Contains(script, "notify.activated") {
+		t.Error("the toast script uses the fixed notify.activated source identifier")
+	}
+
+	half := 50
+	looping := newToastRequest(&Toast{Type: Info, Message: "x", Sound: "ms-winsoundevent:Notification.Looping.Alarm", Volume: &half})
+	if looping.SoundFile == "" || looping.Events == "" {
+		t.Errorf("a looping sound played by the script has no events to stop it")
+	}
+	plain, err := buildToastScript(newToastRequest(&Toast{Type: Info, Message: "x"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plain, "Register-ObjectEvent") {
+		t.Error("a toast without --wait or a looping sound subscribes to events")
+	}
+}
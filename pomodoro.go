@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// pomodoroPhase is a work period or break of "notify pomodoro"
+type pomodoroPhase struct {
+	Title    string
+	Length   time.Duration
+	Work     bool
+	Announce string // message of the phase, shown when it starts
+}
+
+// pomodoroPlan returns the phases of rounds work periods, with a short
+// break between them and a long one after every fourth
+func pomodoroPlan(rounds int, work, short, long time.Duration, task string) []pomodoroPhase {
+	var phases []pomodoroPhase
+	for i := 1; i <= rounds; i++ {
+		phases = append(phases, pomodoroPhase{
+			Title:    fmt.Sprintf("Pomodoro %d of %d", i, rounds),
+			Length:   work,
+			Work:     true,
+			Announce: orDefault(task, "Time to focus"),
+		})
+		switch {
+		case i == rounds:
+		case i%4 == 0:
+			phases = append(phases, pomodoroPhase{Title: "Long break", Length: long, Announce: "Step away for a while"})
+		default:
+			phases = append(phases, pomodoroPhase{Title: "Break", Length: short, Announce: "Stretch, drink some water"})
+		}
+	}
+	return phases
+}
+
+// runPomodoro implements "notify pomodoro", work periods and breaks shown
+// as countdown toasts, optionally as Windows 11 focus sessions
+func runPomodoro(args []string) error {
+	opts := newNotifyOptions()
+	opts.AutoClose = false
+	work, short, long := 25*time.Minute, 5*time.Minute, 15*time.Minute
+	rounds := 4
+	focus := false
+	every := 5 * time.Second
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "work", Set: func(v string) (err error) { work, err = parseDuration(v); return }},
+		cliFlag{Name: "break", Set: func(v string) (err error) { short, err = parseDuration(v); return }},
+		cliFlag{Name: "long-break", Set: func(v string) (err error) { long, err = parseDuration(v); return }},
+		cliFlag{Name: "rounds", Set: func(v string) (err error) { rounds, err = parseCount(v); return }},
+		cliFlag{Name: "focus", Bool: true, Set: func(v string) (err error) { focus, err = parseStrictBool(v); return }},
+		cliFlag{Name: "every", Set: func(v string) (err error) { every, err = parseDuration(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showPomodoroHelp(); os.Exit(0); return nil }},
+	)
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if rounds < 1 {
+		return errors.New("--rounds must be at least 1")
+	}
+	if every < time.Second {
+		return errors.New("--every must be at least 1s")
+	}
+	for name, d := range map[string]time.Duration{"--work": work, "--break": short, "--long-break": long} {
+		if d < time.Minute {
+			return fmt.Errorf("%s must be at least 1m", name)
+		}
+	}
+	task := strings.Join(words, " ")
+	if _, err := opts.build(orDefault(task, "Pomodoro")); err != nil {
+		return err
+	}
+
+	// Ctrl+C ends the focus session started for the work period too
+	if focus {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			stopFocusSession()
+			os.Exit(130)
+		}()
+	}
+
+	tag := "pomodoro-" + newToken()
+	start := time.Now()
+	for i, phase := range pomodoroPlan(rounds, work, short, long, task) {
+		opts.Title = phase.Title
+		n, err := opts.build(phase.Announce)
+		if err != nil {
+			return err
+		}
+		end := time.Now().Add(phase.Length)
+		n.Tag = tag
+		n.Progress = countdownProgress(phase.Length, end)
+		if i > 0 {
+			// The phase changing is what the user waits for
+			n.Sound = notify.SoundDefault
+		}
+
+		if err := sendNotification(n); err != nil {
+			return err
+		}
+		// The session starts after the toast, which it may otherwise defer
+		if phase.Work && focus {
+			if err := startFocusSession(phase.Length); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: continuing without focus sessions: %v\n", err)
+				focus = false
+			}
+		}
+		fmt.Printf("%s until %s\n", phase.Title, end.Format("15:04:05"))
+		if err := followCountdown(n, phase.Length, end, every); err != nil {
+			return err
+		}
+		if phase.Work && focus {
+			if err := stopFocusSession(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	opts.Type, opts.Title, opts.AutoClose = "success", "Pomodoros done", true
+	n, err := opts.build(fmt.Sprintf("%d work periods of %s, finished after %s", rounds, formatDuration(work), formatDuration(time.Since(start))))
+	if err != nil {
+		return err
+	}
+	n.Tag = tag
+	return sendNotification(n)
+}
+
+func showPomodoroHelp() {
+	fmt.Print(`Work in pomodoros: focused periods with breaks between them
+
+Shows each work period and break as a toast counting down, which the
+next one replaces with a sound. A long break follows every fourth work
+period. With --focus, each work period is also a Windows 11 focus
+session, which turns on Do Not Disturb and hides taskbar badges as set
+up in Settings > System > Focus. To hold notify's own notifications back
+during any focus session, set 'focus_session: true' in the defer section
+of config.yaml; --urgent ones are still shown.
+
+Usage:
+  notify pomodoro [TASK] [OPTIONS]
+
+Options:
+  --work DURATION        Length of the work periods (default: 25m)
+  --break DURATION       Length of the short breaks (default: 5m)
+  --long-break DURATION  Length of the break after every fourth (default: 15m)
+  --rounds N             Work periods before stopping (default: 4)
+  --focus                Start a focus session for each work period
+  --every DURATION       How often the remaining time is updated (default: 5s)
+  Plus the notification options of 'notify --help', e.g. --app.
+
+Examples:
+  notify pomodoro "Write the design doc" --focus
+  notify pomodoro --work 50m --break 10m --rounds 2
+`)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// previewConfig enriches notifications with a preview of the first link in
+// their message
+type previewConfig struct {
+	Enabled bool          `yaml:"enabled"` // fetch previews without --preview
+	Timeout time.Duration `yaml:"timeout"` // for fetching the page
+}
+
+// Pages are only read up to their head, which holds the preview tags
+const maxPreviewPage = 512 << 10
+
+var (
+	linkPattern  = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+[^\s<>"'` + "`" + `.,;:!?)\]]`)
+	metaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern  = regexp.MustCompile(`(?s)([a-zA-Z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// linkPreview is what a page says about itself
+type linkPreview struct {
+	URL   string // the page, after redirects
+	Title string
+	Image string // absolute http or https URL
+}
+
+// previewLink adds the page title, image and link of the first link in
+// the message to n, when previews are enabled. Private messages are never
+// previewed, as their links may be one-time secrets.
+func previewLink(n *Notification, config *Config, parent *span) {
+	enabled := config.Preview.Enabled
+	if n.Preview != nil {
+		enabled = *n.Preview
+	}
+	link := linkPattern.FindString(n.Message)
+	if !enabled || n.Private || link == "" {
+		return
+	}
+
+	fetch := startSpan(parent, "preview")
+	p, err := fetchPreview(link, config.Preview.Timeout)
+	fetch.finish(err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no preview of %s: %v\n", link, err)
+		return
+	}
+
+	if n.Title == typeTitle(n.Type) && p.Title != "" {
+		n.Title = oneLine(p.Title, notify.MaxTitleLength)
+	}
+	if n.Image == "" {
+		n.Image = p.Image
+	}
+	if n.Link == "" && n.OnClick == "" {
+		n.Link = link
+	}
+}
+
+// fetchPreview reads the Open Graph title and image of a page, falling
+// back to its <title>
+func fetchPreview(link string, timeout time.Duration) (*linkPreview, error) {
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "notify link preview")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil, fmt.Errorf("not a web page")
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewPage))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &linkPreview{URL: resp.Request.URL.String()}
+	meta := map[string]string{}
+	for _, tag := range metaPattern.FindAllString(string(page), -1) {
+		attrs := map[string]string{}
+		for _, a := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3])
+		}
+		key := strings.ToLower(orDefault(attrs["property"], attrs["name"]))
+		if _, seen := meta[key]; !seen && key != "" {
+			meta[key] = strings.TrimSpace(attrs["content"])
+		}
+	}
+
+	p.Title = orDefault(meta["og:title"], meta["twitter:title"])
+	if m := titlePattern.FindStringSubmatch(string(page)); p.Title == "" && m != nil {
+		p.Title = strings.TrimSpace(html.UnescapeString(m[1]))
+	}
+	if image := orDefault(meta["og:image"], meta["twitter:image"]); image != "" {
+		if u, err := resp.Request.URL.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			p.Image = u.String()
+		}
+	}
+	if p.Title == "" && p.Image == "" {
+		return nil, fmt.Errorf("the page has no title or image")
+	}
+	return p, nil
+}
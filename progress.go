@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Default patterns recognising progress in status lines
+var (
+	percentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+	ratioPattern   = regexp.MustCompile(`(\d+)\s*(?:of|/)\s*(\d+)`)
+)
+
+// progressTracker keeps the latest state read from stdin
+type progressTracker struct {
+	mu    sync.Mutex
+	line  string
+	value float64 // negative until a line reports progress
+	label string
+	done  bool
+	err   error
+}
+
+func (t *progressTracker) set(line string, patterns []*regexp.Regexp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.line = line
+	if value, label, ok := parseProgress(line, patterns); ok {
+		t.value, t.label = value, label
+	}
+}
+
+func (t *progressTracker) finish(err error) {
+	t.mu.Lock()
+	t.done, t.err = true, err
+	t.mu.Unlock()
+}
+
+func (t *progressTracker) get() (string, float64, string, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.line, t.value, t.label, t.done, t.err
+}
+
+// parseProgress finds the progress reported by line. A pattern with one
+// group matches a percentage, one with two groups matches "done of total".
+func parseProgress(line string, patterns []*regexp.Regexp) (float64, string, bool) {
+	for _, re := range patterns {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch len(m) {
+		case 2:
+			pct, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			return max(pct, 0) / 100, strconv.FormatFloat(pct, 'f', -1, 64) + "%", true
+		case 3:
+			done, err1 := strconv.ParseFloat(m[1], 64)
+			total, err2 := strconv.ParseFloat(m[2], 64)
+			if err1 != nil || err2 != nil || total <= 0 {
+				continue
+			}
+			return max(done, 0) / total, m[1] + " of " + m[2], true
+		}
+	}
+	return 0, "", false
+}
+
+// compilePattern parses a --pattern regex, which needs one or two groups
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if n := re.NumSubexp(); n != 1 && n != 2 {
+		return nil, fmt.Errorf("pattern needs 1 group (percent) or 2 groups (done, total), got %d", n)
+	}
+	return re, nil
+}
+
+// runProgress implements "notify progress", a live status card for a job
+// that writes its progress to stdin
+func runProgress(args []string) error {
+	opts := newNotifyOptions()
+	opts.AutoClose = false
+	tag := ""
+	every := 5 * time.Second
+	quiet := false
+	patterns := []*regexp.Regexp{percentPattern, ratioPattern}
+	custom := false
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "tag", Set: func(v string) error { tag = v; return nil }},
+		cliFlag{Name: "every", Set: func(v string) (err error) { every, err = parseDuration(v); return }},
+		cliFlag{Name: "pattern", Set: func(v string) error {
+			re, err := compilePattern(v)
+			if err != nil {
+				return err
+			}
+			if !custom {
+				patterns, custom = nil, true
+			}
+			patterns = append(patterns, re)
+			return nil
+		}},
+		cliFlag{Name: "quiet", Bool: true, Set: func(v string) (err error) { quiet, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showProgressHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if every < time.Second {
+		return fmt.Errorf("--every must be at least 1s")
+	}
+	if opts.Title == "" {
+		opts.Title = "Running"
+	}
+
+	message := strings.Join(words, " ")
+	if message == "" {
+		message = "Job in progress"
+	}
+	n, err := opts.build(message)
+	if err != nil {
+		return err
+	}
+	if tag == "" {
+		tag = "progress-" + newToken()
+	}
+	n.Tag = tag
+
+	start := time.Now()
+	n.Progress = stopwatchProgress(start, "Starting...", -1, "")
+	if err := sendNotification(n); err != nil {
+		return err
+	}
+
+	// Read status lines in the background, passing them through
+	tracker := &progressTracker{value: -1}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !quiet {
+				fmt.Println(line)
+			}
+			if strings.TrimSpace(line) != "" {
+				tracker.set(line, patterns)
+			}
+		}
+		err := scanner.Err()
+		if err == io.EOF {
+			err = nil
+		}
+		tracker.finish(err)
+	}()
+
+	if err := tracker.follow(n, start, every); err != nil {
+		return err
+	}
+	line, _, _, _, readErr := tracker.get()
+	return finishProgress(n, start, line, readErr)
+}
+
+// follow updates the status card of n with the tracked state every so
+// often until the tracker is done. Updates stop once the toast is gone;
+// cards shown by a relay can't be updated.
+func (t *progressTracker) follow(n *Notification, start time.Time, every time.Duration) error {
+	visible := n.Remote == ""
+	sequence := uint32(1)
+	next := time.Now().Add(every)
+	for {
+		line, value, label, done, _ := t.get()
+		if done {
+			return nil
+		}
+
+		if visible && time.Now().After(next) {
+			var err error
+			sequence++
+			visible, err = updateToast(n, stopwatchProgress(start, line, value, label), sequence)
+			if err != nil {
+				return err
+			}
+			next = time.Now().Add(every)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// stopwatchProgress shows the elapsed time and latest status line, plus
+// the reported progress and an estimate of the time left once known
+func stopwatchProgress(start time.Time, line string, value float64, label string) *notify.Progress {
+	elapsed := time.Since(start)
+	p := &notify.Progress{
+		Value:  value,
+		Label:  formatDuration(elapsed) + " elapsed",
+		Status: oneLine(line, 80),
+	}
+	if value > 0 {
+		p.Label = label + " · " + p.Label
+		if value < 1 {
+			left := time.Duration(float64(elapsed) * (1 - value) / value)
+			p.Label += ", about " + formatDuration(left) + " left"
+		}
+	}
+	return p
+}
+
+// finishProgress replaces the status card with a final notification
+func finishProgress(n *Notification, start time.Time, line string, readErr error) error {
+	elapsed := formatDuration(time.Since(start))
+
+	n.Progress = nil
+	n.Sticky = false
+	if readErr != nil {
+		n.Type = "error"
+		n.Title = "Failed"
+		n.Message = fmt.Sprintf("Reading status failed after %s: %v", elapsed, readErr)
+	} else {
+		n.Type = "success"
+		n.Title = "Finished"
+		n.Message = fmt.Sprintf("Finished after %s", elapsed)
+		if line != "" {
+			n.Message += "\n" + oneLine(line, 120)
+		}
+	}
+
+	if err := sendNotification(n); err != nil {
+		return err
+	}
+	return readErr
+}
+
+func showProgressHelp() {
+	fmt.Print(`Show a live status card for a long job, fed from stdin
+
+Every few seconds the toast is updated with the elapsed time and the
+latest line read from stdin; when stdin closes it is replaced with a
+final "Finished" notification. Input is passed through to stdout.
+
+Lines like "42%" or "Downloaded 42 of 100" drive the progress bar. Use
+--pattern for other formats: a regex with one group matches a percentage,
+one with two groups matches the done and total counts.
+
+Usage:
+  COMMAND | notify progress [MESSAGE] [OPTIONS]
+
+Options:
+  --tag TAG          Identifies the toast (default: generated)
+  --every DURATION   How often the toast is updated (default: 5s)
+  --pattern REGEX    How progress is read from lines (repeatable)
+  --quiet            Don't echo stdin to stdout
+  Plus the notification options of 'notify --help', e.g. --title.
+
+Examples:
+  ./backup.sh 2>&1 | notify progress "Nightly backup" --title Backup --tag backup
+  rsync --info=progress2 src/ dst/ | notify progress "Syncing" --pattern "(\d+)%"
+  ./convert.sh | notify progress --pattern "frame (\d+)/(\d+)"
+`)
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QR codes are encoded in byte mode with medium error correction, which
+// phone cameras read reliably off a screen. Versions up to 10 hold 213
+// bytes, plenty for links and otpauth: URIs.
+const maxQRVersion = 10
+
+// qrBlocks is the error correction layout of a version at level M
+type qrBlocks struct {
+	ecPerBlock int
+	groups     [2]struct{ blocks, dataPerBlock int }
+}
+
+var qrVersions = [maxQRVersion + 1]qrBlocks{
+	1:  {10, [2]struct{ blocks, dataPerBlock int }{{1, 16}}},
+	2:  {16, [2]struct{ blocks, dataPerBlock int }{{1, 28}}},
+	3:  {26, [2]struct{ blocks, dataPerBlock int }{{1, 44}}},
+	4:  {18, [2]struct{ blocks, dataPerBlock int }{{2, 32}}},
+	5:  {24, [2]struct{ blocks, dataPerBlock int }{{2, 43}}},
+	6:  {16, [2]struct{ blocks, dataPerBlock int }{{4, 27}}},
+	7:  {18, [2]struct{ blocks, dataPerBlock int }{{4, 31}}},
+	8:  {22, [2]struct{ blocks, dataPerBlock int }{{2, 38}, {2, 39}}},
+	9:  {22, [2]struct{ blocks, dataPerBlock int }{{3, 36}, {2, 37}}},
+	10: {26, [2]struct{ blocks, dataPerBlock int }{{4, 43}, {1, 44}}},
+}
+
+// Centers of the alignment patterns by version
+var qrAlignment = [maxQRVersion + 1][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// dataCodewords returns the number of data bytes a version holds
+func (b qrBlocks) dataCodewords() int {
+	return b.groups[0].blocks*b.groups[0].dataPerBlock + b.groups[1].blocks*b.groups[1].dataPerBlock
+}
+
+// qrCode is a QR symbol, true for dark modules
+type qrCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool // finder, timing, alignment and format modules
+}
+
+// encodeQR encodes data in the smallest version that holds it
+func encodeQR(data []byte) (*qrCode, error) {
+	version := 0
+	for v := 1; v <= maxQRVersion; v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*qrVersions[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("QR code payload is %d bytes, the maximum is 213", len(data))
+	}
+
+	q := &qrCode{size: 17 + 4*version}
+	q.modules = make([][]bool, q.size)
+	q.function = make([][]bool, q.size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, q.size)
+		q.function[i] = make([]bool, q.size)
+	}
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(qrCodewords(data, version))
+
+	// Use the mask that is easiest to scan
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+	return q, nil
+}
+
+// set draws a function module at column x and row y
+func (q *qrCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFunctionPatterns draws everything but the data
+func (q *qrCode) drawFunctionPatterns(version int) {
+	for i := range q.size {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {q.size - 4, 3}, {3, q.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < q.size && y >= 0 && y < q.size {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+
+	centers := qrAlignment[version]
+	last := len(centers) - 1
+	for i, cx := range centers {
+		for j, cy := range centers {
+			// The finder patterns take these corners
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format modules, drawn once the mask is chosen
+	q.drawFormat(0)
+
+	if version >= 7 {
+		rem := version
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := range 18 {
+			dark := bits>>i&1 != 0
+			a, b := q.size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormat draws both copies of the error correction level and mask
+func (q *qrCode) drawFormat(mask int) {
+	data := 0<<3 | mask // level M is 00
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := range 6 {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+// qrCodewords returns the data and error correction bytes of data,
+// interleaved in the order they are drawn
+func qrCodewords(data []byte, version int) []byte {
+	layout := qrVersions[version]
+	capacity := layout.dataCodewords()
+
+	var bits []bool
+	add := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, value>>i&1 != 0)
+		}
+	}
+	add(0b0100, 4)
+	if version >= 10 {
+		add(len(data), 16)
+	} else {
+		add(len(data), 8)
+	}
+	for _, b := range data {
+		add(int(b), 8)
+	}
+	add(0, min(4, 8*capacity-len(bits)))
+	add(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+
+	// Split into blocks, each with its own error correction
+	divisor := rsDivisor(layout.ecPerBlock)
+	var blocks, ecc [][]byte
+	for _, g := range layout.groups {
+		for range g.blocks {
+			block := codewords[:g.dataPerBlock]
+			codewords = codewords[g.dataPerBlock:]
+			blocks = append(blocks, block)
+			ecc = append(ecc, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	for _, parts := range [][][]byte{blocks, ecc} {
+		longest := len(parts[len(parts)-1])
+		for i := range longest {
+			for _, part := range parts {
+				if i < len(part) {
+					out = append(out, part[i])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// rsMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func rsMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of a degree,
+// without its leading 1
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = rsMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = rsMultiply(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction bytes of data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= rsMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// drawCodewords fills the data modules in the zigzag order of the spec
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range q.size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by a mask; applying it again
+// undoes it
+func (q *qrCode) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to scan: long runs, blocks of one
+// color, patterns that look like finders and an unbalanced share of dark
+// modules
+func (q *qrCode) penalty() int {
+	score, dark := 0, 0
+	finder := []bool{true, false, true, true, true, false, true}
+	for a := range q.size {
+		for _, line := range []func(int) bool{
+			func(b int) bool { return q.modules[a][b] },
+			func(b int) bool { return q.modules[b][a] },
+		} {
+			run := 1
+			for b := 1; b < q.size; b++ {
+				if line(b) == line(b-1) {
+					run++
+					if run == 5 {
+						score += 3
+					} else if run > 5 {
+						score++
+					}
+				} else {
+					run = 1
+				}
+			}
+			for b := 0; b+len(finder) <= q.size; b++ {
+				match := true
+				for k, want := range finder {
+					if line(b+k) != want {
+						match = false
+						break
+					}
+				}
+				lightBefore := b < 4 || !line(b-1) && !line(b-2) && !line(b-3) && !line(b-4)
+				after := b + len(finder)
+				lightAfter := after+4 > q.size || !line(after) && !line(after+1) && !line(after+2) && !line(after+3)
+				if match && (lightBefore || lightAfter) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	for y := range q.size {
+		for x := range q.size {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := q.modules[y][x]
+				if q.modules[y-1][x] == c && q.modules[y][x-1] == c && q.modules[y-1][x-1] == c {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := q.size * q.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return score + max(k, 0)*10
+}
+
+// qrImage renders data as a QR code PNG, centered on a white 2:1 canvas
+// so toasts, which crop hero images to that shape, show all of it
+func qrImage(data []byte) ([]byte, error) {
+	q, err := encodeQR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	const scale = 8
+	side := (q.size + 8) * scale // with the 4 module quiet zone
+	img := image.NewGray(image.Rect(0, 0, 2*side, side))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	left := side/2 + 4*scale
+	for y := range q.size {
+		for x := range q.size {
+			if !q.modules[y][x] {
+				continue
+			}
+			for dy := range scale {
+				for dx := range scale {
+					img.SetGray(left+x*scale+dx, 4*scale+y*scale+dy, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
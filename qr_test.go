@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeQRVersion(t *testing.T) {
+	// Byte mode capacities at level M, from the tables of ISO/IEC 18004
+	tests := []struct {
+		length  int
+		version int // 0 when too long
+	}{
+		{0, 1}, {14, 1}, {15, 2}, {26, 2}, {27, 3}, {42, 3}, {43, 4}, {62, 4},
+		{63, 5}, {84, 5}, {106, 6}, {122, 7}, {152, 8}, {180, 9}, {181, 10},
+		{213, 10}, {214, 0},
+	}
+	for _, tt := range tests {
+		q, err := encodeQR(bytes.Repeat([]byte("a"), tt.length))
+		if tt.version == 0 {
+			if err == nil || !strings.Contains(err.Error(), "maximum is 213") {
+				t.Errorf("%d bytes: encodeQR() error = %v, want too long", tt.length, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d bytes: %v", tt.length, err)
+		}
+		if want := 17 + 4*tt.version; q.size != want {
+			t.Errorf("%d bytes: size %d, want version %d of size %d", tt.length, q.size, tt.version, want)
+		}
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// Version 1-M codewords of "01234567" in numeric mode, from ISO/IEC
+	// 18004 Annex I, and of "HELLO WORLD" in alphanumeric mode
+	tests := []struct {
+		data string
+		ecc  string
+	}{
+		{"10 20 0C 56 61 80 EC 11 EC 11 EC 11 EC 11 EC 11", "A5 24 D4 C1 ED 36 C7 87 2C 55"},
+		{"20 5B 0B 78 D1 72 DC 4D 43 40 EC 11 EC 11 EC 11", "C4 23 27 77 EB D7 E7 E2 5D 17"},
+	}
+	for _, tt := range tests {
+		if got := rsRemainder(unhex(t, tt.data), rsDivisor(10)); !bytes.Equal(got, unhex(t, tt.ecc)) {
+			t.Errorf("rsRemainder(%s) = % X, want %s", tt.data, got, tt.ecc)
+		}
+	}
+}
+
+func TestQRCodewords(t *testing.T) {
+	// Mode 0100, length 5, "hello", the terminator and padding
+	data := unhex(t, "40 56 86 56 C6 C6 F0 EC 11 EC 11 EC 11 EC 11 EC")
+	got := qrCodewords([]byte("hello"), 1)
+	if !bytes.Equal(got[:16], data) || !bytes.Equal(got[16:], rsRemainder(data, rsDivisor(10))) {
+		t.Fatalf("qrCodewords() = % X, want the data % X and its error correction", got, data)
+	}
+
+	// Versions with several blocks take a byte from each in turn
+	got = qrCodewords(bytes.Repeat([]byte{0xAA}, 60), 4)
+	block := func(b []byte) []byte {
+		out := []byte{}
+		for i := 0; i < len(b); i += 2 {
+			out = append(out, b[i])
+		}
+		return out
+	}
+	first, second := block(got[:64]), block(got[1:64])
+	if !bytes.Equal(block(got[64:]), rsRemainder(first, rsDivisor(18))) ||
+		!bytes.Equal(block(got[65:]), rsRemainder(second, rsDivisor(18))) {
+		t.Fatalf("qrCodewords() = % X, want two interleaved blocks", got)
+	}
+}
+
+func TestQRFormat(t *testing.T) {
+	// Format information of level M by mask, from ISO/IEC 18004 Annex C
+	want := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	for mask, bits := range want {
+		q, _ := encodeQR(nil)
+		q.drawFormat(mask)
+
+		// Both copies, from the most significant bit
+		var first, second strings.Builder
+		module := func(b *strings.Builder, x, y int) {
+			if q.modules[y][x] {
+				b.WriteByte('1')
+			} else {
+				b.WriteByte('0')
+			}
+		}
+		for i := 14; i >= 9; i-- {
+			module(&first, 14-i, 8)
+		}
+		module(&first, 7, 8)
+		module(&first, 8, 8)
+		module(&first, 8, 7)
+		for i := 5; i >= 0; i-- {
+			module(&first, 8, i)
+		}
+		for i := 14; i >= 8; i-- {
+			module(&second, 8, q.size-15+i)
+		}
+		for i := 7; i >= 0; i-- {
+			module(&second, q.size-1-i, 8)
+		}
+		if first.String() != bits || second.String() != bits {
+			t.Errorf("mask %d: format %s and %s, want %s", mask, first.String(), second.String(), bits)
+		}
+	}
+}
+
+func TestQRMasks(t *testing.T) {
+	// The top left corner of each mask, from ISO/IEC 18004 Figure 23
+	want := [][]string{
+		{"#.#.#.", ".#.#.#", "#.#.#.", ".#.#.#", "#.#.#.", ".#.#.#"},
+		{"######", "......", "######", "......", "######", "......"},
+		{"#..#..", "#..#..", "#..#..", "#..#..", "#..#..", "#..#.."},
+		{"#..#..", "..#..#", ".#..#.", "#..#..", "..#..#", ".#..#."},
+		{"###...", "###...", "...###", "...###", "###...", "###..."},
+		{"######", "#.....", "#..#..", "#.#.#.", "#..#..", "#....."},
+		{"######", "###...", "##.##.", "#.#.#.", "#.##.#", "#...##"},
+		{"#.#.#.", "...###", "#...##", ".#.#.#", "###...", ".###.."},
+	}
+	for mask, rows := range want {
+		q := &qrCode{size: 6, modules: make([][]bool, 6), function: make([][]bool, 6)}
+		for y := range 6 {
+			q.modules[y], q.function[y] = make([]bool, 6), make([]bool, 6)
+		}
+		q.function[0][0] = true
+		q.applyMask(mask)
+		for y, row := range rows {
+			for x, c := range row {
+				if dark := c == '#' && (x > 0 || y > 0); q.modules[y][x] != dark {
+					t.Errorf("mask %d: module %d,%d dark = %v, want %v", mask, x, y, q.modules[y][x], dark)
+				}
+			}
+		}
+
+		// Masking again undoes it
+		q.applyMask(mask)
+		for y := range 6 {
+			for x := range 6 {
+				if q.modules[y][x] {
+					t.Fatalf("mask %d: module %d,%d still dark after undoing the mask", mask, x, y)
+				}
+			}
+		}
+	}
+}
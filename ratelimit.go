@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket per client: each client may send burst
+// requests at once, refilled at rate requests per second
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	clients map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows perMinute requests per minute with bursts of
+// burst requests; a zero perMinute disables limiting
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(max(burst, 1)),
+		clients: map[string]*bucket{},
+	}
+}
+
+// allow takes a token for client, or returns how long until one is
+// available
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b := l.clients[client]
+	if b == nil {
+		if len(l.clients) >= 10000 {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := math.Ceil((1 - b.tokens) / l.rate)
+	return false, time.Duration(wait) * time.Second
+}
+
+// sweep forgets clients whose bucket has refilled, as they are
+// indistinguishable from new clients
+func (l *rateLimiter) sweep(now time.Time) {
+	for client, b := range l.clients {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.clients, client)
+		}
+	}
+}
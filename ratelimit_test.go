@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		burst   int
+		idle    time.Duration // before the last request
+		allowed int           // of burst+1 requests, then after idle
+		wait    time.Duration // until the last rejected request would pass
+	}{
+		{"burst then rejected", 3, 0, 3, 10 * time.Second},
+		{"burst of one", 1, 0, 1, 10 * time.Second},
+		{"refilled by one", 3, 10 * time.Second, 4, 10 * time.Second},
+		{"partly refilled", 3, 5 * time.Second, 3, 5 * time.Second},
+		{"refilled up to the burst", 3, time.Hour, 6, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(6, tt.burst) // a request every 10s
+			allowed := 0
+			for range tt.burst + 1 {
+				if ok, _ := l.allow("a"); ok {
+					allowed++
+				}
+			}
+
+			// Idle time is simulated by moving the last refill back
+			var wait time.Duration
+			if tt.idle > 0 {
+				l.clients["a"].last = l.clients["a"].last.Add(-tt.idle)
+				for range tt.burst + 1 {
+					ok, w := l.allow("a")
+					if !ok {
+						wait = w
+						break
+					}
+					allowed++
+				}
+			} else {
+				_, wait = l.allow("a")
+			}
+			if allowed != tt.allowed || wait != tt.wait {
+				t.Fatalf("allowed %d, wait %s, want %d, %s", allowed, wait, tt.allowed, tt.wait)
+			}
+
+			// Other clients have buckets of their own
+			if ok, _ := l.allow("b"); !ok {
+				t.Fatal("another client was rejected")
+			}
+		})
+	}
+
+	if l := newRateLimiter(0, 5); l != nil {
+		t.Fatalf("newRateLimiter(0, 5) = %+v, want no limit", l)
+	}
+}
@@ -0,0 +1,651 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/ecdh"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Relays refuse messages that passed through this many relays, which
+// stops forwarding loops between misconfigured hubs
+const maxRelayHops = 5
+
+// relayServer accepts notifications over HTTP and forwards or shows them
+type relayServer struct {
+	token         string                   // required from clients when set
+	targets       []string                 // relays to forward to; empty shows toasts locally
+	key           *ecdh.PrivateKey         // opens encrypted messages, nil without a key
+	requireSealed bool                     // reject unencrypted messages
+	verify        verifier                 // signatures required from clients
+	sentrySecret  []byte                   // client secret signing Sentry webhooks
+	out           *relaySender             // credentials for the targets
+	limit         *rateLimiter             // per client limit, nil for none
+	maxBody       int64                    // largest accepted request body
+	maxAttachment int64                    // largest accepted attachment, on top of maxBody
+	publicURL     string                   // address of the relay in hosted attachment links
+	origins       []string                 // pages allowed to call the relay, see cors
+	formats       map[string]*targetFormat // profiles of targets, by --to value
+	openAPI       []byte                   // rendered OpenAPI document
+	keepInbound   int                      // webhook payloads stored for 'notify inbound'
+	backend       string                   // of config.yaml, checked by /readyz
+	started       time.Time
+	queued        atomic.Int32 // notifications waiting to be shown
+	displayMu     sync.Mutex
+}
+
+// runRelay implements "notify relay"
+func runRelay(args []string) error {
+	listen, pipe := "", ""
+	keyFile := ""
+	signSecret, signKey, toToken := "", "", ""
+	certFile, certKey, clientCA := "", "", ""
+	rate, burst := 30, 10
+	var files tlsFiles
+	srv := &relayServer{token: os.Getenv("NOTIFY_TOKEN"), maxBody: 64 << 10, maxAttachment: maxAttachmentSize, keepInbound: 50, started: time.Now()}
+	srv.verify.secret = []byte(os.Getenv("NOTIFY_SIGNING_SECRET"))
+	srv.sentrySecret = []byte(os.Getenv("NOTIFY_SENTRY_SECRET"))
+
+	flags := []cliFlag{
+		{Name: "listen", Set: func(v string) error { listen = v; return nil }},
+		{Name: "token", Set: func(v string) error { srv.token = v; return nil }},
+		{Name: "pipe", Set: func(v string) error { pipe = strings.TrimPrefix(v, `\\.\pipe\`); return nil }},
+		{Name: "to", Set: func(v string) error {
+			if _, err := notify.RelayEndpoint(v); err != nil {
+				return err
+			}
+			srv.targets = append(srv.targets, v)
+			return nil
+		}},
+		{Name: "to-token", Set: func(v string) error { toToken = v; return nil }},
+		{Name: "key", Set: func(v string) error { keyFile = v; return nil }},
+		{Name: "require-encryption", Bool: true, Set: func(v string) (err error) { srv.requireSealed, err = parseStrictBool(v); return }},
+		{Name: "verify-secret", Set: func(v string) error { srv.verify.secret = []byte(v); return nil }},
+		{Name: "verify-key", Set: func(v string) error {
+			key, err := parseVerifyKey(v)
+			if err != nil {
+				return err
+			}
+			srv.verify.keys = append(srv.verify.keys, key)
+			return nil
+		}},
+		{Name: "sentry-secret", Set: func(v string) error { srv.sentrySecret = []byte(v); return nil }},
+		{Name: "sign-secret", Set: func(v string) error { signSecret = v; return nil }},
+		{Name: "sign-key", Set: func(v string) error { signKey = v; return nil }},
+		{Name: "rate", Set: func(v string) (err error) { rate, err = parseCount(v); return }},
+		{Name: "burst", Set: func(v string) (err error) { burst, err = parseGroupSize(v); return }},
+		{Name: "allow-origin", Set: func(v string) error {
+			origin, err := parseOrigin(v)
+			srv.origins = append(srv.origins, origin)
+			return err
+		}},
+		{Name: "max-body", Set: func(v string) (err error) { srv.maxBody, err = parseSize(v); return }},
+		{Name: "max-attachment", Set: func(v string) (err error) { srv.maxAttachment, err = parseSize(v); return }},
+		{Name: "keep-inbound", Set: func(v string) (err error) { srv.keepInbound, err = parseCount(v); return }},
+		{Name: "public-url", Set: func(v string) error {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("--public-url %q is not an http or https URL", v)
+			}
+			srv.publicURL = v
+			return nil
+		}},
+		{Name: "tls-cert", Set: func(v string) error { certFile = v; return nil }},
+		{Name: "tls-key", Set: func(v string) error { certKey = v; return nil }},
+		{Name: "client-ca", Set: func(v string) error { clientCA = v; return nil }},
+		{Name: "ca", Set: func(v string) error { files.CA = v; return nil }},
+		{Name: "client-cert", Set: func(v string) error { files.Cert = v; return nil }},
+		{Name: "client-key", Set: func(v string) error { files.Key = v; return nil }},
+		{Name: "openapi", Bool: true, Set: func(string) error { writeOpenAPI(os.Stdout); os.Exit(0); return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showRelayHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	if srv.key, err = loadOptionalKey(keyFile); err != nil {
+		return err
+	}
+	if srv.requireSealed && srv.key == nil && len(srv.targets) == 0 {
+		return fmt.Errorf("--require-encryption needs a key, run 'notify keygen' first")
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if srv.formats, err = config.Relay.targetFormats(srv.targets); err != nil {
+		return err
+	}
+	srv.backend = config.Backend
+	for _, target := range srv.targets {
+		if f := srv.formats[target]; f != nil {
+			log.Printf("Formatting notifications to %s as %s", target, f.name)
+			if f.attachments == "link" && srv.publicURL == "" {
+				log.Printf("Warning: attachments to %s are dropped, set --public-url to link them", target)
+			}
+		}
+	}
+
+	srv.limit = newRateLimiter(rate, burst)
+	if srv.out, err = newRelaySender(toToken, signSecret, signKey, files); err != nil {
+		return err
+	}
+	tlsConfig, err := serverTLSConfig(certFile, certKey, clientCA)
+	if err != nil {
+		return err
+	}
+
+	// Without credentials only programs of this machine may send
+	if srv.token == "" && !srv.verify.enabled() && clientCA == "" {
+		if listen == "" {
+			listen = "127.0.0.1:8787"
+		} else if !isLoopback(listen) {
+			return fmt.Errorf("without --token, --verify-secret, --verify-key or --client-ca the relay only listens on this machine, e.g. on 127.0.0.1:8787, not on %s", listen)
+		}
+	}
+	listen = cmp.Or(listen, ":8787")
+
+	var doc bytes.Buffer
+	writeOpenAPI(&doc)
+	srv.openAPI = doc.Bytes()
+
+	mux := http.NewServeMux()
+	for _, route := range relayRoutes {
+		mux.HandleFunc(route.Method+" "+route.Path, route.Handler(srv))
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           srv.cors(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         tlsConfig,
+	}
+
+	if len(srv.targets) == 0 {
+		go srv.catchUpLoop()
+	}
+	if pipe != "" {
+		go srv.servePipe(pipe)
+		log.Printf(`Reading notifications from \\.\pipe\%s`, pipe)
+	}
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	if len(srv.targets) > 0 {
+		log.Printf("Relay listening on %s://%s, forwarding to %s", scheme, listen, strings.Join(srv.targets, ", "))
+	} else {
+		log.Printf("Relay listening on %s://%s, showing notifications locally", scheme, listen)
+	}
+
+	if tlsConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// handleNotify serves POST /notify, tracing the request as a continuation
+// of the sender's trace
+func (s *relayServer) handleNotify(w http.ResponseWriter, r *http.Request) {
+	trace := startRemoteSpan(r.Header.Get("traceparent"), "relay.notify", "client.address", remoteHost(r))
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.notify(rec, r, trace)
+
+	trace.set("http.response.status_code", strconv.Itoa(rec.status))
+	var err error
+	if rec.status >= 400 {
+		err = errors.New(http.StatusText(rec.status))
+	}
+	trace.finish(err)
+	go flushTraces()
+}
+
+// statusRecorder remembers the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// notify authenticates, decodes and delivers a notification
+func (s *relayServer) notify(w http.ResponseWriter, r *http.Request, trace *span) {
+	// Guest tokens are checked once the type of the notification is known
+	guest := ""
+	if !s.authorized(r) {
+		guest = guestBearer(r)
+	}
+	if !s.authorized(r) && guest == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="notify"`)
+		http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if s.limit != nil {
+		if ok, wait := s.limit.allow(remoteHost(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+			http.Error(w, "too many notifications, slow down", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	// Reading, verifying and decoding the request is traced as parsing
+	parse := startSpan(trace, "parse")
+	parsed := false
+	defer func() {
+		if !parsed {
+			parse.finish(errors.New("invalid request"))
+		}
+	}()
+
+	body, err := s.readBody(w, r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Signatures cover the raw body, so check them before decoding
+	if s.verify.enabled() {
+		if err := s.verify.verify(r, body); err != nil {
+			log.Printf("%s: rejected: %v", remoteHost(r), err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var m notify.RelayMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if m.Sealed == nil && !s.checkSize(w, &m) {
+		return
+	}
+	if m.Hops >= maxRelayHops {
+		http.Error(w, "too many relay hops, check for a forwarding loop", http.StatusLoopDetected)
+		return
+	}
+
+	// Encrypted messages are passed on unread, only the last relay opens them
+	if m.Sealed != nil && len(s.targets) > 0 {
+		parsed = true
+		parse.finish(nil)
+		if _, ok := s.allowGuest(w, guest, ""); !ok {
+			return
+		}
+		if err := s.forward(&m, trace); err != nil {
+			log.Printf("%s: encrypted notification failed: %v", remoteHost(r), err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		log.Printf("%s: encrypted notification forwarded", remoteHost(r))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if m.Sealed != nil {
+		if s.key == nil {
+			http.Error(w, "this relay has no key for encrypted notifications, run 'notify keygen'", http.StatusBadRequest)
+			return
+		}
+		inner, err := openSealed(m.Sealed, s.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.checkSize(w, inner) {
+			return
+		}
+		inner.Hops = m.Hops
+		m = *inner
+	} else if s.requireSealed {
+		http.Error(w, "this relay only accepts encrypted notifications, send with --encrypt-to", http.StatusBadRequest)
+		return
+	}
+	// The sender's own account of where it is can't be trusted
+	m.Source = remoteHost(r)
+
+	n, err := receivedNotification(&m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parsed = true
+	parse.finish(nil)
+	n.trace = trace
+	name, ok := s.allowGuest(w, guest, n.Type)
+	if !ok {
+		return
+	}
+	if name != "" {
+		m.Source = name + " at " + m.Source
+		n.Source = m.Source
+	}
+
+	if err := s.deliver(&m, n); err != nil {
+		log.Printf("%s: %q failed: %v", m.Source, n.Title, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("%s: %q delivered", m.Source, n.Title)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readBody reads a request body of up to --max-body bytes. Bodies with an
+// attachment, or sealed ones that may hold one, may be larger by the
+// encoded --max-attachment; the rest of them is checked once decoded.
+func (s *relayServer) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	attachLimit := s.maxBody + int64(base64.StdEncoding.EncodedLen(int(s.maxAttachment)))
+	sealedLimit := s.maxBody + int64(base64.StdEncoding.EncodedLen(int(attachLimit)))
+	body := http.MaxBytesReader(w, r.Body, sealedLimit)
+
+	start, err := io.ReadAll(io.LimitReader(body, s.maxBody+1))
+	if err != nil || int64(len(start)) <= s.maxBody {
+		return start, err
+	}
+	limit := int64(0)
+	switch largeField(start) {
+	case "attachment":
+		limit = attachLimit
+	case "sealed":
+		limit = sealedLimit
+	default:
+		return nil, &http.MaxBytesError{Limit: s.maxBody}
+	}
+	rest, err := io.ReadAll(io.LimitReader(body, limit-int64(len(start))+1))
+	if err == nil && int64(len(start)+len(rest)) > limit {
+		err = &http.MaxBytesError{Limit: limit}
+	}
+	return append(start, rest...), err
+}
+
+// largeField returns "attachment" or "sealed" when the start of a request
+// body has one of these fields, before the end of the start is reached
+func largeField(start []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(start))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return ""
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if key, _ := t.(string); key == "attachment" || key == "sealed" {
+			return key
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
+// checkSize answers with 413 when a message is larger than --max-body
+// without its attachment, or the attachment is larger than
+// --max-attachment
+func (s *relayServer) checkSize(w http.ResponseWriter, m *notify.RelayMessage) bool {
+	if m.Attachment != nil && int64(len(m.Attachment.Data)) > s.maxAttachment {
+		http.Error(w, fmt.Sprintf("attachment is larger than %d bytes", s.maxAttachment), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	bare := *m
+	bare.Attachment = nil
+	if data, _ := json.Marshal(bare); int64(len(data)) > s.maxBody {
+		http.Error(w, fmt.Sprintf("request body is larger than %d bytes", s.maxBody), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	return true
+}
+
+// authorized checks the bearer token of a request
+func (s *relayServer) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1
+}
+
+// allowGuest counts a notification of type t sent with a guest token,
+// returning the name of the token, and answers the request when the token
+// may not send it. Encrypted messages forwarded unread have no type, and
+// need a token for any type.
+func (s *relayServer) allowGuest(w http.ResponseWriter, guest, t string) (string, bool) {
+	if guest == "" {
+		return "", true
+	}
+	name, err := useGuestToken(guest, t)
+	if err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, errGuestUnknown) {
+			status = http.StatusUnauthorized
+			w.Header().Set("WWW-Authenticate", `Bearer realm="notify"`)
+		}
+		http.Error(w, err.Error(), status)
+		return "", false
+	}
+	return name, true
+}
+
+// deliver forwards a message to the targets, or shows it locally when
+// there are none
+func (s *relayServer) deliver(m *notify.RelayMessage, n *Notification) error {
+	if len(s.targets) == 0 {
+		// Toasts share temporary icon files, so show one at a time
+		s.queued.Add(1)
+		defer s.queued.Add(-1)
+		s.displayMu.Lock()
+		defer s.displayMu.Unlock()
+		return sendNotification(n)
+	}
+	return s.forward(m, n.trace)
+}
+
+// catchUpLoop shows the catch-up summary once notifications are no longer
+// deferred, without waiting for the next one to arrive
+func (s *relayServer) catchUpLoop() {
+	for range time.Tick(time.Minute) {
+		config, err := loadConfig()
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		s.displayMu.Lock()
+		count, err := catchUp(config, nil, false)
+		s.displayMu.Unlock()
+		if err != nil {
+			log.Printf("Warning: could not show the catch-up summary: %v", err)
+		} else if count > 0 {
+			log.Printf("Summarized %d deferred notifications", count)
+		}
+	}
+}
+
+// forward sends a message to every target, succeeding if any accepted it
+func (s *relayServer) forward(m *notify.RelayMessage, trace *span) error {
+	route := startSpan(trace, "route", "notify.route", "forward")
+	route.finish(nil)
+
+	forward := *m
+	forward.Hops++
+
+	// Attachments linked instead of sent are hosted once for all targets
+	var host func(*notify.Attachment) (string, error)
+	if s.publicURL != "" {
+		hosted := ""
+		host = func(a *notify.Attachment) (link string, err error) {
+			if hosted == "" {
+				hosted, err = s.hostAttachment(a)
+			}
+			return hosted, err
+		}
+	}
+
+	var errs []error
+	for _, target := range s.targets {
+		out, err := s.formats[target].apply(&forward, host)
+		if err == nil {
+			err = s.out.post(target, out, trace)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(s.targets) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Printf("Warning: %v", err)
+	}
+	return nil
+}
+
+// isLoopback reports whether a listen address only accepts connections
+// from this machine
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// remoteHost returns the address of the client that sent a request
+func remoteHost(r *http.Request) string {
+	host := r.RemoteAddr
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		host = host[:i]
+	}
+	return strings.Trim(host, "[]")
+}
+
+func showRelayHelp() {
+	fmt.Print(`Run a relay that accepts notifications from other machines
+
+The relay listens for notifications sent with 'notify --remote' and
+forwards them to the --to relays, or shows them on this machine when
+there are none. Senders only need the relay's token; the relay holds the
+credentials of its targets.
+
+Signed requests carry an X-Notify-Timestamp header with the Unix time, an
+X-Notify-Nonce header with a random value and an X-Notify-Signature header
+with "sha256=HEX" (HMAC-SHA256) and/or "ed25519=BASE64" signatures over
+the method, path, timestamp, nonce and body, each followed by a newline
+but the body. When a secret or key is set, unsigned and tampered requests
+are rejected, as are replays: a timestamp more than five minutes off, or
+a nonce accepted before.
+
+Notifications sent with --encrypt-to are forwarded unread and only
+decrypted by the relay that shows them, using the key from 'notify keygen'.
+
+The relay section of config.yaml can give each --to relay a formatting
+profile (full, plain, terse or a custom template) applied when forwarding.
+Attachments are forwarded, dropped or replaced by a link to a copy the
+relay serves under --public-url, depending on the profile.
+
+Usage:
+  notify relay [OPTIONS]
+  notify serve [OPTIONS]    (the same, e.g. 'notify serve --listen :8686 --token T')
+
+Options:
+  --listen ADDR      Address to listen on (default: :8787, or 127.0.0.1:8787
+                     without --token, --verify-* or --client-ca, which other
+                     addresses need)
+  --token TOKEN      Token senders must present (default: $NOTIFY_TOKEN)
+  --to URL           Forward to this relay instead of showing toasts (repeatable)
+  --to-token TOKEN   Token presented to the --to relays
+  --verify-secret S  Require requests signed with this HMAC secret (default: $NOTIFY_SIGNING_SECRET)
+  --verify-key KEY   Require requests signed by this Ed25519 public key (repeatable)
+  --sentry-secret S  Require Sentry webhooks signed with this client secret
+                     (default: $NOTIFY_SENTRY_SECRET)
+  --sign-secret S    Sign requests to the --to relays with this HMAC secret
+  --sign-key FILE    Sign requests to the --to relays with this Ed25519 key
+  --rate N           Notifications per minute per client, 0 for no limit (default: 30)
+  --burst N          Notifications a client may send at once (default: 10)
+  --max-body SIZE    Largest accepted request, without its attachment and once
+                     decrypted, e.g. 64KB or 1MB (default: 64KB)
+  --max-attachment SIZE
+                     Largest accepted --attach file, on top of --max-body (default: 8MB)
+  --keep-inbound N   Webhook payloads kept for 'notify inbound', 0 for none
+                     (default: 50)
+  --public-url URL   Address of this relay, for links to attachments that
+                     formatting profiles don't forward
+  --allow-origin ORIGIN
+                     Let web pages from ORIGIN, like http://localhost:3000,
+                     call the relay; * for any (repeatable)
+  --pipe NAME        Also read notifications written to \\.\pipe\NAME (Windows)
+  --tls-cert FILE    Serve HTTPS with this certificate
+  --tls-key FILE     Private key of --tls-cert
+  --client-ca FILE   Require client certificates signed by this CA (mutual TLS)
+  --ca FILE          Trust this CA for the --to relays instead of the system roots
+  --client-cert FILE Client certificate presented to the --to relays
+  --client-key FILE  Private key of --client-cert
+  --openapi          Print the OpenAPI document of the API and exit
+  --key FILE         Private key for encrypted notifications (default: the 'notify keygen' key)
+  --require-encryption
+                     Reject notifications not sent with --encrypt-to
+
+Sending to a relay:
+  notify "Backup done" --remote hub.lan:8787 --token secret
+
+API:
+  POST /notify with a JSON body like
+  {"type": "success", "title": "Backup", "message": "Backup done"}
+  and the header "Authorization: Bearer TOKEN".
+  POST /hooks/SERVICE turns webhooks of github, gitlab, grafana, sentry
+  and uptime-kuma into toasts; add ?token=TOKEN to the webhook URL, or
+  use the token as the GitHub webhook secret. POST /sentry is the same
+  as /hooks/sentry. 'notify inbound' lists, shows and replays the
+  payloads received.
+  GET /interactions lists what was done with the toasts shown here.
+  GET /openapi.json describes the API for generating clients.
+  Web pages can call the API from the --allow-origin origins.
+  GET /healthz and GET /readyz are liveness and readiness probes.
+
+Pipe:
+  Each line written to the --pipe is a notification: MESSAGE,
+  TYPE|MESSAGE or TYPE|TITLE|MESSAGE, e.g.
+  echo error^|Disk full > \\.\pipe\notify
+  Only local programs of this user or administrators can write to it,
+  and it shares the --rate limit.
+
+Examples:
+  notify relay --token secret
+  notify relay --token secret --to https://desktop.lan:8787 --to https://laptop.lan:8787 --to-token other
+`)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+func TestRelayReadBody(t *testing.T) {
+	// Attachments may add 40 encoded bytes to the 64 of the body, sealed
+	// bodies the 140 of an encoded body with an attachment
+	s := &relayServer{maxBody: 64, maxAttachment: 30}
+	body := func(start string, size int) string {
+		return start + strings.Repeat("x", size-len(start)-2) + `"}`
+	}
+	tests := []struct {
+		name  string
+		body  string
+		limit int64 // of the error, 0 when the body is read
+	}{
+		{"empty", "", 0},
+		{"at the cap", body(`{"message":"`, 64), 0},
+		{"over the cap", body(`{"message":"`, 65), 64},
+		{"attachment at the cap", body(`{"attachment":{"data":""},"message":"`, 104), 0},
+		{"attachment over the cap", body(`{"attachment":{"data":""},"message":"`, 105), 104},
+		{"attachment after the cap", body(`{"message":"`+strings.Repeat("x", 60)+`","attachment":{"data":"`, 100), 64},
+		{"sealed over the attachment cap", body(`{"sealed":{"data":"`, 105), 0},
+		{"sealed at the cap", body(`{"sealed":{"data":"`, 204), 0},
+		{"sealed over the cap", body(`{"sealed":{"data":"`, 205), 204},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/notify", strings.NewReader(tt.body))
+			got, err := s.readBody(httptest.NewRecorder(), r)
+			var tooLarge *http.MaxBytesError
+			switch {
+			case tt.limit == 0 && err != nil:
+				t.Fatalf("readBody() error = %v", err)
+			case tt.limit == 0 && string(got) != tt.body:
+				t.Fatalf("readBody() = %q, want the whole body", got)
+			case tt.limit != 0 && (!errors.As(err, &tooLarge) || tooLarge.Limit != tt.limit):
+				t.Fatalf("readBody() error = %v, want larger than %d bytes", err, tt.limit)
+			}
+		})
+	}
+}
+
+func TestRelayCheckSize(t *testing.T) {
+	s := &relayServer{maxBody: 64, maxAttachment: 30}
+	empty, err := json.Marshal(notify.RelayMessage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := func(size int) string { return strings.Repeat("x", size-len(empty)) }
+	tests := []struct {
+		name string
+		m    notify.RelayMessage
+		ok   bool
+	}{
+		{"at the cap", notify.RelayMessage{Message: message(64)}, true},
+		{"over the cap", notify.RelayMessage{Message: message(65)}, false},
+		{"attachment at its cap", notify.RelayMessage{Message: message(64), Attachment: &notify.Attachment{Data: make([]byte, 30)}}, true},
+		{"attachment over its cap", notify.RelayMessage{Message: "x", Attachment: &notify.Attachment{Data: make([]byte, 31)}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if ok := s.checkSize(w, &tt.m); ok != tt.ok {
+				t.Fatalf("checkSize() = %v, want %v", ok, tt.ok)
+			}
+			if !tt.ok && w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status %d, want 413", w.Code)
+			}
+		})
+	}
+}
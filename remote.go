@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// relaySender posts messages to relays with one set of credentials
+type relaySender struct {
+	token  string
+	sign   *signer
+	client *http.Client
+}
+
+// newRelaySender loads the signing key and certificates for sending
+func newRelaySender(token, signSecret, signKey string, files tlsFiles) (*relaySender, error) {
+	sign, err := newSigner(signSecret, signKey)
+	if err != nil {
+		return nil, err
+	}
+	config, err := files.config()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	if config != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		client.Transport = transport
+	}
+	return &relaySender{token: token, sign: sign, client: client}, nil
+}
+
+// newRelayMessage converts a notification for sending to a relay
+func newRelayMessage(n *Notification) *notify.RelayMessage {
+	autoClose := !n.Sticky
+	return &notify.RelayMessage{
+		Type:       n.Type,
+		Title:      n.Title,
+		Message:    n.Message,
+		Timeout:    int(n.Timeout / time.Second),
+		AutoClose:  &autoClose,
+		App:        n.App,
+		Category:   normalizeCategory(n.Category),
+		Group:      n.Group,
+		GroupSize:  n.GroupSize,
+		Fallback:   n.Fallback,
+		Urgent:     n.Urgent,
+		Private:    n.Private,
+		Wake:       int(n.Wake / time.Second),
+		Link:       n.Link,
+		Image:      n.Image,
+		Tag:        n.Tag,
+		Volume:     n.Volume,
+		Duck:       n.Duck,
+		Speak:      n.Speak,
+		Attachment: n.Attachment,
+	}
+}
+
+// notification validates a received message, applying the defaults of
+// the command line options
+func receivedNotification(m *notify.RelayMessage) (*Notification, error) {
+	opts := newNotifyOptions()
+	if m.Type != "" {
+		opts.Type = m.Type
+	}
+	if m.Timeout != 0 {
+		opts.Timeout = m.Timeout
+	}
+	if m.AutoClose != nil {
+		opts.AutoClose = *m.AutoClose
+	}
+	opts.Title = m.Title
+	opts.App = strings.TrimSpace(m.App)
+	opts.Category = normalizeCategory(m.Category)
+	opts.Group = strings.TrimSpace(m.Group)
+	opts.GroupSize = m.GroupSize
+	fallback, err := parseFallback(m.Fallback)
+	if err != nil {
+		return nil, err
+	}
+	opts.Fallback = fallback
+	opts.Urgent = m.Urgent
+	opts.Private = m.Private
+	opts.Wake = time.Duration(m.Wake) * time.Second
+	if m.Volume != nil && (*m.Volume < 0 || *m.Volume > 100) {
+		return nil, fmt.Errorf("volume must be 0-100, got %d", *m.Volume)
+	}
+	opts.Volume = m.Volume
+	opts.Duck = m.Duck
+	opts.Speak = m.Speak
+
+	n, err := opts.build(m.Message)
+	if err != nil {
+		return nil, err
+	}
+	n.Link, n.Image, n.Tag = m.Link, m.Image, m.Tag
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	if m.Attachment != nil {
+		if err := m.Attachment.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	n.Attachment = m.Attachment
+	n.Source = m.Source
+	return n, nil
+}
+
+// post sends a message to a relay, authenticating with the token and
+// signing the request when set. The request is traced below parent.
+func (s *relaySender) post(relay string, m *notify.RelayMessage, parent *span) (err error) {
+	endpoint, err := notify.RelayEndpoint(relay)
+	if err != nil {
+		return err
+	}
+
+	deliver := startSpan(parent, "deliver", "notify.target", endpoint)
+	defer func() { deliver.finish(err) }()
+	if deliver != nil {
+		deliver.kind = spanClient
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	if s.sign != nil {
+		s.sign.sign(req, body)
+	}
+	if deliver != nil {
+		req.Header.Set("traceparent", deliver.traceparent())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("relay %s answered %s: %s", endpoint, resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
+
+// sendRemote delivers a notification through the relay in n.Remote, or
+// publishes it to the ntfy topic of an ntfy:// n.Remote, encrypting it to
+// n.EncryptTo when set
+func sendRemote(n *Notification, parent *span) error {
+	m := newRelayMessage(n)
+	if n.EncryptTo != "" {
+		recipient, err := parsePublicKey(n.EncryptTo)
+		if err != nil {
+			return err
+		}
+		sealed, err := sealMessage(m, recipient)
+		if err != nil {
+			return err
+		}
+		m = &notify.RelayMessage{Sealed: sealed}
+	}
+	if strings.HasPrefix(n.Remote, "ntfy://") {
+		t, err := parseNtfyTopic(n.Remote)
+		if err != nil {
+			return err
+		}
+		return publishNtfy(t, cmp.Or(n.Token, os.Getenv("NTFY_TOKEN")), m, parent)
+	}
+
+	sender, err := newRelaySender(n.Token, n.SignSecret, n.SignKey, n.TLS)
+	if err != nil {
+		return err
+	}
+	return sender.post(n.Remote, m, parent)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// Waiting "notify when" processes check their schedule this often, so
+// cancelling or pausing it takes effect within this time
+const schedulePoll = 15 * time.Second
+
+// errScheduleCancelled stops a "notify when" cancelled with "notify schedule cancel"
+var errScheduleCancelled = errors.New("cancelled")
+
+// scheduleRecord tracks a notification waiting in "notify when"
+type scheduleRecord struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Message  string        `json:"message"`
+	When     string        `json:"when"`             // like "--at tomorrow 9am"
+	Zone     string        `json:"zone"`             // of --at, Local for this machine's
+	Repeat   string        `json:"repeat,omitempty"` // daily or weekdays
+	Jitter   time.Duration `json:"jitter,omitempty"`
+	CatchUp  string        `json:"catch_up,omitempty"` // fire or skip missed occurrences
+	Holidays bool          `json:"holidays,omitempty"` // holidays are skipped
+	Next     time.Time     `json:"next"`
+	Paused   bool          `json:"paused,omitempty"`
+	PID      int           `json:"pid"` // of the waiting process
+	Created  time.Time     `json:"created"`
+}
+
+// addSchedule records a waiting notification under id, or under the
+// lowest free number when id is empty, and returns the id
+func addSchedule(id string, schedule *whenSchedule, when string, n *Notification, next time.Time) (string, error) {
+	err := updateState(func(s *State) error {
+		if s.Schedules == nil {
+			s.Schedules = map[string]*scheduleRecord{}
+		}
+		if id == "" {
+			for i := 1; id == ""; i++ {
+				if _, taken := s.Schedules[strconv.Itoa(i)]; !taken {
+					id = strconv.Itoa(i)
+				}
+			}
+		} else if record, taken := s.Schedules[id]; taken && processRunning(record.PID) {
+			return fmt.Errorf("schedule %q already exists, cancel it first or choose another --id", id)
+		}
+		s.Schedules[id] = &scheduleRecord{
+			Type:     n.Type,
+			Title:    n.Title,
+			Message:  n.storedMessage(),
+			When:     when,
+			Zone:     schedule.Location.String(),
+			Repeat:   schedule.Repeat,
+			Jitter:   schedule.Jitter,
+			CatchUp:  schedule.CatchUp,
+			Holidays: schedule.Holidays != nil,
+			Next:     next,
+			PID:      os.Getpid(),
+			Created:  time.Now(),
+		}
+		return nil
+	})
+	return id, err
+}
+
+// removeScheduleOnInterrupt forgets the schedule when Ctrl+C stops the
+// waiting process
+func removeScheduleOnInterrupt(id string) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		removeSchedule(id)
+		os.Exit(130)
+	}()
+}
+
+// removeSchedule forgets a schedule owned by this process
+func removeSchedule(id string) {
+	err := updateState(func(s *State) error {
+		if record, ok := s.Schedules[id]; ok && record.PID == os.Getpid() {
+			delete(s.Schedules, id)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// setScheduleNext records the next occurrence of a repeating schedule
+func setScheduleNext(id string, next time.Time) error {
+	return updateState(func(s *State) error {
+		if record, ok := s.Schedules[id]; ok {
+			record.Next = next
+		}
+		return nil
+	})
+}
+
+// waitForSchedule sleeps until the wall clock reaches t and returns the
+// schedule as it is then. A paused one-time schedule is held until it is
+// resumed, while a repeating one is returned paused so the occurrence is
+// skipped. The wall clock is read every poll because the monotonic clock
+// stops while the machine sleeps.
+func waitForSchedule(id string, t time.Time) (*scheduleRecord, error) {
+	t = t.Round(0)
+	for {
+		state, err := loadState()
+		if err != nil {
+			return nil, err
+		}
+		record, ok := state.Schedules[id]
+		if !ok || record.PID != os.Getpid() {
+			return nil, errScheduleCancelled
+		}
+
+		remaining := time.Until(t)
+		if remaining <= 0 && (!record.Paused || record.Repeat != "") {
+			return record, nil
+		}
+		time.Sleep(min(max(remaining, 0)+time.Millisecond, schedulePoll))
+	}
+}
+
+// runSchedule implements "notify schedule list|show|cancel|pause|resume"
+func runSchedule(args []string) error {
+	flags := []cliFlag{
+		{Name: "help", Bool: true, Set: func(string) error { showScheduleHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 || words[0] == "list" {
+		if len(words) > 1 {
+			return fmt.Errorf("unexpected argument: %s", words[1])
+		}
+		return listSchedules()
+	}
+
+	actions := []string{"list", "show", "cancel", "pause", "resume"}
+	action, ids := words[0], words[1:]
+	if len(ids) == 0 {
+		return fmt.Errorf("usage: notify schedule %s ID...", action)
+	}
+	switch action {
+	case "show":
+		return showSchedules(ids)
+	case "cancel", "pause", "resume":
+		return changeSchedules(action, ids)
+	}
+	return fmt.Errorf("unknown schedule command %q.%s Commands are: %s", action, suggest.DidYouMean(action, actions, ""), strings.Join(actions, ", "))
+}
+
+// liveSchedules returns the schedules whose process is still waiting,
+// removing those that ended without cleaning up, e.g. at a restart
+func liveSchedules() (map[string]*scheduleRecord, error) {
+	var live map[string]*scheduleRecord
+	err := updateState(func(s *State) error {
+		for id, record := range s.Schedules {
+			if !processRunning(record.PID) {
+				delete(s.Schedules, id)
+			}
+		}
+		live = s.Schedules
+		return nil
+	})
+	return live, err
+}
+
+// listSchedules prints the waiting notifications, soonest first
+func listSchedules() error {
+	schedules, err := liveSchedules()
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		fmt.Println("Nothing scheduled")
+		return nil
+	}
+
+	ids := make([]string, 0, len(schedules))
+	for id := range schedules {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return schedules[ids[i]].Next.Before(schedules[ids[j]].Next)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNEXT\tSTATE\tREPEAT\tTITLE\tMESSAGE")
+	for _, id := range ids {
+		record := schedules[id]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, record.Next.Local().Format("2006-01-02 15:04"), record.state(),
+			orDefault(record.Repeat, "-"), oneLine(record.Title, 30), oneLine(record.Message, 50))
+	}
+	return w.Flush()
+}
+
+// showSchedules prints the details of schedules
+func showSchedules(ids []string) error {
+	schedules, err := liveSchedules()
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		record, ok := schedules[id]
+		if !ok {
+			return fmt.Errorf("no schedule with id %q", id)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		next := record.Next
+		if loc, err := time.LoadLocation(record.Zone); err == nil {
+			next = next.In(loc)
+		}
+		fmt.Printf("ID:       %s\n", id)
+		fmt.Printf("Title:    %s\n", record.Title)
+		fmt.Printf("Message:  %s\n", record.Message)
+		fmt.Printf("Type:     %s\n", record.Type)
+		fmt.Printf("When:     %s\n", record.When)
+		repeat := orDefault(record.Repeat, "no")
+		if record.Holidays {
+			repeat += ", skipping holidays"
+		}
+		fmt.Printf("Repeat:   %s\n", repeat)
+		if record.Jitter > 0 {
+			fmt.Printf("Jitter:   up to %s\n", formatDuration(record.Jitter))
+		}
+		fmt.Printf("Catch-up: %s\n", orDefault(record.CatchUp, "fire"))
+		fmt.Printf("Next:     %s\n", describeWhen(next))
+		fmt.Printf("State:    %s (process %d)\n", record.state(), record.PID)
+		fmt.Printf("Created:  %s\n", record.Created.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// changeSchedules cancels, pauses or resumes schedules
+func changeSchedules(action string, ids []string) error {
+	if _, err := liveSchedules(); err != nil {
+		return err
+	}
+	err := updateState(func(s *State) error {
+		for _, id := range ids {
+			record, ok := s.Schedules[id]
+			if !ok {
+				return fmt.Errorf("no schedule with id %q", id)
+			}
+			switch action {
+			case "cancel":
+				delete(s.Schedules, id)
+			case "pause":
+				record.Paused = true
+			case "resume":
+				record.Paused = false
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	done := map[string]string{"cancel": "Cancelled", "pause": "Paused", "resume": "Resumed"}[action]
+	for _, id := range ids {
+		fmt.Printf("%s %s\n", done, id)
+	}
+	return nil
+}
+
+// state describes whether the schedule is waiting or paused
+func (r *scheduleRecord) state() string {
+	switch {
+	case r.Paused && !r.Next.After(time.Now()) && r.Repeat == "":
+		return "paused, due"
+	case r.Paused:
+		return "paused"
+	}
+	return "waiting"
+}
+
+func showScheduleHelp() {
+	fmt.Print(`Manage notifications waiting in 'notify when'
+
+Each 'notify when' records its notification until it is shown, so it
+can be looked at and changed from another terminal.
+
+Usage:
+  notify schedule [list]        List waiting notifications, soonest first
+  notify schedule show ID...    Show the details of notifications
+  notify schedule cancel ID...  Cancel notifications; their 'notify when' exits
+  notify schedule pause ID...   Hold notifications: a one-time one is shown
+                                once resumed, a repeating one skips its
+                                occurrences while paused
+  notify schedule resume ID...  Resume paused notifications
+
+Changes take effect within 15 seconds. Ids are numbers unless chosen
+with 'notify when --id ID'.
+
+Example:
+  notify when --tz Asia/Karachi --at 09:00 "Team is online" --repeat weekdays --id standup
+  notify schedule pause standup
+`)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "syscall"
+
+// Exit code of a process that hasn't exited
+const stillActive = 259
+
+// processRunning reports whether a process with the id exists
+func processRunning(pid int) bool {
+	process, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// Access is denied to processes of other users, which do exist
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(process)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(process, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
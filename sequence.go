@@ -0,0 +1,240 @@
+package main
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// sequenceFile is the YAML document played by "notify sequence". It is
+// either a list of steps or a mapping with shared settings and steps.
+type sequenceFile struct {
+	App      string         `yaml:"app"`
+	Category string         `yaml:"category"`
+	Steps    []sequenceStep `yaml:"steps"`
+}
+
+// sequenceStep is one notification of a sequence
+type sequenceStep struct {
+	Type        string        `yaml:"type"`
+	Title       string        `yaml:"title"`
+	Message     string        `yaml:"message"`
+	Timeout     int           `yaml:"timeout"`
+	Delay       time.Duration `yaml:"delay"`        // pause before showing the step
+	Wait        string        `yaml:"wait"`         // "click" waits until the toast is clicked
+	WaitTimeout time.Duration `yaml:"wait_timeout"` // give up waiting after this long
+}
+
+// runSequence implements "notify sequence FILE"
+func runSequence(args []string) error {
+	dryRun := false
+
+	flags := []cliFlag{
+		{Name: "dry-run", Bool: true, Set: func(v string) (err error) { dryRun, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showSequenceHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return errors.New("usage: notify sequence FILE [--dry-run]")
+	}
+
+	seq, err := loadSequence(words[0])
+	if err != nil {
+		return err
+	}
+
+	notifications := make([]*Notification, len(seq.Steps))
+	for i, step := range seq.Steps {
+		n, err := step.notification(seq)
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		notifications[i] = n
+	}
+
+	for i, step := range seq.Steps {
+		n := notifications[i]
+		if dryRun {
+			fmt.Printf("Step %d (after %s, wait: %s)\n", i+1, step.Delay, orDefault(step.Wait, "none"))
+			printDryRun(n)
+			continue
+		}
+
+		if step.Delay > 0 {
+			time.Sleep(step.Delay)
+		}
+
+		var token string
+		if step.Wait == "click" {
+			token = newToken()
+			n.OnClick = activationURI("continue", token)
+			n.ClickHint = "Click to continue"
+		}
+
+		fmt.Printf("[%d/%d] %s\n", i+1, len(seq.Steps), oneLine(n.Message, 60))
+		if err := sendNotification(n); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		if token != "" {
+			if err := waitForClick(token, step.WaitTimeout); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadSequence reads and checks a sequence file
+func loadSequence(path string) (*sequenceFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// A bare list is shorthand for a file with only steps
+	if list, ok := tree.([]any); ok {
+		tree = map[string]any{"steps": list}
+	}
+
+	seq := &sequenceFile{}
+	if err := decodeYAML(reflectValue(seq), tree, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(seq.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+	return seq, nil
+}
+
+// notification builds and validates the notification for a step
+func (s sequenceStep) notification(seq *sequenceFile) (*Notification, error) {
+	nType := orDefault(strings.ToLower(s.Type), "info")
+	if !isValidType(nType) {
+		return nil, fmt.Errorf("invalid type %q.%s", s.Type, suggest.DidYouMean(s.Type, notify.Types, ""))
+	}
+	if s.Wait != "" && s.Wait != "click" {
+		return nil, fmt.Errorf("invalid wait %q, the only gate is \"click\"", s.Wait)
+	}
+
+	n := &Notification{Notification: notify.Notification{
+		Type:     nType,
+		Title:    orDefault(strings.TrimSpace(s.Title), typeTitle(nType)),
+		Message:  strings.TrimSpace(s.Message),
+		Timeout:  time.Duration(cmp.Or(s.Timeout, 5)) * time.Second,
+		Sticky:   s.Wait == "click",
+		App:      seq.App,
+		Category: normalizeCategory(seq.Category),
+	}}
+	return n, n.validate()
+}
+
+// waitForClick blocks until the toast launched with the token is clicked
+func waitForClick(token string, timeout time.Duration) error {
+	marker, err := clickMarker(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("      waiting for a click...")
+	start := time.Now()
+	for {
+		if _, err := os.Stat(marker); err == nil {
+			os.Remove(marker)
+			return nil
+		}
+		if timeout > 0 && time.Since(start) > timeout {
+			return fmt.Errorf("not clicked within %s", timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// markClicked records a click for waitForClick
+func markClicked(token string) error {
+	marker, err := clickMarker(token)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(marker, nil, 0600)
+}
+
+// clickMarker returns the file recording a click on the toast with the
+// token. The token arrives in notify: URIs any page can open, so only
+// tokens made by newToken are accepted.
+func clickMarker(token string) (string, error) {
+	if !isToken(token) {
+		return "", fmt.Errorf("invalid click token %q", token)
+	}
+	return dataFile("clicked-" + token)
+}
+
+// isToken reports whether s has the form returned by newToken
+func isToken(s string) bool {
+	if len(s) != 16 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newToken returns a random identifier
+func newToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// orDefault returns s, or def when s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func showSequenceHelp() {
+	fmt.Print(`Play an ordered series of notifications from a YAML file
+
+Usage:
+  notify sequence FILE [--dry-run]
+
+Options:
+  --dry-run   Check the file and print the steps without notifying
+
+File format:
+  app: Onboarding            # optional sender name, see --app
+  steps:
+    - title: Welcome
+      message: Let's get your machine ready
+    - message: Install the VPN client, then click this notification
+      type: warning
+      wait: click            # wait until the toast is clicked
+      wait_timeout: 10m      # optional
+    - message: All done!
+      type: success
+      delay: 3s              # pause before this step
+`)
+}
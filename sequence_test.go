@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkClickedRejectsForeignTokens(t *testing.T) {
+	isolate(t)
+	dir, err := dataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	victim := filepath.Join(filepath.Dir(dir), "victim.txt")
+	if err := os.WriteFile(victim, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"../victim.txt", "..%2Fvictim.txt", "", "0123456789ABCDEF", "0123456789abcde", "0123456789abcdef0"} {
+		if err := markClicked(token); err == nil {
+			t.Errorf("markClicked(%q) was accepted", token)
+		}
+	}
+	if data, _ := os.ReadFile(victim); string(data) != "keep" {
+		t.Fatalf("victim = %q, want it untouched", data)
+	}
+
+	token := newToken()
+	if err := markClicked(token); err != nil {
+		t.Fatalf("markClicked(%q) = %v", token, err)
+	}
+	if err := handleActivation(activationURI("continue", "../../victim.txt")); err == nil {
+		t.Error("notify:continue with a path was accepted")
+	}
+}
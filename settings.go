@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sarfraznawaz2005/notify/internal/powershell"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// settingsScript reads the notification settings of $APP_ID. Registry
+// values that were never changed are missing, which means on.
+const settingsScript = `
+$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($APP_ID)
+$key = 'HKCU:\Software\Microsoft\Windows\CurrentVersion'
+$push = Get-ItemProperty "$key\PushNotifications" -ErrorAction SilentlyContinue
+$global = Get-ItemProperty "$key\Notifications\Settings" -ErrorAction SilentlyContinue
+$app = Get-ItemProperty "$key\Notifications\Settings\$APP_ID" -ErrorAction SilentlyContinue
+ConvertTo-Json -Compress -InputObject ([PSCustomObject]@{
+    Setting      = [string]$notifier.Setting
+    Banners      = $app.ShowBanner
+    ActionCenter = $app.ShowInActionCenter
+    Sounds       = $global.NOC_GLOBAL_SETTING_ALLOW_NOTIFICATION_SOUND
+    LockScreen   = $push.LockScreenToastEnabled
+})
+`
+
+// notificationSettings are the Windows settings deciding whether and how
+// the toasts of an app are shown
+type notificationSettings struct {
+	App          string `json:"app"`
+	AppID        string `json:"app_id"`
+	Setting      string `json:"setting"`       // NotificationSetting of the toast notifier
+	Banners      bool   `json:"banners"`       // toasts pop up, instead of only going to Action Center
+	ActionCenter bool   `json:"action_center"` // toasts are kept in Action Center
+	Sounds       bool   `json:"sounds"`        // notification sounds, for all apps
+	LockScreen   bool   `json:"lock_screen"`   // toasts are shown on the lock screen, for all apps
+	FocusAssist  string `json:"focus_assist,omitempty"`
+	FocusSession bool   `json:"focus_session"`
+}
+
+// runSettings implements "notify settings"
+func runSettings(args []string) error {
+	app := ""
+	asJSON, open := false, false
+
+	flags := []cliFlag{
+		{Name: "app", Set: func(v string) error { app = strings.TrimSpace(v); return nil }},
+		{Name: "json", Bool: true, Set: func(v string) (err error) { asJSON, err = parseStrictBool(v); return }},
+		{Name: "open", Bool: true, Set: func(v string) (err error) { open, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showSettingsHelp(); os.Exit(0); return nil }},
+	}
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	s, err := readNotificationSettings(app)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else if err := printNotificationSettings(s); err != nil {
+		return err
+	}
+
+	if open {
+		// The per-app switches are on the list of senders of this page
+		if _, err := powershell.Run("Start-Process 'ms-settings:notifications'"); err != nil {
+			return fmt.Errorf("cannot open Settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// readNotificationSettings returns the notification settings of an app
+// set with --app
+func readNotificationSettings(app string) (*notificationSettings, error) {
+	id := notify.AppUserModelID(app)
+	out, err := powershell.Run(powershell.Prelude + "$APP_ID = " + powershell.Quote(id) + "\n" + settingsScript)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the notification settings: %w", err)
+	}
+
+	var raw struct {
+		Setting      string
+		Banners      *int
+		ActionCenter *int
+		Sounds       *int
+		LockScreen   *int
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &raw); err != nil {
+		return nil, fmt.Errorf("unexpected settings output: %w", err)
+	}
+	on := func(v *int) bool { return v == nil || *v != 0 }
+	return &notificationSettings{
+		App:          notify.AppName(app),
+		AppID:        id,
+		Setting:      raw.Setting,
+		Banners:      on(raw.Banners),
+		ActionCenter: on(raw.ActionCenter),
+		Sounds:       on(raw.Sounds),
+		LockScreen:   on(raw.LockScreen),
+		FocusAssist:  focusAssist(),
+		FocusSession: focusSessionActive(),
+	}, nil
+}
+
+// printNotificationSettings prints the settings with what they mean for
+// notify's toasts
+func printNotificationSettings(s *notificationSettings) error {
+	onOff := func(on bool, off string) string {
+		if on {
+			return "on"
+		}
+		return "off, " + off
+	}
+	notifications := "on"
+	if s.Setting != "Enabled" {
+		notifications = "off, " + (&notify.BlockedError{Setting: s.Setting}).Error()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "App:\t%s (%s)\n", s.App, s.AppID)
+	fmt.Fprintf(w, "Notifications:\t%s\n", notifications)
+	fmt.Fprintf(w, "Banners:\t%s\n", onOff(s.Banners, "toasts only go to Action Center"))
+	fmt.Fprintf(w, "Action Center:\t%s\n", onOff(s.ActionCenter, "toasts are gone once their banner closes"))
+	fmt.Fprintf(w, "Sounds:\t%s\n", onOff(s.Sounds, "for all apps"))
+	fmt.Fprintf(w, "Lock screen:\t%s\n", onOff(s.LockScreen, "for all apps"))
+	fmt.Fprintf(w, "Do not disturb:\t%s\n", orDefault(s.FocusAssist, "off"))
+	if s.FocusSession {
+		fmt.Fprintf(w, "Focus session:\trunning, banners may be held back until it ends\n")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if s.Setting != "Enabled" || !s.Banners || s.FocusAssist != "" {
+		fmt.Println("\nToasts won't pop up. Run 'notify settings --open' to change this in Settings.")
+	}
+	return nil
+}
+
+func showSettingsHelp() {
+	fmt.Print(`Show the Windows settings deciding whether notify's toasts are shown
+
+Reports whether notifications, banners and Action Center are on for
+notify's app, whether notification sounds and toasts on the lock screen
+are on, and whether Do Not Disturb or a focus session is active. Useful
+when toasts don't appear.
+
+Usage:
+  notify settings [--app NAME] [--json] [--open]
+
+Options:
+  --app NAME   Show the settings of an app set with --app
+  --json       Print the settings as JSON for scripts
+  --open       Also open Settings > System > Notifications
+`)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// shortenConfig replaces long links in messages with short ones, so they
+// don't take up a toast or blow the length limit of an SMS
+type shortenConfig struct {
+	Enabled   bool          `yaml:"enabled"`    // shorten without --shorten
+	MinLength int           `yaml:"min_length"` // links this long or longer are shortened
+	API       string        `yaml:"api"`        // shortener URL with {url}, empty for the daemon's
+	Listen    string        `yaml:"listen"`     // where 'notify daemon' serves its short links
+	Timeout   time.Duration `yaml:"timeout"`    // for calling the API
+}
+
+// Short links of the daemon are kept this long
+const shortLinkAge = 30 * 24 * time.Hour
+
+// shortLink is a link shortened by the daemon
+type shortLink struct {
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+}
+
+// validate checks the shorten section of config.yaml
+func (c *shortenConfig) validate() error {
+	if c.MinLength < 1 {
+		return errors.New("shorten: min_length must be at least 1")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("shorten: the timeout must be positive")
+	}
+	if c.API != "" {
+		u, err := url.Parse(c.API)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.Contains(c.API, "{url}") {
+			return fmt.Errorf("shorten: the api %q must be an http or https URL containing {url}", c.API)
+		}
+	}
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("shorten: invalid listen address %q: %w", c.Listen, err)
+	}
+	return nil
+}
+
+// shortenLinks replaces the links of the message at least min_length
+// long, when shortening is enabled. Private messages are left alone, as
+// their links may be one-time secrets the shortener would keep.
+func shortenLinks(n *Notification, config *Config, parent *span) {
+	enabled := config.Shorten.Enabled
+	if n.Shorten != nil {
+		enabled = *n.Shorten
+	}
+	if !enabled || n.Private {
+		return
+	}
+	c := config.Shorten
+
+	// The daemon's links only open while it runs
+	if c.API == "" {
+		conn, err := dialDaemon()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: links not shortened, no 'notify daemon' is running to open them")
+			return
+		}
+		conn.Close()
+	}
+
+	shorten := startSpan(parent, "shorten")
+	var errs []error
+	n.Message = linkPattern.ReplaceAllStringFunc(n.Message, func(link string) string {
+		if len(link) < c.MinLength {
+			return link
+		}
+		short, err := c.shorten(link)
+		if err != nil {
+			errs = append(errs, err)
+			return link
+		}
+		return short
+	})
+	err := errors.Join(errs...)
+	shorten.finish(err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: links not shortened: %v\n", err)
+	}
+}
+
+// shorten returns the short link of a link, from the API or the daemon
+func (c *shortenConfig) shorten(link string) (string, error) {
+	if c.API == "" {
+		return c.daemonLink(link)
+	}
+
+	client := &http.Client{Timeout: c.Timeout}
+	resp, err := client.Get(strings.ReplaceAll(c.API, "{url}", url.QueryEscape(link)))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("shortener: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	short := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(short, "http://") && !strings.HasPrefix(short, "https://") {
+		return "", fmt.Errorf("shortener answered %q instead of a link", oneLine(short, 60))
+	}
+	return short, nil
+}
+
+// daemonLink stores a link for the daemon to open, under the start of its
+// hash, so shortening a link twice gives the same short link
+func (c *shortenConfig) daemonLink(link string) (string, error) {
+	sum := sha256.Sum256([]byte(link))
+	id := base64.RawURLEncoding.EncodeToString(sum[:])[:8]
+
+	err := updateState(func(s *State) error {
+		now := time.Now()
+		for key, l := range s.ShortLinks {
+			if now.Sub(l.Created) > shortLinkAge {
+				delete(s.ShortLinks, key)
+			}
+		}
+		if s.ShortLinks == nil {
+			s.ShortLinks = map[string]*shortLink{}
+		}
+		s.ShortLinks[id] = &shortLink{URL: link, Created: now}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	// A daemon listening on every address is reached here on localhost
+	host, port, _ := net.SplitHostPort(c.Listen)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/s/" + id, nil
+}
+
+// serveShortLinks redirects the daemon's short links to their links
+func serveShortLinks(listen string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /s/{id}", func(w http.ResponseWriter, r *http.Request) {
+		state, err := loadState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		l, ok := state.ShortLinks[r.PathValue("id")]
+		if !ok || time.Since(l.Created) > shortLinkAge {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, l.URL, http.StatusFound)
+	})
+	if err := http.ListenAndServe(listen, mux); err != nil {
+		log.Printf("Warning: short links: %v", err)
+	}
+}
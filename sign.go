@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers carrying request signatures
+const (
+	signatureHeader = "X-Notify-Signature"
+	timestampHeader = "X-Notify-Timestamp"
+	nonceHeader     = "X-Notify-Nonce"
+)
+
+// Signed requests older or newer than this are rejected as replays; newer
+// ones are rejected when seen before
+const maxClockSkew = 5 * time.Minute
+
+// signer signs requests to relays with a shared HMAC secret, an Ed25519
+// key or both
+type signer struct {
+	secret []byte
+	key    ed25519.PrivateKey
+}
+
+// newSigner returns the signer for a secret and key file, or nil when
+// both are empty
+func newSigner(secret, keyFile string) (*signer, error) {
+	if secret == "" && keyFile == "" {
+		return nil, nil
+	}
+	s := &signer{secret: []byte(secret)}
+	if keyFile != "" {
+		key, err := loadSigningKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+	}
+	return s, nil
+}
+
+// sign adds the timestamp, nonce and signature headers for body to req
+func (s *signer) sign(req *http.Request, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := newToken()
+	payload := signedPayload(req.Method, req.URL.Path, timestamp, nonce, body)
+
+	var signatures []string
+	if len(s.secret) > 0 {
+		signatures = append(signatures, "sha256="+hex.EncodeToString(hmacSum(s.secret, payload)))
+	}
+	if s.key != nil {
+		signatures = append(signatures, "ed25519="+base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)))
+	}
+
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(nonceHeader, nonce)
+	req.Header.Set(signatureHeader, strings.Join(signatures, ","))
+}
+
+// verifier checks request signatures against a shared secret and a list
+// of trusted Ed25519 public keys
+type verifier struct {
+	secret []byte
+	keys   []ed25519.PublicKey
+
+	mu   sync.Mutex
+	seen map[string]time.Time // nonces of signed requests accepted, until they expire
+}
+
+// enabled reports whether requests must be signed
+func (v *verifier) enabled() bool {
+	return len(v.secret) > 0 || len(v.keys) > 0
+}
+
+// verify accepts a request if any of its signatures is valid, its
+// timestamp is recent and its nonce wasn't accepted before
+func (v *verifier) verify(r *http.Request, body []byte) error {
+	header := r.Header.Get(signatureHeader)
+	timestamp := r.Header.Get(timestampHeader)
+	nonce := r.Header.Get(nonceHeader)
+	if header == "" || timestamp == "" || nonce == "" {
+		return errors.New("request is not signed")
+	}
+	if len(nonce) > 64 {
+		return fmt.Errorf("invalid %s header", nonceHeader)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", timestampHeader)
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("signature timestamp is too old or in the future, check the sender's clock")
+	}
+
+	payload := signedPayload(r.Method, r.URL.Path, timestamp, nonce, body)
+	if !v.valid(header, payload) {
+		return errors.New("invalid signature")
+	}
+	return v.remember(nonce, time.Unix(unix, 0).Add(maxClockSkew))
+}
+
+// valid reports whether any of the signatures of a header signs payload
+func (v *verifier) valid(header string, payload []byte) bool {
+	for _, signature := range strings.Split(header, ",") {
+		scheme, value, _ := strings.Cut(strings.TrimSpace(signature), "=")
+		switch scheme {
+		case "sha256":
+			given, err := hex.DecodeString(value)
+			if err == nil && len(v.secret) > 0 && hmac.Equal(given, hmacSum(v.secret, payload)) {
+				return true
+			}
+		case "ed25519":
+			given, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				continue
+			}
+			for _, key := range v.keys {
+				if ed25519.Verify(key, payload, given) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// remember records the nonce of a signed request until it expires,
+// rejecting it when it was accepted before. The nonce is signed, so a
+// replay can't change it without invalidating the signature.
+func (v *verifier) remember(nonce string, expires time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	for n, t := range v.seen {
+		if now.After(t) {
+			delete(v.seen, n)
+		}
+	}
+	if _, ok := v.seen[nonce]; ok {
+		return errors.New("request was received before, replays are rejected")
+	}
+	if v.seen == nil {
+		v.seen = map[string]time.Time{}
+	}
+	v.seen[nonce] = expires
+	return nil
+}
+
+// signedPayload binds the method, path, timestamp and nonce to the body,
+// so none can be replaced and a request can't be replayed elsewhere
+func signedPayload(method, path, timestamp, nonce string, body []byte) []byte {
+	return append([]byte(method+"\n"+path+"\n"+timestamp+"\n"+nonce+"\n"), body...)
+}
+
+func hmacSum(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// parseVerifyKey decodes a base64 Ed25519 public key as printed by
+// "notify keygen --signing"
+func parseVerifyKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid signing public key %q", s)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// loadSigningKey reads a signing key file written by keygen
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid signing key file %s", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// defaultSigningKeyFile returns where keygen stores the signing key
+func defaultSigningKeyFile() (string, error) {
+	return dataFile("sign.key")
+}
+
+// keygenSigning creates the Ed25519 key used by --sign-key
+func keygenSigning(path string, force bool) error {
+	if key, err := loadSigningKey(path); err == nil && !force {
+		fmt.Printf("Signing key %s already exists (use --force to replace it). Public key:\n", path)
+		fmt.Println(base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+		return nil
+	}
+
+	public, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signing key written to %s. Public key for 'notify relay --verify-key':\n", path)
+	fmt.Println(base64.StdEncoding.EncodeToString(public))
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignedRequests(t *testing.T) {
+	s := &signer{secret: []byte("s3cret")}
+	v := &verifier{secret: []byte("s3cret")}
+
+	// Requests with the same body in the same second are told apart by
+	// their nonce
+	first := httptest.NewRequest("GET", "/interactions", nil)
+	second := httptest.NewRequest("GET", "/interactions", nil)
+	s.sign(first, nil)
+	s.sign(second, nil)
+	if err := v.verify(first, nil); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := v.verify(second, nil); err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+
+	if err := v.verify(first, nil); err == nil || !strings.Contains(err.Error(), "replays are rejected") {
+		t.Fatalf("replay: verify() = %v, want it rejected", err)
+	}
+
+	body := []byte(`{"message":"x"}`)
+	signed := httptest.NewRequest("POST", "/notify", nil)
+	s.sign(signed, body)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"other path", "POST", "/hooks/generic", string(body)},
+		{"other method", "PUT", "/notify", string(body)},
+		{"other body", "POST", "/notify", `{"message":"y"}`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		r.Header = signed.Header.Clone()
+		if err := v.verify(r, []byte(tt.body)); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("%s: verify() = %v, want an invalid signature", tt.name, err)
+		}
+	}
+
+	unsigned := httptest.NewRequest("POST", "/notify", nil)
+	if err := v.verify(unsigned, body); err == nil {
+		t.Error("an unsigned request was accepted")
+	}
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"cmp"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// OID of the snmpTrapOID.0 variable naming the trap in SNMPv2c traps
+const snmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+
+// Names of well-known trap and variable OIDs
+var snmpNames = map[string]string{
+	"1.3.6.1.6.3.1.1.5.1":     "coldStart",
+	"1.3.6.1.6.3.1.1.5.2":     "warmStart",
+	"1.3.6.1.6.3.1.1.5.3":     "linkDown",
+	"1.3.6.1.6.3.1.1.5.4":     "linkUp",
+	"1.3.6.1.6.3.1.1.5.5":     "authenticationFailure",
+	"1.3.6.1.6.3.1.1.5.6":     "egpNeighborLoss",
+	"1.3.6.1.2.1.1.3":         "sysUpTime",
+	"1.3.6.1.2.1.1.5":         "sysName",
+	"1.3.6.1.2.1.2.2.1.1":     "ifIndex",
+	"1.3.6.1.2.1.2.2.1.2":     "ifDescr",
+	"1.3.6.1.2.1.2.2.1.7":     "ifAdminStatus",
+	"1.3.6.1.2.1.2.2.1.8":     "ifOperStatus",
+	"1.3.6.1.2.1.31.1.1.1.1":  "ifName",
+	"1.3.6.1.2.1.31.1.1.1.18": "ifAlias",
+	"1.3.6.1.6.3.1.1.4.3":     "snmpTrapEnterprise",
+	"1.3.6.1.6.3.18.1.3":      "snmpTrapAddress",
+	"1.3.6.1.6.3.18.1.4":      "snmpTrapCommunity",
+}
+
+// Notification types of the generic traps; other traps are warnings
+var snmpTrapTypes = map[string]string{
+	"coldStart":             "info",
+	"warmStart":             "info",
+	"linkDown":              "error",
+	"linkUp":                "success",
+	"authenticationFailure": "warning",
+	"egpNeighborLoss":       "error",
+}
+
+// snmpTrap is a received SNMPv1 or SNMPv2c trap or inform
+type snmpTrap struct {
+	Community string
+	Agent     string // agent address of SNMPv1 traps
+	OID       string // identifies the trap
+	Vars      []snmpVar
+	Inform    bool // the sender waits for a response
+}
+
+// snmpVar is a variable binding of a trap
+type snmpVar struct {
+	OID   string
+	Value string
+}
+
+// snmpServer turns selected SNMP traps into notifications
+type snmpServer struct {
+	relay       *relayServer
+	oids        []string // accepted trap OID prefixes, empty for all
+	communities []string // accepted communities, empty for all
+}
+
+// runSNMP implements "notify snmp"
+func runSNMP(args []string) error {
+	listen := ":162"
+	out := newListenerOptions()
+	srv := &snmpServer{relay: out.relay}
+
+	flags := append(out.flags(), []cliFlag{
+		{Name: "listen", Set: func(v string) error { listen = v; return nil }},
+		{Name: "oid", Set: func(v string) error {
+			for oid := range strings.SplitSeq(v, ",") {
+				oid, err := parseOIDFilter(oid)
+				if err != nil {
+					return err
+				}
+				srv.oids = append(srv.oids, oid)
+			}
+			return nil
+		}},
+		{Name: "community", Set: func(v string) error { srv.communities = append(srv.communities, v); return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showSNMPHelp(); os.Exit(0); return nil }},
+	}...)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	conn, err := net.ListenPacket("udp", listen)
+	if err != nil {
+		return err
+	}
+	if err := out.start(); err != nil {
+		return err
+	}
+
+	if len(srv.relay.targets) > 0 {
+		log.Printf("SNMP trap receiver listening on udp://%s, forwarding to %s", listen, strings.Join(srv.relay.targets, ", "))
+	} else {
+		log.Printf("SNMP trap receiver listening on udp://%s, showing notifications locally", listen)
+	}
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		data := slices.Clone(buf[:n])
+		go srv.handle(conn, addr, data)
+	}
+}
+
+// handle acknowledges informs and shows or forwards the traps that pass
+// the filters
+func (s *snmpServer) handle(conn net.PacketConn, addr net.Addr, data []byte) {
+	from, _, _ := net.SplitHostPort(addr.String())
+	trap, response, err := parseSNMPTrap(data)
+	if err != nil {
+		log.Printf("%s: invalid SNMP trap: %v", from, err)
+		return
+	}
+	if len(s.communities) > 0 && !slices.Contains(s.communities, trap.Community) {
+		log.Printf("%s: SNMP trap with unknown community %q ignored", from, trap.Community)
+		return
+	}
+	if trap.Inform {
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			log.Printf("%s: could not acknowledge SNMP inform: %v", from, err)
+		}
+	}
+	if !s.matches(trap.OID) {
+		return
+	}
+
+	trace := startSpan(nil, "snmp.trap", "client.address", from, "snmp.trap_oid", trap.OID)
+	s.relay.deliverEvent("SNMP", from, trap.relayMessage(from), trace)
+}
+
+// matches reports whether a trap OID is one of the selected ones
+func (s *snmpServer) matches(oid string) bool {
+	if len(s.oids) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(s.oids, func(prefix string) bool {
+		return oid == prefix || strings.HasPrefix(oid, prefix+".")
+	})
+}
+
+// relayMessage turns a trap into a notification from its agent, listing
+// its variables
+func (t *snmpTrap) relayMessage(from string) *notify.RelayMessage {
+	name := snmpName(t.OID)
+	var lines []string
+	for _, v := range t.Vars {
+		if len(lines) == 10 {
+			lines = append(lines, fmt.Sprintf("and %d more", len(t.Vars)-10))
+			break
+		}
+		lines = append(lines, oneLine(snmpName(v.OID)+" = "+v.Value, 100))
+	}
+
+	return &notify.RelayMessage{
+		App:      "SNMP",
+		Category: "snmp",
+		Type:     cmp.Or(snmpTrapTypes[name], "warning"),
+		Title:    oneLine(cmp.Or(t.Agent, from)+": "+name, notify.MaxTitleLength),
+		Message:  cmp.Or(strings.Join(lines, "\n"), t.OID),
+	}
+}
+
+// snmpName replaces the longest known prefix of an OID with its name,
+// keeping the index that follows, like ifDescr.3
+func snmpName(oid string) string {
+	best := ""
+	for prefix := range snmpNames {
+		if (oid == prefix || strings.HasPrefix(oid, prefix+".")) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return oid
+	}
+	return snmpNames[best] + oid[len(best):]
+}
+
+// parseOIDFilter accepts a numeric OID or the name of a generic trap
+func parseOIDFilter(s string) (string, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), ".")
+	for oid, name := range snmpNames {
+		if strings.EqualFold(s, name) {
+			return oid, nil
+		}
+	}
+	for part := range strings.SplitSeq(s, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			names := slices.Sorted(maps.Values(snmpNames))
+			return "", fmt.Errorf("%q is not an OID like 1.3.6.1.4.1.9 or a known name.%s", s, suggest.DidYouMean(s, names, ""))
+		}
+	}
+	return s, nil
+}
+
+// parseSNMPTrap decodes an SNMPv1 trap, or an SNMPv2c trap or inform. For
+// informs it also returns the response acknowledging it.
+func parseSNMPTrap(data []byte) (*snmpTrap, []byte, error) {
+	message, _, err := readBER(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts, err := berChildren(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if message.Tag != 0x30 || len(parts) < 3 || parts[0].Tag != 0x02 {
+		return nil, nil, errors.New("not an SNMP message")
+	}
+	version := berInt(parts[0].Bytes)
+	if version == 3 {
+		return nil, nil, errors.New("SNMPv3 isn't supported, send SNMPv1 or SNMPv2c traps")
+	}
+	if len(parts) != 3 || parts[1].Tag != 0x04 {
+		return nil, nil, errors.New("not an SNMPv1 or SNMPv2c message")
+	}
+
+	trap := &snmpTrap{Community: string(parts[1].Bytes)}
+	pdu := parts[2]
+	fields, err := berChildren(pdu)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var bindings berValue
+	switch {
+	case pdu.Tag == 0xA4 && version == 0:
+		// Trap-PDU: enterprise, agent-addr, generic-trap, specific-trap,
+		// time-stamp, variable-bindings
+		if len(fields) != 6 {
+			return nil, nil, errors.New("invalid SNMPv1 trap")
+		}
+		if ip := fields[1].Bytes; len(ip) == 4 {
+			trap.Agent = net.IP(ip).String()
+		}
+		generic, specific := berInt(fields[2].Bytes), berInt(fields[3].Bytes)
+		if generic < 6 {
+			trap.OID = "1.3.6.1.6.3.1.1.5." + strconv.FormatInt(generic+1, 10)
+		} else {
+			// Enterprise specific traps as mapped by RFC 3584
+			trap.OID = fmt.Sprintf("%s.0.%d", berOID(fields[0].Bytes), specific)
+		}
+		bindings = fields[5]
+
+	case (pdu.Tag == 0xA7 || pdu.Tag == 0xA6) && version == 1:
+		// SNMPv2-Trap-PDU and InformRequest-PDU: request-id, error-status,
+		// error-index, variable-bindings
+		if len(fields) != 4 {
+			return nil, nil, errors.New("invalid SNMPv2c trap")
+		}
+		trap.Inform = pdu.Tag == 0xA6
+		bindings = fields[3]
+
+	default:
+		return nil, nil, fmt.Errorf("unexpected SNMP PDU type 0x%02X, only traps and informs are accepted", pdu.Tag)
+	}
+
+	vars, err := berChildren(bindings)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range vars {
+		pair, err := berChildren(v)
+		if err != nil || len(pair) != 2 || pair[0].Tag != 0x06 {
+			return nil, nil, errors.New("invalid variable binding")
+		}
+		oid := berOID(pair[0].Bytes)
+		switch {
+		case oid == snmpTrapOID:
+			trap.OID = berOID(pair[1].Bytes)
+		case oid == "1.3.6.1.2.1.1.3.0":
+			// sysUpTime.0 of SNMPv2c traps says little about the event
+		default:
+			trap.Vars = append(trap.Vars, snmpVar{OID: oid, Value: snmpValue(pair[1])})
+		}
+	}
+	if trap.OID == "" {
+		return nil, nil, errors.New("the trap has no snmpTrapOID.0")
+	}
+
+	var response []byte
+	if trap.Inform {
+		// The response is the inform with the PDU type changed to
+		// GetResponse, which keeps every length the same
+		header := len(message.Raw) - len(message.Bytes)
+		response = slices.Concat(message.Raw[:header], parts[0].Raw, parts[1].Raw, []byte{0xA2}, pdu.Raw[1:])
+	}
+	return trap, response, nil
+}
+
+// snmpValue formats the value of a variable binding
+func snmpValue(v berValue) string {
+	switch v.Tag {
+	case 0x02: // INTEGER
+		return strconv.FormatInt(berInt(v.Bytes), 10)
+	case 0x04: // OCTET STRING, often text but also MAC addresses
+		if s := string(v.Bytes); utf8.ValidString(s) && !strings.ContainsFunc(s, func(r rune) bool {
+			return !unicode.IsPrint(r) && !unicode.IsSpace(r)
+		}) {
+			return s
+		}
+		return hexColons(v.Bytes)
+	case 0x05: // NULL
+		return ""
+	case 0x06: // OBJECT IDENTIFIER
+		return snmpName(berOID(v.Bytes))
+	case 0x40: // IpAddress
+		if len(v.Bytes) == 4 {
+			return net.IP(v.Bytes).String()
+		}
+	case 0x41, 0x42, 0x46: // Counter32, Gauge32, Counter64
+		var n uint64
+		for _, b := range v.Bytes {
+			n = n<<8 | uint64(b)
+		}
+		return strconv.FormatUint(n, 10)
+	case 0x43: // TimeTicks, hundredths of a second
+		return (time.Duration(berInt(v.Bytes)) * 10 * time.Millisecond).String()
+	case 0x80:
+		return "noSuchObject"
+	case 0x81:
+		return "noSuchInstance"
+	case 0x82:
+		return "endOfMibView"
+	}
+	return hexColons(v.Bytes)
+}
+
+// hexColons formats bytes like 00:1a:2b
+func hexColons(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = hex.EncodeToString([]byte{c})
+	}
+	return strings.Join(parts, ":")
+}
+
+// berValue is an element of BER encoded data
+type berValue struct {
+	Tag   byte
+	Bytes []byte // contents
+	Raw   []byte // the whole encoding, with tag and length
+}
+
+// readBER splits the first element off data
+func readBER(data []byte) (berValue, []byte, error) {
+	truncated := errors.New("truncated BER data")
+	if len(data) < 2 {
+		return berValue{}, nil, truncated
+	}
+	length, header := int(data[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7F
+		if n == 0 || n > 3 || len(data) < 2+n {
+			return berValue{}, nil, errors.New("invalid BER length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		header += n
+	}
+	if len(data)-header < length {
+		return berValue{}, nil, truncated
+	}
+	end := header + length
+	return berValue{Tag: data[0], Bytes: data[header:end], Raw: data[:end]}, data[end:], nil
+}
+
+// berChildren decodes the elements of a constructed element
+func berChildren(v berValue) ([]berValue, error) {
+	var children []berValue
+	for data := v.Bytes; len(data) > 0; {
+		child, rest, err := readBER(data)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		data = rest
+	}
+	return children, nil
+}
+
+// berInt decodes a two's complement integer
+func berInt(b []byte) int64 {
+	var n int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(c)
+	}
+	return n
+}
+
+// berOID decodes an object identifier into dotted form
+func berOID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var parts []string
+	var n uint64
+	for _, c := range b {
+		n = n<<7 | uint64(c&0x7F)
+		if c&0x80 != 0 {
+			continue
+		}
+		if parts == nil {
+			// The first byte packs the first two numbers
+			first := min(n/40, 2)
+			parts = append(parts, strconv.FormatUint(first, 10), strconv.FormatUint(n-first*40, 10))
+		} else {
+			parts = append(parts, strconv.FormatUint(n, 10))
+		}
+		n = 0
+	}
+	return strings.Join(parts, ".")
+}
+
+func showSNMPHelp() {
+	fmt.Print(`Receive SNMP traps and notify on the selected ones
+
+Switches, routers, UPSes and printers report events with SNMP traps. This
+receives SNMPv1 and SNMPv2c traps and informs over UDP and turns them
+into notifications titled with the agent and trap name, listing the
+trap's variables. linkDown shows as an error, linkUp as a success,
+cold and warm starts as info, and other traps as warnings.
+
+Usage:
+  notify snmp [OPTIONS]
+
+Options:
+  --listen ADDR      UDP address to listen on (default: :162)
+  --oid LIST         Only traps with these OIDs or OID prefixes, or generic trap
+                     names like linkDown (repeatable)
+  --community NAME   Only traps sent with this community (repeatable)
+  --to URL           Forward to this relay instead of showing toasts (repeatable)
+  --to-token TOKEN   Token presented to the --to relays
+  --ca FILE          Trust this CA for the --to relays instead of the system roots
+  --client-cert FILE Client certificate presented to the --to relays
+  --client-key FILE  Private key of --client-cert
+  --rate N           Notifications per minute per agent, 0 for no limit (default: 30)
+  --burst N          Notifications an agent may cause at once (default: 10)
+  --help             Show this help
+
+Port 162 needs root on Linux and macOS; use --listen :1162 there.
+
+Examples:
+  notify snmp
+  notify snmp --oid linkDown,linkUp --community lab
+  notify snmp --oid 1.3.6.1.4.1.318 --to https://desktop.lan:8787 --to-token secret
+`)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// unhex decodes hex written with spaces between the bytes
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReadBER(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		length int    // of the contents
+		rest   int    // bytes after the element
+		err    string // part of the error
+	}{
+		{"empty", []byte{0x04, 0x00}, 0, 0, ""},
+		{"short", []byte{0x04, 0x02, 'h', 'i', 0x05, 0x00}, 2, 2, ""},
+		{"longest short", append([]byte{0x04, 0x7F}, make([]byte, 127)...), 127, 0, ""},
+		{"shortest long", append([]byte{0x04, 0x81, 0x80}, make([]byte, 128)...), 128, 0, ""},
+		{"long of two bytes", append([]byte{0x04, 0x82, 0x01, 0x00}, make([]byte, 257)...), 256, 1, ""},
+		{"long of three bytes", append([]byte{0x04, 0x83, 0x01, 0x00, 0x00}, make([]byte, 65536)...), 65536, 0, ""},
+		{"no length", []byte{0x04}, 0, 0, "truncated"},
+		{"truncated short", []byte{0x04, 0x02, 'h'}, 0, 0, "truncated"},
+		{"truncated long", append([]byte{0x04, 0x81, 0x80}, make([]byte, 127)...), 0, 0, "truncated"},
+		{"truncated length", []byte{0x04, 0x82, 0x01}, 0, 0, "invalid BER length"},
+		{"indefinite length", []byte{0x30, 0x80, 0x00, 0x00}, 0, 0, "invalid BER length"},
+		{"four byte length", []byte{0x04, 0x84, 0x00, 0x00, 0x00, 0x01, 0x00}, 0, 0, "invalid BER length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, rest, err := readBER(tt.data)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("readBER() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Tag != tt.data[0] || len(v.Bytes) != tt.length || len(rest) != tt.rest || len(v.Raw)+len(rest) != len(tt.data) {
+				t.Fatalf("readBER() = tag %02X, %d bytes, %d raw, %d rest, want %d bytes and %d rest",
+					v.Tag, len(v.Bytes), len(v.Raw), len(rest), tt.length, tt.rest)
+			}
+		})
+	}
+}
+
+func TestBEROID(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", ""},
+		{"2B 06 01 06 03 01 01 05 01", "1.3.6.1.6.3.1.1.5.1"},
+		{"2B 06 01 04 01 82 37", "1.3.6.1.4.1.311"},
+		{"2B 06 01 04 01 7F", "1.3.6.1.4.1.127"},
+		{"2B 06 01 04 01 81 00", "1.3.6.1.4.1.128"},
+		{"2B 06 01 04 01 8F FF FF FF 7F", "1.3.6.1.4.1.4294967295"},
+		{"00", "0.0"},
+		{"27", "0.39"},
+		{"28", "1.0"},
+		{"81 34 03", "2.100.3"},
+	}
+	for _, tt := range tests {
+		if got := berOID(unhex(t, tt.data)); got != tt.want {
+			t.Errorf("berOID(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestBERInt(t *testing.T) {
+	tests := []struct {
+		data string
+		want int64
+	}{
+		{"00", 0},
+		{"7F", 127},
+		{"00 80", 128},
+		{"80", -128},
+		{"FF", -1},
+		{"FF 7F", -129},
+		{"01 00 00", 65536},
+	}
+	for _, tt := range tests {
+		if got := berInt(unhex(t, tt.data)); got != tt.want {
+			t.Errorf("berInt(%s) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseSNMPInform(t *testing.T) {
+	// An SNMPv2c linkDown inform with ifIndex.2 = 2 from the public
+	// community
+	inform := unhex(t, "30 42 02 01 01 04 06 70 75 62 6C 69 63 A6 35 02 01 07 02 01 00 02 01 00 30 2A"+
+		" 30 17 06 0A 2B 06 01 06 03 01 01 04 01 00 06 09 2B 06 01 06 03 01 01 05 03"+
+		" 30 0F 06 0A 2B 06 01 02 01 02 02 01 01 02 02 01 02")
+	trap, response, err := parseSNMPTrap(inform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trap.Community != "public" || trap.OID != "1.3.6.1.6.3.1.1.5.3" || !trap.Inform {
+		t.Fatalf("trap = %+v, want a linkDown inform from public", trap)
+	}
+	if len(trap.Vars) != 1 || trap.Vars[0].OID != "1.3.6.1.2.1.2.2.1.1.2" || trap.Vars[0].Value != "2" {
+		t.Fatalf("variables = %+v, want ifIndex.2 = 2", trap.Vars)
+	}
+
+	// The response is the same message as a GetResponse
+	want := bytes.Clone(inform)
+	want[13] = 0xA2
+	if !bytes.Equal(response, want) {
+		t.Fatalf("response = % X\nwant       % X", response, want)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// soundConfig chooses the sound of each notification from a sound pack,
+// Windows' own sounds or wav files
+type soundConfig struct {
+	Pack       string            `yaml:"pack"`       // directory of wav files
+	Types      map[string]string `yaml:"types"`      // sound of each type
+	Categories map[string]string `yaml:"categories"` // sound of each category, before its type
+}
+
+// Sounds Windows can play for toasts, named without the ms-winsoundevent:
+// Notification. prefix
+var windowsSounds = func() []string {
+	sounds := []string{"Default", "IM", "Mail", "Reminder", "SMS"}
+	for _, kind := range []string{"Alarm", "Call"} {
+		sounds = append(sounds, "Looping."+kind)
+		for i := 2; i <= 10; i++ {
+			sounds = append(sounds, "Looping."+kind+strconv.Itoa(i))
+		}
+	}
+	return sounds
+}()
+
+// validate checks that the pack exists and every mapped sound can be found
+func (c *soundConfig) validate() error {
+	if c.Pack != "" {
+		if info, err := os.Stat(c.Pack); err != nil || !info.IsDir() {
+			return fmt.Errorf("sound pack %s is not a directory", c.Pack)
+		}
+	}
+	for t, name := range c.Types {
+		if !isValidType(t) {
+			return fmt.Errorf("invalid type %q in sounds types.%s", t, suggest.DidYouMean(t, notify.Types, ""))
+		}
+		if _, err := c.resolve(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range c.Categories {
+		if _, err := c.resolve(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve turns a sound name into what the toast plays: silent, a Windows
+// sound or the path of a wav file. Names are looked up in the pack first,
+// then among Windows' sounds; paths of wav files are used as they are.
+func (c *soundConfig) resolve(name string) (string, error) {
+	if strings.EqualFold(name, notify.SoundSilent) {
+		return notify.SoundSilent, nil
+	}
+	if strings.EqualFold(filepath.Ext(name), ".wav") {
+		if _, err := os.Stat(name); err != nil {
+			return "", fmt.Errorf("sound %s: %w", name, err)
+		}
+		return name, nil
+	}
+	if c.Pack != "" {
+		path := filepath.Join(c.Pack, name+".wav")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	for _, sound := range windowsSounds {
+		if strings.EqualFold(name, sound) || strings.EqualFold(name, strings.TrimPrefix(sound, "Looping.")) {
+			return "ms-winsoundevent:Notification." + sound, nil
+		}
+	}
+
+	names := append(c.packSounds(), windowsSounds...)
+	return "", fmt.Errorf("unknown sound %q.%s Run 'notify sounds list' to see the sounds", name, suggest.DidYouMean(name, names, ""))
+}
+
+// sound returns the configured sound of a notification, or "" to keep
+// the sound of its type. Without a mapping, pack sounds named after the
+// type, like error.wav, are used.
+func (c *soundConfig) sound(n *Notification) string {
+	name, ok := c.Categories[n.Category]
+	if !ok || n.Category == "" {
+		name, ok = c.Types[n.Type]
+	}
+	if !ok && c.Pack != "" {
+		if _, err := os.Stat(filepath.Join(c.Pack, n.Type+".wav")); err == nil {
+			name = n.Type
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	// The config was validated when loaded, but files may be gone since
+	sound, err := c.resolve(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return ""
+	}
+	return sound
+}
+
+// packSounds returns the names of the wav files in the pack
+func (c *soundConfig) packSounds() []string {
+	var names []string
+	entries, _ := os.ReadDir(c.Pack)
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".wav"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// runSounds implements "notify sounds"
+func runSounds(args []string) error {
+	category := ""
+	flags := []cliFlag{
+		{Name: "category", Set: func(v string) error { category = normalizeCategory(v); return nil }},
+		{Name: "help", Bool: true, Set: func(string) error { showSoundsHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(words) == 0 || words[0] == "list" {
+		if len(words) > 1 {
+			return fmt.Errorf("unexpected argument: %s", words[1])
+		}
+		listSounds(&config.Sounds)
+		return nil
+	}
+	if words[0] != "test" {
+		actions := []string{"list", "test"}
+		return fmt.Errorf("unknown sounds command %q.%s Commands are: %s", words[0], suggest.DidYouMean(words[0], actions, ""), strings.Join(actions, ", "))
+	}
+	if len(words) != 2 {
+		return fmt.Errorf("usage: notify sounds test SOUND|TYPE")
+	}
+	return testSound(&config.Sounds, words[1], category)
+}
+
+// listSounds prints the available sounds and what each type and category
+// plays
+func listSounds(c *soundConfig) {
+	if c.Pack != "" {
+		fmt.Printf("Sound pack %s:\n  %s\n\n", c.Pack, orDefault(strings.Join(c.packSounds(), ", "), "(no wav files)"))
+	}
+	fmt.Printf("Windows sounds:\n  %s\n\n", strings.Join(windowsSounds, ", "))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLAYED FOR\tSOUND")
+	for _, t := range notify.Types {
+		sound := c.sound(&Notification{Notification: notify.Notification{Type: t}})
+		if sound == "" {
+			sound = notify.DefaultSound(t)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", t, notify.SoundName(sound))
+	}
+	categories := make([]string, 0, len(c.Categories))
+	for category := range c.Categories {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+	for _, category := range categories {
+		sound, _ := c.resolve(c.Categories[category])
+		fmt.Fprintf(w, "category %s\t%s\n", category, notify.SoundName(sound))
+	}
+	w.Flush()
+}
+
+// testSound shows a toast playing a sound, or the sound of a type
+func testSound(c *soundConfig, name, category string) error {
+	n := &Notification{Notification: notify.Notification{Type: "info", Title: "Sound test", Category: category}}
+	if isValidType(name) {
+		n.Type = name
+		n.Sound = c.sound(n)
+	} else {
+		sound, err := c.resolve(name)
+		if err != nil {
+			return err
+		}
+		n.Sound = sound
+	}
+	if n.Sound == "" {
+		n.Sound = notify.DefaultSound(n.Type)
+	}
+	n.Message = "Playing " + notify.SoundName(n.Sound)
+	return displayNotification(n, nil)
+}
+
+func showSoundsHelp() {
+	fmt.Print(`List and preview notification sounds
+
+The sounds section of config.yaml picks the sound of each type and
+category from a sound pack (a directory of wav files), Windows' own
+sounds or wav files anywhere:
+
+  sounds:
+    pack: C:\Sounds\retro
+    types:
+      error: alarm        # alarm.wav from the pack
+      success: Reminder   # a Windows sound
+    categories:
+      ci: build           # before the type, for 'notify --category ci'
+
+Pack sounds named after a type, like error.wav, play for that type
+without a mapping, and silent plays nothing. Countdown alarms and
+notifications silenced during calls keep their sound.
+
+Usage:
+  notify sounds [list]             List the sounds and what each type and category plays
+  notify sounds test SOUND|TYPE    Show a toast playing a sound, or a type's sound
+
+Options:
+  --category NAME    With test TYPE, play what this category's notifications play
+
+Examples:
+  notify sounds test alarm
+  notify sounds test error --category ci
+`)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Largest file "notify spool" turns into a notification
+const maxSpoolFile = 1 << 20
+
+// A file is picked up once it hasn't changed for this long, so one still
+// being written is left alone
+const spoolSettle = time.Second
+
+// runSpool implements "notify spool DIR", which turns files dropped in a
+// directory into notifications
+func runSpool(args []string) error {
+	out := newListenerOptions()
+	interval := time.Second
+	archive, remove := "", false
+
+	// Files come from this machine, so rate limits don't apply
+	flags := slices.DeleteFunc(out.flags(), func(f cliFlag) bool { return f.Name == "rate" || f.Name == "burst" })
+	flags = append(flags,
+		cliFlag{Name: "archive", Set: func(v string) error { archive = v; return nil }},
+		cliFlag{Name: "delete", Bool: true, Set: func(v string) (err error) { remove, err = parseStrictBool(v); return }},
+		cliFlag{Name: "interval", Set: func(v string) (err error) { interval, err = parseDuration(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showSpoolHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) != 1 {
+		return errors.New("usage: notify spool DIR [OPTIONS]")
+	}
+	dir := words[0]
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if archive == "" {
+		archive = filepath.Join(dir, "archive")
+	}
+	failed := filepath.Join(dir, "failed")
+	for _, d := range []string{archive, failed} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return err
+		}
+	}
+
+	if err := out.start(); err != nil {
+		return err
+	}
+	log.Printf("Watching %s for notification files", dir)
+
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !spoolReady(dir, entry) {
+				continue
+			}
+			path := filepath.Join(dir, name)
+
+			m, err := readSpoolFile(path)
+			if err == nil {
+				err = out.relay.deliverLocal("spool.file", name, m)
+			}
+			stamp := time.Now().Format("20060102-150405-")
+			switch {
+			case err != nil:
+				log.Printf("%s: %v, moved to %s", name, err, failed)
+				err = os.Rename(path, filepath.Join(failed, stamp+name))
+			case remove:
+				err = os.Remove(path)
+			default:
+				err = os.Rename(path, filepath.Join(archive, stamp+name))
+			}
+			if err != nil {
+				// Picked up again next time, when the writer has let go
+				log.Printf("Warning: %v", err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// spoolReady reports whether a dropped file is complete: not hidden, not
+// named as a temporary file, and unchanged for a moment
+func spoolReady(dir string, entry os.DirEntry) bool {
+	name := entry.Name()
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tmp", ".part", ".partial", ".crdownload":
+		return false
+	}
+	info, err := entry.Info()
+	return err == nil && time.Since(info.ModTime()) >= spoolSettle
+}
+
+// readSpoolFile reads a dropped file: a .json file is a notification as
+// sent to a relay, anything else is text whose first line is the title
+// when more lines follow
+func readSpoolFile(path string) (*notify.RelayMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxSpoolFile+1))
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSpoolFile {
+		return nil, fmt.Errorf("larger than %s", formatBytes(maxSpoolFile))
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		m := &notify.RelayMessage{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(m); err != nil {
+			return nil, fmt.Errorf("invalid notification: %w", err)
+		}
+		return m, nil
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("not UTF-8 text")
+	}
+	text := strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	m := &notify.RelayMessage{Message: text}
+	if first, rest, ok := strings.Cut(text, "\n"); ok && utf8.RuneCountInString(first) <= notify.MaxTitleLength {
+		m.Title, m.Message = strings.TrimSpace(first), strings.TrimSpace(rest)
+	}
+	return m, nil
+}
+
+// deliverLocal shows or forwards a notification handed over on this
+// machine, like a dropped file, logging it under name
+func (s *relayServer) deliverLocal(spanName, name string, m *notify.RelayMessage) error {
+	trace := startSpan(nil, spanName, "notify.title", m.Title)
+	defer func() { go flushTraces() }()
+
+	n, err := receivedNotification(m)
+	if err == nil {
+		n.trace = trace
+		err = s.deliver(m, n)
+	}
+	trace.finish(err)
+	if err == nil {
+		log.Printf("%s: %q delivered", name, n.Title)
+	}
+	return err
+}
+
+func showSpoolHelp() {
+	fmt.Print(`Turn files dropped in a directory into notifications
+
+For tools that can only write files: every file appearing in DIR becomes
+a notification and is then moved to DIR\archive (or deleted). Files that
+can't be read or shown go to DIR\failed.
+
+A .json file holds a notification as sent to a relay, e.g.
+  {"message": "Backup done", "title": "Backup", "type": "success"}
+Any other file is text: its first line is the title when more lines
+follow, otherwise the whole file is the message.
+
+Files are picked up once they haven't changed for a second. Names
+starting with . or ~ and ending with .tmp or .part are skipped, so
+writing under such a name and renaming when done is safest.
+
+Usage:
+  notify spool DIR [OPTIONS]
+
+Options:
+  --archive DIR      Where shown files are moved (default: DIR\archive)
+  --delete           Delete shown files instead of archiving them
+  --interval DURATION
+                     How often DIR is checked (default: 1s)
+  --to URL           Forward to this relay instead of showing toasts (repeatable)
+  --to-token TOKEN   Token presented to the --to relays
+  --ca FILE          Trust this CA for the --to relays instead of the system roots
+  --client-cert FILE Client certificate presented to the --to relays
+  --client-key FILE  Private key of --client-cert
+  --help             Show this help
+
+Example:
+  notify spool C:\ProgramData\notify\spool
+`)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// statsReport summarizes the history
+type statsReport struct {
+	Since       time.Time             `json:"since"`
+	Total       int                   `json:"total"`
+	Delivered   int                   `json:"delivered"`
+	Failed      int                   `json:"failed"`
+	Muted       int                   `json:"muted"`
+	Deferred    int                   `json:"deferred"`
+	FailureRate float64               `json:"failure_rate"` // failed share of delivery attempts
+	ByType      map[string]*statCount `json:"by_type"`
+	ByTarget    map[string]*statCount `json:"by_target"`
+	ByHour      [24]int               `json:"by_hour"` // local hour of day
+	Senders     []namedCount          `json:"top_senders"`
+	Categories  []namedCount          `json:"top_categories"`
+}
+
+// statCount counts notifications and failed deliveries
+type statCount struct {
+	Count  int `json:"count"`
+	Failed int `json:"failed"`
+}
+
+type namedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Number of senders and categories listed
+const statsTop = 5
+
+// runStats implements "notify stats"
+func runStats(args []string) error {
+	since := 30 * 24 * time.Hour
+	asJSON := false
+
+	flags := []cliFlag{
+		{Name: "since", Set: func(v string) (err error) { since, err = parseRetention(v); return }},
+		{Name: "json", Bool: true, Set: func(v string) (err error) { asJSON, err = parseStrictBool(v); return }},
+		{Name: "help", Bool: true, Set: func(string) error { showStatsHelp(); os.Exit(0); return nil }},
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		return err
+	}
+
+	var start time.Time
+	if since > 0 {
+		start = time.Now().Add(-since)
+	}
+	report := buildStats(entries, start)
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printStats(report)
+	return nil
+}
+
+// buildStats summarizes the entries sent after start
+func buildStats(entries []historyEntry, start time.Time) *statsReport {
+	report := &statsReport{
+		Since:    start,
+		ByType:   map[string]*statCount{},
+		ByTarget: map[string]*statCount{},
+	}
+	senders := map[string]int{}
+	categories := map[string]int{}
+
+	count := func(m map[string]*statCount, key string, failed bool) {
+		c := m[key]
+		if c == nil {
+			c = &statCount{}
+			m[key] = c
+		}
+		c.Count++
+		if failed {
+			c.Failed++
+		}
+	}
+
+	for _, e := range entries {
+		if e.Time.Before(start) || e.Status == statusInteraction {
+			continue
+		}
+
+		report.Total++
+		failed := e.Status == statusFailed
+		switch e.Status {
+		case statusDelivered:
+			report.Delivered++
+		case statusFailed:
+			report.Failed++
+		case statusMuted:
+			report.Muted++
+		case statusDeferred:
+			report.Deferred++
+		}
+
+		target := e.Target
+		if target == "" {
+			target = "toast"
+		}
+		count(report.ByType, e.Type, failed)
+		count(report.ByTarget, target, failed)
+		report.ByHour[e.Time.Local().Hour()]++
+
+		sender := notify.AppName(e.App)
+		if e.Source != "" {
+			sender += " (" + e.Source + ")"
+		}
+		senders[sender]++
+		if e.Category != "" {
+			categories[e.Category]++
+		}
+	}
+
+	if attempts := report.Delivered + report.Failed; attempts > 0 {
+		report.FailureRate = float64(report.Failed) / float64(attempts)
+	}
+	report.Senders = topCounts(senders, statsTop)
+	report.Categories = topCounts(categories, statsTop)
+	return report
+}
+
+// topCounts returns the n largest counts, ties sorted by name
+func topCounts(counts map[string]int, n int) []namedCount {
+	list := make([]namedCount, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, namedCount{name, count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list[:min(n, len(list))]
+}
+
+// printStats prints the report as tables
+func printStats(r *statsReport) {
+	if r.Since.IsZero() {
+		fmt.Printf("All notifications: %d\n", r.Total)
+	} else {
+		fmt.Printf("Notifications since %s: %d\n", r.Since.Local().Format("2006-01-02 15:04"), r.Total)
+	}
+	if r.Total == 0 {
+		return
+	}
+	fmt.Printf("Delivered %d, failed %d (%.1f%% of attempts), muted %d, deferred %d\n",
+		r.Delivered, r.Failed, r.FailureRate*100, r.Muted, r.Deferred)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	printCounts := func(title string, counts map[string]*statCount) {
+		fmt.Fprintf(w, "\n%s\tCOUNT\tFAILED\n", title)
+		keys := make([]string, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts[keys[i]].Count != counts[keys[j]].Count {
+				return counts[keys[i]].Count > counts[keys[j]].Count
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", key, counts[key].Count, counts[key].Failed)
+		}
+	}
+	printTop := func(title string, list []namedCount) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s\tCOUNT\n", title)
+		for _, item := range list {
+			fmt.Fprintf(w, "%s\t%d\n", item.Name, item.Count)
+		}
+	}
+
+	printCounts("TYPE", r.ByType)
+	printCounts("TARGET", r.ByTarget)
+	printTop("TOP SENDERS", r.Senders)
+	printTop("TOP CATEGORIES", r.Categories)
+	w.Flush()
+
+	// Hour of day as a bar chart scaled to the busiest hour
+	busiest := 0
+	for _, n := range r.ByHour {
+		busiest = max(busiest, n)
+	}
+	fmt.Println("\nHOUR")
+	for hour, n := range r.ByHour {
+		bar := strings.Repeat("#", (n*40+busiest-1)/busiest)
+		fmt.Printf("%02d  %-40s %d\n", hour, bar, n)
+	}
+}
+
+func showStatsHelp() {
+	fmt.Print(`Summarize the notification history
+
+Shows counts by type and target, the busiest hours of the day, the top
+senders and categories, and how many deliveries failed.
+
+Usage:
+  notify stats [--since DURATION] [--json]
+
+Options:
+  --since DURATION   Only count notifications from this period, 0 for all (default: 30d)
+  --json             Print the report as JSON
+
+Examples:
+  notify stats
+  notify stats --since 7d --json
+`)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// Most bytes of standard input a message is made from; the rest is read
+// and dropped, so the command writing it doesn't fail
+const stdinReadLimit = 1 << 20
+
+// Ends of longer messages from standard input that can be kept
+var stdinKeeps = []string{"start", "end"}
+
+// Terminal escape sequences, e.g. colors, in command output
+var escapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// stdinConfig shortens messages read from standard input, e.g. the output
+// of 'command | notify'
+type stdinConfig struct {
+	MaxLength int    `yaml:"max_length"` // characters kept of longer messages
+	Keep      string `yaml:"keep"`       // start or end of longer messages
+}
+
+// validate checks the stdin section of config.yaml
+func (c *stdinConfig) validate() error {
+	if c.MaxLength < 1 {
+		return fmt.Errorf("stdin: max_length must be at least 1")
+	}
+	if !slices.Contains(stdinKeeps, c.Keep) {
+		return fmt.Errorf("stdin: invalid keep %q.%s Valid values are: %s",
+			c.Keep, suggest.DidYouMean(c.Keep, stdinKeeps, ""), strings.Join(stdinKeeps, ", "))
+	}
+	return nil
+}
+
+// parseKeep checks a --keep value
+func parseKeep(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !slices.Contains(stdinKeeps, s) {
+		return "", fmt.Errorf("invalid value %q.%s Valid values are: %s",
+			s, suggest.DidYouMean(s, stdinKeeps, ""), strings.Join(stdinKeeps, ", "))
+	}
+	return s, nil
+}
+
+// parseMaxLength parses a --max-length value
+func parseMaxLength(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("expected a number of characters of at least 1, got %q", s)
+	}
+	return n, nil
+}
+
+// stdinPiped reports whether standard input is a pipe or a file, which a
+// message can be read from without waiting for someone to type it
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
+// readStdinMessage reads a message from standard input, without escape
+// sequences and shortened as c says
+func readStdinMessage(c stdinConfig) (string, error) {
+	var data []byte
+	if c.Keep == "end" {
+		tail := &tailBuffer{limit: stdinReadLimit}
+		if _, err := io.Copy(tail, os.Stdin); err != nil {
+			return "", err
+		}
+		data = tail.data
+	} else {
+		var err error
+		if data, err = io.ReadAll(io.LimitReader(os.Stdin, stdinReadLimit)); err != nil {
+			return "", err
+		}
+		io.Copy(io.Discard, os.Stdin)
+	}
+
+	text := strings.ToValidUTF8(string(data), "")
+	text = escapePattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return shortenMessage(strings.TrimSpace(text), c.MaxLength, c.Keep), nil
+}
+
+// shortenMessage cuts text to max characters, keeping its start or end
+// and marking the cut with an ellipsis
+func shortenMessage(text string, max int, keep string) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	if keep == "end" {
+		return "…" + strings.TrimLeft(string(runes[len(runes)-max+1:]), " \t\n")
+	}
+	return strings.TrimRight(string(runes[:max-1]), " \t\n") + "…"
+}
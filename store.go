@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is notify's persistent state, shared between invocations
+type State struct {
+	// Muted categories and when the mute ends (zero means until unmuted)
+	Mutes map[string]time.Time `json:"mutes,omitempty"`
+
+	// Notifications sent with --require-ack, by id
+	Acks map[string]*ackRecord `json:"acks,omitempty"`
+
+	// Notifications summarized by --group, by group name
+	Groups map[string]*groupRecord `json:"groups,omitempty"`
+
+	// Notifications held back for the catch-up summary, oldest first
+	Deferred []groupItem `json:"deferred,omitempty"`
+
+	// Notifications waiting in "notify when", by id
+	Schedules map[string]*scheduleRecord `json:"schedules,omitempty"`
+
+	// Expectations of "notify expect", by check-in name
+	Expectations map[string]*expectRecord `json:"expectations,omitempty"`
+
+	// When "notify checkin" last ran, by check-in name
+	Checkins map[string]time.Time `json:"checkins,omitempty"`
+
+	// Busy times last read from the calendar
+	Calendar *calendarCache `json:"calendar,omitempty"`
+
+	// When the history file was last pruned
+	HistoryPruned time.Time `json:"history_pruned,omitzero"`
+
+	// Secret key of the hashes of history with privacy: hashed
+	HistoryKey []byte `json:"history_key,omitempty"`
+
+	// Links shortened for 'notify daemon' to open, by id
+	ShortLinks map[string]*shortLink `json:"short_links,omitempty"`
+
+	// Tokens of 'notify token' for guest senders, by name
+	GuestTokens map[string]*guestToken `json:"guest_tokens,omitempty"`
+}
+
+// dataDir returns notify's directory under the user config directory
+// (%APPDATA%\notify on Windows, ~/.config/notify elsewhere), creating it
+func dataDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "notify")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// dataFile returns the path of a file in the data directory
+func dataFile(name string) (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// loadState reads the current state without locking it
+func loadState() (*State, error) {
+	path, err := dataFile("state.json")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("corrupt state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// updateState loads the state, applies fn and saves the result while
+// holding the state lock, so concurrent invocations don't lose changes
+func updateState(fn func(*State) error) error {
+	unlock, err := lockData("state")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	pruneState(state, time.Now())
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := dataFile("state.json")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't truncate the state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// pruneState drops what no longer has an effect: ended mutes, acks
+// acknowledged or pending for too long and groups that start over
+func pruneState(s *State, now time.Time) {
+	for category, until := range s.Mutes {
+		if !until.IsZero() && now.After(until) {
+			delete(s.Mutes, category)
+		}
+	}
+	for id, a := range s.Acks {
+		if a.expired(now) {
+			delete(s.Acks, id)
+		}
+	}
+	for name, g := range s.Groups {
+		if now.Sub(g.Updated) > groupIdle {
+			delete(s.Groups, name)
+		}
+	}
+}
+
+// lockData takes an exclusive lock file in the data directory and returns
+// the function that releases it. The lock file holds a random owner, so a
+// process only releases its own lock. Locks older than staleLock are
+// assumed to belong to a crashed process and are taken over by
+// takeOverLock.
+func lockData(name string) (func(), error) {
+	const staleLock = 10 * time.Second
+
+	path, err := dataFile(name + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	owner := newToken()
+	release := func() {
+		if data, err := os.ReadFile(path); err == nil && string(data) == owner {
+			os.Remove(path)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = file.WriteString(owner)
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return release, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if takeOverLock(path, owner, staleLock) {
+			return release, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// takeOverLock replaces the lock file at path with one held by owner when
+// it is older than stale, reporting whether owner now holds the lock. The
+// new lock is renamed over the stale one, so the file never goes missing
+// for a third process to create. Processes taking over the same stale lock
+// at once replace each other's locks, so ownership is checked again
+// afterwards and only the last of them keeps it.
+func takeOverLock(path, owner string, stale time.Duration) bool {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= stale {
+		return false
+	}
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	temp := path + "." + owner + ".tmp"
+	if err := os.WriteFile(temp, []byte(owner), 0600); err != nil {
+		return false
+	}
+	// Give up when another process took the lock over since it was read
+	if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, previous) {
+		os.Remove(temp)
+		return false
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return false
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	current, err := os.ReadFile(path)
+	return err == nil && string(current) == owner
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLockDataTakesOverStaleLocks(t *testing.T) {
+	isolate(t)
+	path, err := dataFile("test.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("crashed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockData("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := os.ReadFile(path)
+	if err != nil || !isToken(string(owner)) {
+		t.Fatalf("lock file holds %q, %v, want the new owner", owner, err)
+	}
+
+	// A lock taken over by another process isn't released by the old owner
+	if err := os.WriteFile(path, []byte("another"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if data, err := os.ReadFile(path); err != nil || string(data) != "another" {
+		t.Fatalf("lock file = %q, %v, want the other owner's lock kept", data, err)
+	}
+	os.Remove(path)
+
+	unlock, err = lockData("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("lock file left after release: %v", err)
+	}
+}
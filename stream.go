@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Longest line shown in a stream notification
+const maxStreamLine = 300
+
+// Named groups of --match patterns that fill in the notification
+var streamGroups = []string{"title", "type", "message"}
+
+// Levels written in logs, by the notification type they map to
+var logLevels = map[string]string{
+	"fatal": "error", "critical": "error", "crit": "error", "error": "error", "err": "error", "alert": "error", "emerg": "error", "panic": "error",
+	"warning": "warning", "warn": "warning",
+	"success": "success", "ok": "success",
+	"info": "info", "notice": "info", "debug": "info", "trace": "info",
+}
+
+// Keys of JSON log lines read for each field by default, the first one
+// present wins
+var defaultJSONKeys = map[string][]string{
+	"title":   {"logger", "logger_name", "log.logger"},
+	"type":    {"level", "severity", "lvl", "log.level", "@l"},
+	"message": {"msg", "message", "@m", "@mt"},
+}
+
+// streamFields are the parts of a line that fill in a notification
+type streamFields struct {
+	Title   string
+	Type    string
+	Message string
+}
+
+// streamGuard decides which stdin lines of "notify stream" are shown:
+// repeats of a recent line are dropped, as are lines over the rate limit
+// or over the cap of each minute
+type streamGuard struct {
+	limit     *rateLimiter
+	perMinute int // cap of each minute, 0 for none
+	dedup     time.Duration
+	seen      map[string]time.Time // when each recent line was last shown
+
+	window time.Time // start of the current minute of the cap
+	shown  int       // lines shown in it
+
+	skipped     int       // lines dropped since the last notification
+	skippedType string    // the most urgent type among them
+	firstSkip   time.Time // when the first of them was dropped
+}
+
+// allow reports whether a line of type typ is shown now
+func (g *streamGuard) allow(line, typ string, now time.Time) bool {
+	if g.dedup > 0 {
+		if last, ok := g.seen[line]; ok && now.Sub(last) < g.dedup {
+			g.skip(typ, now)
+			return false
+		}
+	}
+	if now.Sub(g.window) >= time.Minute {
+		g.window, g.shown = now, 0
+	}
+	if g.perMinute > 0 && g.shown >= g.perMinute {
+		g.skip(typ, now)
+		return false
+	}
+	if g.limit != nil {
+		if ok, _ := g.limit.allow("stdin"); !ok {
+			g.skip(typ, now)
+			return false
+		}
+	}
+
+	g.shown++
+	if g.dedup > 0 {
+		if len(g.seen) >= 10000 {
+			for l, t := range g.seen {
+				if now.Sub(t) >= g.dedup {
+					delete(g.seen, l)
+				}
+			}
+		}
+		g.seen[line] = now
+	}
+	return true
+}
+
+// skip counts a dropped line
+func (g *streamGuard) skip(typ string, now time.Time) {
+	if g.skipped == 0 {
+		g.firstSkip, g.skippedType = now, typ
+	}
+	g.skipped++
+	if typeRank(typ) > typeRank(g.skippedType) {
+		g.skippedType = typ
+	}
+}
+
+// takeSkipped returns how many lines were dropped since it was last
+// called, and the most urgent type among them
+func (g *streamGuard) takeSkipped() (int, string) {
+	n, typ := g.skipped, g.skippedType
+	g.skipped, g.skippedType = 0, ""
+	return n, typ
+}
+
+// compileStreamPattern parses a --match regex, whose named groups must
+// be among streamGroups
+func compileStreamPattern(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" && !slices.Contains(streamGroups, name) {
+			return nil, fmt.Errorf("unknown group %q in %s.%s Groups are: %s", name, expr, suggest.DidYouMean(name, streamGroups, ""), strings.Join(streamGroups, ", "))
+		}
+	}
+	return re, nil
+}
+
+// parseJSONLine reads the fields of a structured log line, a JSON
+// object, looking up the keys given for each field. Dotted keys like
+// log.level reach into nested objects.
+func parseJSONLine(line string, keys map[string][]string) (streamFields, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return streamFields{}, false
+	}
+	var object map[string]any
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return streamFields{}, false
+	}
+
+	lookup := func(field string) string {
+		for _, key := range keys[field] {
+			if value, ok := jsonLogValue(object, key); ok {
+				return value
+			}
+		}
+		return ""
+	}
+	return streamFields{
+		Title:   lookup("title"),
+		Type:    lookup("type"),
+		Message: cmp.Or(lookup("message"), line),
+	}, true
+}
+
+// jsonLogValue returns the value of a key as text
+func jsonLogValue(object map[string]any, key string) (string, bool) {
+	value, ok := object[key]
+	if !ok {
+		first, rest, nested := strings.Cut(key, ".")
+		inner, isObject := object[first].(map[string]any)
+		if !nested || !isObject {
+			return "", false
+		}
+		return jsonLogValue(inner, rest)
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", false
+	}
+	data, _ := json.Marshal(value)
+	return string(data), true
+}
+
+// parseJSONKeys reads a --field like title=logger,name
+func parseJSONKeys(keys map[string][]string, replaced map[string]bool, v string) error {
+	field, list, ok := strings.Cut(v, "=")
+	field = strings.TrimSpace(field)
+	if !ok || strings.TrimSpace(list) == "" {
+		return fmt.Errorf("%q should look like FIELD=KEY, e.g. title=logger", v)
+	}
+	if !slices.Contains(streamGroups, field) {
+		return fmt.Errorf("unknown field %q.%s Fields are: %s", field, suggest.DidYouMean(field, streamGroups, ""), strings.Join(streamGroups, ", "))
+	}
+	if !replaced[field] {
+		keys[field], replaced[field] = nil, true
+	}
+	for key := range strings.SplitSeq(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[field] = append(keys[field], key)
+		}
+	}
+	return nil
+}
+
+// matchLine applies the --match patterns to a line whose fields were
+// read already. Lines matching none of the patterns are skipped, and the
+// named groups of the first match fill in the fields.
+func matchLine(patterns []*regexp.Regexp, line string, fields streamFields) (streamFields, bool) {
+	if len(patterns) == 0 {
+		return fields, true
+	}
+	for _, re := range patterns {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			switch value := strings.TrimSpace(m[i]); {
+			case value == "":
+			case name == "title":
+				fields.Title = value
+			case name == "type":
+				fields.Type = value
+			case name == "message":
+				fields.Message = value
+			}
+		}
+		return fields, true
+	}
+	return fields, false
+}
+
+// logType maps a level like WARN or Error to a notification type.
+// Numbers are the levels of pino and bunyan: 50 error, 40 warn, 30 info.
+func logType(level string) (string, bool) {
+	level = strings.TrimSpace(level)
+	if n, err := strconv.Atoi(level); err == nil {
+		switch {
+		case n >= 50:
+			return "error", true
+		case n >= 40:
+			return "warning", true
+		}
+		return "info", true
+	}
+	t, ok := logLevels[strings.ToLower(level)]
+	return t, ok
+}
+
+// runStream implements "notify stream", one notification per stdin line
+func runStream(args []string) error {
+	opts := newNotifyOptions()
+	rate, burst := 10, 5
+	guard := &streamGuard{dedup: time.Minute, seen: map[string]time.Time{}}
+	quiet, overflow := false, false
+	minRank := typeRank("info")
+	var patterns []*regexp.Regexp
+	jsonKeys, replaced := maps.Clone(defaultJSONKeys), map[string]bool{}
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "rate", Set: func(v string) (err error) { rate, err = parseCount(v); return }},
+		cliFlag{Name: "burst", Set: func(v string) (err error) { burst, err = parseGroupSize(v); return }},
+		cliFlag{Name: "max-per-minute", Set: func(v string) (err error) { guard.perMinute, err = parseCount(v); return }},
+		cliFlag{Name: "min-level", Set: func(v string) error {
+			t, ok := logType(v)
+			if !ok {
+				return fmt.Errorf("unknown level %q, use info, warning or error", v)
+			}
+			minRank = typeRank(t)
+			return nil
+		}},
+		cliFlag{Name: "summary-overflow", Bool: true, Set: func(v string) (err error) { overflow, err = parseStrictBool(v); return }},
+		cliFlag{Name: "dedup", Set: func(v string) (err error) { guard.dedup, err = parseRetention(v); return }},
+		cliFlag{Name: "match", Set: func(v string) error {
+			re, err := compileStreamPattern(v)
+			if err != nil {
+				return err
+			}
+			patterns = append(patterns, re)
+			return nil
+		}},
+		cliFlag{Name: "field", Set: func(v string) error { return parseJSONKeys(jsonKeys, replaced, v) }},
+		cliFlag{Name: "quiet", Bool: true, Set: func(v string) (err error) { quiet, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showStreamHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s (lines are read from stdin)", words[0])
+	}
+	if _, err := opts.build("stream"); err != nil {
+		return err
+	}
+	guard.limit = newRateLimiter(rate, burst)
+
+	// Lines are read in the background so overflow summaries go out
+	// while the input is quiet
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		readErr <- scanner.Err()
+		close(lines)
+	}()
+
+	show := func(o notifyOptions, text string) error {
+		n, err := o.build(text)
+		if err != nil {
+			return err
+		}
+		if err := sendNotification(n); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		return nil
+	}
+	summary := func(text string) error {
+		skipped, typ := guard.takeSkipped()
+		summaryOpts := *opts
+		summaryOpts.Type = typ
+		return show(summaryOpts, fmt.Sprintf(text, skipped))
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var line string
+		select {
+		case now := <-ticker.C:
+			if overflow && guard.skipped > 0 && now.Sub(guard.firstSkip) >= time.Minute {
+				if err := summary("%d more suppressed"); err != nil {
+					return err
+				}
+			}
+			continue
+		case l, ok := <-lines:
+			if !ok {
+				if err := <-readErr; err != nil {
+					return err
+				}
+				// Don't let the last dropped lines go unmentioned
+				if guard.skipped > 0 {
+					return summary("%d more suppressed at the end of the stream")
+				}
+				return nil
+			}
+			line = l
+		}
+
+		if !quiet {
+			fmt.Println(line)
+		}
+		fields, structured := parseJSONLine(line, jsonKeys)
+		if !structured {
+			fields = streamFields{Message: line}
+		}
+		fields, ok := matchLine(patterns, line, fields)
+		if !ok {
+			continue
+		}
+		lineOpts := *opts
+		if t, ok := logType(fields.Type); ok {
+			lineOpts.Type = t
+		}
+		// Success counts as info, so --min-level info keeps it
+		if max(typeRank(lineOpts.Type), typeRank("info")) < minRank {
+			continue
+		}
+		text := oneLine(fields.Message, maxStreamLine)
+		title := oneLine(fields.Title, notify.MaxTitleLength)
+		if text == "" || !guard.allow(title+"\n"+text, lineOpts.Type, time.Now()) {
+			continue
+		}
+
+		if !overflow && guard.skipped > 0 {
+			skipped, _ := guard.takeSkipped()
+			text += fmt.Sprintf("\nRepeated or excess lines skipped: %d", skipped)
+		}
+		lineOpts.Title = cmp.Or(title, opts.Title)
+		if err := show(lineOpts, text); err != nil {
+			return err
+		}
+	}
+}
+
+func showStreamHelp() {
+	fmt.Print(`Show a notification for each line read from stdin
+
+Meant for following logs: every non-empty line becomes a notification,
+and input is passed through to stdout. To keep a noisy log from flooding
+the screen, a line repeating one shown within --dedup is skipped, and
+lines over the rate limit are dropped; the next notification says how
+many were skipped.
+
+Structured logs work too: lines that are JSON objects take their type
+from the level (or severity, lvl, log.level), the message from msg (or
+message) and the title from logger, unless --field maps other keys.
+
+Usage:
+  COMMAND | notify stream [OPTIONS]
+  COMMAND | notify --follow [OPTIONS]
+
+Options:
+  --rate N           Notifications per minute (default: 10, 0 for no limit)
+  --burst N          Notifications allowed at once (default: 5)
+  --max-per-minute N At most N notifications in any minute (default: no cap
+                     beyond --rate)
+  --dedup DURATION   Skip lines repeating one shown this recently (default:
+                     1m, 0 to show every repeat)
+  --min-level LEVEL  Ignore lines below this level: info, warning or error
+  --summary-overflow Instead of counting skipped lines in the next
+                     notification, show a separate "17 more suppressed"
+                     summary a minute after lines start being dropped
+  --match REGEX      Only show lines matching this (repeatable). Named
+                     groups fill in the notification: (?P<title>...),
+                     (?P<type>...) taking levels like ERROR or warn, and
+                     (?P<message>...) instead of the whole line
+  --field FIELD=KEY  JSON key read for title, type or message in structured
+                     log lines, e.g. title=logger (repeatable, commas for
+                     fallbacks; dotted keys reach into nested objects)
+  --quiet            Don't echo stdin to stdout
+  Plus the notification options of 'notify --help', e.g. --type and --title.
+
+Examples:
+  tail -f app.log | grep ERROR | notify stream --type error --title "App"
+  tail -f app.log | notify stream --match '(?P<type>ERROR|WARN) \[(?P<title>[^]]+)\] (?P<message>.*)'
+  kubectl logs -f api | notify stream --min-level warning --max-per-minute 5 --summary-overflow
+`)
+}
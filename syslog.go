@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Syslog facility names by code
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Syslog severity names by level, most severe first
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Other common spellings of the severities
+var syslogSeverityAliases = map[string]int{
+	"emergency": 0, "panic": 0, "critical": 2, "error": 3, "warn": 4, "informational": 6,
+}
+
+// Longest syslog message accepted, as in the relay's default body limit
+const maxSyslogMessage = 64 << 10
+
+// syslogMessage is a parsed RFC 5424 or BSD (RFC 3164) syslog message
+type syslogMessage struct {
+	Facility int
+	Severity int
+	Hostname string
+	AppName  string
+	Message  string
+}
+
+// syslogServer turns matching syslog messages into notifications, shown
+// or forwarded the way the relay does
+type syslogServer struct {
+	relay      *relayServer
+	facilities []int // accepted facilities, empty for all
+	severity   int   // least severe level shown
+	patterns   []*regexp.Regexp
+}
+
+// runSyslog implements "notify syslog"
+func runSyslog(args []string) error {
+	udp, tcp := "", ""
+	out := newListenerOptions()
+	srv := &syslogServer{relay: out.relay, severity: 4}
+
+	flags := append(out.flags(), []cliFlag{
+		{Name: "udp", Set: func(v string) error { udp = v; return nil }},
+		{Name: "tcp", Set: func(v string) error { tcp = v; return nil }},
+		{Name: "facility", Set: func(v string) error {
+			for name := range strings.SplitSeq(v, ",") {
+				facility, err := parseSyslogFacility(name)
+				if err != nil {
+					return err
+				}
+				srv.facilities = append(srv.facilities, facility)
+			}
+			return nil
+		}},
+		{Name: "severity", Set: func(v string) (err error) { srv.severity, err = parseSyslogSeverity(v); return }},
+		{Name: "match", Set: func(v string) error {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("invalid --match pattern: %w", err)
+			}
+			srv.patterns = append(srv.patterns, re)
+			return nil
+		}},
+		{Name: "help", Bool: true, Set: func(string) error { showSyslogHelp(); os.Exit(0); return nil }},
+	}...)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+	if udp == "" && tcp == "" {
+		udp = ":514"
+	}
+
+	if err := out.start(); err != nil {
+		return err
+	}
+
+	// Either listener failing stops the command
+	errs := make(chan error, 2)
+	var listening []string
+	if udp != "" {
+		conn, err := net.ListenPacket("udp", udp)
+		if err != nil {
+			return err
+		}
+		listening = append(listening, "udp://"+udp)
+		go func() { errs <- srv.serveUDP(conn) }()
+	}
+	if tcp != "" {
+		listener, err := net.Listen("tcp", tcp)
+		if err != nil {
+			return err
+		}
+		listening = append(listening, "tcp://"+tcp)
+		go func() { errs <- srv.serveTCP(listener) }()
+	}
+
+	if len(srv.relay.targets) > 0 {
+		log.Printf("Syslog listening on %s, forwarding to %s", strings.Join(listening, ", "), strings.Join(srv.relay.targets, ", "))
+	} else {
+		log.Printf("Syslog listening on %s, showing notifications locally", strings.Join(listening, ", "))
+	}
+	return <-errs
+}
+
+// serveUDP handles one message per datagram
+func (s *syslogServer) serveUDP(conn net.PacketConn) error {
+	buf := make([]byte, maxSyslogMessage)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		host, _, _ := net.SplitHostPort(addr.String())
+		go s.handle(bytes.Clone(buf[:n]), host)
+	}
+}
+
+// serveTCP accepts connections carrying a stream of messages
+func (s *syslogServer) serveTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn reads the messages of one TCP connection until it closes
+func (s *syslogServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+
+	r := bufio.NewReaderSize(conn, maxSyslogMessage)
+	for {
+		frame, err := readSyslogFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("%s: closing syslog connection: %v", host, err)
+			}
+			return
+		}
+		if len(bytes.TrimSpace(frame)) > 0 {
+			s.handle(frame, host)
+		}
+	}
+}
+
+// readSyslogFrame reads a message framed by octet counting ("LENGTH MSG")
+// or ended by a newline, the two framings of RFC 6587
+func readSyslogFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := r.ReadSlice(' ')
+		if err != nil {
+			return nil, fmt.Errorf("invalid message length: %w", err)
+		}
+		length, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || length > maxSyslogMessage {
+			return nil, fmt.Errorf("invalid message length %q", prefix[:len(prefix)-1])
+		}
+		frame := make([]byte, length)
+		_, err = io.ReadFull(r, frame)
+		return frame, err
+	}
+
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("message longer than %d bytes", maxSyslogMessage)
+	}
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		err = nil
+	}
+	return bytes.Clone(line), err
+}
+
+// handle shows or forwards a received message when it passes the filters
+func (s *syslogServer) handle(data []byte, from string) {
+	m, err := parseSyslog(data)
+	if err != nil {
+		log.Printf("%s: invalid syslog message: %v", from, err)
+		return
+	}
+	if !s.matches(m) {
+		return
+	}
+
+	trace := startSpan(nil, "syslog.message", "client.address", from,
+		"syslog.facility", syslogFacilities[m.Facility], "syslog.severity", syslogSeverities[m.Severity])
+	s.relay.deliverEvent("syslog", from, m.relayMessage(from), trace)
+}
+
+// matches reports whether a message passes the facility, severity and
+// pattern filters
+func (s *syslogServer) matches(m *syslogMessage) bool {
+	if m.Severity > s.severity {
+		return false
+	}
+	if len(s.facilities) > 0 && !slices.Contains(s.facilities, m.Facility) {
+		return false
+	}
+	if len(s.patterns) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(s.patterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(m.Message)
+	})
+}
+
+// relayMessage turns a syslog message into a notification from host,
+// typed by its severity
+func (m *syslogMessage) relayMessage(from string) *notify.RelayMessage {
+	typ := "info"
+	switch {
+	case m.Severity <= 3:
+		typ = "error"
+	case m.Severity == 4:
+		typ = "warning"
+	}
+
+	title := cmp.Or(m.Hostname, from)
+	if m.AppName != "" {
+		title += ": " + m.AppName
+	}
+	return &notify.RelayMessage{
+		App:      "Syslog",
+		Category: "syslog",
+		Type:     typ,
+		Title:    oneLine(title, notify.MaxTitleLength),
+		Message:  cmp.Or(oneLine(m.Message, 1000), syslogSeverities[m.Severity]),
+	}
+}
+
+// Tag of a BSD syslog message, like "sshd[42]: "
+var bsdSyslogTag = regexp.MustCompile(`^([^\s:\[\]]{1,48})(\[[^\]]*\])?:\s*`)
+
+// parseSyslog parses an RFC 5424 message, falling back to the BSD format
+// of RFC 3164 that much network gear still sends
+func parseSyslog(data []byte) (*syslogMessage, error) {
+	s := strings.TrimRight(string(data), "\r\n\x00")
+
+	rest, ok := strings.CutPrefix(s, "<")
+	end := strings.IndexByte(rest, '>')
+	if !ok || end < 1 || end > 3 {
+		return nil, errors.New("missing priority")
+	}
+	priority, err := strconv.Atoi(rest[:end])
+	if err != nil || priority > 191 {
+		return nil, fmt.Errorf("invalid priority %q", rest[:end])
+	}
+	m := &syslogMessage{Facility: priority / 8, Severity: priority % 8}
+	rest = rest[end+1:]
+
+	if after, ok := strings.CutPrefix(rest, "1 "); ok {
+		return m, m.parseRFC5424(after)
+	}
+	m.parseBSD(rest)
+	return m, nil
+}
+
+// parseRFC5424 parses what follows the version:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG].
+// The timestamp is checked but not kept, as messages are shown on arrival.
+func (m *syslogMessage) parseRFC5424(s string) error {
+	var fields [5]string
+	for i := range fields {
+		var ok bool
+		if fields[i], s, ok = strings.Cut(s, " "); !ok {
+			return errors.New("truncated RFC 5424 header")
+		}
+		if fields[i] == "-" {
+			fields[i] = ""
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fields[0]); fields[0] != "" && err != nil {
+		return fmt.Errorf("invalid timestamp %q", fields[0])
+	}
+	m.Hostname, m.AppName = fields[1], fields[2]
+
+	// Skip the structured data: "-" or [ID PARAM="VALUE" ...] elements,
+	// where values may contain escaped quotes and brackets
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		s = rest
+	} else {
+		i, quoted := 0, false
+		for i < len(s) && s[i] == '[' {
+			for i++; i < len(s) && (quoted || s[i] != ']'); i++ {
+				switch s[i] {
+				case '\\':
+					i++
+				case '"':
+					quoted = !quoted
+				}
+			}
+			if i >= len(s) {
+				return errors.New("unterminated structured data")
+			}
+			i++
+		}
+		if i == 0 {
+			return errors.New("invalid structured data")
+		}
+		s = s[i:]
+	}
+
+	s = strings.TrimPrefix(s, " ")
+	m.Message = strings.TrimSpace(strings.TrimPrefix(s, "\ufeff"))
+	return nil
+}
+
+// parseBSD parses "Mmm dd hh:mm:ss HOSTNAME TAG: MSG". Senders often leave
+// out parts of it, so whatever doesn't fit is kept as the message.
+func (m *syslogMessage) parseBSD(s string) {
+	if len(s) > len(time.Stamp) && s[len(time.Stamp)] == ' ' {
+		if _, err := time.Parse(time.Stamp, s[:len(time.Stamp)]); err == nil {
+			s = s[len(time.Stamp)+1:]
+
+			if host, rest, ok := strings.Cut(s, " "); ok && !strings.HasSuffix(host, ":") {
+				m.Hostname, s = host, rest
+			}
+		}
+	}
+	if tag := bsdSyslogTag.FindStringSubmatch(s); tag != nil {
+		m.AppName = tag[1]
+		s = s[len(tag[0]):]
+	}
+	m.Message = strings.TrimSpace(s)
+}
+
+// parseSyslogFacility accepts a facility name or code
+func parseSyslogFacility(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := slices.Index(syslogFacilities, s); i >= 0 {
+		return i, nil
+	}
+	if code, err := strconv.Atoi(s); err == nil && code >= 0 && code < len(syslogFacilities) {
+		return code, nil
+	}
+	return 0, fmt.Errorf("unknown facility %q.%s Facilities are: %s",
+		s, suggest.DidYouMean(s, syslogFacilities, ""), strings.Join(syslogFacilities, ", "))
+}
+
+// parseSyslogSeverity accepts a severity name or level
+func parseSyslogSeverity(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := slices.Index(syslogSeverities, s); i >= 0 {
+		return i, nil
+	}
+	if level, ok := syslogSeverityAliases[s]; ok {
+		return level, nil
+	}
+	if level, err := strconv.Atoi(s); err == nil && level >= 0 && level < len(syslogSeverities) {
+		return level, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q.%s Severities are: %s",
+		s, suggest.DidYouMean(s, syslogSeverities, ""), strings.Join(syslogSeverities, ", "))
+}
+
+func showSyslogHelp() {
+	fmt.Print(`Listen for syslog messages and notify on the ones that matter
+
+Routers, switches, NAS boxes and other appliances can send their logs to
+this machine. Messages in RFC 5424 and the older BSD format are accepted
+over UDP, and over TCP with octet counting or newline framing. Messages at
+or above --severity that match the filters become notifications: errors
+for emerg to err, warnings for warning, and info toasts below that.
+
+Usage:
+  notify syslog [OPTIONS]
+
+Options:
+  --udp ADDR         Listen for UDP messages on this address (default: :514)
+  --tcp ADDR         Listen for TCP messages on this address; without --udp
+                     only TCP is used
+  --severity LEVEL   Least severe level to notify on: emerg, alert, crit, err,
+                     warning, notice, info or debug (default: warning)
+  --facility LIST    Only these facilities, e.g. auth,local0 (repeatable)
+  --match REGEX      Only messages matching this pattern (repeatable, any
+                     pattern may match)
+  --to URL           Forward to this relay instead of showing toasts (repeatable)
+  --to-token TOKEN   Token presented to the --to relays
+  --ca FILE          Trust this CA for the --to relays instead of the system roots
+  --client-cert FILE Client certificate presented to the --to relays
+  --client-key FILE  Private key of --client-cert
+  --rate N           Notifications per minute per sender, 0 for no limit (default: 30)
+  --burst N          Notifications a sender may cause at once (default: 10)
+  --help             Show this help
+
+Ports below 1024 need root on Linux and macOS; use --udp :5514 there, or
+send the messages to a relay with --to.
+
+Examples:
+  notify syslog
+  notify syslog --udp :5514 --tcp :5514 --severity err
+  notify syslog --facility auth,authpriv --match "Failed password|Invalid user"
+  notify syslog --severity crit --to https://desktop.lan:8787 --to-token secret
+`)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want syslogMessage
+		err  string // part of the error
+	}{
+		{"RFC 5424", `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event`,
+			syslogMessage{Facility: 20, Severity: 5, Hostname: "mymachine.example.com", AppName: "evntslog", Message: "An application event"}, ""},
+		{"RFC 5424 offset timestamp", `<34>1 2003-08-24T05:14:15.000003-07:00 host su - - - 'su root' failed`,
+			syslogMessage{Facility: 4, Severity: 2, Hostname: "host", AppName: "su", Message: "'su root' failed"}, ""},
+		{"RFC 5424 nil values", `<14>1 - - - - - -`, syslogMessage{Facility: 1, Severity: 6}, ""},
+		{"RFC 5424 BOM", "<14>1 - host app - - - \ufeffhello\r\n", syslogMessage{Facility: 1, Severity: 6, Hostname: "host", AppName: "app", Message: "hello"}, ""},
+		{"structured data", `<165>1 2003-10-11T22:14:15.003Z host app - ID47 [exampleSDID@32473 iut="3" eventSource="Application"][ex@1 a="b"] Event`,
+			syslogMessage{Facility: 20, Severity: 5, Hostname: "host", AppName: "app", Message: "Event"}, ""},
+		{"escaped structured data", `<165>1 - host app - - [ex@1 quote="say \"hi\"" bracket="\]" close="]" slash="\\"] Event`,
+			syslogMessage{Facility: 20, Severity: 5, Hostname: "host", AppName: "app", Message: "Event"}, ""},
+		{"structured data only", `<165>1 - host app - - [ex@1 a="b"]`,
+			syslogMessage{Facility: 20, Severity: 5, Hostname: "host", AppName: "app"}, ""},
+		{"BSD", `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`,
+			syslogMessage{Facility: 4, Severity: 2, Hostname: "mymachine", AppName: "su", Message: "'su root' failed for lonvick on /dev/pts/8"}, ""},
+		{"BSD with pid", `<30>Oct  1 02:03:04 nas sshd[42]: Accepted key`,
+			syslogMessage{Facility: 3, Severity: 6, Hostname: "nas", AppName: "sshd", Message: "Accepted key"}, ""},
+		{"BSD without a timestamp", `<13>kernel: link down`, syslogMessage{Facility: 1, Severity: 5, AppName: "kernel", Message: "link down"}, ""},
+		{"BSD bare message", `<0>disk on fire`, syslogMessage{Message: "disk on fire"}, ""},
+		{"highest priority", `<191>x`, syslogMessage{Facility: 23, Severity: 7, Message: "x"}, ""},
+		{"priority too high", `<192>x`, syslogMessage{}, "invalid priority"},
+		{"priority too long", `<0001>x`, syslogMessage{}, "missing priority"},
+		{"priority not a number", `<1a>x`, syslogMessage{}, "invalid priority"},
+		{"empty priority", `<>x`, syslogMessage{}, "missing priority"},
+		{"no priority", `Oct 11 22:14:15 host x`, syslogMessage{}, "missing priority"},
+		{"invalid timestamp", `<14>1 2003-10-11 22:14:15 host app - - - x`, syslogMessage{}, "invalid timestamp"},
+		{"truncated header", `<14>1 - host app`, syslogMessage{}, "truncated"},
+		{"unterminated structured data", `<14>1 - host app - - [ex@1 a="]"`, syslogMessage{}, "unterminated structured data"},
+		{"invalid structured data", `<14>1 - host app - - x`, syslogMessage{}, "invalid structured data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseSyslog([]byte(tt.data))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseSyslog() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *m != tt.want {
+				t.Fatalf("parseSyslog() = %+v, want %+v", *m, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSyslogFrame(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("11 <14>1 hello<14>line one\n5 <0>x\n<14>last"))
+	for _, want := range []string{"<14>1 hello", "<14>line one\n", "<0>x\n", "<14>last"} {
+		frame, err := readSyslogFrame(r)
+		if err != nil || string(frame) != want {
+			t.Fatalf("readSyslogFrame() = %q, %v, want %q", frame, err, want)
+		}
+	}
+
+	if _, err := readSyslogFrame(bufio.NewReader(strings.NewReader("99999999 <14>x"))); err == nil || !strings.Contains(err.Error(), "invalid message length") {
+		t.Fatalf("readSyslogFrame() error = %v, want an invalid length", err)
+	}
+}
+
+func TestSyslogRelayMessage(t *testing.T) {
+	tests := []struct {
+		severity int
+		want     string
+	}{
+		{0, "error"}, {3, "error"}, {4, "warning"}, {5, "info"}, {7, "info"},
+	}
+	for _, tt := range tests {
+		m := &syslogMessage{Severity: tt.severity, AppName: "sshd"}
+		got := m.relayMessage("192.0.2.1")
+		if got.Type != tt.want || got.Title != "192.0.2.1: sshd" || got.Message != syslogSeverities[tt.severity] {
+			t.Errorf("severity %d: relayMessage() = %+v, want a %s from 192.0.2.1: sshd", tt.severity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// Result lines of the Test Anything Protocol, used by node --test, tape
+// and pytest-tap
+var tapPattern = regexp.MustCompile(`^(\s*)(not ok|ok)(?:\s+\d+)?(?:\s+-)?(?:\s+([^#]*?))?\s*(?:#\s*((?i:skip|todo))\b.*)?$`)
+
+// Directories skipped when --watch looks for changes
+var unwatchedDirs = []string{"node_modules", "vendor", "bin", "obj", "dist"}
+
+// testResults are the outcomes read from the output of a test runner
+type testResults struct {
+	Format  string // go, jest or tap, once recognized
+	Passed  int
+	Failed  int
+	Skipped int
+	First   string // name of the first failing test
+	Detail  string // first line of its failure message
+
+	output  map[string][]string // go test output by test, for failure messages
+	build   []string            // go build errors
+	broken  map[string]bool     // go packages with a failed test
+	failing bool                // reading the TAP diagnostics of First
+}
+
+// goTestEvent is a line of go test -json
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// jestReport is the part of jest --json read for the summary
+type jestReport struct {
+	NumTotalTests   *int
+	NumPassedTests  int
+	NumFailedTests  int
+	NumPendingTests int
+	NumTodoTests    int
+	TestResults     []struct {
+		Name             string
+		Message          string
+		AssertionResults []struct {
+			FullName        string
+			Status          string
+			FailureMessages []string
+		}
+	}
+}
+
+// read takes a line of the runner's output and returns what is shown of
+// it: the text of go test -json, nothing of jest's JSON report, and other
+// lines as they are
+func (r *testResults) read(line string) string {
+	if r.Format == "" || r.Format == "go" {
+		var event goTestEvent
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &event) == nil && event.Action != "" {
+			r.Format = "go"
+			r.goEvent(&event)
+			return strings.TrimSuffix(event.Output, "\n")
+		}
+	}
+	if r.Format == "" || r.Format == "jest" {
+		var report jestReport
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &report) == nil && report.NumTotalTests != nil {
+			r.Format = "jest"
+			r.jestReport(&report)
+			return ""
+		}
+	}
+	if r.Format == "" || r.Format == "tap" {
+		r.tapLine(line)
+	}
+	return line
+}
+
+// goEvent counts the top-level tests of go test -json, taking the failure
+// message from the output of the first failing test or package
+func (r *testResults) goEvent(e *goTestEvent) {
+	if r.output == nil {
+		r.output, r.broken = map[string][]string{}, map[string]bool{}
+	}
+	key := e.Package + " " + e.Test
+	switch e.Action {
+	case "output":
+		r.output[key] = append(r.output[key], e.Output)
+	case "build-output":
+		r.build = append(r.build, e.Output)
+	case "pass", "fail", "skip":
+		if e.Action == "fail" && r.First == "" {
+			r.First = orDefault(e.Test, e.Package)
+			r.Detail = goFailure(append(r.output[key], r.build...))
+		}
+		if e.Test == "" {
+			// A package failing without a failed test didn't build or crashed
+			if e.Action == "fail" && !r.broken[e.Package] {
+				r.Failed++
+			}
+		} else if !strings.Contains(e.Test, "/") {
+			switch e.Action {
+			case "pass":
+				r.Passed++
+			case "fail":
+				r.Failed++
+				r.broken[e.Package] = true
+			case "skip":
+				r.Skipped++
+			}
+		}
+		delete(r.output, key)
+	}
+}
+
+// goFailure returns the first line of a failing test's output that isn't
+// go test's own framing
+func goFailure(output []string) string {
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", line == "FAIL", line == "PASS",
+			strings.HasPrefix(line, "=== "), strings.HasPrefix(line, "--- "),
+			strings.HasPrefix(line, "FAIL\t"), strings.HasPrefix(line, "ok "),
+			strings.HasPrefix(line, "# "), strings.HasPrefix(line, "exit status"):
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// jestReport reads the counts and first failure of jest --json
+func (r *testResults) jestReport(report *jestReport) {
+	r.Passed += report.NumPassedTests
+	r.Failed += report.NumFailedTests
+	r.Skipped += report.NumPendingTests + report.NumTodoTests
+	for _, file := range report.TestResults {
+		for _, a := range file.AssertionResults {
+			if a.Status == "failed" && r.First == "" {
+				r.First = a.FullName
+				if len(a.FailureMessages) > 0 {
+					r.Detail = firstLine(a.FailureMessages[0])
+				}
+			}
+		}
+		// Suites that fail to run have a message and no results
+		if file.Message != "" && len(file.AssertionResults) == 0 {
+			r.Failed++
+			if r.First == "" {
+				r.First, r.Detail = filepath.Base(file.Name), firstLine(file.Message)
+			}
+		}
+	}
+}
+
+// tapLine counts the top-level results of TAP output, reading the failure
+// message from the diagnostics of the first failure
+func (r *testResults) tapLine(line string) {
+	m := tapPattern.FindStringSubmatch(line)
+	if m == nil {
+		if r.failing && r.Detail == "" {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+			if ok && (key == "message" || key == "error") {
+				r.Detail = strings.Trim(strings.TrimSpace(value), `'"|`)
+			}
+		}
+		return
+	}
+	r.Format = "tap"
+	r.failing = false
+	if m[2] == "not ok" && m[4] == "" && r.First == "" {
+		r.First, r.failing = m[3], true
+	}
+	if m[1] != "" {
+		// Subtests are counted with their parent
+		return
+	}
+	switch {
+	case m[4] != "":
+		r.Skipped++
+	case m[2] == "ok":
+		r.Passed++
+	default:
+		r.Failed++
+	}
+}
+
+// firstLine returns the first non-blank line of a message
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// summary turns the results and exit of a run into the notification's
+// type, title and message
+func (r *testResults) summary(subject, name string, runErr error, elapsed time.Duration) (string, string, string) {
+	took := formatDuration(elapsed)
+	var exit *exec.ExitError
+	switch {
+	case r.Format == "" && runErr == nil:
+		return "success", subject + " passed", fmt.Sprintf("%s finished after %s, no test results were recognized", name, took)
+	case r.Format == "" && errors.As(runErr, &exit):
+		return "error", subject + " failed", fmt.Sprintf("%s exited with code %d after %s", name, exit.ExitCode(), took)
+	case r.Format == "":
+		return "error", subject + " failed", fmt.Sprintf("%s failed after %s: %v", name, took, runErr)
+	}
+
+	counts := fmt.Sprintf("%d passed", r.Passed)
+	if r.Skipped > 0 {
+		counts += fmt.Sprintf(", %d skipped", r.Skipped)
+	}
+	if r.Failed == 0 && runErr == nil {
+		return "success", subject + " passed", fmt.Sprintf("%s in %s", counts, took)
+	}
+
+	message := fmt.Sprintf("%d failed, %s in %s", r.Failed, counts, took)
+	if r.Failed == 0 {
+		// The runner failed after the tests, e.g. on coverage thresholds
+		message = fmt.Sprintf("%s exited with an error after %s, %s", name, took, counts)
+	}
+	if r.First != "" {
+		message += "\n" + oneLine(r.First, 100)
+		if r.Detail != "" {
+			message += ": " + oneLine(r.Detail, 200)
+		}
+	}
+	return "error", subject + " failed", message
+}
+
+// runTests runs the command once, passing its output through while reading
+// the results
+func runTests(words []string) (*testResults, time.Duration, error) {
+	start := time.Now()
+	results := &testResults{}
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return results, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return results, time.Since(start), err
+	}
+
+	// Jest writes its report as one long line
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if shown := results.read(strings.TrimRight(line, "\r\n")); shown != "" || line == "\n" {
+				fmt.Println(shown)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "Warning: reading the test output: %v\n", err)
+			}
+			break
+		}
+	}
+	err = cmd.Wait()
+	return results, time.Since(start), err
+}
+
+// treeVersion returns a fingerprint of the names, sizes and modification
+// times of the files below dir, leaving out hidden and build directories
+func treeVersion(dir string) uint64 {
+	h := fnv.New64a()
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() && path != dir && (strings.HasPrefix(name, ".") || slices.Contains(unwatchedDirs, name)) {
+			return filepath.SkipDir
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return h.Sum64()
+}
+
+// runTest implements "notify test", which runs a test command and notifies
+// its pass and fail counts and first failure, once or on every change.
+// Without a command it sends sample notifications instead.
+func runTest(args []string) error {
+	opts := newNotifyOptions()
+	watch, allTargets := false, false
+	tag := ""
+	var types []string
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "watch", Bool: true, Set: func(v string) (err error) { watch, err = parseStrictBool(v); return }},
+		cliFlag{Name: "tag", Set: func(v string) error { tag = v; return nil }},
+		cliFlag{Name: "all-targets", Bool: true, Set: func(v string) (err error) { allTargets, err = parseStrictBool(v); return }},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showTestHelp(); os.Exit(0); return nil }},
+	)
+	// Samples are sent for every --type given
+	for i, f := range flags {
+		if f.Name == "type" {
+			flags[i].Set = func(v string) error { types = append(types, v); return f.Set(v) }
+		}
+	}
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		if watch || tag != "" {
+			return errors.New("--watch and --tag need a test command, e.g. 'notify test --watch -- go test -json ./...'")
+		}
+		return sendTestSamples(opts, types, allTargets)
+	}
+	if allTargets {
+		return errors.New("--all-targets only applies to sample notifications, without a test command")
+	}
+	subject := orDefault(strings.TrimSpace(opts.Title), "Tests")
+	name := filepath.Base(words[0])
+
+	// Each result replaces the one before
+	if tag == "" {
+		tag = "test-" + newToken()
+	}
+
+	passing := false
+	for {
+		version := treeVersion(".")
+		results, elapsed, runErr := runTests(words)
+		typ, title, message := results.summary(subject, name, runErr, elapsed)
+
+		// While watching, passes are only worth a toast after a failure
+		if !watch || typ == "error" || !passing {
+			opts.Type, opts.Title = typ, oneLine(title, notify.MaxTitleLength)
+			n, err := opts.build(message)
+			if err != nil {
+				return err
+			}
+			n.Tag = tag
+			if err := sendNotification(n); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		passing = typ == "success"
+
+		if !watch {
+			var exit *exec.ExitError
+			if errors.As(runErr, &exit) {
+				flushTraces()
+				os.Exit(max(exit.ExitCode(), 1))
+			}
+			return runErr
+		}
+
+		fmt.Fprintf(os.Stderr, "notify: %s, watching for changes (Ctrl+C to stop)\n", title)
+		for treeVersion(".") == version {
+			time.Sleep(time.Second)
+		}
+		// Let editors finish saving
+		time.Sleep(300 * time.Millisecond)
+	}
+}
+
+// Messages of the sample notifications of "notify test", by type
+var testMessages = map[string]string{
+	"success": "Sample success notification, e.g. a finished build",
+	"error":   "Sample error notification, e.g. a failed deploy",
+	"info":    "Sample info notification, e.g. a new message",
+	"warning": "Sample warning notification, e.g. a disk almost full",
+}
+
+// sendTestSamples sends a sample notification of each of types, or of
+// every type, here or to --remote and with allTargets to the relays of
+// config.yaml, to check delivery end to end
+func sendTestSamples(opts *notifyOptions, types []string, allTargets bool) error {
+	if len(types) == 0 {
+		types = notify.Types
+	}
+	destinations := []string{opts.Remote}
+	if allTargets {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(config.Relay.Targets) == 0 {
+			return errors.New("--all-targets needs relays in the targets of the relay section of config.yaml")
+		}
+		for _, target := range slices.Sorted(maps.Keys(config.Relay.Targets)) {
+			if target != opts.Remote {
+				destinations = append(destinations, target)
+			}
+		}
+	}
+
+	host, _ := os.Hostname()
+	failed := 0
+	for _, destination := range destinations {
+		for _, t := range types {
+			opts.Type, opts.Remote = t, destination
+			opts.Title = "Test: " + typeTitle(t)
+			n, err := opts.build(testMessages[t] + ", sent by notify test on " + cmp.Or(host, "this machine"))
+			if err != nil {
+				return err
+			}
+
+			where := cmp.Or(destination, "this machine")
+			if err := sendNotification(n); err != nil {
+				failed++
+				fmt.Printf("%s %-8s %s: %v\n", notify.Symbol("error"), t, where, err)
+				continue
+			}
+			fmt.Printf("%s %-8s %s\n", notify.Symbol("success"), t, where)
+		}
+	}
+
+	if total := len(destinations) * len(types); failed > 0 {
+		return fmt.Errorf("%d of %d test notifications failed", failed, total)
+	}
+	fmt.Println("All test notifications were sent, check that each of them appeared")
+	return nil
+}
+
+func showTestHelp() {
+	fmt.Print(`Run tests and notify their results
+
+The notification has the pass, fail and skip counts, and the name and
+message of the first failing test, read from the runner's output:
+  go test -json            The test output is shown as usual
+  jest --json              Jest's report is read instead of shown
+  TAP                      node --test --test-reporter=tap, tape, pytest --tap-stream
+Other output falls back to the exit code. notify test exits with the
+command's exit code.
+
+With --watch the tests run again whenever a file below the current
+directory changes (hidden directories, node_modules, vendor, bin, obj and
+dist aside). Failures always notify, passes only after a failure, and each
+result replaces the one before.
+
+Without a command, notify test sends a sample notification of each type
+to check delivery after setting notify up or changing config.yaml. They
+take the same route as any other, through muting, quiet hours and the
+backend; whether they appeared is for you to check.
+
+Usage:
+  notify test [OPTIONS] [--] COMMAND [ARGS]
+  notify test [--type TYPE]... [--all-targets] [OPTIONS]
+
+Options:
+  --watch            Run again on changes until stopped with Ctrl+C
+  --tag TAG          Identifies the result toast (default: generated)
+  --title TITLE      What is tested, e.g. "API tests" (default: Tests)
+  --type TYPE        Without a command, only send samples of this type, may
+                     be repeated
+  --all-targets      Also send the samples to each relay in the targets of
+                     the relay section of config.yaml
+  Plus the notification options of 'notify --help', e.g. --remote.
+
+Examples:
+  notify test -- go test -json ./...
+  notify test --watch --title "API tests" -- npx jest --json
+  notify test -- node --test --test-reporter=tap
+  notify test --all-targets
+`)
+}
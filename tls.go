@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsFiles names the certificate files used to connect to a relay
+type tlsFiles struct {
+	CA   string // trusted CA for the relay's certificate, instead of the system roots
+	Cert string // client certificate for relays requiring one
+	Key  string // private key of Cert
+}
+
+// config returns the client TLS configuration, or nil for the defaults
+func (f tlsFiles) config() (*tls.Config, error) {
+	if f.CA == "" && f.Cert == "" && f.Key == "" {
+		return nil, nil
+	}
+	if (f.Cert == "") != (f.Key == "") {
+		return nil, fmt.Errorf("--client-cert and --client-key must be used together")
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if f.CA != "" {
+		pool, err := loadCertPool(f.CA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if f.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// serverTLSConfig returns the relay's TLS configuration, requiring client
+// certificates signed by clientCA when it is set
+func serverTLSConfig(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+	if certFile == "" {
+		if clientCA != "" {
+			return nil, fmt.Errorf("--client-ca needs --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCA != "" {
+		pool, err := loadCertPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// loadCertPool reads the PEM certificates in path
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
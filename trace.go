@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds of the OTLP protocol
+const (
+	spanInternal = 1
+	spanServer   = 2
+	spanClient   = 3
+)
+
+// tracer collects spans and exports them with OTLP over HTTP/JSON. It is
+// configured with the standard OpenTelemetry environment variables and
+// tracing is off when no endpoint is set.
+type tracer struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+var tracing = newTracer()
+
+// newTracer reads the OTEL_* environment variables, returning nil when
+// tracing isn't configured
+func newTracer() *tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" || os.Getenv("OTEL_TRACES_EXPORTER") == "none" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+
+	t := &tracer{
+		endpoint: endpoint,
+		headers:  map[string]string{},
+		service:  os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if t.service == "" {
+		t.service = "notify"
+	}
+
+	// Headers are a comma separated list of key=value pairs
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			t.headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return t
+}
+
+// span is a timed step of delivering a notification. All methods accept
+// a nil span, which is what startSpan returns when tracing is off.
+type span struct {
+	traceID [16]byte
+	id      [8]byte
+	parent  [8]byte
+	name    string
+	kind    int
+	start   time.Time
+	end     time.Time
+	attrs   map[string]string
+	err     error
+}
+
+// startSpan starts a span below parent, or a new trace when parent is
+// nil. attrs are key and value pairs.
+func startSpan(parent *span, name string, attrs ...string) *span {
+	if tracing == nil {
+		return nil
+	}
+	s := &span{name: name, kind: spanInternal, start: time.Now(), attrs: map[string]string{}}
+	if parent != nil {
+		s.traceID, s.parent = parent.traceID, parent.id
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.id[:])
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs[attrs[i]] = attrs[i+1]
+	}
+	return s
+}
+
+// startRemoteSpan starts a server span continuing the trace of a W3C
+// traceparent header, or a new trace when the header is missing
+func startRemoteSpan(traceparent, name string, attrs ...string) *span {
+	s := startSpan(nil, name, attrs...)
+	if s == nil {
+		return nil
+	}
+	s.kind = spanServer
+
+	// Format: version-traceid-parentid-flags
+	parts := strings.Split(traceparent, "-")
+	if len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+		traceID, err1 := hex.DecodeString(parts[1])
+		parent, err2 := hex.DecodeString(parts[2])
+		if err1 == nil && err2 == nil {
+			copy(s.traceID[:], traceID)
+			copy(s.parent[:], parent)
+		}
+	}
+	return s
+}
+
+// set adds an attribute
+func (s *span) set(key, value string) {
+	if s != nil {
+		s.attrs[key] = value
+	}
+}
+
+// traceparent returns the W3C header propagating this span
+func (s *span) traceparent() string {
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.id[:]) + "-01"
+}
+
+// finish ends the span, recording err as its status
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end, s.err = time.Now(), err
+	tracing.mu.Lock()
+	tracing.spans = append(tracing.spans, s)
+	tracing.mu.Unlock()
+}
+
+// flushTraces exports the finished spans. Tracing must never break
+// notifications, so failures only produce a warning.
+func flushTraces() {
+	if tracing == nil {
+		return
+	}
+	tracing.mu.Lock()
+	spans := tracing.spans
+	tracing.spans = nil
+	tracing.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	if err := tracing.export(spans); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not export traces: %v\n", err)
+	}
+}
+
+// export posts spans to the collector
+func (t *tracer) export(spans []*span) error {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		otlp := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.id[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attrs),
+		}
+		if s.parent != [8]byte{} {
+			otlp["parentSpanId"] = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != nil {
+			otlp["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+		}
+		otlpSpans = append(otlpSpans, otlp)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]string{"service.name": t.service}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "notify"},
+				"spans": otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector %s answered %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// otlpAttributes converts attributes to OTLP key-value pairs
+func otlpAttributes(attrs map[string]string) []any {
+	list := make([]any, 0, len(attrs))
+	for key, value := range attrs {
+		list = append(list, map[string]any{
+			"key":   key,
+			"value": map[string]any{"stringValue": value},
+		})
+	}
+	return list
+}
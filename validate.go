@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// parseTimeout converts a --timeout value into seconds
+func parseTimeout(s string) (int, error) {
+	val, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number of seconds", s)
+	}
+	if max := int(notify.MaxTimeout / time.Second); val < 1 || val > max {
+		return 0, fmt.Errorf("timeout must be 1-%d seconds, got %d", max, val)
+	}
+	return val, nil
+}
+
+// parseGroupSize converts a --group-size value
+func parseGroupSize(s string) (int, error) {
+	val, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || val < 1 {
+		return 0, fmt.Errorf("%q is not a positive whole number", s)
+	}
+	return val, nil
+}
+
+// parseCount parses a whole number that may be zero
+func parseCount(s string) (int, error) {
+	val, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("%q is not a whole number", s)
+	}
+	return val, nil
+}
+
+// parseVolume parses a sound volume in percent
+func parseVolume(s string) (*int, error) {
+	volume, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if err != nil || volume < 0 || volume > 100 {
+		return nil, fmt.Errorf("volume must be 0-100, got %q", s)
+	}
+	return &volume, nil
+}
+
+// parsePercent parses a positive percentage such as 10% or 2.5
+func parsePercent(s string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || percent <= 0 {
+		return 0, fmt.Errorf("%q is not a positive percentage", s)
+	}
+	return percent, nil
+}
+
+// parseSize parses a byte size such as 512, 64KB or 1MB
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"K", 1 << 10}, {"M", 1 << 20}, {"B", 1}}
+
+	value, unit := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for _, u := range units {
+		if v, ok := strings.CutSuffix(value, u.suffix); ok {
+			value, unit = strings.TrimSpace(v), u.size
+			break
+		}
+	}
+	val, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || val < 1 {
+		return 0, fmt.Errorf("%q is not a size like 512, 64KB or 1MB", s)
+	}
+	return val * unit, nil
+}
+
+// parseDuration parses a Go duration such as 90s or 2h30m, also accepting
+// whole days like 7d
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a positive duration like 30m, 2h or 1d", s)
+	}
+	return d, nil
+}
+
+// parseRetention parses a duration like parseDuration, where 0 means
+// forever
+func parseRetention(s string) (time.Duration, error) {
+	if strings.TrimSpace(s) == "0" {
+		return 0, nil
+	}
+	return parseDuration(s)
+}
+
+// parseStrictBool converts a boolean option value, rejecting anything unclear
+func parseStrictBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "1", "on":
+		return true, nil
+	case "false", "no", "0", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a boolean, use true or false", s)
+}
+
+// validate checks the notification with the checks of the notify
+// package and those of the options only the command has, and returns an
+// actionable error for the first problem found
+func (n *Notification) validate() error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if n.GroupSize > 0 && n.Group == "" {
+		return fmt.Errorf("--group-size needs --group")
+	}
+
+	if n.Window != "" && n.Fallback != "flash" && n.Fallback != "badge" {
+		return fmt.Errorf("--window needs --fallback flash or --fallback badge")
+	}
+
+	if n.Wake > 0 {
+		if !n.Urgent {
+			return fmt.Errorf("--wake needs --urgent")
+		}
+		if n.Wake > notify.MaxTimeout {
+			return fmt.Errorf("--wake must be at most %s, got %s", notify.MaxTimeout, n.Wake)
+		}
+	}
+
+	if strings.HasPrefix(n.Remote, "ntfy://") {
+		if _, err := parseNtfyTopic(n.Remote); err != nil {
+			return err
+		}
+		if n.Attachment != nil {
+			return fmt.Errorf("--attach and --qr can't be sent to ntfy topics")
+		}
+	} else if n.Remote != "" {
+		if _, err := notify.RelayEndpoint(n.Remote); err != nil {
+			return err
+		}
+	}
+	if n.Remote != "" {
+		if n.AckID != "" {
+			return fmt.Errorf("--require-ack can't be used with --remote")
+		}
+		if n.Wait {
+			return fmt.Errorf("--wait can't be used with --remote")
+		}
+	}
+
+	if n.TLS != (tlsFiles{}) {
+		if n.Remote == "" {
+			return fmt.Errorf("--ca, --client-cert and --client-key need --remote")
+		}
+		if _, err := n.TLS.config(); err != nil {
+			return err
+		}
+	}
+
+	if n.SignKey != "" {
+		if n.Remote == "" {
+			return fmt.Errorf("--sign-key needs --remote")
+		}
+		if _, err := loadSigningKey(n.SignKey); err != nil {
+			return err
+		}
+	}
+
+	if n.EncryptTo != "" {
+		if n.Remote == "" {
+			return fmt.Errorf("--encrypt-to needs --remote")
+		}
+		if _, err := parsePublicKey(n.EncryptTo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printDryRun shows the notification that would be displayed
+func printDryRun(n *Notification) {
+	fmt.Println("Notification is valid (dry run, nothing displayed):")
+	fmt.Printf("  App:       %s (%s)\n", notify.AppName(n.App), notify.AppUserModelID(n.App))
+	fmt.Printf("  Type:      %s\n", n.Type)
+	fmt.Printf("  Title:     %s\n", n.Title)
+	fmt.Printf("  Message:   %s\n", n.Message)
+	if n.AckID != "" {
+		fmt.Printf("  Ack ID:    %s\n", n.AckID)
+	}
+	if n.Category != "" {
+		fmt.Printf("  Category:  %s\n", n.Category)
+	}
+	if n.Group != "" {
+		fmt.Printf("  Group:     %s\n", n.Group)
+	}
+	if n.Remote != "" {
+		fmt.Printf("  Remote:    %s\n", n.Remote)
+	}
+	if n.Session != "" {
+		fmt.Printf("  Session:   %s\n", n.Session)
+	}
+	if n.Fallback != "" {
+		fmt.Printf("  Fallback:  %s\n", n.Fallback)
+	}
+	if n.Backend != "" {
+		fmt.Printf("  Backend:   %s\n", n.Backend)
+	}
+	if n.Inject != nil {
+		fmt.Printf("  Inject:    %s delay, %s\n", n.Inject.Delay, orDefault(n.Inject.Fail, "no failure"))
+	}
+	if n.Window != "" {
+		fmt.Printf("  Window:    %s\n", n.Window)
+	}
+	if n.Urgent {
+		fmt.Printf("  Urgent:    true\n")
+	}
+	if n.Wake > 0 {
+		fmt.Printf("  Wake:      %s\n", n.Wake)
+	}
+	if n.Volume != nil {
+		fmt.Printf("  Volume:    %d%%\n", *n.Volume)
+	}
+	if n.Duck {
+		fmt.Printf("  Duck:      true\n")
+	}
+	if n.Speak {
+		fmt.Printf("  Speak:     true\n")
+	}
+	if a := n.Attachment; a != nil {
+		fmt.Printf("  Attach:    %s (%s, %d bytes)\n", a.Name, a.Type, len(a.Data))
+	}
+	fmt.Printf("  Timeout:   %s\n", n.Timeout)
+	fmt.Printf("  AutoClose: %t\n", !n.Sticky)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildNotification parses command line arguments like the main command
+// and builds the notification
+func buildNotification(t *testing.T, args ...string) (*Notification, error) {
+	t.Helper()
+	opts := newNotifyOptions()
+	words, err := parseArgs(args, opts.flags())
+	if err != nil {
+		return nil, err
+	}
+	return opts.build(strings.Join(words, " "))
+}
+
+func TestValidateNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  string // part of the error, empty when valid
+	}{
+		{"message", []string{"Build", "finished"}, ""},
+		{"options", []string{"Low", "disk", "--type", "warning", "--title", "Disk", "--timeout", "30", "--group", "disks", "--group-size", "3"}, ""},
+		{"unknown type", []string{"x", "--type", "fatal"}, `invalid type "fatal"`},
+		{"blank message", []string{"  "}, "message is empty"},
+		{"blank title", []string{"x", "--title", "  "}, "title is empty"},
+		{"long title", []string{"x", "--title", strings.Repeat("t", 65)}, "move the details into the message"},
+		{"no timeout", []string{"x", "--timeout", "0"}, "timeout must be 1-3600 seconds"},
+		{"long timeout", []string{"x", "--timeout", "3601"}, "timeout must be 1-3600 seconds"},
+		{"group size alone", []string{"x", "--group-size", "3"}, "--group-size needs --group"},
+		{"window alone", []string{"x", "--window", "Terminal"}, "--window needs --fallback"},
+		{"window with flash", []string{"x", "--window", "Terminal", "--fallback", "flash"}, ""},
+		{"wake alone", []string{"x", "--wake", "1m"}, "--wake needs --urgent"},
+		{"long wake", []string{"x", "--urgent", "--wake", "2h"}, "--wake must be at most 1h0m0s"},
+		{"wake", []string{"x", "--urgent", "--wake", "1m"}, ""},
+		{"remote", []string{"x", "--remote", "desk:8787"}, ""},
+		{"bad remote", []string{"x", "--remote", "ftp://desk"}, "use http or https"},
+		{"ack with remote", []string{"x", "--remote", "desk", "--require-ack", "a1"}, "--require-ack can't be used with --remote"},
+		{"wait with remote", []string{"x", "--remote", "desk", "--wait"}, "--wait can't be used with --remote"},
+		{"ntfy with qr", []string{"x", "--remote", "ntfy://ntfy.sh/builds", "--qr", "https://example.com"}, "can't be sent to ntfy topics"},
+		{"ca alone", []string{"x", "--ca", "ca.pem"}, "need --remote"},
+		{"sign key alone", []string{"x", "--sign-key", "sign.key"}, "--sign-key needs --remote"},
+		{"encrypt alone", []string{"x", "--encrypt-to", "key"}, "--encrypt-to needs --remote"},
+		{"attach and qr", []string{"x", "--attach", "a.txt", "--qr", "y"}, "can't be used together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			_, err := buildNotification(t, tt.args...)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("build() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("build() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBuildDefaults(t *testing.T) {
+	isolate(t)
+	n, err := buildNotification(t, "  Deployed  ", "--autoclose", "false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Type != "info" || n.Title != "Info" || n.Message != "Deployed" {
+		t.Errorf("notification = %q %q %q, want the defaults and the trimmed message", n.Type, n.Title, n.Message)
+	}
+	if n.Timeout != 5*time.Second || !n.Sticky {
+		t.Errorf("timeout %s, sticky %t, want 5s and sticky", n.Timeout, n.Sticky)
+	}
+}
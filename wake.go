@@ -0,0 +1,16 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// awake tracks displays kept on after urgent notifications; the command
+// waits for them before exiting, while the relay lets them run
+var awake sync.WaitGroup
+
+// keepAwake turns the display on and keeps it and the system awake for d
+// without blocking
+func keepAwake(d time.Duration) {
+	awake.Go(func() { wakeDisplay(d) })
+}
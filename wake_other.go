@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+import "time"
+
+// wakeDisplay is only available on Windows
+func wakeDisplay(d time.Duration) {}
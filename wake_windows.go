@@ -0,0 +1,50 @@
+package main
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+var (
+	procSetThreadExecutionState = kernel32.NewProc("SetThreadExecutionState")
+	procSendInput               = user32.NewProc("SendInput")
+)
+
+// SetThreadExecutionState flags
+const (
+	esSystemRequired  = 0x1
+	esDisplayRequired = 0x2
+	esContinuous      = 0x80000000
+)
+
+// mouseInput is INPUT holding a MOUSEINPUT; the nested struct gets the
+// union's alignment
+type mouseInput struct {
+	Type  uint32
+	Mouse struct {
+		DX, DY    int32
+		MouseData uint32
+		Flags     uint32
+		Time      uint32
+		ExtraInfo uintptr
+	}
+}
+
+// wakeDisplay turns the display on and keeps it and the system awake for d
+func wakeDisplay(d time.Duration) {
+	// The execution state belongs to the calling thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	procSetThreadExecutionState.Call(esContinuous | esSystemRequired | esDisplayRequired)
+	defer procSetThreadExecutionState.Call(esContinuous)
+
+	// A display that is already off only comes back on with user input,
+	// so send a mouse move that doesn't move the pointer
+	var input mouseInput    // INPUT_MOUSE
+	input.Mouse.Flags = 0x1 // MOUSEEVENTF_MOVE
+	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+
+	time.Sleep(d)
+}
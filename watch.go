@@ -0,0 +1,334 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// watchConfig configures "notify watch", which keeps an eye on this
+// machine and notifies when something needs attention
+type watchConfig struct {
+	Interval time.Duration  `yaml:"interval"` // how often to check
+	Counters []counterWatch `yaml:"counters"` // Windows performance counters
+	Network  networkWatch   `yaml:"network"`
+
+	DNS            []dnsWatch    `yaml:"dns"`             // records to follow
+	PublicIP       bool          `yaml:"public_ip"`       // follow the public IP address
+	PublicIPURL    string        `yaml:"public_ip_url"`   // answers with the public IP as text
+	LookupInterval time.Duration `yaml:"lookup_interval"` // how often DNS and the public IP are checked
+
+	Drives   bool `yaml:"drives"`   // USB drives and memory cards connected and removed
+	Printers bool `yaml:"printers"` // print jobs finishing, failing or getting stuck
+
+	Clipboard clipboardWatch `yaml:"clipboard"` // copied text matching patterns
+}
+
+// counterWatch alerts when a performance counter stays above or below a
+// threshold for a while
+type counterWatch struct {
+	Counter string        `yaml:"counter"` // like \Processor(_Total)\% Processor Time
+	Name    string        `yaml:"name"`    // shown in the title instead of the counter
+	Above   *float64      `yaml:"above"`
+	Below   *float64      `yaml:"below"`
+	For     time.Duration `yaml:"for"`  // how long the threshold must be crossed
+	Type    string        `yaml:"type"` // of the alert, warning by default
+}
+
+// validate checks the watch settings of config.yaml
+func (c *watchConfig) validate() error {
+	if c.Interval < time.Second {
+		return fmt.Errorf("the watch interval must be at least 1s")
+	}
+	for i, w := range c.Counters {
+		where := fmt.Sprintf("watch counters[%d]", i)
+		if strings.TrimSpace(w.Counter) == "" {
+			return fmt.Errorf("%s: counter is required", where)
+		}
+		if w.Above == nil && w.Below == nil {
+			return fmt.Errorf("%s: set above, below or both", where)
+		}
+		if w.For < 0 {
+			return fmt.Errorf("%s: for can't be negative", where)
+		}
+		if w.Type != "" && !isValidType(w.Type) {
+			return fmt.Errorf("%s: invalid type %q.%s", where, w.Type, suggest.DidYouMean(w.Type, notify.Types, ""))
+		}
+	}
+	for i, w := range c.DNS {
+		if err := w.validate(fmt.Sprintf("watch dns[%d]", i)); err != nil {
+			return err
+		}
+	}
+	if c.LookupInterval < time.Second {
+		return fmt.Errorf("the watch lookup_interval must be at least 1s")
+	}
+	if err := c.Clipboard.validate(); err != nil {
+		return err
+	}
+	return c.Network.validate()
+}
+
+// watchCheck runs one round of a watcher and returns the notifications
+// it calls for
+type watchCheck func(now time.Time) ([]*notify.RelayMessage, error)
+
+// runWatch implements "notify watch"
+func runWatch(args []string) error {
+	// Alerts come from this machine, so rate limits don't apply
+	out := newListenerOptions()
+	flags := slices.DeleteFunc(out.flags(), func(f cliFlag) bool { return f.Name == "rate" || f.Name == "burst" })
+	flags = append(flags, cliFlag{
+		Name: "help", Bool: true, Set: func(string) error { showWatchHelp(); os.Exit(0); return nil },
+	})
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		return fmt.Errorf("unexpected argument: %s", words[0])
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	var checks []watchCheck
+	if len(config.Watch.Counters) > 0 {
+		check, err := watchCounters(config.Watch.Counters)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	if network := config.Watch.Network; network.Internet || network.WiFi || network.VPN {
+		check, err := watchNetwork(network)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	if len(config.Watch.DNS) > 0 || config.Watch.PublicIP {
+		publicIP := ""
+		if config.Watch.PublicIP {
+			publicIP = config.Watch.PublicIPURL
+		}
+		checks = append(checks, watchLookups(config.Watch.DNS, publicIP, config.Watch.LookupInterval))
+	}
+	if config.Watch.Drives {
+		check, err := watchDrives()
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	if config.Watch.Printers {
+		check, err := watchPrinters()
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 && len(config.Watch.Clipboard.Patterns) == 0 {
+		return fmt.Errorf("nothing to watch, add a watch section to config.yaml (see 'notify watch --help')")
+	}
+
+	if err := out.start(); err != nil {
+		return err
+	}
+	source := ""
+	if len(out.relay.targets) > 0 {
+		source, _ = os.Hostname()
+	}
+	deliver := func(m *notify.RelayMessage) {
+		m.Source = source
+		out.relay.deliverWatch(m)
+	}
+	if len(config.Watch.Clipboard.Patterns) > 0 {
+		if err := watchClipboard(config.Watch.Clipboard, deliver); err != nil {
+			return err
+		}
+	}
+	if len(out.relay.targets) > 0 {
+		log.Printf("Watching every %s, forwarding to %s", config.Watch.Interval, strings.Join(out.relay.targets, ", "))
+	} else {
+		log.Printf("Watching every %s, showing notifications locally", config.Watch.Interval)
+	}
+
+	for now := time.Now(); ; now = <-time.After(config.Watch.Interval) {
+		for _, check := range checks {
+			messages, err := check(now)
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			for _, m := range messages {
+				deliver(m)
+			}
+		}
+	}
+}
+
+// deliverWatch shows or forwards a notification of "notify watch"
+func (s *relayServer) deliverWatch(m *notify.RelayMessage) {
+	trace := startSpan(nil, "watch.alert", "notify.title", m.Title)
+	defer func() { go flushTraces() }()
+
+	n, err := receivedNotification(m)
+	if err == nil {
+		n.trace = trace
+		err = s.deliver(m, n)
+	}
+	trace.finish(err)
+	if err != nil {
+		log.Printf("%q failed: %v", m.Title, err)
+		return
+	}
+	log.Printf("%q delivered", m.Title)
+}
+
+// watchCounters samples performance counters, alerting once a counter has
+// crossed its threshold for long enough and again when it recovers
+func watchCounters(watches []counterWatch) (watchCheck, error) {
+	paths := make([]string, len(watches))
+	for i, w := range watches {
+		paths[i] = w.Counter
+	}
+	query, err := openCounters(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	crossed := make([]time.Time, len(watches)) // when each threshold was crossed, zero if not
+	alerted := make([]bool, len(watches))
+	return func(now time.Time) ([]*notify.RelayMessage, error) {
+		values, err := query.sample()
+		if err != nil {
+			return nil, err
+		}
+
+		var messages []*notify.RelayMessage
+		for i, w := range watches {
+			value := values[i]
+			if math.IsNaN(value) {
+				// The counter's instance may come back, like a process
+				continue
+			}
+			name := cmp.Or(w.Name, w.Counter)
+			m := &notify.RelayMessage{App: "Watch", Category: "watch", Tag: oneLine("counter "+name, notify.MaxTagLength)}
+
+			switch {
+			case w.Above != nil && value > *w.Above, w.Below != nil && value < *w.Below:
+				if crossed[i].IsZero() {
+					crossed[i] = now
+				}
+				if alerted[i] || now.Sub(crossed[i]) < w.For {
+					continue
+				}
+				alerted[i] = true
+				m.Type = cmp.Or(w.Type, "warning")
+				m.Title = fmt.Sprintf("%s is %s", name, counterThreshold(w, value))
+				m.Message = fmt.Sprintf("Now %s", formatCounter(value))
+				if w.For > 0 {
+					m.Message += fmt.Sprintf(", for %s", formatDuration(now.Sub(crossed[i])))
+				}
+
+			default:
+				crossed[i] = time.Time{}
+				if !alerted[i] {
+					continue
+				}
+				alerted[i] = false
+				m.Type = "success"
+				m.Title = name + " is back to normal"
+				m.Message = fmt.Sprintf("Now %s", formatCounter(value))
+			}
+			m.Title = oneLine(m.Title, notify.MaxTitleLength)
+			messages = append(messages, m)
+		}
+		return messages, nil
+	}, nil
+}
+
+// counterThreshold describes the threshold a value crossed, like
+// "above 90"
+func counterThreshold(w counterWatch, value float64) string {
+	if w.Above != nil && value > *w.Above {
+		return "above " + formatCounter(*w.Above)
+	}
+	return "below " + formatCounter(*w.Below)
+}
+
+// formatCounter renders a counter value with at most one decimal
+func formatCounter(v float64) string {
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0")
+}
+
+func showWatchHelp() {
+	fmt.Print(`Watch this machine and notify when something needs attention
+
+Runs until stopped, checking what the watch section of config.yaml lists:
+
+  watch:
+    interval: 10s          # how often to check (default: 10s)
+    counters:              # Windows performance counters
+      - counter: '\Processor(_Total)\% Processor Time'
+        name: CPU
+        above: 90
+        for: 2m            # only alert when it lasts this long
+      - counter: '\Memory\Available MBytes'
+        name: Free memory
+        below: 500
+        type: error        # type of the alert (default: warning)
+    network:
+      internet: true       # connection lost and back online
+      wifi: true           # joining, leaving and switching Wi-Fi networks
+      vpn: true            # VPNs connecting and disconnecting
+      probe: URL           # checked for internet access (default: the
+                           # Windows connectivity check)
+    dns:                   # DNS records, e.g. of a dynamic DNS name
+      - name: home.example.com
+        type: A            # A, AAAA, CNAME, MX, NS or TXT (default: A)
+        server: 1.1.1.1    # resolver to ask (default: the system's)
+    public_ip: true        # this machine's address as seen from the internet
+    public_ip_url: URL     # answers with the address (default: api.ipify.org)
+    lookup_interval: 5m    # how often DNS and the public IP are checked
+    drives: true           # USB drives and memory cards connected and removed
+    printers: true         # print jobs printed, failed, cancelled or stuck
+    clipboard:             # copied text matching patterns, e.g. sign-in codes
+      apps: [outlook.exe]  # only copies made in these programs (default: any)
+      patterns:
+        - name: Sign-in code
+          match: '\b\d{6}\b'
+
+Each counter alerts once when it crosses its threshold for the whole
+'for' period, and shows a success when it is back to normal, replacing
+the alert. Counter paths use the English names, as listed by
+'typeperf -q'. The internet counts as lost after two failed probes in a
+row; captive portals redirecting the probe count as offline. DNS records
+and the public IP show their old and new values when they change. Print
+jobs are followed while they are queued, so a job printed within one
+interval may go unreported. Clipboard notifications show only the
+matching text, are kept out of history, and skip copies that password
+managers exclude from clipboard monitoring.
+
+Usage:
+  notify watch [OPTIONS]
+
+Options:
+  --to URL           Forward to this relay instead of showing toasts (repeatable)
+  --to-token TOKEN   Token presented to the --to relays
+  --ca FILE          Trust this CA for the --to relays instead of the system roots
+  --client-cert FILE Client certificate presented to the --to relays
+  --client-key FILE  Private key of --client-cert
+  --help             Show this help
+`)
+}
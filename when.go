@@ -0,0 +1,295 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	// Windows has no time zone database of its own, so --tz needs Go's
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	_ "time/tzdata"
+)
+
+// Repeat rules of "notify when"
+var whenRepeats = []string{"daily", "weekdays"}
+
+// What "notify when" does with an occurrence missed while the machine
+// slept: show it late, or skip it
+var whenCatchUps = []string{"fire", "skip"}
+
+// An occurrence shown later than this counts as missed
+const whenMissedAfter = time.Minute
+
+// whenSchedule is when "notify when" shows its notification: a wall clock
+// time in a time zone, on a date or repeating
+type whenSchedule struct {
+	Location *time.Location
+	Date     time.Time // midnight of the day in Location, zero for the next occurrence
+	Hour     int
+	Minute   int
+	Repeat   string    // "", daily or weekdays
+	At       time.Time // exact moment of a delay like --in 2h, instead of the above
+
+	Jitter   time.Duration    // each occurrence is delayed by up to this much
+	CatchUp  string           // fire or skip missed occurrences
+	Holidays *holidayCalendar // days skipped, nil for none
+}
+
+// runWhen implements "notify when --at TIME MESSAGE"
+func runWhen(args []string) error {
+	opts := newNotifyOptions()
+	schedule := &whenSchedule{Location: time.Local, CatchUp: "fire"}
+	at, in, id := "", "", ""
+	parseOnly, skipHolidays := false, false
+
+	flags := append(opts.flags(),
+		cliFlag{Name: "at", Set: func(v string) error { at = v; return nil }},
+		cliFlag{Name: "in", Set: func(v string) error { in = v; return nil }},
+		cliFlag{Name: "id", Set: func(v string) error {
+			if v == "" || strings.ContainsFunc(v, unicode.IsSpace) {
+				return fmt.Errorf("%q is not a valid id, use a word like standup", v)
+			}
+			id = v
+			return nil
+		}},
+		cliFlag{Name: "parse-only", Bool: true, Set: func(v string) (err error) { parseOnly, err = parseStrictBool(v); return }},
+		cliFlag{Name: "tz", Set: func(v string) (err error) { schedule.Location, err = loadTimeZone(v); return }},
+		cliFlag{Name: "repeat", Set: func(v string) error {
+			if !slices.Contains(whenRepeats, v) {
+				return fmt.Errorf("invalid --repeat %q.%s Use: %s", v, suggest.DidYouMean(v, whenRepeats, ""), strings.Join(whenRepeats, ", "))
+			}
+			schedule.Repeat = v
+			return nil
+		}},
+		cliFlag{Name: "skip-holidays", Bool: true, Set: func(v string) (err error) { skipHolidays, err = parseStrictBool(v); return }},
+		cliFlag{Name: "jitter", Set: func(v string) (err error) { schedule.Jitter, err = parseDuration(v); return }},
+		cliFlag{Name: "catch-up", Set: func(v string) error {
+			if !slices.Contains(whenCatchUps, v) {
+				return fmt.Errorf("invalid --catch-up %q.%s Use: %s", v, suggest.DidYouMean(v, whenCatchUps, ""), strings.Join(whenCatchUps, ", "))
+			}
+			schedule.CatchUp = v
+			return nil
+		}},
+		cliFlag{Name: "help", Bool: true, Set: func(string) error { showWhenHelp(); os.Exit(0); return nil }},
+	)
+
+	words, err := parseArgs(args, flags)
+	if err != nil {
+		return err
+	}
+	if (at == "") == (in == "") || (len(words) == 0 && !parseOnly) {
+		return errors.New("usage: notify when --at TIME|--in DELAY [--tz ZONE] MESSAGE [OPTIONS]")
+	}
+	// --at is read once the zone is known, whatever the order of the options
+	now := time.Now()
+	if in != "" {
+		err = schedule.parseIn(in, now)
+	} else {
+		err = schedule.parseAt(at, now)
+	}
+	if err != nil {
+		return err
+	}
+	if !schedule.At.IsZero() && schedule.Repeat != "" {
+		return errors.New("--repeat needs a time of day, not a delay")
+	}
+	if skipHolidays {
+		if schedule.Repeat == "" {
+			return errors.New("--skip-holidays needs --repeat")
+		}
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if schedule.Holidays, err = newHolidayCalendar(config.Schedule.Holidays, now); err != nil {
+			return err
+		}
+	}
+
+	next := schedule.next(now)
+	if next.IsZero() {
+		return fmt.Errorf("%s is in the past", cmp.Or(at, in))
+	}
+	if parseOnly {
+		fmt.Printf("%s  %s\n", next.Format(time.RFC3339), describeWhen(next))
+		return nil
+	}
+	message := strings.Join(words, " ")
+	first, err := opts.build(message)
+	if err != nil {
+		return err
+	}
+
+	when := "--at " + at
+	if in != "" {
+		when = "--in " + in
+	}
+	due := schedule.jittered(next)
+	if id, err = addSchedule(id, schedule, when, first, due); err != nil {
+		return err
+	}
+	defer removeSchedule(id)
+	removeScheduleOnInterrupt(id)
+
+	for {
+		fmt.Printf("Waiting until %s as schedule %s (Ctrl+C to cancel)\n", describeWhen(due), id)
+		record, err := waitForSchedule(id, due)
+		if errors.Is(err, errScheduleCancelled) {
+			fmt.Printf("Schedule %s was cancelled\n", id)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch late := time.Since(due); {
+		case record.Paused:
+			fmt.Printf("Schedule %s is paused, skipped %s\n", id, describeWhen(due))
+		case late > whenMissedAfter && schedule.CatchUp == "skip":
+			fmt.Printf("Missed %s by %s, skipped\n", describeWhen(due), formatDuration(late))
+		default:
+			n, err := opts.build(message)
+			if err != nil {
+				return err
+			}
+			if err := sendNotification(n); err != nil {
+				if schedule.Repeat == "" {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		if schedule.Repeat == "" {
+			return nil
+		}
+
+		// Occurrences missed while asleep are shown at most once
+		schedule.Holidays.refresh(time.Now())
+		due = schedule.jittered(schedule.next(time.Now()))
+		if err := setScheduleNext(id, due); err != nil {
+			return err
+		}
+	}
+}
+
+// jittered delays an occurrence by a random part of the jitter, so
+// machines sharing a schedule don't all act at the same moment
+func (w *whenSchedule) jittered(t time.Time) time.Time {
+	if w.Jitter <= 0 {
+		return t
+	}
+	return t.Add(rand.N(w.Jitter))
+}
+
+// loadTimeZone returns an IANA time zone like Asia/Karachi
+func loadTimeZone(name string) (*time.Location, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("--tz needs a time zone like Asia/Karachi or America/New_York")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q, use a name like Asia/Karachi or America/New_York", name)
+	}
+	return loc, nil
+}
+
+// next returns the first occurrence after the given time, or zero when a
+// one-time date has passed. Days are counted on the calendar of the zone,
+// so the wall clock time holds across daylight saving changes; a time
+// the change skips comes out shifted by it, like 02:30 becoming 03:30.
+func (w *whenSchedule) next(after time.Time) time.Time {
+	if !w.At.IsZero() {
+		if w.At.After(after) {
+			return w.At.In(w.Location)
+		}
+		return time.Time{}
+	}
+	day := w.Date
+	if day.IsZero() || (w.Repeat != "" && day.Before(after)) {
+		local := after.In(w.Location)
+		day = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	}
+	for range 400 {
+		t := time.Date(day.Year(), day.Month(), day.Day(), w.Hour, w.Minute, 0, 0, w.Location)
+		if t.Hour() != w.Hour || t.Minute() != w.Minute {
+			// Go resolves a skipped time with the offset from before the
+			// change, which lands before it; move past the change instead
+			_, before := t.Zone()
+			_, after := t.Add(6 * time.Hour).Zone()
+			t = t.Add(time.Duration(after-before) * time.Second)
+		}
+		weekend := t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+		if t.After(after) && (w.Repeat != "weekdays" || !weekend) && !w.Holidays.contains(t) {
+			return t
+		}
+		if !w.Date.IsZero() && w.Repeat == "" {
+			return time.Time{}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, w.Location)
+	}
+	return time.Time{}
+}
+
+// describeWhen renders an occurrence in its zone and, when that differs,
+// on this machine's clock
+func describeWhen(t time.Time) string {
+	s := t.Format("Mon 2 Jan 15:04")
+	if t.Location() == time.Local {
+		return s
+	}
+	s += " " + t.Location().String()
+	_, offset := t.Zone()
+	if _, here := t.Local().Zone(); here != offset {
+		s += " (" + t.Local().Format("Mon 2 Jan 15:04") + " here)"
+	}
+	return s
+}
+
+func showWhenHelp() {
+	fmt.Print(`Show a notification at a time in any time zone
+
+Waits until the time comes, then shows the notification. With --tz the
+time is read on that zone's clock, e.g. when a teammate's day starts,
+and daylight saving changes on either side are accounted for. Waiting
+notifications are listed and managed with 'notify schedule'.
+
+Usage:
+  notify when --at TIME [--tz ZONE] MESSAGE [OPTIONS]
+  notify when --in DELAY MESSAGE [OPTIONS]
+
+Options:
+  --at TIME          A time like 09:00 or 9am, a day like tomorrow, friday,
+                     next monday or 2026-03-08, or both, e.g. "tomorrow 9am";
+                     a day alone means 09:00
+  --in DELAY         A delay like 90m or "2 hours 30 minutes"; --at takes
+                     one too, e.g. --at "in 2 hours"
+  --tz ZONE          IANA time zone of --at, like Asia/Karachi (default: this
+                     machine's)
+  --repeat RULE      Show it again every day ('daily') or Monday to Friday in
+                     the zone ('weekdays') until stopped
+  --skip-holidays    With --repeat, skip the days of the holiday calendars
+                     listed in config.yaml:
+                       schedule:
+                         holidays: [URL or path of an iCalendar feed]
+  --id ID            Name it for 'notify schedule' (default: a number)
+  --jitter DURATION  Delay each occurrence by a random time up to this, so
+                     machines sharing a schedule don't all act at once
+  --catch-up POLICY  What to do with a time missed while the machine slept:
+                     'fire' shows it on waking (default), 'skip' drops it
+                     and waits for the next one
+  --parse-only       Print the resolved time and exit, to check a phrase
+  Plus the notification options of 'notify --help', e.g. --type and --title.
+
+Examples:
+  notify when --tz Asia/Karachi --at 09:00 "Team is online" --repeat weekdays
+  notify when --at "2026-03-08 17:30" "Release freeze starts" --type warning
+  notify when --at "next monday 10am" --parse-only
+  notify when --in "2 hours 30 minutes" "Take the laundry out"
+`)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+	"github.com/sarfraznawaz2005/notify/pkg/notify"
+)
+
+// isInteractive reports whether stdin is attached to a terminal
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// runWizard prompts for the notification details and where to send it,
+// using the given options as defaults
+func runWizard(opts *notifyOptions) (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println("No message given - let's build a notification.")
+	fmt.Println()
+
+	var message string
+	for message == "" {
+		answer, err := prompt(reader, "Message", "")
+		if err != nil {
+			return "", err
+		}
+		message = answer
+	}
+
+	for {
+		answer, err := prompt(reader, "Type ("+strings.Join(notify.Types, ", ")+")", opts.Type)
+		if err != nil {
+			return "", err
+		}
+		answer = strings.ToLower(answer)
+		if isValidType(answer) {
+			opts.Type = answer
+			break
+		}
+		fmt.Printf("  %q is not a valid type\n", answer)
+	}
+
+	answer, err := prompt(reader, "Title (empty for default)", opts.Title)
+	if err != nil {
+		return "", err
+	}
+	opts.Title = answer
+
+	if err := promptTarget(reader, opts); err != nil {
+		return "", err
+	}
+
+	fmt.Println()
+	return message, nil
+}
+
+// wizardTargets are where the wizard sends notifications: this machine,
+// a notify relay or an ntfy topic, as with --remote
+var wizardTargets = []string{"local", "relay", "ntfy"}
+
+// promptTarget asks where to send the notification, and for the --remote
+// options of a relay or an ntfy topic
+func promptTarget(reader *bufio.Reader, opts *notifyOptions) error {
+	target := "local"
+	switch {
+	case strings.HasPrefix(opts.Remote, "ntfy://"):
+		target = "ntfy"
+	case opts.Remote != "":
+		target = "relay"
+	}
+	for {
+		answer, err := prompt(reader, "Send to ("+strings.Join(wizardTargets, ", ")+")", target)
+		if err != nil {
+			return err
+		}
+		answer = strings.ToLower(answer)
+		if slices.Contains(wizardTargets, answer) {
+			target = answer
+			break
+		}
+		fmt.Printf("  %q is not a valid target.%s\n", answer, suggest.DidYouMean(answer, wizardTargets, ""))
+	}
+
+	switch target {
+	case "local":
+		opts.Remote = ""
+		return nil
+	case "relay":
+		def := opts.Remote
+		if strings.HasPrefix(def, "ntfy://") {
+			def = ""
+		}
+		for {
+			answer, err := prompt(reader, "Relay address, e.g. hub.lan:8787", def)
+			if err != nil {
+				return err
+			}
+			if _, err := notify.RelayEndpoint(answer); err == nil {
+				opts.Remote = answer
+				break
+			}
+			fmt.Printf("  %q is not a relay address\n", answer)
+		}
+	case "ntfy":
+		def := ""
+		if strings.HasPrefix(opts.Remote, "ntfy://") {
+			def = opts.Remote
+		}
+		for {
+			answer, err := prompt(reader, "Topic, ntfy://host/topic or a topic on ntfy.sh", def)
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(answer, "/") {
+				answer = "ntfy://ntfy.sh/" + answer
+			}
+			if _, err := parseNtfyTopic(answer); err == nil && strings.HasPrefix(answer, "ntfy://") {
+				opts.Remote = answer
+				break
+			}
+			fmt.Printf("  %q is not an ntfy topic\n", answer)
+		}
+	}
+
+	// Tokens aren't echoed as defaults
+	question := "Token (empty for none)"
+	if opts.Token != "" {
+		question = "Token (empty to keep the one set)"
+	}
+	token, err := prompt(reader, question, "")
+	if err != nil {
+		return err
+	}
+	opts.Token = cmp.Or(token, opts.Token)
+
+	key, err := prompt(reader, "Encrypt to public key (empty for none)", opts.EncryptTo)
+	if err != nil {
+		return err
+	}
+	opts.EncryptTo = key
+	return nil
+}
+
+// prompt prints a question and reads one trimmed line, falling back to def
+func prompt(reader *bufio.Reader, question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("no input: %w", err)
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sarfraznawaz2005/notify/internal/suggest"
+)
+
+// notify reads a small, commonly used subset of YAML so that it doesn't
+// need third party dependencies: block mappings and sequences, plain and
+// quoted scalars, flow sequences of scalars ([a, b]), literal (|) and
+// folded (>) block scalars, and comments. Scalars are kept as strings and
+// converted when decoded into a typed value. Anchors, aliases, tags, flow
+// mappings, complex keys, directives and multiple documents are rejected
+// rather than misread.
+
+// What plain scalars starting with an indicator would be in full YAML
+var yamlIndicators = map[byte]string{
+	'&': "anchors", '*': "aliases", '!': "tags", '{': "flow mappings",
+	'@': "values starting with @", '`': "values starting with `",
+}
+
+// yamlLine is a non-blank source line
+type yamlLine struct {
+	num    int    // 1-based line number
+	indent int    // leading spaces
+	text   string // content without indentation and comments
+	raw    string // original line, used by block scalars
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML parses a document into nested map[string]any, []any, string
+// and nil values
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+
+	content, ended := false, false
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(raw, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		if strings.HasPrefix(raw, "%") {
+			return nil, fmt.Errorf("line %d: directives are not supported", i+1)
+		}
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+
+		// Markers may start and end the one document, not separate more
+		switch {
+		case text == "---" && content, ended && trimmed != "" && text != "...":
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		case text == "...":
+			ended = true
+		case trimmed != "" && text != "---":
+			content = true
+		}
+		if trimmed == "" || text == "---" || text == "..." {
+			// Blank lines still matter inside block scalars
+			p.lines = append(p.lines, yamlLine{num: i + 1, indent: -1, raw: raw})
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed, raw: raw})
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	value, err := p.parseBlock(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+// skipBlank moves past blank and comment-only lines
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].indent < 0 {
+		p.pos++
+	}
+}
+
+// parseBlock parses the mapping or sequence starting at the current line
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	line := p.lines[p.pos]
+	if isSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if line.text == "?" || strings.HasPrefix(line.text, "? ") {
+		return nil, fmt.Errorf("line %d: complex keys are not supported", line.num)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+
+	// A lone scalar, e.g. a whole document containing just a value
+	p.pos++
+	return parseYAMLScalar(line.text, line.num)
+}
+
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	result := map[string]any{}
+
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		if isSequenceItem(line.text) {
+			break
+		}
+
+		if line.text == "?" || strings.HasPrefix(line.text, "? ") {
+			return nil, fmt.Errorf("line %d: complex keys are not supported", line.num)
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		if feature, ok := yamlIndicators[line.text[0]]; ok {
+			return nil, fmt.Errorf("line %d: %s are not supported, quote the key if it is text", line.num, feature)
+		}
+		if _, dup := result[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		value, err := p.parseValue(rest, indent, line.num, true)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	result := []any{}
+
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+
+		// "- key: value" and "- - item" start a block indented to the
+		// column of the item
+		_, _, isKey := splitYAMLKey(item)
+		if (isKey && !strings.HasPrefix(item, "[")) || isSequenceItem(item) {
+			offset := len(line.text) - len(item)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + offset, text: item, raw: line.raw}
+			value, err := p.parseBlock(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		p.pos++
+		value, err := p.parseValue(item, indent, line.num, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// parseValue parses what follows a key or sequence dash: an inline scalar,
+// a block scalar or a nested block on the following lines
+func (p *yamlParser) parseValue(rest string, indent, num int, inMapping bool) (any, error) {
+	if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") {
+		if !slices.Contains([]string{"|", "|-", ">", ">-"}, rest) {
+			return nil, fmt.Errorf("line %d: block scalar header %q is not supported, use |, |-, > or >-", num, rest)
+		}
+		return p.parseBlockScalar(rest, indent)
+	}
+	if rest != "" {
+		return parseYAMLScalar(rest, num)
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+	// Sequences may sit at the same indentation as their mapping key
+	if next.indent > indent || (inMapping && next.indent == indent && isSequenceItem(next.text)) {
+		return p.parseBlock(next.indent)
+	}
+	return nil, nil
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) multi-line string
+func (p *yamlParser) parseBlockScalar(header string, indent int) (any, error) {
+	folded := strings.HasPrefix(header, ">")
+	chomp := strings.TrimLeft(header, "|>")
+
+	var lines []string
+	blockIndent := -1
+	end, kept := p.pos, 0
+	for i := p.pos; i < len(p.lines); i++ {
+		line := p.lines[i]
+		if line.indent < 0 {
+			lines = append(lines, "")
+			continue
+		}
+		if line.indent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = line.indent
+		}
+		lines = append(lines, line.raw[min(blockIndent, line.indent):])
+		end, kept = i+1, len(lines)
+	}
+
+	// Trailing blank lines belong to whatever follows
+	lines = lines[:kept]
+	p.pos = end
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case line == "":
+				b.WriteString("\n")
+				continue
+			case i > 0 && lines[i-1] != "":
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	if chomp != "-" && text != "" {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// isSequenceItem reports whether text is a "- item" line
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" outside of quotes
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", false
+		}
+		key, err := parseYAMLScalar(text[:end+1], 0)
+		if err != nil {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		return key.(string), strings.TrimSpace(rest), true
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote ending the string that
+// starts at text[0], or -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a trailing # comment outside of quotes
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' || quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only start a string at the beginning of a scalar
+			if i == 0 || strings.ContainsRune(" :-[,", rune(line[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseYAMLScalar converts a single scalar or a flow sequence of scalars
+func parseYAMLScalar(text string, num int) (any, error) {
+	switch {
+	case text == "~" || text == "null":
+		return nil, nil
+
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated [ list", num)
+		}
+		items := []any{}
+		for _, part := range splitFlow(text[1 : len(text)-1]) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			item, err := parseYAMLScalar(part, num)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case strings.HasPrefix(text, `"`):
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("line %d: bad double-quoted string", num)
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad double-quoted string: %v", num, err)
+		}
+		return s, nil
+
+	case strings.HasPrefix(text, "'"):
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("line %d: bad single-quoted string", num)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	if feature, ok := yamlIndicators[text[0]]; ok {
+		return nil, fmt.Errorf("line %d: %s are not supported, quote the value if it is text", num, feature)
+	}
+	return text, nil
+}
+
+// splitFlow splits the inside of a flow sequence on commas outside quotes
+func splitFlow(text string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// unmarshalYAML parses data and stores the result in the value pointed to
+// by v. Struct fields are matched by their yaml tag or lowercased name, and
+// unknown keys are reported so typos don't go unnoticed.
+func unmarshalYAML(data []byte, v any) error {
+	tree, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	return decodeYAML(reflectValue(v), tree, "")
+}
+
+// reflectValue returns the value pointed to by v, for decodeYAML
+func reflectValue(v any) reflect.Value {
+	return reflect.ValueOf(v).Elem()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decodeYAML assigns a parsed node to a typed value
+func decodeYAML(v reflect.Value, node any, path string) error {
+	if node == nil {
+		return nil
+	}
+
+	where := func() string {
+		if path == "" {
+			return "document"
+		}
+		return path
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeYAML(v.Elem(), node, path)
+	}
+	if v.Kind() == reflect.Interface {
+		v.Set(reflect.ValueOf(node))
+		return nil
+	}
+
+	switch n := node.(type) {
+	case map[string]any:
+		switch v.Kind() {
+		case reflect.Struct:
+			return decodeYAMLStruct(v, n, path)
+		case reflect.Map:
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			for key, child := range n {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := decodeYAML(elem, child, joinYAMLPath(path, key)); err != nil {
+					return err
+				}
+				v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			}
+			return nil
+		}
+		return fmt.Errorf("%s: expected a single value, found a mapping", where())
+
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("%s: expected a single value, found a list", where())
+		}
+		slice := reflect.MakeSlice(v.Type(), len(n), len(n))
+		for i, child := range n {
+			if err := decodeYAML(slice.Index(i), child, fmt.Sprintf("%s[%d]", where(), i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case string:
+		return decodeYAMLScalar(v, n, where())
+	}
+
+	return fmt.Errorf("%s: unsupported value", where())
+}
+
+func decodeYAMLStruct(v reflect.Value, node map[string]any, path string) error {
+	fields := map[string]int{}
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		name := yamlFieldName(v.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		fields[name] = i
+		names = append(names, name)
+	}
+
+	for key, child := range node {
+		i, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown key %q%s.%s", key, inYAMLPath(path), suggest.DidYouMean(key, names, ""))
+		}
+		if err := decodeYAML(v.Field(i), child, joinYAMLPath(path, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeYAMLScalar(v reflect.Value, s, where string) error {
+	// A single value is accepted where a list is expected
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		return decodeYAML(v, []any{s}, where)
+	}
+
+	switch {
+	case v.Type() == durationType:
+		if s == "0" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := parseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", where, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == reflect.TypeOf(time.Time{}):
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an RFC 3339 time", where, s)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseStrictBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", where, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a whole number", where, s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a positive whole number", where, s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", where, s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%s: expected a mapping or list, found %q", where, s)
+	}
+	return nil
+}
+
+// yamlFieldName returns the key of a struct field, "" for fields that
+// aren't read from YAML
+func yamlFieldName(field reflect.StructField) string {
+	name := field.Tag.Get("yaml")
+	if !field.IsExported() || name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// marshalYAML writes v in the subset of YAML that unmarshalYAML reads.
+// Unset pointers, empty maps and lists, and binary data are left out.
+func marshalYAML(v any) []byte {
+	var b strings.Builder
+	writeYAMLMapping(&b, reflect.ValueOf(v), 0)
+	return []byte(b.String())
+}
+
+// writeYAMLMapping writes the fields of a struct or the entries of a map
+func writeYAMLMapping(b *strings.Builder, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name := yamlFieldName(v.Type().Field(i)); name != "" {
+				writeYAMLEntry(b, name, v.Field(i), indent)
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, key := range keys {
+			writeYAMLEntry(b, key.String(), v.MapIndex(key), indent)
+		}
+	}
+}
+
+// writeYAMLEntry writes a key and its value
+func writeYAMLEntry(b *strings.Builder, key string, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	pad := strings.Repeat("  ", indent)
+
+	switch {
+	case v.Type() == durationType || v.Type() == reflect.TypeOf(time.Time{}):
+	case v.Kind() == reflect.Struct:
+		fmt.Fprintf(b, "%s%s:\n", pad, key)
+		writeYAMLMapping(b, v, indent+1)
+		return
+	case v.Kind() == reflect.Map:
+		if v.Len() > 0 {
+			fmt.Fprintf(b, "%s%s:\n", pad, key)
+			writeYAMLMapping(b, v, indent+1)
+		}
+		return
+	case v.Kind() == reflect.Slice:
+		if v.Len() == 0 || v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		fmt.Fprintf(b, "%s%s:\n", pad, key)
+		for i := range v.Len() {
+			item := v.Index(i)
+			for item.Kind() == reflect.Pointer {
+				item = item.Elem()
+			}
+			if item.Kind() != reflect.Struct {
+				fmt.Fprintf(b, "%s  - %s\n", pad, yamlScalar(item))
+				continue
+			}
+			// The first field goes on the line of the dash
+			var fields strings.Builder
+			writeYAMLMapping(&fields, item, indent+2)
+			text := fields.String()
+			if len(text) >= len(pad)+4 {
+				text = pad + "  - " + text[len(pad)+4:]
+			}
+			b.WriteString(text)
+		}
+		return
+	}
+	fmt.Fprintf(b, "%s%s: %s\n", pad, key, yamlScalar(v))
+}
+
+// yamlScalar formats a single value, quoting strings YAML would read
+// differently
+func yamlScalar(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		if v.Int() == 0 {
+			return "0"
+		}
+		return time.Duration(v.Int()).String()
+	case v.Type() == reflect.TypeOf(time.Time{}):
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	case v.Kind() != reflect.String:
+		return fmt.Sprint(v.Interface())
+	}
+
+	s := v.String()
+	if s == "" || s == "~" || s == "null" || strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsAny(s, "\n\t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func joinYAMLPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func inYAMLPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
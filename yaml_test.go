@@ -0,0 +1,247 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{"empty", "", nil},
+		{"comments only", "# nothing\n\n  # here\n", nil},
+		{"scalars", "a: 1\nb: two words\nc: ~\nd: null\ne:\n", map[string]any{"a": "1", "b": "two words", "c": nil, "d": nil, "e": nil}},
+		{"document markers", "---\na: 1\n...\n", map[string]any{"a": "1"}},
+		{"quoted indicators", "a: '*.log'\nb: \"&x\"\nc: [\"!x\", '{y}']\nd: x*y", map[string]any{"a": "*.log", "b": "&x", "c": []any{"!x", "{y}"}, "d": "x*y"}},
+		{"comments", "a: 1 # one\n# whole line\nb: x#y\n", map[string]any{"a": "1", "b": "x#y"}},
+		{"double quoted", `a: "x: y # not a comment"` + "\n" + `b: "tab\there \"q\""`,
+			map[string]any{"a": "x: y # not a comment", "b": "tab\there \"q\""}},
+		{"single quoted", "a: 'it''s # here'\nb: ''", map[string]any{"a": "it's # here", "b": ""}},
+		{"quoted key", `"a: b": 1` + "\n" + `'c d': 2`, map[string]any{"a: b": "1", "c d": "2"}},
+		{"colon in value", "url: https://example.com:8080/x", map[string]any{"url": "https://example.com:8080/x"}},
+		{"nested maps", "a:\n  b:\n    c: 1\n  d: 2\ne: 3",
+			map[string]any{"a": map[string]any{"b": map[string]any{"c": "1"}, "d": "2"}, "e": "3"}},
+		{"list", "- a\n- 'b'\n-\n", []any{"a", "b", nil}},
+		{"list under key", "a:\n  - 1\n  - 2\n", map[string]any{"a": []any{"1", "2"}}},
+		{"list at key indentation", "a:\n- 1\n- 2\nb: 3", map[string]any{"a": []any{"1", "2"}, "b": "3"}},
+		{"list of maps", "steps:\n  - title: one\n    wait: click\n  - title: two\n",
+			map[string]any{"steps": []any{map[string]any{"title": "one", "wait": "click"}, map[string]any{"title": "two"}}}},
+		{"nested lists", "- - a\n  - b\n- c\n", []any{[]any{"a", "b"}, "c"}},
+		{"flow list", `a: [x, "y, z", 'w']` + "\nb: []", map[string]any{"a": []any{"x", "y, z", "w"}, "b": []any{}}},
+		{"literal", "a: |\n  one\n   two\n\n  three\nb: 1", map[string]any{"a": "one\n two\n\nthree\n", "b": "1"}},
+		{"literal strip", "a: |-\n  one\n  two\n", map[string]any{"a": "one\ntwo"}},
+		{"folded", "a: >\n  one\n  two\n\n  three\n", map[string]any{"a": "one two\nthree\n"}},
+		{"windows newlines", "a: 1\r\nb: 2\r\n", map[string]any{"a": "1", "b": "2"}},
+		{"lone scalar", "hello", "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name, in, err string
+	}{
+		{"tab indentation", "a:\n\tb: 1", "line 2: tabs are not allowed"},
+		{"deeper key", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"deeper item", "- a\n  - b", "line 2: unexpected indentation"},
+		{"dedented trailer", "a:\n    b: 1\n  c: 2", "line 3: unexpected indentation"},
+		{"not a key", "a: 1\nb", `line 2: expected "key: value"`},
+		{"duplicate key", "a: 1\na: 2", `line 2: duplicate key "a"`},
+		{"unterminated list", "a: [1, 2", "line 1: unterminated [ list"},
+		{"bad double quotes", `a: "x" y`, "line 1: bad double-quoted string"},
+		{"bad escape", `a: "\q"`, "line 1: bad double-quoted string"},
+		{"bad single quotes", "a: 'x' y", "line 1: bad single-quoted string"},
+		{"anchor", "base: &base\n  a: 1", "line 1: anchors are not supported"},
+		{"alias", "a: 1\nb: *base", "line 2: aliases are not supported, quote the value"},
+		{"merge key", "a:\n  <<: *base", "line 2: aliases are not supported"},
+		{"tag", "a: !!str 1", "line 1: tags are not supported"},
+		{"flow mapping", "a: {b: 1}", "line 1: flow mappings are not supported"},
+		{"flow mapping item", "- {b: 1}", "line 1: flow mappings are not supported"},
+		{"flow mapping in list", "a: [x, {b: 1}]", "line 1: flow mappings are not supported"},
+		{"alias in list", "a: [x, *y]", "line 1: aliases are not supported"},
+		{"anchored key", "&k a: 1", "line 1: anchors are not supported, quote the key"},
+		{"reserved", "a: @x", "line 1: values starting with @ are not supported"},
+		{"complex key", "? a\n: 1", "line 1: complex keys are not supported"},
+		{"directive", "%YAML 1.2\n---\na: 1", "line 1: directives are not supported"},
+		{"second document", "a: 1\n---\nb: 2", "line 2: multiple documents are not supported"},
+		{"after the end", "a: 1\n...\nb: 2", "line 3: multiple documents are not supported"},
+		{"keep chomping", "a: |+\n  x\n", `line 1: block scalar header "|+" is not supported`},
+		{"indentation indicator", "a: >2\n   x\n", `line 1: block scalar header ">2" is not supported`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("parseYAML() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// yamlTest exercises the types unmarshalYAML and marshalYAML support
+type yamlTest struct {
+	Name     string            `yaml:"name"`
+	Count    int               `yaml:"count"`
+	Size     uint              `yaml:"size"`
+	Ratio    float64           `yaml:"ratio"`
+	On       bool              `yaml:"on"`
+	Every    time.Duration     `yaml:"every"`
+	At       time.Time         `yaml:"at"`
+	Limit    *int              `yaml:"limit"`
+	Tags     []string          `yaml:"tags"`
+	Labels   map[string]string `yaml:"labels"`
+	Steps    []yamlTestStep    `yaml:"steps"`
+	Nested   yamlTestStep      `yaml:"nested"`
+	Data     []byte            `yaml:"data"`
+	Untagged string
+	Skipped  string `yaml:"-"`
+}
+
+type yamlTestStep struct {
+	Title string        `yaml:"title"`
+	Wait  time.Duration `yaml:"wait"`
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	in := `
+name: backup
+count: -3
+size: 7
+ratio: 2.5
+on: yes
+every: 1d
+at: 2026-10-16T08:30:00Z
+limit: 0
+tags: nightly
+labels:
+  team: ops
+  tier: "1"
+steps:
+  - title: Start
+    wait: 90s
+  - title: Done
+nested:
+  title: inner
+untagged: plain
+`
+	var got yamlTest
+	if err := unmarshalYAML([]byte(in), &got); err != nil {
+		t.Fatal(err)
+	}
+	zero := 0
+	want := yamlTest{
+		Name: "backup", Count: -3, Size: 7, Ratio: 2.5, On: true,
+		Every:    24 * time.Hour,
+		At:       time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC),
+		Limit:    &zero,
+		Tags:     []string{"nightly"},
+		Labels:   map[string]string{"team": "ops", "tier": "1"},
+		Steps:    []yamlTestStep{{Title: "Start", Wait: 90 * time.Second}, {Title: "Done"}},
+		Nested:   yamlTestStep{Title: "inner"},
+		Untagged: "plain",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unmarshalYAML() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name, in, err string
+	}{
+		{"unknown key", "nmae: x", `unknown key "nmae". Did you mean name?`},
+		{"unknown nested key", "nested:\n  titel: x", `unknown key "titel" in nested.`},
+		{"skipped field", "skipped: x", `unknown key "skipped"`},
+		{"bad number", "count: many", `count: "many" is not a whole number`},
+		{"negative size", "size: -1", `size: "-1" is not a positive whole number`},
+		{"bad float", "ratio: half", `ratio: "half" is not a number`},
+		{"bad bool", "on: maybe", `on: "maybe" is not a boolean`},
+		{"bad duration", "every: soon", "every: \"soon\" is not a positive duration"},
+		{"bad time", "at: today", `at: "today" is not an RFC 3339 time`},
+		{"bad list item", "steps:\n  - title: x\n    wait: -1s", "steps[0].wait"},
+		{"list for scalar", "name: [a, b]", "name: expected a single value, found a list"},
+		{"mapping for scalar", "name:\n  first: a", "name: expected a single value, found a mapping"},
+		{"scalar for mapping", "nested: x", `nested: expected a mapping or list, found "x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v yamlTest
+			err := unmarshalYAML([]byte(tt.in), &v)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("unmarshalYAML() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMarshalYAMLRoundTrip(t *testing.T) {
+	limit := 5
+	in := yamlTest{
+		Name:   "- starts like an item: with # a comment",
+		Count:  -1,
+		Ratio:  0.25,
+		On:     true,
+		Every:  90 * time.Minute,
+		At:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Limit:  &limit,
+		Tags:   []string{"a", "null", " padded ", "line\nbreak"},
+		Labels: map[string]string{"b": "'quoted'", "a": "~"},
+		Steps:  []yamlTestStep{{Title: "one", Wait: time.Second}, {Title: "[two]"}},
+		Nested: yamlTestStep{Title: "x: y"},
+		Data:   []byte("binary data is left out"),
+	}
+
+	data := marshalYAML(&in)
+	var out yamlTest
+	if err := unmarshalYAML(data, &out); err != nil {
+		t.Fatalf("unmarshalYAML() = %v of\n%s", err, data)
+	}
+	in.Data = nil
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("round trip = %+v, want %+v, through\n%s", out, in, data)
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	v := struct {
+		Name  string            `yaml:"name"`
+		Empty []string          `yaml:"empty"`
+		None  map[string]string `yaml:"none"`
+		Unset *int              `yaml:"unset"`
+		Wait  time.Duration     `yaml:"wait"`
+		Steps []yamlTestStep    `yaml:"steps"`
+	}{
+		Name:  "x",
+		Steps: []yamlTestStep{{Title: "a", Wait: time.Minute}},
+	}
+	want := "name: x\nwait: 0\nsteps:\n  - title: a\n    wait: 1m0s\n"
+	if got := string(marshalYAML(v)); got != want {
+		t.Fatalf("marshalYAML() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDefaultConfigRoundTrip(t *testing.T) {
+	config := defaultConfig()
+	var out Config
+	if err := unmarshalYAML(marshalYAML(config), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&out, config) {
+		t.Fatalf("round trip of the default config = %+v, want %+v", out, *config)
+	}
+}